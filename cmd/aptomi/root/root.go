@@ -46,6 +46,9 @@ func init() {
 	common.AddStringFlag(Command, "ui.schema", "ui-schema", "", "http", envPrefix+"_SCHEMA", "Server UI schema")
 	common.AddBoolFlag(Command, "ui.enable", "ui", "", true, envPrefix+"_UI", "Enable server to serve UI")
 	common.AddDurationFlag(Command, "enforcer.interval", "enforcer-interval", "", 60*time.Second, envPrefix+"_ENFORCER_INTERVAL", "Enforcer interval")
+	common.AddDurationFlag(Command, "enforcer.debounce", "enforcer-debounce", "", 2*time.Second, envPrefix+"_ENFORCER_DEBOUNCE", "Time to wait for more enforcement triggers before enforcement starts")
+	common.AddDurationFlag(Command, "enforcer.maxdelay", "enforcer-max-delay", "", 10*time.Second, envPrefix+"_ENFORCER_MAX_DELAY", "Max time enforcement can be delayed by waiting for more triggers")
+	common.AddDurationFlag(Command, "enforcer.watchinterval", "enforcer-watch-interval", "", 10*time.Second, envPrefix+"_ENFORCER_WATCH_INTERVAL", "Interval of checking users, secrets and cluster health for changes")
 	common.AddStringFlag(Command, "profile.cpu", "cpuprofile", "", "", envPrefix+"_CPU_PROFILE", "File to write debug CPU profiling information using Go runtime/pprof")
 	common.AddStringFlag(Command, "profile.trace", "traceprofile", "", "", envPrefix+"_TRACE_PROFILE", "File to write debug tracing information using Go runtime/trace")
 
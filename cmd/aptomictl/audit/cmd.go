@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/cmd/common"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// NewCommand returns cobra command for audit subcommand
+func NewCommand(cfg *config.Client) *cobra.Command {
+	filter := &engine.AuditFilter{}
+	var from, to string
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show audit log",
+		Long:  "Show audit log of all policy changes, actual state resets and logins (including failed attempts)",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			filter.From = parseTime(from)
+			filter.To = parseTime(to)
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).Audit().Show(filter)
+			if err != nil {
+				log.Fatalf("error while showing audit log: %s", err)
+			}
+
+			if len(result.Entries) == 0 {
+				fmt.Println("No audit entries found")
+				return
+			}
+
+			entries := make([]runtime.Displayable, len(result.Entries))
+			for idx, entry := range result.Entries {
+				entries[idx] = entry
+			}
+
+			data, err := common.Format(cfg.Output, true, entries...)
+			if err != nil {
+				log.Fatalf("error while formatting audit log: %s", err)
+			}
+			fmt.Println(string(data))
+		},
+	}
+
+	cmd.Flags().StringVarP(&filter.User, "user", "u", "", "Show only entries for operations performed by the given user")
+	cmd.Flags().StringVarP(&filter.Namespace, "namespace", "n", "", "Show only entries affecting objects in the given namespace")
+	cmd.Flags().StringVarP(&filter.Kind, "kind", "k", "", "Show only entries affecting objects of the given kind")
+	cmd.Flags().StringVar(&from, "from", "", "Show only entries created after the given time (RFC3339, e.g. 2017-11-01T00:00:00Z)")
+	cmd.Flags().StringVar(&to, "to", "", "Show only entries created before the given time (RFC3339, e.g. 2017-11-01T00:00:00Z)")
+	cmd.Flags().IntVarP(&filter.Limit, "limit", "l", 100, "Show only the given number of the most recent entries (use --to to see older ones)")
+
+	return cmd
+}
+
+func parseTime(value string) time.Time {
+	if len(value) == 0 {
+		return time.Time{}
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Fatalf("error while parsing time '%s': %s", value, err)
+	}
+	return result
+}
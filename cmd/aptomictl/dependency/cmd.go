@@ -16,6 +16,7 @@ func NewCommand(cfg *config.Client) *cobra.Command {
 	cmd.AddCommand(
 		newStatusCommand(cfg),
 		newEndpointsCommand(cfg),
+		newMigrateCommand(cfg),
 	)
 
 	return cmd
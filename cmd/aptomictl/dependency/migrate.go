@@ -0,0 +1,65 @@
+package dependency
+
+import (
+	"github.com/Aptomi/aptomi/cmd/aptomictl/io"
+	"github.com/Aptomi/aptomi/cmd/aptomictl/util"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+func newMigrateCommand(cfg *config.Client) *cobra.Command {
+	paths := make([]string, 0)
+	var wait bool
+	var waitInterval time.Duration
+	var waitAttempts int
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "dependency migrate",
+		Long:  "Move dependencies on sticky services to the placement (context, cluster, allocation keys) calculated from the current policy",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			allObjects, err := io.ReadLangObjects(paths)
+			if err != nil {
+				log.Fatalf("error while reading policy files: %s", err)
+			}
+
+			dependencies := []*lang.Dependency{}
+			for _, obj := range allObjects {
+				if d, ok := obj.(*lang.Dependency); ok {
+					dependencies = append(dependencies, d)
+				}
+			}
+			if len(dependencies) <= 0 {
+				log.Fatalf("no dependencies found in %s", paths)
+			}
+
+			// call API, get policy update result
+			clientObj := rest.New(cfg, http.NewClient(cfg))
+			result, err := clientObj.Dependency().Migrate(dependencies)
+			if err != nil {
+				log.Fatalf("error while requesting dependency migration: %s", err)
+			}
+
+			// print policy update result to the screen
+			util.PrintPolicyUpdateResult(result, log.WarnLevel, cfg)
+
+			// wait for actions to finish, if needed
+			if wait {
+				util.WaitForRevisionActionsToFinish(waitAttempts, waitInterval, clientObj, result)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&paths, "policyPaths", "f", make([]string, 0), "Paths to files/dirs with dependency files")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait until all actions are fully applied")
+	cmd.Flags().DurationVar(&waitInterval, "wait-interval", 2*time.Second, "Seconds to sleep between wait attempts")
+	cmd.Flags().IntVar(&waitAttempts, "wait-attempts", 150, "Number of wait attempts before failing the wait process")
+
+	return cmd
+}
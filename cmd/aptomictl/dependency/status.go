@@ -14,6 +14,7 @@ import (
 	"github.com/gosuri/uilive"
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
+	"strings"
 	"time"
 )
 
@@ -23,6 +24,8 @@ func newStatusCommand(cfg *config.Client) *cobra.Command {
 	var waitInterval time.Duration
 	var waitAttempts int
 	var waitFlag string
+	var showOutputs bool
+	var showDetails bool
 
 	cmd := &cobra.Command{
 		Use:   "status",
@@ -51,18 +54,18 @@ func newStatusCommand(cfg *config.Client) *cobra.Command {
 
 			if !wait {
 				// query dependency status and print a one-time table with current results
-				_, result = printStatusOfDependencies(cfg, dependencies, api.DependencyQueryFlag(waitFlag), writer, -1)
+				_, result = printStatusOfDependencies(cfg, dependencies, api.DependencyQueryFlag(waitFlag), writer, -1, showOutputs, showDetails)
 			} else {
 				// print live updates until dependencies are ready or timeout happens
 				attempt := 0
 				retry.Do(waitAttempts, waitInterval, func() bool {
-					keepWaiting, _ := printStatusOfDependencies(cfg, dependencies, api.DependencyQueryFlag(waitFlag), writer, attempt) // nolint: gas
+					keepWaiting, _ := printStatusOfDependencies(cfg, dependencies, api.DependencyQueryFlag(waitFlag), writer, attempt, showOutputs, showDetails) // nolint: gas
 					attempt++
 					return !keepWaiting
 				})
 
 				// print final results
-				_, result = printStatusOfDependencies(cfg, dependencies, api.DependencyQueryFlag(waitFlag), writer, -1)
+				_, result = printStatusOfDependencies(cfg, dependencies, api.DependencyQueryFlag(waitFlag), writer, -1, showOutputs, showDetails)
 			}
 
 			// stop live updates
@@ -82,12 +85,14 @@ func newStatusCommand(cfg *config.Client) *cobra.Command {
 	)
 	cmd.Flags().DurationVar(&waitInterval, "wait-interval", 2*time.Second, "Seconds to sleep between wait attempts")
 	cmd.Flags().IntVar(&waitAttempts, "wait-attempts", 150, "Number of wait attempts before failing the wait process")
+	cmd.Flags().BoolVar(&showOutputs, "outputs", false, "Print outputs, which services delivered back to dependencies (sensitive outputs are shown only to dependency owner)")
+	cmd.Flags().BoolVar(&showDetails, "details", false, "Print status details recorded by the enforcer (contract, context, cluster, component instances and time of the last phase transition)")
 
 	return cmd
 }
 
 // TODO: ideally we should use common.Format() here to support writing into json and yaml, but runtime.Displayable() doesn't blend too well with an external state (i.e. dKey, waitFlag, attempt) as well as maps and sorted keys
-func printStatusOfDependencies(cfg *config.Client, dependencies []*lang.Dependency, waitFlag api.DependencyQueryFlag, writer *uilive.Writer, attempt int) (bool, error) { // nolint: interfacer
+func printStatusOfDependencies(cfg *config.Client, dependencies []*lang.Dependency, waitFlag api.DependencyQueryFlag, writer *uilive.Writer, attempt int, showOutputs bool, showDetails bool) (bool, error) { // nolint: interfacer
 	result, errAPI := rest.New(cfg, http.NewClient(cfg)).Dependency().Status(dependencies, waitFlag)
 	if errAPI != nil {
 		panic(fmt.Sprintf("error while requesting dependency status: %s", errAPI))
@@ -111,27 +116,89 @@ func printStatusOfDependencies(cfg *config.Client, dependencies []*lang.Dependen
 		}
 	}
 	fmt.Fprint(writer, table, "\n")
+
+	if showOutputs {
+		fmt.Fprint(writer, getOutputsTable(result), "\n")
+	}
+	if showDetails {
+		fmt.Fprint(writer, getDetailsTable(result), "\n")
+	}
 	return keepWaiting, err
 }
 
+func getDetailsTable(result *api.DependenciesStatus) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 120
+	table.Wrap = true
+	table.AddRow("DEPENDENCY", "CONTRACT", "CONTEXT", "CLUSTER", "SINCE", "COMPONENTS")
+	for _, dKey := range util.GetSortedStringKeys(result.Status) {
+		recorded := result.Status[dKey].Recorded
+		if recorded == nil {
+			continue
+		}
+		table.AddRow(dKey, recorded.Contract, recorded.Context, recorded.Cluster, recorded.LastTransitionTime.Format(time.RFC3339), strings.Join(recorded.ComponentKeys, "\n"))
+	}
+	return table
+}
+
+func getOutputsTable(result *api.DependenciesStatus) *uitable.Table {
+	table := uitable.New()
+	table.MaxColWidth = 120
+	table.Wrap = true
+	table.AddRow("DEPENDENCY", "OUTPUT", "VALUE")
+	for _, dKey := range util.GetSortedStringKeys(result.Status) {
+		outputs := result.Status[dKey].Outputs
+		for _, name := range util.GetSortedStringKeys(outputs) {
+			table.AddRow(dKey, name, outputs[name])
+		}
+	}
+	return table
+}
+
 func getHeader(waitFlag api.DependencyQueryFlag) []interface{} {
-	result := []interface{}{"DEPENDENCY", "FOUND", "DEPLOYED"}
+	result := []interface{}{"DEPENDENCY", "VERSION", "FOUND", "DEPLOYED"}
 	if waitFlag == api.DependencyQueryDeploymentStatusAndReadiness {
 		result = append(result, "READY")
 	}
+	result = append(result, "PHASE", "MESSAGE")
 	return result
 }
 
 func getRow(dKey string, dStatus *api.DependencyStatus, waitFlag api.DependencyQueryFlag, attempt int) []interface{} {
-	result := []interface{}{dKey, getFoundStr(dStatus), getDeployedStr(dStatus, attempt)}
+	result := []interface{}{dKey, getVersionStr(dStatus), getFoundStr(dStatus), getDeployedStr(dStatus, attempt)}
 	if waitFlag == api.DependencyQueryDeploymentStatusAndReadiness {
 		result = append(result, getReadyStr(dStatus, attempt))
 	}
+	result = append(result, getPhaseStr(dStatus), getMessageStr(dStatus))
 	return result
 }
 
 const spinner = "|/-\\"
 
+func getVersionStr(dsi *api.DependencyStatus) string {
+	if len(dsi.ContractVersion) <= 0 {
+		return "-"
+	}
+	if len(dsi.Deprecated) > 0 {
+		return fmt.Sprintf("%s (deprecated: %s)", dsi.ContractVersion, dsi.Deprecated)
+	}
+	return dsi.ContractVersion
+}
+
+func getPhaseStr(dsi *api.DependencyStatus) string {
+	if dsi.Recorded == nil {
+		return "-"
+	}
+	return dsi.Recorded.Phase
+}
+
+func getMessageStr(dsi *api.DependencyStatus) string {
+	if dsi.Recorded == nil || len(dsi.Recorded.Message) <= 0 {
+		return "-"
+	}
+	return dsi.Recorded.Message
+}
+
 func getFoundStr(dsi *api.DependencyStatus) string {
 	if !dsi.Found {
 		return "no"
@@ -17,6 +17,9 @@ func NewCommand(cfg *config.Client) *cobra.Command {
 		newShowCommand(cfg),                       // show
 		newHandlePolicyChangesCommand(cfg, true),  // apply
 		newHandlePolicyChangesCommand(cfg, false), // delete
+		newImpactCommand(cfg),                     // impact
+		newTestCommand(cfg),                       // test
+		newLintCommand(cfg),                       // lint
 	)
 
 	return cmd
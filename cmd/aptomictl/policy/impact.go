@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/cmd/aptomictl/io"
+	"github.com/Aptomi/aptomi/cmd/common"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newImpactCommand(cfg *config.Client) *cobra.Command {
+	paths := make([]string, 0)
+	var deleted bool
+
+	cmd := &cobra.Command{
+		Use:   "impact",
+		Short: "policy impact",
+		Long:  "Show dependencies, users, component instances and clusters which would be impacted by the given changes in policy, grouped by create/update/delete. Changes don't get made",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			allObjects, err := io.ReadLangObjects(paths)
+			if err != nil {
+				log.Fatalf("error while reading policy files: %s", err)
+			}
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).Policy().Impact(allObjects, deleted)
+			if err != nil {
+				log.Fatalf("error while calculating impact of policy changes: %s", err)
+			}
+
+			if len(result.Warnings) > 0 {
+				fmt.Println("Warnings:")
+				for _, warning := range result.Warnings {
+					fmt.Printf("* %s\n", warning)
+				}
+			}
+
+			groups := []runtime.Displayable{}
+			for _, group := range result.Impact {
+				groups = append(groups, group)
+			}
+			data, err := common.Format(cfg.Output, true, groups...)
+			if err != nil {
+				log.Fatalf("error while formatting impact of policy changes: %s", err)
+			}
+			fmt.Println(string(data))
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&paths, "policyPaths", "f", make([]string, 0), "Paths to files/dirs with policy files")
+	if err := cmd.MarkFlagRequired("policyPaths"); err != nil {
+		panic(err)
+	}
+	cmd.Flags().BoolVar(&deleted, "delete", false, "Calculate impact of deleting the given objects from policy, instead of creating/updating them")
+
+	return cmd
+}
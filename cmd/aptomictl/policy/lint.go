@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/cmd/aptomictl/io"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/external/users"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/lint"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func newLintCommand(cfg *config.Client) *cobra.Command { // nolint: unparam
+	paths := make([]string, 0)
+	userPaths := make([]string, 0)
+	labelKeys := make([]string, 0)
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "policy lint",
+		Long:  "Check local policy files for unreachable contexts, unused objects, rules which can never match and conflicting label operations",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			allObjects, err := io.ReadLangObjects(paths)
+			if err != nil {
+				log.Fatalf("error while reading policy files: %s", err)
+			}
+
+			policy := lang.NewPolicy()
+			for _, obj := range allObjects {
+				langObj, ok := obj.(lang.Base)
+				if !ok {
+					log.Fatalf("object is not a policy object: %s", obj.GetKind())
+				}
+				err = policy.AddObject(langObj)
+				if err != nil {
+					log.Fatalf("error while adding object into the policy: %s", err)
+				}
+			}
+
+			err = policy.Validate()
+			if err != nil {
+				log.Fatalf("policy is invalid: %s", err)
+			}
+
+			// label keys of users are not known from the policy, so they are taken from user files
+			for _, userPath := range userPaths {
+				for _, user := range users.NewUserLoaderFromFile(userPath, nil).LoadUsersAll().Users {
+					for key := range user.Labels {
+						labelKeys = append(labelKeys, key)
+					}
+				}
+			}
+
+			warnings := lint.NewLinter(policy, labelKeys).Lint()
+			for _, warning := range warnings {
+				fmt.Println(warning)
+			}
+			if len(warnings) > 0 {
+				fmt.Printf("%d problem(s) found\n", len(warnings))
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&paths, "policyPaths", "f", make([]string, 0), "Paths to files/dirs with policy files")
+	if err := cmd.MarkFlagRequired("policyPaths"); err != nil {
+		panic(err)
+	}
+	cmd.Flags().StringSliceVarP(&userPaths, "users", "u", make([]string, 0), "Paths to files with users, to get the keys of user labels from")
+	cmd.Flags().StringSliceVar(&labelKeys, "label-keys", make([]string, 0), "Keys of labels which are set outside of the policy (e.g. user labels)")
+
+	return cmd
+}
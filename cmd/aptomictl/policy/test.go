@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"github.com/Aptomi/aptomi/cmd/aptomictl/io"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine/policytest"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func newTestCommand(cfg *config.Client) *cobra.Command { // nolint: unparam
+	paths := make([]string, 0)
+	testPaths := make([]string, 0)
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "policy test",
+		Long:  "Resolve dependencies from test case files against local policy files (without contacting the server) and check expected results",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			allObjects, err := io.ReadLangObjects(paths)
+			if err != nil {
+				log.Fatalf("error while reading policy files: %s", err)
+			}
+
+			objects := make([]lang.Base, 0, len(allObjects))
+			for _, obj := range allObjects {
+				langObj, ok := obj.(lang.Base)
+				if !ok {
+					log.Fatalf("object is not a policy object: %s", obj.GetKind())
+				}
+				objects = append(objects, langObj)
+			}
+
+			testCases, err := policytest.LoadTestCases(testPaths)
+			if err != nil {
+				log.Fatalf("error while reading test cases: %s", err)
+			}
+
+			results := policytest.Run(objects, testCases)
+			if !policytest.PrintResults(os.Stdout, results, verbose) {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&paths, "policyPaths", "f", make([]string, 0), "Paths to files/dirs with policy files")
+	if err := cmd.MarkFlagRequired("policyPaths"); err != nil {
+		panic(err)
+	}
+	cmd.Flags().StringSliceVarP(&testPaths, "testPaths", "t", make([]string, 0), "Paths to files/dirs with test case files")
+	if err := cmd.MarkFlagRequired("testPaths"); err != nil {
+		panic(err)
+	}
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print policy resolution log for failed test cases")
+
+	return cmd
+}
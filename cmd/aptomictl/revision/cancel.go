@@ -0,0 +1,30 @@
+package revision
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newCancelCommand(cfg *config.Client) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "revision cancel",
+		Long:  "Cancel the revision which is currently being applied. Running actions get stopped and all outstanding actions get skipped",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			result, err := rest.New(cfg, http.NewClient(cfg)).Revision().Cancel()
+
+			if err != nil {
+				log.Fatalf("error while cancelling revision: %s", err)
+			}
+
+			fmt.Printf("Cancellation of revision %d has been requested\n", result.GetGeneration())
+		},
+	}
+
+	return cmd
+}
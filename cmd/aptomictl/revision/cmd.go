@@ -15,6 +15,7 @@ func NewCommand(cfg *config.Client) *cobra.Command {
 
 	cmd.AddCommand(
 		newShowCommand(cfg),
+		newCancelCommand(cfg),
 	)
 
 	return cmd
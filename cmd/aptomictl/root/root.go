@@ -1,6 +1,7 @@
 package root
 
 import (
+	"github.com/Aptomi/aptomi/cmd/aptomictl/audit"
 	"github.com/Aptomi/aptomi/cmd/aptomictl/dependency"
 	"github.com/Aptomi/aptomi/cmd/aptomictl/gen"
 	"github.com/Aptomi/aptomi/cmd/aptomictl/login"
@@ -60,6 +61,7 @@ func init() {
 		dependency.NewCommand(Config),
 		policy.NewCommand(Config),
 		revision.NewCommand(Config),
+		audit.NewCommand(Config),
 		state.NewCommand(Config),
 		gen.NewCommand(Config),
 		version.NewCommand(Config),
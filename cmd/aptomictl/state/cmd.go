@@ -15,6 +15,8 @@ func NewCommand(cfg *config.Client) *cobra.Command {
 
 	cmd.AddCommand(
 		newEnforceCommand(cfg),
+		newPauseCommand(cfg),
+		newResumeCommand(cfg),
 	)
 
 	return cmd
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/util"
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newPauseCommand(cfg *config.Client) *cobra.Command {
+	var cluster string
+	var namespace string
+
+	cmd := &cobra.Command{
+		Use:   "pause",
+		Short: "state pause",
+		Long:  "Pause the enforcer, either completely or for a given cluster or namespace. Policy keeps getting resolved, but actions don't get applied until the enforcer is resumed",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			scope, name := pauseScope(cluster, namespace)
+			result, err := rest.New(cfg, http.NewClient(cfg)).State().Pause(scope, name)
+			if err != nil {
+				log.Fatalf("error while pausing enforcer: %s", err)
+			}
+
+			printEnforcerPause(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&cluster, "cluster", "", "Pause the enforcer only for component instances in a given cluster")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Pause the enforcer only for component instances in a given namespace")
+
+	return cmd
+}
+
+func newResumeCommand(cfg *config.Client) *cobra.Command {
+	var cluster string
+	var namespace string
+
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "state resume",
+		Long:  "Resume the enforcer. If neither cluster nor namespace is specified, the enforcer gets resumed for everything that has been paused",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			scope, name := pauseScope(cluster, namespace)
+			result, err := rest.New(cfg, http.NewClient(cfg)).State().Resume(scope, name)
+			if err != nil {
+				log.Fatalf("error while resuming enforcer: %s", err)
+			}
+
+			printEnforcerPause(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&cluster, "cluster", "", "Resume the enforcer only for component instances in a given cluster")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Resume the enforcer only for component instances in a given namespace")
+
+	return cmd
+}
+
+// pauseScope returns pause scope and name for the given flags. Empty scope means the whole enforcer
+func pauseScope(cluster string, namespace string) (string, string) {
+	if len(cluster) > 0 && len(namespace) > 0 {
+		log.Fatalf("only one of cluster or namespace can be specified")
+	}
+	if len(cluster) > 0 {
+		return engine.PauseScopeCluster, cluster
+	}
+	if len(namespace) > 0 {
+		return engine.PauseScopeNamespace, namespace
+	}
+	return "", ""
+}
+
+func printEnforcerPause(pause *engine.EnforcerPause) {
+	if !pause.IsPaused() {
+		fmt.Println("Enforcer is running")
+		return
+	}
+
+	fmt.Println("Enforcer is paused for:")
+	for _, scopeKey := range util.GetSortedStringKeys(pause.Paused) {
+		record := pause.Paused[scopeKey]
+		fmt.Printf("  %s (by %s at %s)\n", scopeKey, record.User, record.PausedAt.Format("2006-01-02 15:04:05"))
+	}
+	if !pause.CheckedAt.IsZero() {
+		fmt.Printf("Actions held back: %d (as of %s)\n", pause.PendingActions, pause.CheckedAt.Format("2006-01-02 15:04:05"))
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/engine/progress"
 	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Aptomi/aptomi/pkg/util/retry"
 	log "github.com/Sirupsen/logrus"
 	"time"
@@ -55,8 +56,8 @@ func WaitForRevisionActionsToFinish(attempts int, interval time.Duration, client
 			}
 		}
 
-		// exit when revision is in completed or error status
-		return rev.Status == engine.RevisionStatusCompleted || rev.Status == engine.RevisionStatusError
+		// exit when revision is in completed, error or cancelled status
+		return rev.Status == engine.RevisionStatusCompleted || rev.Status == engine.RevisionStatusError || rev.Status == engine.RevisionStatusCancelled
 	})
 
 	// stop progress bar
@@ -70,17 +71,32 @@ func WaitForRevisionActionsToFinish(attempts int, interval time.Duration, client
 	} else if rev.Status == engine.RevisionStatusCompleted {
 		if rev.Result.Total > 0 {
 			fmt.Printf("Revision %d completed. Actions: %d succeeded, %d failed, %d skipped\n", rev.GetGeneration(), rev.Result.Success, rev.Result.Failed, rev.Result.Skipped)
+			printRevisionPartitions(rev)
 		} else {
 			fmt.Printf("Revision %d completed\n", rev.GetGeneration())
 		}
 	} else if rev.Status == engine.RevisionStatusError {
 		log.Fatalf("Revision %d failed\n", rev.GetGeneration())
+	} else if rev.Status == engine.RevisionStatusCancelled {
+		printRevisionPartitions(rev)
+		log.Fatalf("Revision %d cancelled. Actions: %d succeeded, %d failed, %d skipped\n", rev.GetGeneration(), rev.Result.Success, rev.Result.Failed, rev.Result.Skipped)
 	} else {
 		log.Fatalf("Unexpected revision status '%s' for revision %d\n", rev.Status, rev.GetGeneration())
 	}
 
 }
 
+// printRevisionPartitions prints results for every partition of the revision, if there are more than one of them
+func printRevisionPartitions(rev *engine.Revision) {
+	if len(rev.Partitions) <= 1 {
+		return
+	}
+	for _, name := range util.GetSortedStringKeys(rev.Partitions) {
+		partition := rev.Partitions[name]
+		fmt.Printf("  %s: %s. Actions: %d succeeded, %d failed, %d skipped\n", name, partition.Status, partition.Result.Success, partition.Result.Failed, partition.Result.Skipped)
+	}
+}
+
 // PrintPolicyUpdateResult prints PolicyUpdateResult to the console
 func PrintPolicyUpdateResult(result *api.PolicyUpdateResult, logLevelObj log.Level, cfg *config.Client) { // nolint: interfacer
 	fmt.Printf("Event Log (>%s):\n", logLevelObj.String())
@@ -91,6 +107,12 @@ func PrintPolicyUpdateResult(result *api.PolicyUpdateResult, logLevelObj log.Lev
 	} else {
 		fmt.Println("* no entries")
 	}
+	if len(result.Warnings) > 0 {
+		fmt.Println("Warnings:")
+		for _, warning := range result.Warnings {
+			fmt.Printf("* %s\n", warning)
+		}
+	}
 	data, err := common.Format(cfg.Output, false, result)
 	if err != nil {
 		panic(fmt.Sprintf("error while formating policy update result: %s", err))
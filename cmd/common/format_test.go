@@ -61,6 +61,7 @@ func makePolicyUpdateResult(policyChanged bool) *api.PolicyUpdateResult {
 	key := resolve.NewComponentInstanceKey(
 		&lang.Cluster{Metadata: lang.Metadata{Name: "cluster"}},
 		&lang.Contract{Metadata: lang.Metadata{Name: "contract", Namespace: "ns"}},
+		nil,
 		&lang.Context{Name: "context"},
 		[]string{"keysresolved"},
 		&lang.Service{Metadata: lang.Metadata{Name: "service"}},
@@ -2,11 +2,13 @@ package api
 
 import (
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/engine/diff"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/trigger"
 	"github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
@@ -41,8 +43,13 @@ func (api *coreAPI) handleActualStateReset(writer http.ResponseWriter, request *
 		panic(fmt.Sprintf("error while loading current policy: %s", err))
 	}
 
-	// check that user is a domain admin
+	// record operation in the audit log, regardless of whether it succeeds or not
 	user := api.getUserRequired(request)
+	audit := api.newAuditEntry(request, engine.AuditActionStateReset, user.Name)
+	audit.PolicyGeneration = genCurrent
+	defer api.auditOnPanic(audit)
+
+	// check that user is a domain admin
 	if !isDomainAdmin(user, policy) {
 		panic(fmt.Sprintf("user is not allowed to perform actual state reset"))
 	}
@@ -53,20 +60,22 @@ func (api *coreAPI) handleActualStateReset(writer http.ResponseWriter, request *
 		noop = false
 	}
 
+	audit.Noop = noop
+
 	// If we are in noop mode
 	if noop {
 		// See that would happen if we reset the actual state, calculate and return resolution log + action plan
 		eventLog := event.NewLog(logrus.InfoLevel, "api-state-reset-noop").AddConsoleHook(api.logLevel)
-		desiredState := resolve.NewPolicyResolver(policy, api.externalData, eventLog).ResolveAllDependencies()
+		desiredState := api.newResolver(policy, eventLog).ResolveAllDependencies()
 		actionPlan := diff.NewPolicyResolutionDiff(desiredState, resolve.NewPolicyResolution(true)).ActionPlan
 
 		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: genCurrent,             // policy generation didn't change
-			PolicyChanged:    false,                  // policy has not been updated in the store
-			WaitForRevision:  runtime.MaxGeneration,  // nothing to wait for
-			PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
+			PolicyGeneration: genCurrent,                                 // policy generation didn't change
+			PolicyChanged:    false,                                      // policy has not been updated in the store
+			WaitForRevision:  runtime.MaxGeneration,                      // nothing to wait for
+			PlanAsText:       maskedPlanAsText(actionPlan, desiredState), // return action plan, so it can be printed by the client
+			EventLog:         maskedEventLog(eventLog, desiredState),     // return policy resolution log
 		})
 
 	} else {
@@ -78,7 +87,7 @@ func (api *coreAPI) handleActualStateReset(writer http.ResponseWriter, request *
 
 		// Calculate and return resolution log + action plan
 		eventLog := event.NewLog(logrus.InfoLevel, "api-state-reset").AddConsoleHook(api.logLevel)
-		desiredState := resolve.NewPolicyResolver(policy, api.externalData, eventLog).ResolveAllDependencies()
+		desiredState := api.newResolver(policy, eventLog).ResolveAllDependencies()
 		actionPlan := diff.NewPolicyResolutionDiff(desiredState, resolve.NewPolicyResolution(true)).ActionPlan
 
 		// If there are changes, we need to wait for the next revision
@@ -95,15 +104,15 @@ func (api *coreAPI) handleActualStateReset(writer http.ResponseWriter, request *
 
 		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: genCurrent,             // policy didn't change
-			PolicyChanged:    false,                  // have any policy object in the store been changed or not
-			WaitForRevision:  waitForRevision,        // which revision to wait for
-			PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
+			PolicyGeneration: genCurrent,                                 // policy didn't change
+			PolicyChanged:    false,                                      // have any policy object in the store been changed or not
+			WaitForRevision:  waitForRevision,                            // which revision to wait for
+			PlanAsText:       maskedPlanAsText(actionPlan, desiredState), // return action plan, so it can be printed by the client
+			EventLog:         maskedEventLog(eventLog, desiredState),     // return policy resolution log
 		})
 
-		// signal to the channel that actual state has changed, that will trigger the enforcement right away
-		api.runEnforcement <- true
+		// actual state has changed, so enqueue a trigger to run the enforcement right away
+		api.triggers.Enqueue(trigger.SourceAPI)
 	}
 
 }
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/trigger"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+func (api *coreAPI) handleActualStatePause(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	api.handleActualStatePauseChange(writer, request, params, true)
+}
+
+func (api *coreAPI) handleActualStateResume(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	api.handleActualStatePauseChange(writer, request, params, false)
+}
+
+func (api *coreAPI) handleActualStatePauseChange(writer http.ResponseWriter, request *http.Request, params httprouter.Params, paused bool) {
+	// Load current policy
+	policy, genCurrent, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading current policy: %s", err))
+	}
+
+	// record operation in the audit log, regardless of whether it succeeds or not
+	auditAction := engine.AuditActionStateResume
+	if paused {
+		auditAction = engine.AuditActionStatePause
+	}
+	user := api.getUserRequired(request)
+	audit := api.newAuditEntry(request, auditAction, user.Name)
+	audit.PolicyGeneration = genCurrent
+	defer api.auditOnPanic(audit)
+
+	// check that user is a domain admin
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to pause or resume the enforcer"))
+	}
+
+	// if scope is not specified, the whole enforcer gets paused (or resumed for all scopes)
+	var scopeKey string
+	if scope := params.ByName("scope"); len(scope) > 0 {
+		scopeKey, err = engine.PauseScopeKey(scope, params.ByName("name"))
+		if err != nil {
+			panic(fmt.Sprintf("invalid pause scope: %s", err))
+		}
+	} else if paused {
+		scopeKey = engine.PauseScopeAll
+	}
+	audit.Objects = append(audit.Objects, &engine.AuditObject{
+		Namespace: runtime.SystemNS,
+		Kind:      engine.EnforcerPauseObject.Kind,
+		Name:      scopeKey,
+	})
+
+	var pause *engine.EnforcerPause
+	if paused {
+		pause, err = api.store.PauseEnforcer(scopeKey, user.Name)
+	} else {
+		pause, err = api.store.ResumeEnforcer(scopeKey)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("error while changing enforcer pause: %s", err))
+	}
+
+	api.contentType.WriteOne(writer, request, pause)
+
+	// enforcer pause has changed, so enqueue a trigger to run the enforcement right away
+	api.triggers.Enqueue(trigger.SourceAPI)
+}
@@ -2,12 +2,15 @@ package api
 
 import (
 	"github.com/Aptomi/aptomi/pkg/api/codec"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/external"
 	"github.com/Aptomi/aptomi/pkg/plugin"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/runtime/store"
+	"github.com/Aptomi/aptomi/pkg/trigger"
 	"github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
+	"net"
 )
 
 type coreAPI struct {
@@ -15,22 +18,30 @@ type coreAPI struct {
 	store                 store.Core
 	externalData          *external.Data
 	pluginRegistryFactory plugin.RegistryFactory
+	resolverOptions       *resolve.Options
 	secret                string
+	trustedProxies        []*net.IPNet
 	logLevel              logrus.Level
-	runEnforcement        chan bool
+	triggers              *trigger.Queue
+	cancelEnforcement     chan runtime.Generation
+	lastResolution        *resolve.LastResolution
 }
 
 // Serve initializes everything needed by REST API and registers all API endpoints in the provided http router
-func Serve(router *httprouter.Router, store store.Core, externalData *external.Data, pluginRegistryFactory plugin.RegistryFactory, secret string, logLevel logrus.Level, runEnforcement chan bool) {
+func Serve(router *httprouter.Router, store store.Core, externalData *external.Data, pluginRegistryFactory plugin.RegistryFactory, resolverOptions *resolve.Options, secret string, trustedProxies []string, logLevel logrus.Level, triggers *trigger.Queue, cancelEnforcement chan runtime.Generation, lastResolution *resolve.LastResolution) {
 	contentTypeHandler := codec.NewContentTypeHandler(runtime.NewRegistry().Append(Objects...))
 	api := &coreAPI{
 		contentType:           contentTypeHandler,
 		store:                 store,
 		externalData:          externalData,
 		pluginRegistryFactory: pluginRegistryFactory,
+		resolverOptions:       resolverOptions,
 		secret:                secret,
+		trustedProxies:        parseTrustedProxies(trustedProxies),
 		logLevel:              logLevel,
-		runEnforcement:        runEnforcement,
+		triggers:              triggers,
+		cancelEnforcement:     cancelEnforcement,
+		lastResolution:        lastResolution,
 	}
 	api.serve(router)
 }
@@ -57,6 +68,10 @@ func (api *coreAPI) serve(router *httprouter.Router) {
 	router.DELETE("/api/v1/policy", auth(api.handlePolicyDelete))
 	router.DELETE("/api/v1/policy/noop/:noop/loglevel/:loglevel", auth(api.handlePolicyDelete))
 
+	// policy impact analysis (proposed changes don't get made)
+	router.POST("/api/v1/policy/impact", auth(api.handlePolicyImpact))
+	router.DELETE("/api/v1/policy/impact", auth(api.handlePolicyImpact))
+
 	// policy & object diagrams
 	router.GET("/api/v1/policy/diagram/object/:ns/:kind/:name", auth(api.handleObjectDiagram))
 	router.GET("/api/v1/policy/diagram/mode/:mode", auth(api.handlePolicyDiagram))
@@ -67,15 +82,33 @@ func (api *coreAPI) serve(router *httprouter.Router) {
 	router.GET("/api/v1/policy/dependency/status/:queryFlag/:idList", auth(api.handleDependencyStatusGet))
 	router.GET("/api/v1/policy/dependency/resources/:ns/:name", auth(api.handleDependencyResourcesGet))
 
+	// migrate dependencies on sticky services to a new placement
+	router.POST("/api/v1/policy/dependency/migrate", auth(api.handleDependencyMigrate))
+
+	// retrieve usage for all quotas
+	router.GET("/api/v1/policy/quota/usage", auth(api.handleQuotaUsageGet))
+
 	// retrieve revision (latest + by a given generation)
 	router.GET("/api/v1/revision", auth(api.handleRevisionGet))
 	router.GET("/api/v1/revision/gen/:gen", auth(api.handleRevisionGet))
 
+	// cancel revision which is currently being applied
+	router.DELETE("/api/v1/revision/current", auth(api.handleRevisionCancel))
+
 	// retrieve revision(s) (for a given policy)
 	router.GET("/api/v1/revisions/policy/:policy", auth(api.handleRevisionsGetByPolicy))
 
 	router.DELETE("/api/v1/actualstate/noop/:noop", auth(api.handleActualStateReset))
 
+	// pause and resume the enforcer (either completely, or for a given cluster or namespace)
+	router.POST("/api/v1/actualstate/pause", auth(api.handleActualStatePause))
+	router.POST("/api/v1/actualstate/pause/:scope/:name", auth(api.handleActualStatePause))
+	router.DELETE("/api/v1/actualstate/pause", auth(api.handleActualStateResume))
+	router.DELETE("/api/v1/actualstate/pause/:scope/:name", auth(api.handleActualStateResume))
+
+	// retrieve audit log entries (filtered by user, ns, kind, from, to query params)
+	router.GET("/api/v1/audit", auth(api.handleAuditGet))
+
 	// return aptomi version
 	router.GET("/version", api.handleVersion)
 	router.GET("/api/v1/version", api.handleVersion)
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultAuditLimit is the number of the most recent audit entries returned, if limit is not specified in the request
+const defaultAuditLimit = 100
+
+// AuditLogObject is an informational data structure with Kind and Constructor for AuditLog
+var AuditLogObject = &runtime.Info{
+	Kind:        "audit-log",
+	Constructor: func() runtime.Object { return &AuditLog{} },
+}
+
+// AuditLog represents a list of audit entries returned by the audit query
+type AuditLog struct {
+	runtime.TypeKind `yaml:",inline"`
+	Entries          []*engine.AuditEntry
+}
+
+// parseTrustedProxies parses a list of IPs and CIDRs of trusted proxies. Config validation ensures they are valid
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	result := []*net.IPNet{}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				panic(fmt.Sprintf("invalid trusted proxy IP: %s", proxy))
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			result = append(result, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(fmt.Sprintf("invalid trusted proxy CIDR: %s", err))
+		}
+		result = append(result, ipNet)
+	}
+	return result
+}
+
+// isTrustedProxy returns true if IP belongs to one of the trusted proxies
+func (api *coreAPI) isTrustedProxy(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range api.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSourceIP returns IP address of the client. X-Forwarded-For header can be set by anyone, so it's taken into
+// account only if request came from a trusted proxy. Header gets processed from right to left, skipping trusted proxies,
+// so the first untrusted address is the client
+func (api *coreAPI) getSourceIP(request *http.Request) string {
+	sourceIP, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		sourceIP = request.RemoteAddr
+	}
+	if !api.isTrustedProxy(sourceIP) {
+		return sourceIP
+	}
+
+	forwarded := strings.Split(request.Header.Get("X-Forwarded-For"), ",")
+	for idx := len(forwarded) - 1; idx >= 0; idx-- {
+		ip := strings.TrimSpace(forwarded[idx])
+		if len(ip) == 0 {
+			continue
+		}
+		sourceIP = ip
+		if !api.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return sourceIP
+}
+
+// newAuditEntry creates an audit entry for the operation performed by the user within the given request
+func (api *coreAPI) newAuditEntry(request *http.Request, action string, user string) *engine.AuditEntry {
+	return engine.NewAuditEntry(action, user, api.getSourceIP(request))
+}
+
+// saveAuditEntry appends entry to the audit log. Failure to write into the audit log is logged, but doesn't break the request
+func (api *coreAPI) saveAuditEntry(entry *engine.AuditEntry) {
+	err := api.store.AppendAuditEntry(entry)
+	if err != nil {
+		logrus.Errorf("error while saving audit entry for '%s' by user '%s': %s", entry.Action, entry.User, err)
+	}
+}
+
+// auditOnPanic should be deferred by the handler. It records the audit entry as successful if handler completed,
+// and as failed if handler panicked (with the panic propagated further to the recovery middleware)
+func (api *coreAPI) auditOnPanic(entry *engine.AuditEntry) {
+	if err := recover(); err != nil {
+		entry.Success = false
+		entry.Error = fmt.Sprintf("%s", err)
+		api.saveAuditEntry(entry)
+		panic(err)
+	}
+	entry.Success = true
+	api.saveAuditEntry(entry)
+}
+
+func (api *coreAPI) handleAuditGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	// load current policy
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading current policy: %s", err))
+	}
+
+	// only domain admins are allowed to see the audit log
+	user := api.getUserRequired(request)
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to view audit log"))
+	}
+
+	query := request.URL.Query()
+	filter := &engine.AuditFilter{
+		User:      query.Get("user"),
+		Namespace: query.Get("ns"),
+		Kind:      query.Get("kind"),
+		From:      parseAuditTime(query.Get("from")),
+		To:        parseAuditTime(query.Get("to")),
+		Limit:     parseAuditLimit(query.Get("limit")),
+	}
+
+	entries, err := api.store.GetAuditEntries(filter)
+	if err != nil {
+		panic(fmt.Sprintf("error while getting audit entries: %s", err))
+	}
+
+	api.contentType.WriteOne(writer, request, &AuditLog{
+		TypeKind: AuditLogObject.GetTypeKind(),
+		Entries:  entries,
+	})
+}
+
+func parseAuditLimit(value string) int {
+	if len(value) == 0 {
+		return defaultAuditLimit
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil || result <= 0 {
+		panic(fmt.Sprintf("invalid limit '%s', expected a positive number", value))
+	}
+	return result
+}
+
+func parseAuditTime(value string) time.Time {
+	if len(value) == 0 {
+		return time.Time{}
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid time '%s', expected RFC3339 format: %s", value, err))
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"github.com/Aptomi/aptomi/pkg/api/codec"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/runtime/store"
+	"github.com/Aptomi/aptomi/pkg/runtime/store/core"
+	"github.com/Aptomi/aptomi/pkg/runtime/store/generic/bolt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetSourceIP(t *testing.T) {
+	api := &coreAPI{trustedProxies: parseTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})}
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		result     string
+	}{
+		// no proxy
+		{"1.2.3.4:5678", "", "1.2.3.4"},
+
+		// header from untrusted client should be ignored
+		{"1.2.3.4:5678", "5.6.7.8", "1.2.3.4"},
+
+		// header from trusted proxy should be used
+		{"10.0.0.1:5678", "5.6.7.8", "5.6.7.8"},
+		{"192.168.1.1:5678", "5.6.7.8", "5.6.7.8"},
+
+		// spoofed entries before the last untrusted address should be ignored
+		{"10.0.0.1:5678", "9.9.9.9, 5.6.7.8", "5.6.7.8"},
+
+		// chain of trusted proxies should be skipped
+		{"10.0.0.1:5678", "9.9.9.9, 5.6.7.8, 192.168.1.1", "5.6.7.8"},
+
+		// trusted proxy without header
+		{"10.0.0.1:5678", "", "10.0.0.1"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil)
+		request.RemoteAddr = test.remoteAddr
+		if len(test.forwarded) > 0 {
+			request.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		assert.Equal(t, test.result, api.getSourceIP(request), "Source IP for request from %s with X-Forwarded-For '%s'", test.remoteAddr, test.forwarded)
+	}
+}
+
+func TestAuditOnPanic(t *testing.T) {
+	api, cleanup := newTestAPI(t)
+	defer cleanup()
+
+	// successful handler
+	func() {
+		entry := engine.NewAuditEntry(engine.AuditActionStateReset, "alice", "127.0.0.1")
+		defer api.auditOnPanic(entry)
+	}()
+
+	// failed handler, panic should be propagated further
+	assert.Panics(t, func() {
+		entry := engine.NewAuditEntry(engine.AuditActionStateReset, "bob", "127.0.0.1")
+		defer api.auditOnPanic(entry)
+		panic("reset failed")
+	}, "Panic should be propagated")
+
+	entries, err := api.store.GetAuditEntries(&engine.AuditFilter{})
+	assert.NoError(t, err, "Audit entries should be retrieved")
+	if assert.Len(t, entries, 2, "Both audit entries should be saved") {
+		assert.True(t, entries[0].Success, "Audit entry should be successful if handler completed")
+		assert.False(t, entries[1].Success, "Audit entry should be failed if handler panicked")
+		assert.Equal(t, "reset failed", entries[1].Error, "Audit entry should contain panic message")
+	}
+}
+
+func TestHandleAuditGet(t *testing.T) {
+	api, cleanup := newTestAPI(t)
+	defer cleanup()
+
+	for _, user := range []string{"alice", "bob"} {
+		assert.NoError(t, api.store.AppendAuditEntry(engine.NewAuditEntry(engine.AuditActionLogin, user, "127.0.0.1")), "Audit entry should be appended")
+	}
+
+	admin := &lang.User{Name: "admin", DomainAdmin: true}
+	user := &lang.User{Name: "alice"}
+
+	// domain admin should get filtered audit log
+	writer := httptest.NewRecorder()
+	api.handleAuditGet(writer, newAuditRequest(admin, "/api/v1/audit?user=alice"), nil)
+	assert.Equal(t, http.StatusOK, writer.Code, "Domain admin should be able to view audit log")
+
+	obj, err := api.contentType.GetCodecByContentType(codec.Default).DecodeOne(writer.Body.Bytes())
+	assert.NoError(t, err, "Audit log should be decoded")
+	if auditLog, ok := obj.(*AuditLog); assert.True(t, ok, "Audit log should be returned") {
+		if assert.Len(t, auditLog.Entries, 1, "Audit log should be filtered") {
+			assert.Equal(t, "alice", auditLog.Entries[0].User, "Audit log should be filtered by user")
+		}
+	}
+
+	// number of entries should be limited
+	writer = httptest.NewRecorder()
+	api.handleAuditGet(writer, newAuditRequest(admin, "/api/v1/audit?limit=1"), nil)
+	obj, err = api.contentType.GetCodecByContentType(codec.Default).DecodeOne(writer.Body.Bytes())
+	assert.NoError(t, err, "Audit log should be decoded")
+	if auditLog, ok := obj.(*AuditLog); assert.True(t, ok, "Audit log should be returned") {
+		if assert.Len(t, auditLog.Entries, 1, "Audit log should be limited") {
+			assert.Equal(t, "bob", auditLog.Entries[0].User, "Audit log should contain the most recent entry")
+		}
+	}
+	assert.Panics(t, func() {
+		api.handleAuditGet(httptest.NewRecorder(), newAuditRequest(admin, "/api/v1/audit?limit=0"), nil)
+	}, "Invalid limit should be rejected")
+
+	// regular user should not be allowed to view audit log
+	assert.Panics(t, func() {
+		api.handleAuditGet(httptest.NewRecorder(), newAuditRequest(user, "/api/v1/audit"), nil)
+	}, "Regular user should not be able to view audit log")
+
+	// time should be in RFC3339 format
+	assert.Panics(t, func() {
+		api.handleAuditGet(httptest.NewRecorder(), newAuditRequest(admin, "/api/v1/audit?from=yesterday"), nil)
+	}, "Invalid time should be rejected")
+}
+
+func newAuditRequest(user *lang.User, url string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	return request.WithContext(context.WithValue(request.Context(), ctxUserKey, user))
+}
+
+func newTestAPI(t *testing.T) (*coreAPI, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "aptomi-api-test")
+	if err != nil {
+		panic(err)
+	}
+
+	b := bolt.NewGenericStore(runtime.NewRegistry().Append(store.Objects...))
+	err = b.Open(config.DB{Connection: filepath.Join(dir, "db.bolt")})
+	if err != nil {
+		panic(err)
+	}
+
+	ds := core.NewStore(b)
+	err = ds.InitPolicy()
+	if err != nil {
+		panic(err)
+	}
+
+	api := &coreAPI{
+		contentType: codec.NewContentTypeHandler(runtime.NewRegistry().Append(Objects...)),
+		store:       ds,
+	}
+	return api, func() {
+		b.Close()         // nolint: errcheck
+		os.RemoveAll(dir) // nolint: errcheck
+	}
+}
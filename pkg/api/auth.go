@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/dgrijalva/jwt-go"
@@ -43,11 +44,17 @@ func (api *coreAPI) handleLogin(writer http.ResponseWriter, request *http.Reques
 		panic(fmt.Sprintf("Unexpected object received: %v", authReq))
 	}
 
+	// record login attempt in the audit log, regardless of whether it succeeds or not
+	audit := api.newAuditEntry(request, engine.AuditActionLogin, authReq.Username)
+	defer api.saveAuditEntry(audit)
+
 	user, err := api.externalData.UserLoader.Authenticate(authReq.Username, authReq.Password)
 	if err != nil {
+		audit.Error = err.Error()
 		serverErr := NewServerError(fmt.Sprintf("Authentication error: %s", err))
 		api.contentType.WriteOne(writer, request, serverErr)
 	} else {
+		audit.Success = true
 		api.contentType.WriteOne(writer, request, &AuthSuccess{
 			AuthSuccessObject.GetTypeKind(),
 			api.newToken(user),
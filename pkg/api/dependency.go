@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
 	"github.com/Aptomi/aptomi/pkg/engine/diff"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/plugin"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -47,10 +50,26 @@ type DependencyStatus struct {
 	Deployed  bool
 	Ready     bool
 	Endpoints map[string]map[string]string
+
+	// ContractVersion is the version of the contract, which dependency is using (empty if contract has no versions)
+	ContractVersion string `yaml:",omitempty"`
+
+	// Deprecated is the deprecation message, if dependency is using a deprecated version of the contract
+	Deprecated string `yaml:",omitempty"`
+
+	// Outputs is a map of values, which the service delivered back to the consumer. Values of sensitive outputs are
+	// only returned to the user who owns the dependency, and masked for everyone else
+	Outputs map[string]string `yaml:",omitempty"`
+
+	// Recorded is the status of the dependency, as recorded by the enforcer during the last enforcement cycle (phase,
+	// message explaining why dependency is failing, where it got resolved into). It's nil if status hasn't been
+	// recorded yet
+	Recorded *engine.DependencyStatus `yaml:",omitempty"`
 }
 
 func (api *coreAPI) handleDependencyStatusGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 	// parse query mode flag (deployment status vs. readiness status) as well as the list of dependency IDs
+	user := api.getUserRequired(request)
 	flag := DependencyQueryFlag(params.ByName("queryFlag"))
 	dependencyIds := strings.Split(params.ByName("idList"), ",")
 
@@ -65,6 +84,7 @@ func (api *coreAPI) handleDependencyStatusGet(writer http.ResponseWriter, reques
 		TypeKind: DependenciesStatusObject.GetTypeKind(),
 		Status:   make(map[string]*DependencyStatus),
 	}
+	dependencies := make(map[string]*lang.Dependency)
 	for _, depID := range dependencyIds {
 		parts := strings.Split(depID, "^")
 		dObj, err := policy.GetObject(lang.DependencyObject.Kind, parts[1], parts[0])
@@ -80,6 +100,7 @@ func (api *coreAPI) handleDependencyStatusGet(writer http.ResponseWriter, reques
 		}
 
 		d := dObj.(*lang.Dependency)
+		dependencies[runtime.KeyForStorable(d)] = d
 		result.Status[runtime.KeyForStorable(d)] = &DependencyStatus{
 			Found:     true,
 			Deployed:  true,
@@ -89,31 +110,83 @@ func (api *coreAPI) handleDependencyStatusGet(writer http.ResponseWriter, reques
 	}
 
 	// load actual and desired states
-	desiredState := resolve.NewPolicyResolver(policy, api.externalData, event.NewLog(logrus.WarnLevel, "api-dependencies-status")).ResolveAllDependencies()
+	desiredState := api.newPolicyResolver(policy, event.NewLog(logrus.WarnLevel, "api-dependencies-status")).ResolveAllDependencies()
 	actualState, err := api.store.GetActualState()
 	if err != nil {
 		panic(fmt.Sprintf("can't load actual state from the store: %s", err))
 	}
 
+	// fetch contract versions, which dependencies are using
+	fetchContractVersionsForDependencies(result, desiredState)
+
+	// fetch outputs, which services delivered back to dependencies
+	fetchOutputsForDependencies(result, user, dependencies, desiredState)
+
 	// fetch deployment status for dependencies
 	fetchDeploymentStatusForDependencies(result, actualState, desiredState)
 
 	// fetch readiness status for dependencies, if we were asked to do so
 	if flag == DependencyQueryDeploymentStatusAndReadiness {
 		plugins := api.pluginRegistryFactory()
-		fetchReadinessStatusForDependencies(result, plugins, policy, actualState, desiredState)
+		fetchReadinessStatusForDependencies(request.Context(), result, plugins, policy, actualState, desiredState)
 	}
 
 	// fetch endpoints for dependencies
 	fetchEndpointsForDependencies(result, actualState)
 
+	// fetch statuses recorded by the enforcer
+	api.fetchRecordedStatusForDependencies(result, dependencies)
+
 	// return the result back
 	api.contentType.WriteOne(writer, request, result)
 }
 
+func (api *coreAPI) fetchRecordedStatusForDependencies(result *DependenciesStatus, dependencies map[string]*lang.Dependency) {
+	for dKey := range dependencies {
+		status, err := api.store.GetDependencyStatus(dKey)
+		if err != nil {
+			panic(fmt.Sprintf("error while loading dependency status from the store: %s", err))
+		}
+		result.Status[dKey].Recorded = status
+	}
+}
+
+func fetchContractVersionsForDependencies(result *DependenciesStatus, desiredState *resolve.PolicyResolution) {
+	for dKey, dStatus := range result.Status {
+		dResolution, ok := desiredState.GetDependencyInstanceMap()[dKey]
+		if !ok || !dResolution.Resolved {
+			continue
+		}
+		dStatus.ContractVersion = dResolution.ContractVersion
+		dStatus.Deprecated = dResolution.Deprecated
+	}
+}
+
+func fetchOutputsForDependencies(result *DependenciesStatus, user *lang.User, dependencies map[string]*lang.Dependency, desiredState *resolve.PolicyResolution) {
+	for dKey, dStatus := range result.Status {
+		dependency, found := dependencies[dKey]
+		dResolution, ok := desiredState.GetDependencyInstanceMap()[dKey]
+		if !found || !ok || !dResolution.Resolved || len(dResolution.Outputs) <= 0 {
+			continue
+		}
+
+		// sensitive outputs are only shown to the user who owns the dependency
+		owner := dependency.User == user.Name
+		dStatus.Outputs = make(map[string]string)
+		for _, output := range dResolution.Outputs {
+			if output.Sensitive && !owner {
+				dStatus.Outputs[output.Name] = secrets.MaskedValue
+			} else {
+				dStatus.Outputs[output.Name] = output.Value
+			}
+		}
+	}
+}
+
 func fetchDeploymentStatusForDependencies(result *DependenciesStatus, actualState *resolve.PolicyResolution, desiredState *resolve.PolicyResolution) {
 	// compare desired vs. actual state and see what's the dependency status for every provided dependency ID
 	diff.NewPolicyResolutionDiff(desiredState, actualState).ActionPlan.Apply(
+		context.Background(),
 		action.WrapSequential(func(act action.Base) error {
 			// if it's attach action is pending on component, let's see which particular dependency it affects
 			if dAction, ok := act.(*component.AttachDependencyAction); ok {
@@ -168,7 +241,7 @@ func fetchDeploymentStatusForDependencies(result *DependenciesStatus, actualStat
 
 }
 
-func fetchReadinessStatusForDependencies(result *DependenciesStatus, plugins plugin.Registry, policy *lang.Policy, actualState *resolve.PolicyResolution, desiredState *resolve.PolicyResolution) {
+func fetchReadinessStatusForDependencies(ctx context.Context, result *DependenciesStatus, plugins plugin.Registry, policy *lang.Policy, actualState *resolve.PolicyResolution, desiredState *resolve.PolicyResolution) {
 	for _, instance := range actualState.ComponentInstanceMap {
 		for dKey := range instance.DependencyKeys {
 			if _, ok := result.Status[dKey]; ok {
@@ -180,7 +253,7 @@ func fetchReadinessStatusForDependencies(result *DependenciesStatus, plugins plu
 					continue
 				}
 
-				instanceStatus, err := codePlugin.Status(instance.GetDeployName(), instance.CalculatedCodeParams, event.NewLog(logrus.WarnLevel, "resources-status"))
+				instanceStatus, err := codePlugin.Status(ctx, instance.GetDeployName(), instance.CalculatedCodeParams, event.NewLog(logrus.WarnLevel, "resources-status"))
 				if err != nil {
 					panic(fmt.Sprintf("Error while getting deployment resources status for component instance %s: %s", instance.GetKey(), err))
 				}
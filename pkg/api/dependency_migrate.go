@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/diff"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/trigger"
+	"github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+func (api *coreAPI) handleDependencyMigrate(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	objects := api.readLang(request)
+	user := api.getUserRequired(request)
+
+	// Record operation in the audit log, regardless of whether it succeeds or not
+	audit := api.newAuditEntry(request, engine.AuditActionDependencyMigrate, user.Name)
+	for _, obj := range objects {
+		audit.AddObject(obj)
+	}
+	defer api.auditOnPanic(audit)
+
+	// Load current policy
+	policy, genCurrent, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading current policy: %s", err))
+	}
+	audit.PolicyGeneration = genCurrent
+
+	// Verify that dependencies exist in the policy and user has permissions to manage them
+	dependencyKeys := []string{}
+	for _, obj := range objects {
+		if obj.GetKind() != lang.DependencyObject.Kind {
+			panic(fmt.Sprintf("only dependencies can be migrated, got %s '%s/%s'", obj.GetKind(), obj.GetNamespace(), obj.GetName()))
+		}
+		dObj, errGet := policy.GetObject(lang.DependencyObject.Kind, obj.GetName(), obj.GetNamespace())
+		if errGet != nil || dObj == nil {
+			panic(fmt.Sprintf("dependency '%s/%s' not found in policy", obj.GetNamespace(), obj.GetName()))
+		}
+		d := dObj.(*lang.Dependency)
+		errManage := policy.View(user).ManageObject(d)
+		if errManage != nil {
+			panic(fmt.Sprintf("error while migrating dependency '%s/%s': %s", obj.GetNamespace(), obj.GetName(), errManage))
+		}
+		dependencyKeys = append(dependencyKeys, runtime.KeyForStorable(d))
+	}
+
+	// Record migration request in the store, so that enforcer moves dependencies to a new placement
+	err = api.store.RequestDependencyMigration(dependencyKeys, user.Name)
+	if err != nil {
+		panic(fmt.Sprintf("error while requesting dependency migration: %s", err))
+	}
+
+	// Calculate and return resolution log + action plan, showing how dependencies are going to be moved
+	eventLog := event.NewLog(logrus.WarnLevel, "api-dependency-migrate").AddConsoleHook(api.logLevel)
+	desiredState := api.newPolicyResolver(policy, eventLog).ResolveAllDependencies()
+	actualState, err := api.store.GetActualState()
+	if err != nil {
+		panic(fmt.Sprintf("can't load actual state from the store: %s", err))
+	}
+	policyDiff := diff.NewPolicyResolutionDiff(desiredState, actualState)
+
+	// If there are changes, we need to wait for the next revision
+	var waitForRevision runtime.Generation
+	if policyDiff.ActionPlan.NumberOfActions() <= 0 {
+		waitForRevision = runtime.MaxGeneration
+	} else {
+		revision, err := api.store.GetLastRevisionForPolicy(genCurrent)
+		if err != nil {
+			panic(fmt.Sprintf("error while loading last revision of the current policy: %s", err))
+		}
+		waitForRevision = revision.GetGeneration().Next()
+	}
+
+	api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
+		TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
+		PolicyGeneration: genCurrent,                                                         // policy didn't change
+		PolicyChanged:    false,                                                              // policy has not been updated in the store
+		WaitForRevision:  waitForRevision,                                                    // which revision to wait for
+		PlanAsText:       maskedPlanAsText(policyDiff.ActionPlan, desiredState, actualState), // return action plan, so it can be printed by the client
+		EventLog:         maskedEventLog(eventLog, desiredState),                             // return policy resolution log
+		Warnings:         policyDiff.Warnings,                                                // return instances which would move, if they were not sticky
+	})
+
+	// migration has been requested, so enqueue a trigger to run the enforcement right away
+	api.triggers.Enqueue(trigger.SourceAPI)
+}
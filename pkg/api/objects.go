@@ -10,8 +10,11 @@ import (
 var (
 	// Objects is a list of all objects used in API
 	Objects = runtime.AppendAll([]*runtime.Info{
+		AuditLogObject,
 		DependenciesStatusObject,
 		PolicyUpdateResultObject,
+		PolicyImpactResultObject,
+		QuotasUsageObject,
 		AuthSuccessObject,
 		AuthRequestObject,
 		ServerErrorObject,
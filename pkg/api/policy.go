@@ -2,12 +2,16 @@ package api
 
 import (
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/diff"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/trigger"
+	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
@@ -75,6 +79,7 @@ type PolicyUpdateResult struct {
 	WaitForRevision  runtime.Generation
 	PlanAsText       *action.PlanAsText
 	EventLog         []*event.APIEvent
+	Warnings         []string
 }
 
 // GetDefaultColumns returns default set of columns to be displayed
@@ -100,10 +105,69 @@ func (result *PolicyUpdateResult) AsColumns() map[string]string {
 	}
 }
 
-func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-	objects := api.readLang(request)
-	user := api.getUserRequired(request)
+// maskedPlanAsText returns action plan as text with secret values masked. Secrets known to all given policy
+// resolutions are masked, so that both previous and new code parameters get hidden
+func maskedPlanAsText(plan *action.Plan, states ...*resolve.PolicyResolution) *action.PlanAsText {
+	result := plan.AsText()
+	for _, state := range states {
+		for idx, actionText := range result.Actions {
+			result.Actions[idx] = maskedActionText(actionText, state.GetSecretMasker())
+		}
+	}
+	return result
+}
 
+// maskedActionText returns a copy of action text with secret values masked. Code params of the component instance
+// are masked by their keys as well, and the difference between them gets recalculated from the masked ones
+func maskedActionText(actionText util.NestedParameterMap, masker *secrets.Masker) util.NestedParameterMap {
+	instanceKey, _ := actionText["key"].(string)
+	result := util.NestedParameterMap{}
+	for key, value := range actionText {
+		switch v := value.(type) {
+		case util.NestedParameterMap:
+			result[key] = masker.MaskParams(instanceKey, v)
+		case string:
+			result[key] = masker.MaskString(v)
+		default:
+			result[key] = value
+		}
+	}
+
+	paramsBefore, okBefore := result["paramsBefore"].(util.NestedParameterMap)
+	params, ok := result["params"].(util.NestedParameterMap)
+	if _, hasDiff := result["paramsDiff"]; hasDiff && okBefore && ok {
+		result["paramsDiff"] = paramsBefore.Diff(params)
+	}
+	return result
+}
+
+// maskedEventLog returns event log entries as API events, with secret values known to policy resolution masked
+func maskedEventLog(eventLog *event.Log, state *resolve.PolicyResolution) []*event.APIEvent {
+	return eventLog.AsMaskedAPIEvents(state.GetSecretMasker().MaskString)
+}
+
+// newResolver creates a policy resolver, which resolves policy according to the server-wide settings
+func (api *coreAPI) newResolver(policy *lang.Policy, eventLog *event.Log) *resolve.PolicyResolver {
+	return resolve.NewPolicyResolver(policy, api.externalData, eventLog).SetOptions(api.resolverOptions)
+}
+
+// newPolicyResolver creates a policy resolver, which keeps placement of existing dependencies on sticky services as
+// recorded in the actual state (unless migration has been requested for those dependencies)
+func (api *coreAPI) newPolicyResolver(policy *lang.Policy, eventLog *event.Log) *resolve.PolicyResolver {
+	actualState, err := api.store.GetActualState()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading actual state: %s", err))
+	}
+	migrations, err := api.store.GetDependencyMigrations()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading dependency migrations: %s", err))
+	}
+	return api.newResolver(policy, eventLog).SetStickyPlacement(actualState, migrations.GetDependencyKeys())
+}
+
+// loadPolicyWithChanges loads the current policy, as well as its copy with the given objects added/updated (or deleted).
+// It verifies that user has permissions to make these changes and that the updated policy is valid
+func (api *coreAPI) loadPolicyWithChanges(objects []lang.Base, user *lang.User, deleted bool) (policy *lang.Policy, policyUpdated *lang.Policy, genCurrent runtime.Generation) {
 	// Load current policy
 	policyUpdated, genCurrent, err := api.store.GetPolicy(runtime.LastGen)
 	if err != nil {
@@ -111,13 +175,22 @@ func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http
 	}
 
 	// Store copy of the current policy before we modify it
-	policy, _, err := api.store.GetPolicy(genCurrent)
+	policy, _, err = api.store.GetPolicy(genCurrent)
 	if err != nil {
 		panic(fmt.Sprintf("error while loading current policy: %s", err))
 	}
 
-	// Verify that user has permissions to create and update objects
+	// Verify that user has permissions to create, update or delete objects
 	for _, obj := range objects {
+		if deleted {
+			errManage := policyUpdated.View(user).ManageObject(obj)
+			if errManage != nil {
+				panic(fmt.Sprintf("error while removing object from policy: %s", errManage))
+			}
+			policyUpdated.RemoveObject(obj)
+			continue
+		}
+
 		errAdd := policyUpdated.AddObject(obj)
 		if errAdd != nil {
 			panic(fmt.Sprintf("error while adding updated object to policy: %s", errAdd))
@@ -134,6 +207,22 @@ func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http
 		panic(fmt.Sprintf("updated policy is invalid: %s", err))
 	}
 
+	return policy, policyUpdated, genCurrent
+}
+
+func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	objects := api.readLang(request)
+	user := api.getUserRequired(request)
+
+	// Record operation in the audit log, regardless of whether it succeeds or not
+	audit := api.newAuditEntry(request, engine.AuditActionPolicyUpdate, user.Name)
+	for _, obj := range objects {
+		audit.AddObject(obj)
+	}
+	defer api.auditOnPanic(audit)
+
+	policy, policyUpdated, genCurrent := api.loadPolicyWithChanges(objects, user, false)
+
 	// Validate clusters using corresponding cluster plugins if policy is valid
 	plugins := api.pluginRegistryFactory()
 	for _, obj := range objects {
@@ -162,21 +251,26 @@ func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http
 		logLevel = logrus.WarnLevel
 	}
 
+	audit.Noop = noop
+
 	// If we are in noop mode
 	if noop {
+		audit.PolicyGeneration = genCurrent
+
 		// Process policy changes, calculate and return resolution log + action plan
 		eventLog := event.NewLog(logLevel, "api-policy-update-noop").AddConsoleHook(api.logLevel)
-		desiredStatePrev := resolve.NewPolicyResolver(policy, api.externalData, event.NewLog(logrus.WarnLevel, "prev")).ResolveAllDependencies()
-		desiredState := resolve.NewPolicyResolver(policyUpdated, api.externalData, eventLog).ResolveAllDependencies()
-		actionPlan := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev).ActionPlan
+		desiredStatePrev := api.newPolicyResolver(policy, event.NewLog(logrus.WarnLevel, "api-policy-update-prev")).ResolveAllDependencies()
+		desiredState := api.newPolicyResolver(policyUpdated, eventLog).ResolveAllDependencies()
+		policyDiff := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev)
 
 		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: genCurrent,             // policy generation didn't change
-			PolicyChanged:    false,                  // policy has not been updated in the store
-			WaitForRevision:  runtime.MaxGeneration,  // nothing to wait for
-			PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
+			PolicyGeneration: genCurrent,                                                              // policy generation didn't change
+			PolicyChanged:    false,                                                                   // policy has not been updated in the store
+			WaitForRevision:  runtime.MaxGeneration,                                                   // nothing to wait for
+			PlanAsText:       maskedPlanAsText(policyDiff.ActionPlan, desiredState, desiredStatePrev), // return action plan, so it can be printed by the client
+			EventLog:         maskedEventLog(eventLog, desiredState),                                  // return policy resolution log
+			Warnings:         policyDiff.Warnings,                                                     // return instances which would move, if they were not sticky
 		})
 
 	} else {
@@ -185,12 +279,13 @@ func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http
 		if err != nil {
 			panic(fmt.Sprintf("error while updating objects in policy: %s", err))
 		}
+		audit.PolicyGeneration = policyData.GetGeneration()
 
 		// Process policy changes, calculate and return resolution log + action plan
 		eventLog := event.NewLog(logLevel, "api-policy-update").AddConsoleHook(api.logLevel)
-		desiredStatePrev := resolve.NewPolicyResolver(policy, api.externalData, event.NewLog(logrus.WarnLevel, "prev")).ResolveAllDependencies()
-		desiredState := resolve.NewPolicyResolver(policyUpdated, api.externalData, eventLog).ResolveAllDependencies()
-		actionPlan := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev).ActionPlan
+		desiredStatePrev := api.newPolicyResolver(policy, event.NewLog(logrus.WarnLevel, "api-policy-update-prev")).ResolveAllDependencies()
+		desiredState := api.newPolicyResolver(policyUpdated, eventLog).ResolveAllDependencies()
+		policyDiff := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev)
 
 		// If there are changes, we need to wait for the next revision
 		var waitForRevision runtime.Generation
@@ -206,16 +301,17 @@ func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http
 
 		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: policyData.GetGeneration(), // policy now has a new generation
-			PolicyChanged:    changed,                    // have any policy object in the store been changed or not
-			WaitForRevision:  waitForRevision,            // which revision to wait for
-			PlanAsText:       actionPlan.AsText(),        // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(),     // return policy resolution log
+			PolicyGeneration: policyData.GetGeneration(),                                              // policy now has a new generation
+			PolicyChanged:    changed,                                                                 // have any policy object in the store been changed or not
+			WaitForRevision:  waitForRevision,                                                         // which revision to wait for
+			PlanAsText:       maskedPlanAsText(policyDiff.ActionPlan, desiredState, desiredStatePrev), // return action plan, so it can be printed by the client
+			EventLog:         maskedEventLog(eventLog, desiredState),                                  // return policy resolution log
+			Warnings:         policyDiff.Warnings,                                                     // return instances which would move, if they were not sticky
 		})
 
 		if changed {
-			// signal to the channel that policy has changed, that will trigger the enforcement right away
-			api.runEnforcement <- true
+			// policy has changed, so enqueue a trigger to run the enforcement right away
+			api.triggers.Enqueue(trigger.SourcePolicy)
 		}
 	}
 }
@@ -224,34 +320,17 @@ func (api *coreAPI) handlePolicyDelete(writer http.ResponseWriter, request *http
 	objects := api.readLang(request)
 	user := api.getUserRequired(request)
 
-	// Load current policy
-	policyUpdated, genCurrent, err := api.store.GetPolicy(runtime.LastGen)
-	if err != nil {
-		panic(fmt.Sprintf("error while loading current policy: %s", err))
-	}
-
-	// Store copy of the current policy before we modify it
-	policy, _, err := api.store.GetPolicy(genCurrent)
-	if err != nil {
-		panic(fmt.Sprintf("error while loading current policy: %s", err))
-	}
-
-	// Verify that user has permissions to delete objects
+	// Record operation in the audit log, regardless of whether it succeeds or not
+	audit := api.newAuditEntry(request, engine.AuditActionPolicyDelete, user.Name)
 	for _, obj := range objects {
-		errManage := policyUpdated.View(user).ManageObject(obj)
-		if errManage != nil {
-			panic(fmt.Sprintf("Error while removing object from policy: %s", errManage))
-		}
-		policyUpdated.RemoveObject(obj)
+		audit.AddObject(obj)
 	}
+	defer api.auditOnPanic(audit)
 
-	err = policyUpdated.Validate()
-	if err != nil {
-		panic(fmt.Sprintf("Updated policy is invalid: %s", err))
-	}
+	policy, policyUpdated, genCurrent := api.loadPolicyWithChanges(objects, user, true)
 
-	desiredStateTmp := resolve.NewPolicyResolver(policyUpdated, api.externalData, event.NewLog(logrus.WarnLevel, "tmp")).ResolveAllDependencies()
-	err = desiredStateTmp.Validate(policyUpdated)
+	desiredStateTmp := api.newResolver(policyUpdated, event.NewLog(logrus.WarnLevel, "api-policy-delete-validate")).ResolveAllDependencies()
+	err := desiredStateTmp.Validate(policyUpdated)
 	if err != nil {
 		panic(fmt.Sprintf("Updated policy is invalid: %s", err))
 	}
@@ -268,21 +347,26 @@ func (api *coreAPI) handlePolicyDelete(writer http.ResponseWriter, request *http
 		logLevel = logrus.WarnLevel
 	}
 
+	audit.Noop = noop
+
 	// If we are in noop mode
 	if noop {
+		audit.PolicyGeneration = genCurrent
+
 		// Process policy changes, calculate and return resolution log + action plan
 		eventLog := event.NewLog(logLevel, "api-policy-delete-noop").AddConsoleHook(api.logLevel)
-		desiredStatePrev := resolve.NewPolicyResolver(policy, api.externalData, event.NewLog(logrus.WarnLevel, "prev")).ResolveAllDependencies()
-		desiredState := resolve.NewPolicyResolver(policyUpdated, api.externalData, eventLog).ResolveAllDependencies()
-		actionPlan := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev).ActionPlan
+		desiredStatePrev := api.newPolicyResolver(policy, event.NewLog(logrus.WarnLevel, "api-policy-delete-prev")).ResolveAllDependencies()
+		desiredState := api.newPolicyResolver(policyUpdated, eventLog).ResolveAllDependencies()
+		policyDiff := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev)
 
 		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: genCurrent,             // policy generation didn't change
-			PolicyChanged:    false,                  // policy has not been updated in the store
-			WaitForRevision:  runtime.MaxGeneration,  // nothing to wait for
-			PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
+			PolicyGeneration: genCurrent,                                                              // policy generation didn't change
+			PolicyChanged:    false,                                                                   // policy has not been updated in the store
+			WaitForRevision:  runtime.MaxGeneration,                                                   // nothing to wait for
+			PlanAsText:       maskedPlanAsText(policyDiff.ActionPlan, desiredState, desiredStatePrev), // return action plan, so it can be printed by the client
+			EventLog:         maskedEventLog(eventLog, desiredState),                                  // return policy resolution log
+			Warnings:         policyDiff.Warnings,                                                     // return instances which would move, if they were not sticky
 		})
 
 	} else {
@@ -291,12 +375,13 @@ func (api *coreAPI) handlePolicyDelete(writer http.ResponseWriter, request *http
 		if err != nil {
 			panic(fmt.Sprintf("error while deleting objects from policy: %s", err))
 		}
+		audit.PolicyGeneration = policyData.GetGeneration()
 
 		// Process policy changes, calculate and return resolution log + action plan
 		eventLog := event.NewLog(logLevel, "api-policy-delete").AddConsoleHook(api.logLevel)
-		desiredStatePrev := resolve.NewPolicyResolver(policy, api.externalData, event.NewLog(logrus.WarnLevel, "prev")).ResolveAllDependencies()
-		desiredState := resolve.NewPolicyResolver(policyUpdated, api.externalData, eventLog).ResolveAllDependencies()
-		actionPlan := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev).ActionPlan
+		desiredStatePrev := api.newPolicyResolver(policy, event.NewLog(logrus.WarnLevel, "api-policy-delete-prev")).ResolveAllDependencies()
+		desiredState := api.newPolicyResolver(policyUpdated, eventLog).ResolveAllDependencies()
+		policyDiff := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev)
 
 		// If there are changes, we need to wait for the next revision
 		var waitForRevision runtime.Generation
@@ -312,16 +397,17 @@ func (api *coreAPI) handlePolicyDelete(writer http.ResponseWriter, request *http
 
 		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: policyData.GetGeneration(), // policy now has a new generation
-			PolicyChanged:    changed,                    // have any policy object in the store been changed or not
-			WaitForRevision:  waitForRevision,            // which revision to wait for
-			PlanAsText:       actionPlan.AsText(),        // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(),     // return policy resolution log
+			PolicyGeneration: policyData.GetGeneration(),                                              // policy now has a new generation
+			PolicyChanged:    changed,                                                                 // have any policy object in the store been changed or not
+			WaitForRevision:  waitForRevision,                                                         // which revision to wait for
+			PlanAsText:       maskedPlanAsText(policyDiff.ActionPlan, desiredState, desiredStatePrev), // return action plan, so it can be printed by the client
+			EventLog:         maskedEventLog(eventLog, desiredState),                                  // return policy resolution log
+			Warnings:         policyDiff.Warnings,                                                     // return instances which would move, if they were not sticky
 		})
 
 		if changed {
-			// signal to the channel that policy has changed, that will trigger the enforcement right away
-			api.runEnforcement <- true
+			// policy has changed, so enqueue a trigger to run the enforcement right away
+			api.triggers.Enqueue(trigger.SourcePolicy)
 		}
 	}
 
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"github.com/Aptomi/aptomi/pkg/engine/diff"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+// PolicyImpactResultObject is an informational data structure with Kind and Constructor for PolicyImpactResult
+var PolicyImpactResultObject = &runtime.Info{
+	Kind:        "policy-impact-result",
+	Constructor: func() runtime.Object { return &PolicyImpactResult{} },
+}
+
+// PolicyImpactResult represents results of the impact analysis of proposed policy changes. It lists dependencies,
+// users, component instances and clusters which would be impacted, grouped by create, update and delete
+type PolicyImpactResult struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// PolicyGeneration is the generation of the policy, which changes have been analyzed against
+	PolicyGeneration runtime.Generation
+
+	// Impact is the list of impacted objects, grouped by the kind of change (create, update and delete)
+	Impact []*diff.ImpactGroup
+
+	// Warnings is a list of instances of sticky services, which would move if they were not sticky
+	Warnings []string
+}
+
+// handlePolicyImpact calculates impact of proposed policy changes without making them. Objects are treated as created
+// or updated for POST requests, and as deleted for DELETE requests. Policy gets resolved with and without the changes,
+// and the difference between two desired states shows who and what would be impacted
+func (api *coreAPI) handlePolicyImpact(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	objects := api.readLang(request)
+	user := api.getUserRequired(request)
+	deleted := request.Method == http.MethodDelete
+
+	policy, policyUpdated, genCurrent := api.loadPolicyWithChanges(objects, user, deleted)
+
+	// Resolve policy with and without the changes, and see what's different
+	desiredStatePrev := api.newPolicyResolver(policy, event.NewLog(logrus.WarnLevel, "api-policy-impact-prev")).ResolveAllDependencies()
+	desiredState := api.newPolicyResolver(policyUpdated, event.NewLog(logrus.WarnLevel, "api-policy-impact")).ResolveAllDependencies()
+	policyDiff := diff.NewPolicyResolutionDiff(desiredState, desiredStatePrev)
+
+	api.contentType.WriteOne(writer, request, &PolicyImpactResult{
+		TypeKind:         PolicyImpactResultObject.GetTypeKind(),
+		PolicyGeneration: genCurrent,
+		Impact:           policyDiff.Impact(policyUpdated, policy),
+		Warnings:         policyDiff.Warnings,
+	})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMaskedActionText(t *testing.T) {
+	masker := secrets.NewMasker(nil)
+	masker.AddSensitiveKeys("main#instance", "license")
+
+	paramsBefore := util.NestedParameterMap{"license": "abc", "password": "qwerty123", "name": "mysql"}
+	params := util.NestedParameterMap{"license": "xyz", "password": "qwerty456", "name": "mysql"}
+	masker.AddSensitiveParams("main#instance", paramsBefore)
+	masker.AddSensitiveParams("main#instance", params)
+
+	masked := maskedActionText(util.NestedParameterMap{
+		"kind":         "action-component-update",
+		"key":          "main#instance",
+		"paramsBefore": paramsBefore,
+		"params":       params,
+		"paramsDiff":   paramsBefore.Diff(params),
+		"pretty":       "[*] main#instance",
+	}, masker)
+
+	assert.Equal(t, secrets.MaskedValue, masked["params"].(util.NestedParameterMap)["license"], "Param marked as sensitive should be masked")
+	assert.Equal(t, secrets.MaskedValue, masked["params"].(util.NestedParameterMap)["password"], "Param matching key pattern should be masked")
+	assert.Equal(t, "mysql", masked["params"].(util.NestedParameterMap)["name"], "Regular param should not be masked")
+	assert.Equal(t, secrets.MaskedValue, masked["paramsBefore"].(util.NestedParameterMap)["license"], "Param marked as sensitive should be masked")
+	assert.Empty(t, masked["paramsDiff"], "Diff should be calculated from masked params")
+	assert.Equal(t, "[*] main#instance", masked["pretty"], "Regular text should not be masked")
+
+	// original params should not be modified
+	assert.Equal(t, "xyz", params["license"])
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+)
+
+// QuotasUsageObject is an informational data structure with Kind and Constructor for QuotasUsage
+var QuotasUsageObject = &runtime.Info{
+	Kind:        "quotas-usage",
+	Constructor: func() runtime.Object { return &QuotasUsage{} },
+}
+
+// QuotasUsage is a struct which holds usage information for all quotas defined in the policy
+type QuotasUsage struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// map containing usage by quota
+	Usage map[string]*resolve.QuotaUsage
+}
+
+func (api *coreAPI) handleQuotaUsageGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	user := api.getUserRequired(request)
+
+	// load the latest policy
+	policy, policyGen, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy from the store: %s", err))
+	}
+
+	// quota usage gets calculated by resolving the policy. desired state calculated by the enforcer gets reused, unless
+	// the policy has changed since then
+	desiredState := api.lastResolution.Get(policyGen)
+	if desiredState == nil {
+		desiredState = api.newPolicyResolver(policy, event.NewLog(logrus.WarnLevel, "api-quota-usage")).ResolveAllDependencies()
+	}
+
+	api.contentType.WriteOne(writer, request, &QuotasUsage{
+		TypeKind: QuotasUsageObject.GetTypeKind(),
+		Usage:    filterQuotaUsage(policy.View(user), desiredState.GetQuotaUsageMap()),
+	})
+}
+
+// Returns usage only for quotas which user is allowed to view. Dependencies which user is not allowed to view are
+// left out from the usage as well
+func filterQuotaUsage(view *lang.PolicyView, usageMap map[string]*resolve.QuotaUsage) map[string]*resolve.QuotaUsage {
+	objects := make(map[string]lang.Base)
+	for _, kind := range []string{lang.QuotaObject.Kind, lang.DependencyObject.Kind} {
+		for _, obj := range view.Policy.GetObjectsByKind(kind) {
+			objects[runtime.KeyForStorable(obj)] = obj
+		}
+	}
+	canView := func(key string) bool {
+		obj, ok := objects[key]
+		return ok && view.ViewObject(obj) == nil
+	}
+
+	result := make(map[string]*resolve.QuotaUsage)
+	for quotaKey, usage := range usageMap {
+		if !canView(usage.Quota) {
+			continue
+		}
+		usageFiltered := *usage
+		usageFiltered.Dependencies = make(map[string]bool)
+		for dKey := range usage.Dependencies {
+			if canView(dKey) {
+				usageFiltered.Dependencies[dKey] = true
+			}
+		}
+		result[quotaKey] = &usageFiltered
+	}
+	return result
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
@@ -27,6 +28,41 @@ func (api *coreAPI) handleRevisionGet(writer http.ResponseWriter, request *http.
 	}
 }
 
+func (api *coreAPI) handleRevisionCancel(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	// Load current policy
+	policy, genCurrent, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading current policy: %s", err))
+	}
+
+	// record operation in the audit log, regardless of whether it succeeds or not
+	user := api.getUserRequired(request)
+	audit := api.newAuditEntry(request, engine.AuditActionRevisionCancel, user.Name)
+	audit.PolicyGeneration = genCurrent
+	defer api.auditOnPanic(audit)
+
+	// check that user is a domain admin
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to cancel revisions"))
+	}
+
+	// only revision which hasn't been fully applied yet can be cancelled
+	revision, err := api.store.GetRevision(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while getting current revision: %s", err))
+	}
+	if revision == nil || (revision.Status != engine.RevisionStatusWaiting && revision.Status != engine.RevisionStatusInProgress) {
+		panic(fmt.Sprintf("there is no revision in progress to cancel"))
+	}
+
+	// signal to the channel that revision should be cancelled, that will stop outstanding actions right away (or
+	// prevent them from starting, if revision hasn't started to be applied yet)
+	api.cancelEnforcement <- revision.GetGeneration()
+
+	// return revision, so the client can wait until it gets marked as cancelled
+	api.contentType.WriteOne(writer, request, revision)
+}
+
 type revisionsWrapper struct {
 	Data interface{}
 }
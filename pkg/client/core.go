@@ -14,6 +14,7 @@ type Core interface {
 	Policy() Policy
 	Dependency() Dependency
 	Revision() Revision
+	Audit() Audit
 	State() State
 	User() User
 	Version() Version
@@ -24,21 +25,31 @@ type Policy interface {
 	Show(gen runtime.Generation) (*engine.PolicyData, error)
 	Apply([]runtime.Object, bool, logrus.Level) (*api.PolicyUpdateResult, error)
 	Delete([]runtime.Object, bool, logrus.Level) (*api.PolicyUpdateResult, error)
+	Impact([]runtime.Object, bool) (*api.PolicyImpactResult, error)
 }
 
 // Dependency is the interface for managing Dependency
 type Dependency interface {
 	Status([]*lang.Dependency, api.DependencyQueryFlag) (*api.DependenciesStatus, error)
+	Migrate([]*lang.Dependency) (*api.PolicyUpdateResult, error)
 }
 
-// Revision is the interface for getting Revisions
+// Revision is the interface for getting and cancelling Revisions
 type Revision interface {
 	Show(gen runtime.Generation) (*engine.Revision, error)
+	Cancel() (*engine.Revision, error)
 }
 
-// State is the interface for resetting Actual State
+// Audit is the interface for querying audit log
+type Audit interface {
+	Show(filter *engine.AuditFilter) (*api.AuditLog, error)
+}
+
+// State is the interface for resetting Actual State and pausing the enforcer
 type State interface {
 	Reset(bool) (*api.PolicyUpdateResult, error)
+	Pause(scope string, name string) (*engine.EnforcerPause, error)
+	Resume(scope string, name string) (*engine.EnforcerPause, error)
 }
 
 // User is the interface for auth and user management
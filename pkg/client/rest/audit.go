@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/api"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type auditClient struct {
+	cfg        *config.Client
+	httpClient http.Client
+}
+
+func (client *auditClient) Show(filter *engine.AuditFilter) (*api.AuditLog, error) {
+	query := url.Values{}
+	if len(filter.User) > 0 {
+		query.Set("user", filter.User)
+	}
+	if len(filter.Namespace) > 0 {
+		query.Set("ns", filter.Namespace)
+	}
+	if len(filter.Kind) > 0 {
+		query.Set("kind", filter.Kind)
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	path := "/audit"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	response, err := client.httpClient.GET(path, api.AuditLogObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.AuditLog), nil
+}
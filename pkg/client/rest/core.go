@@ -28,6 +28,10 @@ func (client *coreClient) Revision() client.Revision {
 	return &revisionClient{client.cfg, client.httpClient}
 }
 
+func (client *coreClient) Audit() client.Audit {
+	return &auditClient{client.cfg, client.httpClient}
+}
+
 func (client *coreClient) State() client.State {
 	return &stateClient{client.cfg, client.httpClient}
 }
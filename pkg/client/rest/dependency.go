@@ -7,6 +7,7 @@ import (
 
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
 	"strings"
 )
 
@@ -32,3 +33,21 @@ func (client *dependencyClient) Status(dependencies []*lang.Dependency, queryFla
 
 	return response.(*api.DependenciesStatus), nil
 }
+
+func (client *dependencyClient) Migrate(dependencies []*lang.Dependency) (*api.PolicyUpdateResult, error) {
+	objects := []runtime.Object{}
+	for _, d := range dependencies {
+		objects = append(objects, d)
+	}
+
+	response, err := client.httpClient.POSTSlice("/policy/dependency/migrate", api.PolicyUpdateResultObject, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.PolicyUpdateResult), nil
+}
@@ -53,3 +53,22 @@ func (client *policyClient) Delete(updated []runtime.Object, noop bool, logLevel
 
 	return response.(*api.PolicyUpdateResult), nil
 }
+
+func (client *policyClient) Impact(changed []runtime.Object, deleted bool) (*api.PolicyImpactResult, error) {
+	var response runtime.Object
+	var err error
+	if deleted {
+		response, err = client.httpClient.DELETESlice("/policy/impact", api.PolicyImpactResultObject, changed)
+	} else {
+		response, err = client.httpClient.POSTSlice("/policy/impact", api.PolicyImpactResultObject, changed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.PolicyImpactResult), nil
+}
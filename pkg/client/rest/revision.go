@@ -27,3 +27,16 @@ func (client *revisionClient) Show(gen runtime.Generation) (*engine.Revision, er
 
 	return response.(*engine.Revision), nil
 }
+
+func (client *revisionClient) Cancel() (*engine.Revision, error) {
+	response, err := client.httpClient.DELETE("/revision/current", engine.RevisionObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*engine.Revision), nil
+}
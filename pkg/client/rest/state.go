@@ -5,6 +5,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/api"
 	"github.com/Aptomi/aptomi/pkg/client/rest/http"
 	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
 )
 
 type stateClient struct {
@@ -20,3 +21,36 @@ func (client *stateClient) Reset(noop bool) (*api.PolicyUpdateResult, error) {
 
 	return revision.(*api.PolicyUpdateResult), nil
 }
+
+func (client *stateClient) Pause(scope string, name string) (*engine.EnforcerPause, error) {
+	response, err := client.httpClient.POST(pausePath(scope, name), engine.EnforcerPauseObject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*engine.EnforcerPause), nil
+}
+
+func (client *stateClient) Resume(scope string, name string) (*engine.EnforcerPause, error) {
+	response, err := client.httpClient.DELETE(pausePath(scope, name), engine.EnforcerPauseObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*engine.EnforcerPause), nil
+}
+
+func pausePath(scope string, name string) string {
+	if len(scope) <= 0 {
+		return "/actualstate/pause"
+	}
+	return fmt.Sprintf("/actualstate/pause/%s/%s", scope, name)
+}
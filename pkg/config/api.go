@@ -8,6 +8,10 @@ type API struct {
 	Host      string `yaml:",omitempty" validate:"required,hostname|ip"`
 	Port      int    `yaml:",omitempty" validate:"required,min=1,max=65535"`
 	APIPrefix string `yaml:",omitempty" validate:"required"`
+
+	// TrustedProxies is a list of IPs or CIDRs of proxies in front of the server (server only). Client IP is taken
+	// from X-Forwarded-For header only if request came from one of them, otherwise it's the address of the connection
+	TrustedProxies []string `yaml:",omitempty" validate:"omitempty,dive,cidr|ip"`
 }
 
 // URL returns server API url to connect to
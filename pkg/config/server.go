@@ -14,6 +14,9 @@ type Server struct {
 	Plugins              Plugins         `validate:"required"`
 	Users                UserSources     `validate:"required"`
 	SecretsDir           string          `validate:"omitempty,dir"` // secrets is not a first-class citizen yet, so it's not required
+	SecretsVault         *Vault          `validate:"omitempty"`     // if defined, secrets will be loaded from Vault instead of SecretsDir
+	Redaction            Redaction       `validate:"-"`
+	Expressions          Expressions     `validate:"-"`
 	Enforcer             Enforcer        `validate:"required"`
 	DomainAdminOverrides map[string]bool `validate:"-"`
 	Auth                 ServerAuth      `validate:"-"`
@@ -39,18 +42,43 @@ type UserSources struct {
 	File []string `validate:"dive,file"`
 }
 
+// Redaction represents configs for masking sensitive values in logs, revisions and API responses
+type Redaction struct {
+	// KeyPatterns is a list of regular expressions for parameter keys, values of which are considered sensitive.
+	// If not specified, default patterns will be used (matching passwords, secrets, tokens, credentials and keys)
+	KeyPatterns []string
+}
+
+// Expressions represents configs for evaluating expressions in the policy
+type Expressions struct {
+	// MissingParams defines what happens when expression refers to a parameter which doesn't exist (e.g. a label
+	// with a typo in its name). It can be "ignore" (expression evaluates to false, default), "warn" (same, but a
+	// warning gets written into resolution log) or "error" (expression fails to evaluate)
+	MissingParams string
+}
+
 // DB represents configs for DB
 type DB struct {
 	Connection string `validate:"required"`
 }
 
-// Enforcer represents configs for Enforcer background process that periodically gets latest policy, calculating
-// difference between it and actual state and then applying calculated actions.
+// Enforcer represents configs for Enforcer background process that gets latest policy, calculating
+// difference between it and actual state and then applying calculated actions. Enforcement gets triggered by changes
+// in policy, users, secrets and cluster health, as well as by explicit API calls. Interval is a safety net, after which
+// enforcement runs even if nothing has triggered it.
 type Enforcer struct {
 	Interval  time.Duration `validate:"-"`
 	Disabled  bool          `validate:"-"`
 	Noop      bool          `validate:"-"`
 	NoopSleep time.Duration `validate:"-"`
+
+	// Debounce is how long enforcer waits for more triggers after the last one, so they get coalesced into a single
+	// enforcement cycle. MaxDelay is the maximum time enforcement can be delayed by debouncing
+	Debounce time.Duration `validate:"-"`
+	MaxDelay time.Duration `validate:"-"`
+
+	// WatchInterval is how often users, secrets and cluster health get checked for changes (zero disables checks)
+	WatchInterval time.Duration `validate:"-"`
 }
 
 // ServerAuth represents server auth config
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"time"
+)
+
+// Vault represents configs for loading user and scoped secrets from Vault-compatible KV (version 2) secrets engine.
+// Either Token or AppRole must be specified for authentication
+type Vault struct {
+	// Address is the URL of Vault server, e.g. https://vault.example.com:8200
+	Address string `validate:"required,url"`
+
+	// Mount is the path where KV v2 secrets engine is mounted, e.g. 'secret'
+	Mount string `validate:"required"`
+
+	// PathTemplate is the text template for the path of user secrets within KV mount, e.g. 'aptomi/users/{{ .User }}'
+	PathTemplate string `validate:"required"`
+
+	// NamespacePathTemplate is the text template for the path of secrets shared within a namespace,
+	// e.g. 'aptomi/namespaces/{{ .Namespace }}'. If empty, namespace secrets will not be loaded
+	NamespacePathTemplate string `validate:"-"`
+
+	// ServicePathTemplate is the text template for the path of service secrets,
+	// e.g. 'aptomi/services/{{ .Namespace }}/{{ .Service }}'. If empty, service secrets will not be loaded
+	ServicePathTemplate string `validate:"-"`
+
+	// ClusterPathTemplate is the text template for the path of cluster secrets,
+	// e.g. 'aptomi/clusters/{{ .Cluster }}'. If empty, cluster secrets will not be loaded
+	ClusterPathTemplate string `validate:"-"`
+
+	// Token is the Vault token to use for authentication
+	Token string `validate:"-"`
+
+	// AppRole is the AppRole auth config to use for authentication, if Token is not specified
+	AppRole *VaultAppRole `validate:"omitempty"`
+
+	// CacheTTL is how long secrets will be cached for before they get reloaded from Vault
+	CacheTTL time.Duration `validate:"-"`
+
+	// Timeout is the timeout for HTTP requests to Vault
+	Timeout time.Duration `validate:"-"`
+}
+
+// VaultAppRole represents configs for Vault AppRole auth method
+type VaultAppRole struct {
+	// Mount is the path where AppRole auth method is mounted (default is 'approle')
+	Mount    string `validate:"-"`
+	RoleID   string `validate:"required"`
+	SecretID string `validate:"required"`
+}
+
+// GetCacheTTL returns how long secrets should be cached for (one minute by default)
+func (cfg *Vault) GetCacheTTL() time.Duration {
+	if cfg.CacheTTL <= 0 {
+		return time.Minute
+	}
+	return cfg.CacheTTL
+}
+
+// GetTimeout returns timeout for HTTP requests to Vault (15 seconds by default)
+func (cfg *Vault) GetTimeout() time.Duration {
+	if cfg.Timeout <= 0 {
+		return 15 * time.Second
+	}
+	return cfg.Timeout
+}
+
+// GetAppRoleMount returns the path where AppRole auth method is mounted
+func (cfg *VaultAppRole) GetAppRoleMount() string {
+	if len(cfg.Mount) <= 0 {
+		return "approle"
+	}
+	return cfg.Mount
+}
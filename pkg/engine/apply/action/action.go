@@ -1,13 +1,15 @@
 package action
 
 import (
+	"context"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 )
 
-// Base interface for all actions which perform actual state updates
+// Base interface for all actions which perform actual state updates. Actions should stop as soon as possible, once
+// the given context gets cancelled (e.g. revision has been cancelled or action timeout has been reached)
 type Base interface {
 	runtime.Storable
-	Apply(*Context) error
+	Apply(context.Context, *Context) error
 	DescribeChanges() util.NestedParameterMap
 }
@@ -1,6 +1,7 @@
 package action
 
 import (
+	"context"
 	"sync"
 )
 
@@ -28,20 +29,21 @@ func (plan *Plan) GetActionGraphNode(key string) *GraphNode {
 	return result
 }
 
-// Apply applies the action plan. It may call fn in multiple go routines, executing the plan in parallel
-func (plan *Plan) Apply(fn ApplyFunction, resultUpdater ApplyResultUpdater) *ApplyResult {
+// Apply applies the action plan. It may call fn in multiple go routines, executing the plan in parallel. Once the
+// context gets cancelled, all outstanding actions are marked as skipped
+func (plan *Plan) Apply(ctx context.Context, fn ApplyFunction, resultUpdater ApplyResultUpdater) *ApplyResult {
 	// update total number of actions and start the revision
 	resultUpdater.SetTotal(plan.NumberOfActions())
 
 	// apply the plan and calculate result (success/failed/skipped actions)
-	plan.applyInternal(fn, resultUpdater)
+	plan.applyInternal(ctx, fn, resultUpdater)
 
 	// tell results updater that we are done and return the results
 	return resultUpdater.Done()
 }
 
 // Apply applies the action plan. It may call fn in multiple go routines, executing the plan in parallel
-func (plan *Plan) applyInternal(fn ApplyFunction, resultUpdater ApplyResultUpdater) {
+func (plan *Plan) applyInternal(ctx context.Context, fn ApplyFunction, resultUpdater ApplyResultUpdater) {
 	deg := make(map[string]int)
 	wasError := make(map[string]error)
 	queue := make(chan string, len(plan.NodeMap))
@@ -66,7 +68,7 @@ func (plan *Plan) applyInternal(fn ApplyFunction, resultUpdater ApplyResultUpdat
 			// Take element off the queue, apply the block of actions and put into queue 0-degree nodes which are waiting on us
 			go func(key string) {
 				defer wg.Done()
-				plan.applyActions(key, fn, queue, deg, wasError, mutex, resultUpdater)
+				plan.applyActions(ctx, key, fn, queue, deg, wasError, mutex, resultUpdater)
 			}(key)
 		}
 		done.Done()
@@ -83,7 +85,7 @@ func (plan *Plan) applyInternal(fn ApplyFunction, resultUpdater ApplyResultUpdat
 }
 
 // This function applies a block of actions and updates nodes which are waiting on this node
-func (plan *Plan) applyActions(key string, fn ApplyFunction, queue chan string, deg map[string]int, wasError map[string]error, mutex *sync.RWMutex, resultUpdater ApplyResultUpdater) {
+func (plan *Plan) applyActions(ctx context.Context, key string, fn ApplyFunction, queue chan string, deg map[string]int, wasError map[string]error, mutex *sync.RWMutex, resultUpdater ApplyResultUpdater) {
 	// locate the node
 	node := plan.NodeMap[key]
 
@@ -93,7 +95,10 @@ func (plan *Plan) applyActions(key string, fn ApplyFunction, queue chan string,
 	foundErr := wasError[key]
 	mutex.RUnlock()
 	for _, action := range node.Actions {
-		// if an error happened before, all subsequent actions are getting marked as skipped
+		// if an error happened before or the plan has been cancelled, all subsequent actions are getting marked as skipped
+		if foundErr == nil && ctx.Err() != nil {
+			foundErr = ctx.Err()
+		}
 		if foundErr != nil {
 			// fmt.Println("skipped ", action.GetName())
 			resultUpdater.AddSkipped()
@@ -144,7 +149,7 @@ func (plan *Plan) NumberOfActions() uint32 {
 	resultUpdater := NewApplyResultUpdaterImpl()
 
 	// apply the plan and calculate result (success/failed/skipped actions)
-	plan.applyInternal(Noop(), resultUpdater)
+	plan.applyInternal(context.Background(), Noop(), resultUpdater)
 
 	// return the number of success actions (all of them will be success due to Noop() action)
 	return resultUpdater.Result.Success
@@ -155,10 +160,57 @@ func (plan *Plan) AsText() *PlanAsText {
 	result := NewPlanAsText()
 
 	// apply the plan and capture actions as text
-	plan.applyInternal(WrapSequential(func(act Base) error {
+	plan.applyInternal(context.Background(), WrapSequential(func(act Base) error {
 		result.Actions = append(result.Actions, act.DescribeChanges())
 		return nil
 	}), NewApplyResultUpdaterImpl())
 
 	return result
 }
+
+// Split splits the action plan into two plans. The first one contains nodes which can be applied, while the second one
+// contains nodes which have to be held back, as well as all nodes which depend on them (i.e. they can't be applied
+// until held nodes get applied). Dependencies between nodes from different plans get dropped
+func (plan *Plan) Split(hold func(key string) bool) (*Plan, *Plan) {
+	// find all nodes which have to be held back, following the chain of dependents
+	held := make(map[string]bool)
+	var markHeld func(node *GraphNode)
+	markHeld = func(node *GraphNode) {
+		if held[node.Key] {
+			return
+		}
+		held[node.Key] = true
+		for _, dependent := range node.BeforeRev {
+			markHeld(dependent)
+		}
+	}
+	for key, node := range plan.NodeMap {
+		if hold(key) {
+			markHeld(node)
+		}
+	}
+
+	// copy nodes with their actions into the corresponding plans
+	runnable, onHold := NewPlan(), NewPlan()
+	planFor := func(key string) *Plan {
+		if held[key] {
+			return onHold
+		}
+		return runnable
+	}
+	for key, node := range plan.NodeMap {
+		planFor(key).GetActionGraphNode(key).Actions = node.Actions
+	}
+
+	// copy dependencies within each of the plans
+	for key, node := range plan.NodeMap {
+		for _, before := range node.Before {
+			if held[key] == held[before.Key] {
+				p := planFor(key)
+				p.GetActionGraphNode(key).AddBefore(p.GetActionGraphNode(before.Key))
+			}
+		}
+	}
+
+	return runnable, onHold
+}
@@ -2,6 +2,7 @@ package action
 
 import (
 	"fmt"
+	"sync"
 	"sync/atomic"
 )
 
@@ -61,3 +62,69 @@ func (updater *ApplyResultUpdaterImpl) Done() *ApplyResult {
 	}
 	return updater.Result
 }
+
+// PartitionResultUpdater is an optional interface for ApplyResultUpdater, which allows to track progress of every
+// partition of the action plan separately
+type PartitionResultUpdater interface {
+	// PartitionUpdated gets called every time progress of a partition changes. Result is a copy and done indicates
+	// whether all actions in the partition have been processed
+	PartitionUpdated(partition string, result ApplyResult, done bool)
+}
+
+// partitionResultUpdater tracks progress of a single partition of the action plan, forwarding all updates to the
+// result updater of the whole plan
+type partitionResultUpdater struct {
+	name   string
+	parent ApplyResultUpdater
+	result *ApplyResult
+	mutex  sync.Mutex
+}
+
+func newPartitionResultUpdater(name string, total uint32, parent ApplyResultUpdater) *partitionResultUpdater {
+	updater := &partitionResultUpdater{
+		name:   name,
+		parent: parent,
+		result: &ApplyResult{Total: total},
+	}
+	updater.update(func() {}, false)
+	return updater
+}
+
+// SetTotal sets the total number of actions in the partition
+func (updater *partitionResultUpdater) SetTotal(total uint32) {
+	updater.update(func() { updater.result.Total = total }, false)
+}
+
+// AddSuccess increments the number of successfully executed actions
+func (updater *partitionResultUpdater) AddSuccess() {
+	updater.update(func() { updater.result.Success++ }, false)
+	updater.parent.AddSuccess()
+}
+
+// AddFailed increments the number of failed actions
+func (updater *partitionResultUpdater) AddFailed() {
+	updater.update(func() { updater.result.Failed++ }, false)
+	updater.parent.AddFailed()
+}
+
+// AddSkipped increments the number of skipped actions
+func (updater *partitionResultUpdater) AddSkipped() {
+	updater.update(func() { updater.result.Skipped++ }, false)
+	updater.parent.AddSkipped()
+}
+
+// Done marks the partition as processed
+func (updater *partitionResultUpdater) Done() *ApplyResult {
+	updater.update(func() {}, true)
+	return updater.result
+}
+
+// Updates partition result and reports it to the parent, holding the lock so that updates don't get reordered
+func (updater *partitionResultUpdater) update(fn func(), done bool) {
+	updater.mutex.Lock()
+	defer updater.mutex.Unlock()
+	fn()
+	if partitionUpdater, ok := updater.parent.(PartitionResultUpdater); ok {
+		partitionUpdater.PartitionUpdated(updater.name, *updater.result, done)
+	}
+}
@@ -0,0 +1,127 @@
+package action
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Partition splits the action plan into independent sub-plans, grouping nodes by the given partition function (e.g. by
+// namespace). If a node depends on a node from another partition, which has actions to be applied (directly or through
+// its own dependencies), both partitions get merged into one and the name of the merged partition lists all of them.
+// Partitions without actions are not returned. It returns a map from partition name to the sub-plan
+func (plan *Plan) Partition(partitionOf func(key string) string) map[string]*Plan {
+	// find nodes which have actions to be applied, either directly or through their dependencies
+	pending := make(map[string]bool)
+	visited := make(map[string]bool)
+	var isPending func(node *GraphNode) bool
+	isPending = func(node *GraphNode) bool {
+		if visited[node.Key] {
+			return pending[node.Key]
+		}
+		visited[node.Key] = true
+		result := len(node.Actions) > 0
+		for _, before := range node.Before {
+			result = isPending(before) || result
+		}
+		pending[node.Key] = result
+		return result
+	}
+
+	// merge partitions, which depend on each other
+	parent := make(map[string]string)
+	var find func(name string) string
+	find = func(name string) string {
+		if _, ok := parent[name]; !ok {
+			parent[name] = name
+		}
+		if parent[name] != name {
+			parent[name] = find(parent[name])
+		}
+		return parent[name]
+	}
+	for key, node := range plan.NodeMap {
+		root := find(partitionOf(key))
+		for _, before := range node.Before {
+			if isPending(before) {
+				parent[find(partitionOf(before.Key))] = root
+			}
+		}
+	}
+
+	// calculate names of merged partitions
+	members := make(map[string][]string)
+	for name := range parent {
+		root := find(name)
+		members[root] = append(members[root], name)
+	}
+	names := make(map[string]string)
+	for root, list := range members {
+		sort.Strings(list)
+		names[root] = strings.Join(list, ",")
+	}
+
+	// copy nodes with their actions into the corresponding sub-plans, as well as dependencies within the sub-plans
+	subPlans := make(map[string]*Plan)
+	subPlanFor := func(key string) *Plan {
+		name := names[find(partitionOf(key))]
+		if _, ok := subPlans[name]; !ok {
+			subPlans[name] = NewPlan()
+		}
+		return subPlans[name]
+	}
+	for key, node := range plan.NodeMap {
+		subPlanFor(key).GetActionGraphNode(key).Actions = node.Actions
+	}
+	for key, node := range plan.NodeMap {
+		for _, before := range node.Before {
+			p := subPlanFor(key)
+			if p == subPlanFor(before.Key) {
+				p.GetActionGraphNode(key).AddBefore(p.GetActionGraphNode(before.Key))
+			}
+		}
+	}
+
+	// drop partitions which have nothing to apply
+	result := make(map[string]*Plan)
+	for name, subPlan := range subPlans {
+		for _, node := range subPlan.NodeMap {
+			if len(node.Actions) > 0 {
+				result[name] = subPlan
+				break
+			}
+		}
+	}
+	return result
+}
+
+// ApplyPartitioned applies partitions of the action plan in parallel and independently from each other, so that a slow
+// or failed action in one partition doesn't affect actions in the other partitions. Function fn gets called once per
+// partition to get the apply function for it. Overall progress gets reported to the result updater and, if it
+// implements PartitionResultUpdater, progress of every partition gets reported to it as well
+func ApplyPartitioned(ctx context.Context, partitions map[string]*Plan, fn func(partition string) ApplyFunction, resultUpdater ApplyResultUpdater) *ApplyResult {
+	// calculate total number of actions in every partition and start the revision
+	total := uint32(0)
+	updaters := make(map[string]*partitionResultUpdater)
+	for name, partition := range partitions {
+		updaters[name] = newPartitionResultUpdater(name, partition.NumberOfActions(), resultUpdater)
+		total += updaters[name].result.Total
+	}
+	resultUpdater.SetTotal(total)
+
+	// apply all partitions in parallel
+	var wg sync.WaitGroup
+	for name, partition := range partitions {
+		wg.Add(1)
+		go func(name string, partition *Plan) {
+			defer wg.Done()
+			partition.applyInternal(ctx, fn(name), updaters[name])
+			updaters[name].Done()
+		}(name, partition)
+	}
+	wg.Wait()
+
+	// tell results updater that we are done and return the results
+	return resultUpdater.Done()
+}
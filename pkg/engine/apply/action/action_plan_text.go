@@ -43,6 +43,7 @@ func (t *PlanAsText) ToString() string {
 		"[>]": "Add Consumers",
 		"[<]": "Remove Consumers",
 		"[@]": "Query Endpoints",
+		"[~]": "Wait for Instances",
 	}
 
 	// combine actions into a string
@@ -44,6 +44,40 @@ func updateComponentInActualState(componentKey string, context *action.Context)
 	return nil
 }
 
+func updateReadyInActualState(componentKey string, ready bool, context *action.Context) error {
+	// look up an existing component in the actual state
+	instance := context.ActualState.ComponentInstanceMap[componentKey]
+	if instance.NotReady == !ready {
+		return nil
+	}
+	instance.NotReady = !ready
+	if ready {
+		instance.NotReadyAttempts = 0
+		instance.NotReadyCheckedAt = time.Time{}
+	}
+
+	// save component instance in the actual state store
+	err := context.ActualStateUpdater.Save(instance)
+	if err != nil {
+		return fmt.Errorf("error while updating actual state: %s", err)
+	}
+	return nil
+}
+
+func recordNotReadyInActualState(componentKey string, context *action.Context) error {
+	// look up an existing component in the actual state
+	instance := context.ActualState.ComponentInstanceMap[componentKey]
+	instance.NotReadyAttempts++
+	instance.NotReadyCheckedAt = time.Now()
+
+	// save component instance in the actual state store
+	err := context.ActualStateUpdater.Save(instance)
+	if err != nil {
+		return fmt.Errorf("error while updating actual state: %s", err)
+	}
+	return nil
+}
+
 func deleteComponentFromActualState(componentKey string, context *action.Context) error {
 	// delete an existing component from the actual state map
 	delete(context.ActualState.ComponentInstanceMap, componentKey)
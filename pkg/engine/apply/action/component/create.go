@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/lang"
@@ -33,15 +34,21 @@ func NewCreateAction(componentKey string, params util.NestedParameterMap) *Creat
 }
 
 // Apply applies the action
-func (a *CreateAction) Apply(context *action.Context) error {
+func (a *CreateAction) Apply(ctx context.Context, context *action.Context) error {
 	// deploy to cloud
-	err := a.processDeployment(context)
+	err := a.processDeployment(ctx, context)
 	if err != nil {
 		return fmt.Errorf("unable to deploy component instance '%s': %s", a.ComponentKey, err)
 	}
 
 	// update actual state
-	return createComponentInActualState(a.ComponentKey, context)
+	err = createComponentInActualState(a.ComponentKey, context)
+	if err != nil {
+		return err
+	}
+
+	// wait for component instance to become ready, before actions of dependent components start
+	return waitForReady(ctx, a.ComponentKey, context)
 }
 
 // DescribeChanges returns text-based description of changes that will be applied
@@ -54,7 +61,7 @@ func (a *CreateAction) DescribeChanges() util.NestedParameterMap {
 	}
 }
 
-func (a *CreateAction) processDeployment(context *action.Context) error {
+func (a *CreateAction) processDeployment(ctx context.Context, context *action.Context) error {
 	instance := context.DesiredState.ComponentInstanceMap[a.ComponentKey]
 	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
 	if err != nil {
@@ -94,5 +101,8 @@ func (a *CreateAction) processDeployment(context *action.Context) error {
 		return err
 	}
 
-	return plugin.Create(instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
+	ctx, cancel := withTimeout(ctx, component.Code.GetTimeout())
+	defer cancel()
+
+	return plugin.Create(ctx, instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
 }
@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/lang"
@@ -33,9 +34,9 @@ func NewDeleteAction(componentKey string, params util.NestedParameterMap) *Delet
 }
 
 // Apply applies the action
-func (a *DeleteAction) Apply(context *action.Context) error {
+func (a *DeleteAction) Apply(ctx context.Context, context *action.Context) error {
 	// delete from cloud
-	err := a.processDeployment(context)
+	err := a.processDeployment(ctx, context)
 	if err != nil {
 		return fmt.Errorf("unable to delete component instance '%s': %s", a.ComponentKey, err)
 	}
@@ -54,7 +55,7 @@ func (a *DeleteAction) DescribeChanges() util.NestedParameterMap {
 	}
 }
 
-func (a *DeleteAction) processDeployment(context *action.Context) error {
+func (a *DeleteAction) processDeployment(ctx context.Context, context *action.Context) error {
 	instance := context.ActualState.ComponentInstanceMap[a.ComponentKey]
 	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
 	if err != nil {
@@ -93,5 +94,8 @@ func (a *DeleteAction) processDeployment(context *action.Context) error {
 		return err
 	}
 
-	return plugin.Destroy(instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
+	ctx, cancel := withTimeout(ctx, component.Code.GetTimeout())
+	defer cancel()
+
+	return plugin.Destroy(ctx, instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
 }
@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -32,7 +33,7 @@ func NewAttachDependencyAction(componentKey string, dependencyID string) *Attach
 }
 
 // Apply applies the action
-func (a *AttachDependencyAction) Apply(context *action.Context) error {
+func (a *AttachDependencyAction) Apply(ctx context.Context, context *action.Context) error {
 	context.EventLog.NewEntry().Debugf("Attaching dependency '%s' to component instance: '%s'", a.DependencyID, a.ComponentKey)
 
 	// add reference to dependency into the actual state
@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -31,7 +32,7 @@ func NewDetachDependencyAction(componentKey string, dependencyID string) *Detach
 }
 
 // Apply applies the action
-func (a *DetachDependencyAction) Apply(context *action.Context) error {
+func (a *DetachDependencyAction) Apply(ctx context.Context, context *action.Context) error {
 	context.EventLog.NewEntry().Debugf("Detaching dependency '%s' from component instance: '%s'", a.DependencyID, a.ComponentKey)
 
 	// remove reference to dependency from the actual state
@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/lang"
@@ -31,14 +32,14 @@ func NewEndpointsAction(componentKey string) *EndpointsAction {
 }
 
 // Apply applies the action
-func (a *EndpointsAction) Apply(context *action.Context) error {
+func (a *EndpointsAction) Apply(ctx context.Context, context *action.Context) error {
 	// if component for some reason doesn't exist in actual state, report an error
 	if context.ActualState.ComponentInstanceMap[a.ComponentKey] == nil {
 		return fmt.Errorf("unable to get endpoints for component instance '%s': it doesn't exist in actual state", a.ComponentKey)
 	}
 
 	// fetch component endpoints and store them in component instance (actual state)
-	err := a.processEndpoints(context)
+	err := a.processEndpoints(ctx, context)
 	if err != nil {
 		return fmt.Errorf("unable to get endpoints for component instance '%s': %s", a.ComponentKey, err)
 	}
@@ -57,7 +58,7 @@ func (a *EndpointsAction) DescribeChanges() util.NestedParameterMap {
 	}
 }
 
-func (a *EndpointsAction) processEndpoints(context *action.Context) error {
+func (a *EndpointsAction) processEndpoints(ctx context.Context, context *action.Context) error {
 	instance := context.ActualState.ComponentInstanceMap[a.ComponentKey]
 	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
 	if err != nil {
@@ -95,7 +96,7 @@ func (a *EndpointsAction) processEndpoints(context *action.Context) error {
 		return err
 	}
 
-	endpoints, err := plugin.Endpoints(instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
+	endpoints, err := plugin.Endpoints(ctx, instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,115 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// HookActionObject is an informational data structure with Kind and Constructor for the action
+var HookActionObject = &runtime.Info{
+	Kind:        "action-component-hook",
+	Constructor: func() runtime.Object { return &HookAction{} },
+}
+
+// HookAction is a action which gets called to execute a lifecycle hook of a component instance (e.g. schema migration
+// before the component gets updated, or a backup before it gets deleted). Hook code gets deployed, then the action waits
+// for it to become ready, and then hook code gets destroyed
+type HookAction struct {
+	runtime.TypeKind `yaml:",inline"`
+	*action.Metadata
+	ComponentKey string
+	Phase        string
+	ClusterName  string
+	DeployName   string
+	Hook         *resolve.ComponentHook
+}
+
+// NewHookAction creates new HookAction for the given component instance. Cluster and hook code are taken from the
+// instance, so the hook can be executed even after the instance is gone from the desired state (e.g. 'post-delete')
+func NewHookAction(instance *resolve.ComponentInstance, phase string, hook *resolve.ComponentHook) *HookAction {
+	return &HookAction{
+		TypeKind:     HookActionObject.GetTypeKind(),
+		Metadata:     action.NewMetadata(HookActionObject.Kind, instance.GetKey(), phase, hook.Name),
+		ComponentKey: instance.GetKey(),
+		Phase:        phase,
+		ClusterName:  instance.GetCluster(),
+		DeployName:   instance.Metadata.Key.GetHookDeployName(hook.Name),
+		Hook:         hook,
+	}
+}
+
+// Apply applies the action
+func (a *HookAction) Apply(ctx context.Context, context *action.Context) error {
+	err := a.processDeployment(ctx, context)
+	if err != nil {
+		return fmt.Errorf("%s hook '%s' failed for component instance '%s': %s", a.Phase, a.Hook.Name, a.ComponentKey, err)
+	}
+	return nil
+}
+
+// DescribeChanges returns text-based description of changes that will be applied
+func (a *HookAction) DescribeChanges() util.NestedParameterMap {
+	return util.NestedParameterMap{
+		"kind":   a.Kind,
+		"key":    a.ComponentKey,
+		"phase":  a.Phase,
+		"hook":   a.Hook.Name,
+		"params": a.Hook.Params,
+		"pretty": fmt.Sprintf("[>] %s (%s hook '%s')", a.ComponentKey, a.Phase, a.Hook.Name),
+	}
+}
+
+func (a *HookAction) processDeployment(ctx context.Context, context *action.Context) (err error) {
+	context.EventLog.NewEntry().Infof("Executing %s hook '%s' for component instance: %s", a.Phase, a.Hook.Name, a.ComponentKey)
+
+	if len(a.ClusterName) <= 0 {
+		return fmt.Errorf("policy doesn't specify deployment target for component instance")
+	}
+
+	clusterObj, err := context.DesiredPolicy.GetObject(lang.ClusterObject.Kind, a.ClusterName, runtime.SystemNS)
+	if err != nil {
+		return err
+	}
+	if clusterObj == nil {
+		return fmt.Errorf("cluster '%s' in not present in policy", a.ClusterName)
+	}
+	cluster := clusterObj.(*lang.Cluster)
+
+	plugin, err := context.Plugins.ForCodeType(cluster, a.Hook.Type)
+	if err != nil {
+		return err
+	}
+
+	// timeout applies to the whole hook, including the time spent waiting for it to become ready
+	ctx, cancel := withTimeout(ctx, a.Hook.Timeout)
+	defer cancel()
+
+	// hook code gets cleaned up regardless of whether it succeeded or not (even if it failed to be created, since it
+	// could have been created partially), so it can be executed again next time. Cleanup gets its own timeout, since
+	// the hook context may have already expired or been cancelled
+	defer func() {
+		cleanupCtx, cleanupCancel := withCleanupTimeout(a.Hook.Timeout)
+		defer cleanupCancel()
+		errDestroy := plugin.Destroy(cleanupCtx, a.DeployName, a.Hook.Params, context.EventLog)
+		if err == nil {
+			err = errDestroy
+		}
+	}()
+
+	err = plugin.Create(ctx, a.DeployName, a.Hook.Params, context.EventLog)
+	if err != nil {
+		return err
+	}
+
+	// hook is considered complete once its code becomes ready (e.g. Kubernetes job has finished)
+	wait := a.Hook.WaitForReady
+	if wait == nil {
+		wait = &lang.WaitForReady{}
+	}
+	return pollUntilReady(ctx, plugin, a.DeployName, a.Hook.Params, wait, fmt.Sprintf("%s hook '%s' of component instance '%s'", a.Phase, a.Hook.Name, a.ComponentKey), context)
+}
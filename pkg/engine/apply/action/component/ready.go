@@ -0,0 +1,117 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"time"
+)
+
+// Component instance, which didn't become ready, gets waited for again with exponentially growing intervals between
+// attempts, so that a component instance which is stuck doesn't get its status polled on every enforcement cycle
+const (
+	notReadyBackoffMin = 30 * time.Second
+	notReadyBackoffMax = 10 * time.Minute
+)
+
+// waitForReady polls status of the component instance until it becomes ready, if component asks to wait for it. Since
+// dependent components have their actions ordered after this one, they won't start until this function returns.
+// While waiting, component instance is marked as not ready in the actual state, so if it doesn't become ready within
+// the timeout (or the server goes down), it will be waited for again during the next enforcement cycles
+func waitForReady(ctx context.Context, componentKey string, context *action.Context) error {
+	instance := context.DesiredState.ComponentInstanceMap[componentKey]
+	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err != nil {
+		return err
+	}
+	component := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName]
+
+	// only code components can be waited for, and only when they ask for it
+	if component == nil || component.Code == nil || component.Code.WaitForReady == nil {
+		return updateReadyInActualState(componentKey, true, context)
+	}
+
+	clusterName := instance.GetCluster()
+	if len(clusterName) <= 0 {
+		return fmt.Errorf("policy doesn't specify deployment target for component instance")
+	}
+
+	clusterObj, err := context.DesiredPolicy.GetObject(lang.ClusterObject.Kind, clusterName, runtime.SystemNS)
+	if err != nil {
+		return err
+	}
+	if clusterObj == nil {
+		return fmt.Errorf("cluster '%s' in not present in policy", clusterName)
+	}
+	cluster := clusterObj.(*lang.Cluster)
+
+	plugin, err := context.Plugins.ForCodeType(cluster, component.Code.Type)
+	if err != nil {
+		return err
+	}
+
+	err = updateReadyInActualState(componentKey, false, context)
+	if err != nil {
+		return err
+	}
+
+	err = pollUntilReady(ctx, plugin, instance.GetDeployName(), instance.CalculatedCodeParams, component.Code.WaitForReady, fmt.Sprintf("component instance '%s'", instance.GetKey()), context)
+	if err != nil {
+		if errRecord := recordNotReadyInActualState(componentKey, context); errRecord != nil {
+			return errRecord
+		}
+		return err
+	}
+
+	return updateReadyInActualState(componentKey, true, context)
+}
+
+// nextReadyCheck returns the time when component instance, which didn't become ready, should be waited for again
+func nextReadyCheck(instance *resolve.ComponentInstance) time.Time {
+	backoff := notReadyBackoffMin
+	for attempt := 1; attempt < instance.NotReadyAttempts && backoff < notReadyBackoffMax; attempt++ {
+		backoff *= 2
+	}
+	if backoff > notReadyBackoffMax {
+		backoff = notReadyBackoffMax
+	}
+	return instance.NotReadyCheckedAt.Add(backoff)
+}
+
+// pollUntilReady polls status of the deployed code until it becomes ready. If it doesn't become ready within the timeout
+// or context gets cancelled, an error is returned. Description is used in log messages and errors to refer to what is
+// being waited for
+func pollUntilReady(ctx context.Context, codePlugin plugin.CodePlugin, deployName string, params util.NestedParameterMap, wait *lang.WaitForReady, description string, context *action.Context) error {
+	timeout := wait.GetTimeout()
+	interval := wait.GetInterval()
+	context.EventLog.NewEntry().Infof("Waiting for %s to become ready (timeout %s)", description, timeout)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ready, statusErr := codePlugin.Status(ctx, deployName, params, context.EventLog)
+		if statusErr != nil {
+			// status can't be retrieved while code is still starting, so keep polling until timeout
+			context.EventLog.NewEntry().Debugf("Error while checking status of %s: %s", description, statusErr)
+		}
+		if ready {
+			context.EventLog.NewEntry().Infof("Ready: %s", description)
+			return nil
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			if statusErr != nil {
+				return fmt.Errorf("%s is not ready after %s: %s", description, timeout, statusErr)
+			}
+			return fmt.Errorf("%s is not ready after %s", description, timeout)
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for %s to become ready: %s", description, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package component
+
+import (
+	"context"
+	"time"
+)
+
+// defaultCleanupTimeout limits cleanup, which has no timeout of its own (e.g. destroying hook code)
+const defaultCleanupTimeout = 5 * time.Minute
+
+// withTimeout returns a context, which gets cancelled after the given timeout. If timeout is zero, then the parent
+// context is returned, so the action is only limited by the parent (e.g. cancellation of the revision)
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// withCleanupTimeout returns a new context, which is not derived from the context of the action, so cleanup can be
+// performed even after the action has timed out or the revision has been cancelled. If timeout is zero, then the
+// default cleanup timeout is used
+func withCleanupTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = defaultCleanupTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/lang"
@@ -35,15 +36,21 @@ func NewUpdateAction(componentKey string, paramsBefore util.NestedParameterMap,
 }
 
 // Apply applies the action
-func (a *UpdateAction) Apply(context *action.Context) error {
+func (a *UpdateAction) Apply(ctx context.Context, context *action.Context) error {
 	// update in the cloud
-	err := a.processDeployment(context)
+	err := a.processDeployment(ctx, context)
 	if err != nil {
 		return fmt.Errorf("unable to update component instance '%s': %s", a.ComponentKey, err)
 	}
 
 	// update actual state
-	return updateComponentInActualState(a.ComponentKey, context)
+	err = updateComponentInActualState(a.ComponentKey, context)
+	if err != nil {
+		return err
+	}
+
+	// wait for component instance to become ready, before actions of dependent components start
+	return waitForReady(ctx, a.ComponentKey, context)
 }
 
 // DescribeChanges returns text-based description of changes that will be applied
@@ -58,7 +65,7 @@ func (a *UpdateAction) DescribeChanges() util.NestedParameterMap {
 	}
 }
 
-func (a *UpdateAction) processDeployment(context *action.Context) error {
+func (a *UpdateAction) processDeployment(ctx context.Context, context *action.Context) error {
 	instance := context.DesiredState.ComponentInstanceMap[a.ComponentKey]
 	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
 	if err != nil {
@@ -96,12 +103,16 @@ func (a *UpdateAction) processDeployment(context *action.Context) error {
 		return err
 	}
 
-	err = plugin.Update(instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
+	ctx, cancel := withTimeout(ctx, component.Code.GetTimeout())
+	defer cancel()
+
+	err = plugin.Update(ctx, instance.GetDeployName(), instance.CalculatedCodeParams, context.EventLog)
 	if err != nil {
 		return err
 	}
 
 	context.ActualState.ComponentInstanceMap[a.ComponentKey].CalculatedCodeParams = instance.CalculatedCodeParams
+	context.ActualState.ComponentInstanceMap[a.ComponentKey].CalculatedHooks = instance.CalculatedHooks
 
 	return nil
 }
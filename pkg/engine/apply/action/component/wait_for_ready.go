@@ -0,0 +1,60 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"time"
+)
+
+// WaitForReadyActionObject is an informational data structure with Kind and Constructor for the action
+var WaitForReadyActionObject = &runtime.Info{
+	Kind:        "action-component-wait-for-ready",
+	Constructor: func() runtime.Object { return &WaitForReadyAction{} },
+}
+
+// WaitForReadyAction is a action which gets called when an existing component instance didn't become ready during one
+// of the previous runs. It only polls status of the component instance, without updating it in the cloud
+type WaitForReadyAction struct {
+	runtime.TypeKind `yaml:",inline"`
+	*action.Metadata
+	ComponentKey string
+}
+
+// NewWaitForReadyAction creates new WaitForReadyAction
+func NewWaitForReadyAction(componentKey string) *WaitForReadyAction {
+	return &WaitForReadyAction{
+		TypeKind:     WaitForReadyActionObject.GetTypeKind(),
+		Metadata:     action.NewMetadata(WaitForReadyActionObject.Kind, componentKey),
+		ComponentKey: componentKey,
+	}
+}
+
+// Apply applies the action
+func (a *WaitForReadyAction) Apply(ctx context.Context, context *action.Context) error {
+	// if component for some reason doesn't exist in actual state, report an error
+	instance := context.ActualState.ComponentInstanceMap[a.ComponentKey]
+	if instance == nil {
+		return fmt.Errorf("unable to wait for component instance '%s': it doesn't exist in actual state", a.ComponentKey)
+	}
+
+	// status doesn't get polled again until backoff has passed. the action still fails, so that actions of dependent
+	// components don't start until component instance becomes ready
+	nextCheck := nextReadyCheck(instance)
+	if time.Now().Before(nextCheck) {
+		return fmt.Errorf("component instance '%s' is not ready, it will be checked again at %s", a.ComponentKey, nextCheck.Format(time.RFC3339))
+	}
+
+	return waitForReady(ctx, a.ComponentKey, context)
+}
+
+// DescribeChanges returns text-based description of changes that will be applied
+func (a *WaitForReadyAction) DescribeChanges() util.NestedParameterMap {
+	return util.NestedParameterMap{
+		"kind":   a.Kind,
+		"key":    a.ComponentKey,
+		"pretty": fmt.Sprintf("[~] %s", a.ComponentKey),
+	}
+}
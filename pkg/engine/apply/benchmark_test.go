@@ -1,6 +1,7 @@
 package apply
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/actual"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
@@ -8,6 +9,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/external"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Sirupsen/logrus"
@@ -392,6 +394,10 @@ func (loader *SecretLoaderImpl) LoadSecretsByUserName(string) map[string]string
 	return nil
 }
 
+func (loader *SecretLoaderImpl) LoadSecretsByScope(secrets.Scope) map[string]string {
+	return nil
+}
+
 func RunEngine(b *testing.B, testName string, desiredPolicy *lang.Policy, externalData *external.Data) {
 	fmt.Printf("Running engine for '%s'\n", testName)
 
@@ -442,7 +448,7 @@ func RunEngine(b *testing.B, testName string, desiredPolicy *lang.Policy, extern
 
 func applyAndCheckBenchmark(b *testing.B, apply *EngineApply, expectedResult action.ApplyResult) *resolve.PolicyResolution {
 	b.Helper()
-	actualState, result := apply.Apply()
+	actualState, result := apply.Apply(context.Background())
 
 	t := &testing.T{}
 	ok := assert.Equal(t, expectedResult.Success, result.Success, "Number of successfully executed actions")
@@ -1,6 +1,7 @@
 package apply
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/actual"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
@@ -56,38 +57,83 @@ func NewEngineApply(desiredPolicy *lang.Policy, desiredState *resolve.PolicyReso
 // As actions get executed, they will instantiate/update/delete components according to the resolved
 // policy, as well as configure the underlying cloud components appropriately. In case of errors (e.g. cloud is not
 // available), actual state may not be equal to desired state after performing all the actions.
-func (apply *EngineApply) Apply() (*resolve.PolicyResolution, *action.ApplyResult) {
-	// process all actions
-	context := action.NewContext(
-		apply.desiredPolicy,
-		apply.desiredState,
-		apply.actualState,
-		apply.actualStateUpdater,
-		apply.externalData,
-		apply.plugins,
-		apply.eventLog,
-	)
+//
+// Once the given context gets cancelled, running actions are asked to stop and all outstanding actions are skipped.
+func (apply *EngineApply) Apply(ctx context.Context) (*resolve.PolicyResolution, *action.ApplyResult) {
+	// split the plan into partitions by namespace, so that teams don't get blocked by failed or slow actions in other
+	// namespaces. Each partition gets its own view of desired and actual state, so partitions can be applied in parallel
+	partitions := apply.actionPlan.Partition(apply.getNamespace)
+	contexts := make(map[string]*action.Context)
+	for name, partition := range partitions {
+		contexts[name] = action.NewContext(
+			apply.desiredPolicy,
+			apply.partitionState(apply.desiredState, partition, true),
+			apply.partitionState(apply.actualState, partition, false),
+			apply.actualStateUpdater,
+			apply.externalData,
+			apply.plugins,
+			apply.eventLog,
+		)
+	}
 
-	// TODO: apply in parallel, https://github.com/Aptomi/aptomi/issues/310
-	// Note that this function will be called in different go routines by apply
+	// Partitions get applied in parallel, and share plugins from the same registry. Plugins are safe for concurrent
+	// use (see plugin.CodePlugin), while every partition updates only its own copy of desired and actual state.
+	//
+	// TODO: apply in parallel within a partition, https://github.com/Aptomi/aptomi/issues/310
 	// So we will need to
-	// (1) Remove WrapSequential to make action execution parallel
+	// (1) Remove WrapSequential to make action execution parallel within a partition
 	// (2) Restrict the number of parallel actions per cluster (make sure plugin can take care of that)
-	// (3) Ensure that plugins are "thread-safe"
-	// (4) Ensure that when we are updating states (e.g. Desired -> Actual), this is also "thread-safe"
-	result := apply.actionPlan.Apply(action.WrapSequential(func(act action.Base) error {
-		err := apply.executeAction(act, context)
-		if err != nil {
-			apply.eventLog.NewEntry().Errorf("error while applying action '%s': %s", act, err)
+	// (3) Ensure that when we are updating states (e.g. Desired -> Actual), this is also "thread-safe"
+	result := action.ApplyPartitioned(ctx, partitions, func(partition string) action.ApplyFunction {
+		actionContext := contexts[partition]
+		return action.WrapSequential(func(act action.Base) error {
+			err := apply.executeAction(ctx, act, actionContext)
+			if err != nil {
+				apply.eventLog.NewEntry().Errorf("error while applying action '%s': %s", act, err)
+			}
+			return err
+		})
+	}, apply.updater)
+
+	// merge changes made to the actual state in every partition
+	for name, partition := range partitions {
+		for key := range partition.NodeMap {
+			if instance, ok := contexts[name].ActualState.ComponentInstanceMap[key]; ok {
+				apply.actualState.ComponentInstanceMap[key] = instance
+			} else {
+				delete(apply.actualState.ComponentInstanceMap, key)
+			}
 		}
-		return err
-	}), apply.updater)
+	}
 
 	// No errors occurred
 	return apply.actualState, result
 }
 
-func (apply *EngineApply) executeAction(action action.Base, context *action.Context) (errResult error) {
+// Returns namespace of the component instance with a given key, which is used to partition the action plan
+func (apply *EngineApply) getNamespace(key string) string {
+	instance := apply.desiredState.ComponentInstanceMap[key]
+	if instance == nil {
+		instance = apply.actualState.ComponentInstanceMap[key]
+	}
+	if instance == nil {
+		return ""
+	}
+	return instance.Metadata.Key.Namespace
+}
+
+// Returns a copy of the state, which only contains component instances from the given partition of the action plan
+func (apply *EngineApply) partitionState(state *resolve.PolicyResolution, partition *action.Plan, isDesired bool) *resolve.PolicyResolution {
+	result := resolve.NewPolicyResolution(isDesired)
+	for key := range partition.NodeMap {
+		if instance, ok := state.ComponentInstanceMap[key]; ok {
+			result.ComponentInstanceMap[key] = instance
+		}
+	}
+	return result
+}
+
+func (apply *EngineApply) executeAction(ctx context.Context, action action.Base, actionContext *action.Context) (errResult error) {
 	// make sure we are converting panics into errors
 	defer func() {
 		if err := recover(); err != nil {
@@ -95,5 +141,5 @@ func (apply *EngineApply) executeAction(action action.Base, context *action.Cont
 		}
 	}()
 
-	return action.Apply(context)
+	return action.Apply(ctx, actionContext)
 }
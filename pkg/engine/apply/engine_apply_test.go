@@ -1,6 +1,8 @@
 package apply
 
 import (
+	"context"
+	"fmt"
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/engine/actual"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
@@ -16,6 +18,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 	"time"
 )
@@ -89,6 +92,391 @@ func checkApplyComponentCreateFail(t *testing.T, failAsPanic bool) {
 	assert.Equal(t, 0, len(actualState.ComponentInstanceMap), "Actual state should not be touched by apply()")
 }
 
+func TestApplyComponentCreateWaitForReady(t *testing.T) {
+	checkApplyComponentCreateWaitForReady(t, fake.NewNoOpCodePlugin(0), action.ApplyResult{Success: 5, Failed: 0, Skipped: 0})
+}
+
+func TestApplyComponentCreateWaitForReadyTimeout(t *testing.T) {
+	checkApplyComponentCreateWaitForReady(t, fake.NewNotReadyCodePlugin(), action.ApplyResult{Success: 0, Failed: 1, Skipped: 4})
+}
+
+func checkApplyComponentCreateWaitForReady(t *testing.T, codePlugin plugin.CodePlugin, expectedResult action.ApplyResult) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy, where component asks to wait until it's ready
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
+	service.Components[0].Code.WaitForReady = &lang.WaitForReady{Timeout: "50ms", Interval: "10ms"}
+	desired := newTestData(t, pBuilder)
+
+	// process all actions
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actualState,
+		actual.NewNoOpActionStateUpdater(),
+		desired.external(),
+		mockRegistryWithCodePlugin(codePlugin),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+	)
+
+	// check that policy apply finished with expected results
+	applyAndCheck(t, applier, expectedResult)
+
+	// if component didn't become ready, it should be reported in the log
+	if expectedResult.Failed > 0 {
+		verifier := event.NewLogVerifier("is not ready after 50ms", true)
+		applier.eventLog.Save(verifier)
+		assert.True(t, verifier.MatchedErrorsCount() > 0, "Event log should have an error message about component not being ready")
+	}
+}
+
+func TestApplyComponentHooks(t *testing.T) {
+	checkApplyComponentHooks(t, fake.NewNoOpCodePlugin(0), action.ApplyResult{Success: 7, Failed: 0, Skipped: 0})
+}
+
+func TestApplyComponentHooksFailure(t *testing.T) {
+	checkApplyComponentHooks(t, fake.NewNotReadyCodePlugin(), action.ApplyResult{Success: 0, Failed: 1, Skipped: 6})
+}
+
+func checkApplyComponentHooks(t *testing.T, codePlugin plugin.CodePlugin, expectedResult action.ApplyResult) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy, where component has pre-create and post-create hooks
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
+	hookCode := &lang.Code{
+		Type:         "helm",
+		Params:       util.NestedParameterMap{"cluster": "{{ .Labels.cluster }}"},
+		WaitForReady: &lang.WaitForReady{Timeout: "50ms", Interval: "10ms"},
+	}
+	service.Components[0].Hooks = &lang.ComponentHooks{
+		PreCreate:  []*lang.Hook{{Name: "init", Code: hookCode}},
+		PostCreate: []*lang.Hook{{Name: "seed", Code: hookCode}},
+	}
+	desired := newTestData(t, pBuilder)
+
+	// process all actions
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actualState,
+		actual.NewNoOpActionStateUpdater(),
+		desired.external(),
+		mockRegistryWithCodePlugin(codePlugin),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+	)
+
+	// check that policy apply finished with expected results (hooks are executed as separate actions)
+	applyAndCheck(t, applier, expectedResult)
+
+	// if pre-create hook failed, it should be reported in the log and component should not be created
+	if expectedResult.Failed > 0 {
+		verifier := event.NewLogVerifier("pre-create hook 'init' failed", true)
+		applier.eventLog.Save(verifier)
+		assert.True(t, verifier.MatchedErrorsCount() > 0, "Event log should have an error message about failed hook")
+	}
+}
+
+// destroyRecordingCodePlugin never reports code as ready and records whether Destroy got called with an expired context
+type destroyRecordingCodePlugin struct {
+	plugin.CodePlugin
+	destroyed         int
+	destroyCtxExpired bool
+}
+
+func (p *destroyRecordingCodePlugin) Destroy(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	p.destroyed++
+	p.destroyCtxExpired = p.destroyCtxExpired || ctx.Err() != nil
+	return p.CodePlugin.Destroy(ctx, deployName, params, eventLog)
+}
+
+func TestApplyComponentHookCleanupAfterTimeout(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy, where component has a hook, which times out before it becomes ready
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
+	hookCode := &lang.Code{
+		Type:         "helm",
+		Params:       util.NestedParameterMap{"cluster": "{{ .Labels.cluster }}"},
+		Timeout:      "50ms",
+		WaitForReady: &lang.WaitForReady{Timeout: "1m", Interval: "10ms"},
+	}
+	service.Components[0].Hooks = &lang.ComponentHooks{
+		PreCreate: []*lang.Hook{{Name: "init", Code: hookCode}},
+	}
+	desired := newTestData(t, pBuilder)
+
+	// process all actions
+	codePlugin := &destroyRecordingCodePlugin{CodePlugin: fake.NewNotReadyCodePlugin()}
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actualState,
+		actual.NewNoOpActionStateUpdater(),
+		desired.external(),
+		mockRegistryWithCodePlugin(codePlugin),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+	)
+	applyAndCheck(t, applier, action.ApplyResult{Success: 0, Failed: 1, Skipped: 5})
+
+	// hook code should be cleaned up with a context, which hasn't expired together with the hook
+	assert.Equal(t, 1, codePlugin.destroyed, "Hook code should be destroyed after timeout")
+	assert.False(t, codePlugin.destroyCtxExpired, "Hook code should be destroyed with a fresh context")
+}
+
+// createFailingCodePlugin fails to create code and records whether Destroy got called
+type createFailingCodePlugin struct {
+	plugin.CodePlugin
+	destroyed int
+}
+
+func (p *createFailingCodePlugin) Create(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	return fmt.Errorf("failed to create '%s'", deployName)
+}
+
+func (p *createFailingCodePlugin) Destroy(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	p.destroyed++
+	return p.CodePlugin.Destroy(ctx, deployName, params, eventLog)
+}
+
+func TestApplyComponentHookCleanupAfterCreateFailure(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy, where component has a hook, which code fails to be created
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
+	hookCode := &lang.Code{
+		Type:   "helm",
+		Params: util.NestedParameterMap{"cluster": "{{ .Labels.cluster }}"},
+	}
+	service.Components[0].Hooks = &lang.ComponentHooks{
+		PreCreate: []*lang.Hook{{Name: "init", Code: hookCode}},
+	}
+	desired := newTestData(t, pBuilder)
+
+	// process all actions
+	codePlugin := &createFailingCodePlugin{CodePlugin: fake.NewNoOpCodePlugin(0)}
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actualState,
+		actual.NewNoOpActionStateUpdater(),
+		desired.external(),
+		mockRegistryWithCodePlugin(codePlugin),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+	)
+	applyAndCheck(t, applier, action.ApplyResult{Success: 0, Failed: 1, Skipped: 5})
+
+	// partially created hook code should still be cleaned up
+	assert.Equal(t, 1, codePlugin.destroyed, "Hook code should be destroyed after it failed to be created")
+}
+
+func TestApplyComponentCreateCancelled(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy
+	desired := newTestData(t, makePolicyBuilder())
+
+	// apply with a context, which has already been cancelled
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actualState,
+		actual.NewNoOpActionStateUpdater(),
+		desired.external(),
+		mockRegistry(true, false),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// check that all actions have been skipped
+	actualState, result := applier.Apply(ctx)
+	assert.Equal(t, action.ApplyResult{Success: 0, Failed: 0, Skipped: 5, Total: 5}, *result, "All actions should be skipped")
+	assert.Empty(t, actualState.ComponentInstanceMap, "Actual state should not be changed")
+}
+
+func TestApplyComponentCreateTimeout(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy, where component has a timeout which is less than time plugin spends on every action
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
+	service.Components[0].Code.Timeout = "20ms"
+	desired := newTestData(t, pBuilder)
+
+	// process all actions
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actualState,
+		actual.NewNoOpActionStateUpdater(),
+		desired.external(),
+		mockRegistryWithCodePlugin(fake.NewNoOpCodePlugin(10*time.Second)),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+	)
+
+	// check that component creation failed due to timeout, and the rest of actions have been skipped
+	applyAndCheck(t, applier, action.ApplyResult{Success: 0, Failed: 1, Skipped: 4})
+
+	verifier := event.NewLogVerifier("context deadline exceeded", true)
+	applier.eventLog.Save(verifier)
+	assert.True(t, verifier.MatchedErrorsCount() > 0, "Event log should have an error message about timeout")
+}
+
+func TestApplyPartitionsAreIndependent(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy, with another namespace where component code always fails
+	pBuilder := makePolicyBuilder()
+	clusterObj := pBuilder.Policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)
+	pBuilder.SwitchNamespace("team")
+	pBuilder.AddRule(pBuilder.CriteriaTrue(), pBuilder.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, clusterObj.Name)))
+	service := pBuilder.AddService()
+	component := pBuilder.CodeComponent(nil, nil)
+	component.Code.Type = "raw"
+	pBuilder.AddServiceComponent(service, component)
+	pBuilder.AddDependency(pBuilder.AddUser(), pBuilder.AddContract(service, pBuilder.CriteriaTrue()))
+	desired := newTestData(t, pBuilder)
+
+	// process all actions
+	updater := &partitionResultRecorder{
+		ApplyResultUpdaterImpl: action.NewApplyResultUpdaterImpl(),
+		partitions:             make(map[string]action.ApplyResult),
+	}
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actualState,
+		actual.NewNoOpActionStateUpdater(),
+		desired.external(),
+		mockRegistryWithCodePlugins(map[string]plugin.CodePlugin{
+			"helm": fake.NewNoOpCodePlugin(0),
+			"raw":  fake.NewFailCodePlugin(false),
+		}),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		updater,
+	)
+
+	// check that failure in one namespace didn't affect the other one
+	actualState = applyAndCheck(t, applier, action.ApplyResult{Success: 5, Failed: 1, Skipped: 4})
+	assert.Equal(t, action.ApplyResult{Success: 5, Failed: 0, Skipped: 0, Total: 5}, updater.partitions["main"], "Namespace 'main' should be applied successfully")
+	assert.Equal(t, action.ApplyResult{Success: 0, Failed: 1, Skipped: 4, Total: 5}, updater.partitions["team"], "Namespace 'team' should fail")
+	assert.Equal(t, 2, len(actualState.ComponentInstanceMap), "Actual state should only have component instances from namespace 'main'")
+}
+
+func TestApplyComponentNotReadyIsWaitedForAgain(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy, where component asks to wait until it's ready
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
+	service.Components[0].Code.WaitForReady = &lang.WaitForReady{Timeout: "50ms", Interval: "10ms"}
+	desired := newTestData(t, pBuilder)
+
+	apply := func(codePlugin plugin.CodePlugin, expectedResult action.ApplyResult) {
+		t.Helper()
+		applier := NewEngineApply(
+			desired.policy(),
+			desired.resolution(),
+			actualState,
+			actual.NewNoOpActionStateUpdater(),
+			desired.external(),
+			mockRegistryWithCodePlugin(codePlugin),
+			diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+			event.NewLog(logrus.DebugLevel, "test-apply"),
+			action.NewApplyResultUpdaterImpl(),
+		)
+		actualState = applyAndCheck(t, applier, expectedResult)
+	}
+
+	getComponentInstance := func() *resolve.ComponentInstance {
+		for _, instance := range actualState.ComponentInstanceMap {
+			if instance.IsCode {
+				return instance
+			}
+		}
+		t.Fatal("Code component instance should be present in actual state")
+		return nil
+	}
+
+	// first cycle, component gets created, but it doesn't become ready
+	apply(fake.NewNotReadyCodePlugin(), action.ApplyResult{Success: 0, Failed: 1, Skipped: 4})
+	assert.True(t, getComponentInstance().NotReady, "Component instance should be marked as not ready in actual state")
+	assert.Equal(t, 1, getComponentInstance().NotReadyAttempts, "Failed attempt should be recorded in actual state")
+
+	// second cycle, component should not be waited for again until backoff passes, but dependent actions still wait
+	codePlugin := &updateCountingCodePlugin{CodePlugin: fake.NewNotReadyCodePlugin()}
+	apply(codePlugin, action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+	assert.Equal(t, 0, codePlugin.statusChecks, "Status should not be checked until backoff passes")
+	assert.Equal(t, 1, getComponentInstance().NotReadyAttempts, "No new attempts should be recorded until backoff passes")
+
+	// third cycle, once backoff passes, component should be waited for again (and it still doesn't become ready)
+	getComponentInstance().NotReadyCheckedAt = time.Now().Add(-time.Hour)
+	apply(codePlugin, action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+	assert.True(t, codePlugin.statusChecks > 0, "Status should be checked once backoff passes")
+	assert.Equal(t, 0, codePlugin.updates, "Component instance should not be updated while it's waited for")
+	assert.True(t, getComponentInstance().NotReady, "Component instance should still be marked as not ready in actual state")
+	assert.Equal(t, 2, getComponentInstance().NotReadyAttempts, "Failed attempt should be recorded in actual state")
+
+	// fourth cycle, component becomes ready and the rest of actions get applied
+	getComponentInstance().NotReadyCheckedAt = time.Now().Add(-time.Hour)
+	apply(fake.NewNoOpCodePlugin(0), action.ApplyResult{Success: 4, Failed: 0, Skipped: 0})
+	assert.False(t, getComponentInstance().NotReady, "Component instance should be marked as ready in actual state")
+	assert.Equal(t, 0, getComponentInstance().NotReadyAttempts, "Failed attempts should be reset once component instance becomes ready")
+
+	// after that, there should be nothing to do
+	assert.Equal(t, uint32(0), diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan.NumberOfActions(), "Ready component instance should not be waited for again")
+}
+
+// updateCountingCodePlugin records how many times code got updated and how many times its status got checked
+type updateCountingCodePlugin struct {
+	plugin.CodePlugin
+	updates      int
+	statusChecks int
+}
+
+func (p *updateCountingCodePlugin) Update(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	p.updates++
+	return p.CodePlugin.Update(ctx, deployName, params, eventLog)
+}
+
+func (p *updateCountingCodePlugin) Status(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
+	p.statusChecks++
+	return p.CodePlugin.Status(ctx, deployName, params, eventLog)
+}
+
 func TestDiffHasUpdatedComponentsAndCheckTimes(t *testing.T) {
 	/*
 		Step 1: actual = empty, desired = test policy, check = dependency update/create times
@@ -121,7 +509,7 @@ func TestDiffHasUpdatedComponentsAndCheckTimes(t *testing.T) {
 	cluster := desired.policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)
 	contract := desired.policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract)
 	service := desired.policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
-	key := resolve.NewComponentInstanceKey(cluster, contract, contract.Contexts[0], nil, service, service.Components[0])
+	key := resolve.NewComponentInstanceKey(cluster, contract, nil, contract.Contexts[0], nil, service, service.Components[0])
 	keyService := key.GetParentServiceKey()
 
 	// Check that original dependency was resolved successfully
@@ -344,7 +732,7 @@ func resolvePolicy(t *testing.T, b *builder.PolicyBuilder) *resolve.PolicyResolu
 
 func applyAndCheck(t *testing.T, apply *EngineApply, expectedResult action.ApplyResult) *resolve.PolicyResolution {
 	t.Helper()
-	actualState, result := apply.Apply()
+	actualState, result := apply.Apply(context.Background())
 
 	ok := assert.Equal(t, expectedResult.Success, result.Success, "Number of successfully executed actions")
 	ok = ok && assert.Equal(t, expectedResult.Failed, result.Failed, "Number of failed actions")
@@ -384,6 +772,17 @@ func getInstanceInternal(t *testing.T, key string, resolution *resolve.PolicyRes
 }
 
 func mockRegistry(applySuccess, failAsPanic bool) plugin.Registry {
+	if applySuccess {
+		return mockRegistryWithCodePlugin(fake.NewNoOpCodePlugin(0))
+	}
+	return mockRegistryWithCodePlugin(fake.NewFailCodePlugin(failAsPanic))
+}
+
+func mockRegistryWithCodePlugin(codePlugin plugin.CodePlugin) plugin.Registry {
+	return mockRegistryWithCodePlugins(map[string]plugin.CodePlugin{"helm": codePlugin})
+}
+
+func mockRegistryWithCodePlugins(codePlugins map[string]plugin.CodePlugin) plugin.Registry {
 	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
 	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
 
@@ -392,12 +791,27 @@ func mockRegistry(applySuccess, failAsPanic bool) plugin.Registry {
 	}
 
 	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
-	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
-		if applySuccess {
-			return fake.NewNoOpCodePlugin(0), nil
+	for codeType, codePlugin := range codePlugins {
+		codePlugin := codePlugin
+		codeTypes["kubernetes"][codeType] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+			return codePlugin, nil
 		}
-		return fake.NewFailCodePlugin(failAsPanic), nil
 	}
 
 	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes)
 }
+
+// partitionResultRecorder records results of every partition of the action plan, once it has been applied
+type partitionResultRecorder struct {
+	*action.ApplyResultUpdaterImpl
+	mutex      sync.Mutex
+	partitions map[string]action.ApplyResult
+}
+
+func (recorder *partitionResultRecorder) PartitionUpdated(partition string, result action.ApplyResult, done bool) {
+	if done {
+		recorder.mutex.Lock()
+		recorder.partitions[partition] = result
+		recorder.mutex.Unlock()
+	}
+}
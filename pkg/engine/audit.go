@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"strings"
+	"time"
+)
+
+// AuditEntryObject is Info for AuditEntry
+var AuditEntryObject = &runtime.Info{
+	Kind:        "audit-entry",
+	Storable:    true,
+	Versioned:   true,
+	Constructor: func() runtime.Object { return &AuditEntry{} },
+}
+
+// AuditEntryKey is the default key for the AuditEntry object (there is only one audit log, every entry is stored as its new generation)
+var AuditEntryKey = runtime.KeyFromParts(runtime.SystemNS, AuditEntryObject.Kind, runtime.EmptyName)
+
+const (
+	// AuditActionLogin represents user login attempt
+	AuditActionLogin = "login"
+	// AuditActionPolicyUpdate represents attempt to add or update objects in the policy
+	AuditActionPolicyUpdate = "policy-update"
+	// AuditActionPolicyDelete represents attempt to delete objects from the policy
+	AuditActionPolicyDelete = "policy-delete"
+	// AuditActionStateReset represents attempt to reset actual state
+	AuditActionStateReset = "state-reset"
+	// AuditActionDependencyMigrate represents attempt to migrate dependencies on sticky services to a new placement
+	AuditActionDependencyMigrate = "dependency-migrate"
+	// AuditActionRevisionCancel represents attempt to cancel the revision which is being applied
+	AuditActionRevisionCancel = "revision-cancel"
+	// AuditActionStatePause represents attempt to pause the enforcer
+	AuditActionStatePause = "state-pause"
+	// AuditActionStateResume represents attempt to resume the enforcer
+	AuditActionStateResume = "state-resume"
+)
+
+// AuditEntry is an immutable record of a single operation performed by a user, which gets appended to the audit log
+type AuditEntry struct {
+	runtime.TypeKind `yaml:",inline"`
+	Metadata         runtime.GenerationMetadata
+
+	// CreatedAt is when the operation was performed
+	CreatedAt time.Time
+
+	// User is the name of the user who performed the operation
+	User string
+
+	// SourceIP is the IP address the request came from
+	SourceIP string
+
+	// Action is the operation performed
+	Action string
+
+	// Noop indicates that the operation was a dry run and nothing has been changed
+	Noop bool
+
+	// Objects is the list of policy objects affected by the operation
+	Objects []*AuditObject
+
+	// Success indicates whether the operation succeeded or not
+	Success bool
+
+	// Error holds an error message if operation failed
+	Error string
+
+	// PolicyGeneration is the policy generation after the operation has been performed
+	PolicyGeneration runtime.Generation
+}
+
+// AuditObject is a reference to a policy object affected by the operation
+type AuditObject struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+// NewAuditEntry creates a new audit entry (not successful by default)
+func NewAuditEntry(action string, user string, sourceIP string) *AuditEntry {
+	return &AuditEntry{
+		TypeKind:  AuditEntryObject.GetTypeKind(),
+		CreatedAt: time.Now(),
+		User:      user,
+		SourceIP:  sourceIP,
+		Action:    action,
+		Objects:   []*AuditObject{},
+	}
+}
+
+// AddObject adds a reference to the affected object into the audit entry
+func (entry *AuditEntry) AddObject(obj runtime.Storable) {
+	entry.Objects = append(entry.Objects, &AuditObject{
+		Namespace: obj.GetNamespace(),
+		Kind:      obj.GetKind(),
+		Name:      obj.GetName(),
+	})
+}
+
+// GetName returns AuditEntry name
+func (entry *AuditEntry) GetName() string {
+	return runtime.EmptyName
+}
+
+// GetNamespace returns AuditEntry namespace
+func (entry *AuditEntry) GetNamespace() string {
+	return runtime.SystemNS
+}
+
+// GetGeneration returns AuditEntry generation
+func (entry *AuditEntry) GetGeneration() runtime.Generation {
+	return entry.Metadata.Generation
+}
+
+// SetGeneration sets AuditEntry generation
+func (entry *AuditEntry) SetGeneration(gen runtime.Generation) {
+	entry.Metadata.Generation = gen
+}
+
+// GetDefaultColumns returns default set of columns to be displayed
+func (entry *AuditEntry) GetDefaultColumns() []string {
+	return []string{"Time", "User", "Source IP", "Action", "Objects", "Result"}
+}
+
+// AsColumns returns AuditEntry representation as columns
+func (entry *AuditEntry) AsColumns() map[string]string {
+	objects := []string{}
+	for _, obj := range entry.Objects {
+		objects = append(objects, runtime.KeyFromParts(obj.Namespace, obj.Kind, obj.Name))
+	}
+
+	action := entry.Action
+	if entry.Noop {
+		action += " (noop)"
+	}
+
+	result := "success"
+	if !entry.Success {
+		result = "failed: " + entry.Error
+	}
+
+	return map[string]string{
+		"Time":      entry.CreatedAt.Format(time.RFC3339),
+		"User":      entry.User,
+		"Source IP": entry.SourceIP,
+		"Action":    action,
+		"Objects":   strings.Join(objects, "\n"),
+		"Result":    result,
+	}
+}
+
+// AuditFilter defines criteria for querying audit log. Empty fields are not taken into account
+type AuditFilter struct {
+	User      string
+	Namespace string
+	Kind      string
+	From      time.Time
+	To        time.Time
+
+	// Limit is the maximum number of the most recent matching entries to return. Zero means no limit
+	Limit int
+}
+
+// Matches returns true if audit entry satisfies all criteria specified in the filter
+func (filter *AuditFilter) Matches(entry *AuditEntry) bool {
+	if len(filter.User) > 0 && filter.User != entry.User {
+		return false
+	}
+	if !filter.From.IsZero() && entry.CreatedAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && entry.CreatedAt.After(filter.To) {
+		return false
+	}
+	if len(filter.Namespace) == 0 && len(filter.Kind) == 0 {
+		return true
+	}
+
+	// at least one of the affected objects should match both namespace and kind
+	for _, obj := range entry.Objects {
+		if (len(filter.Namespace) == 0 || filter.Namespace == obj.Namespace) && (len(filter.Kind) == 0 || filter.Kind == obj.Kind) {
+			return true
+		}
+	}
+	return false
+}
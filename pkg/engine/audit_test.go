@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAuditFilterMatches(t *testing.T) {
+	now := time.Now()
+
+	entry := NewAuditEntry(AuditActionPolicyUpdate, "alice", "10.0.0.1")
+	entry.CreatedAt = now
+	entry.AddObject(&lang.Service{TypeKind: lang.ServiceObject.GetTypeKind(), Metadata: lang.Metadata{Namespace: "main", Name: "db"}})
+	entry.AddObject(&lang.Contract{TypeKind: lang.ContractObject.GetTypeKind(), Metadata: lang.Metadata{Namespace: "social", Name: "db"}})
+
+	login := NewAuditEntry(AuditActionLogin, "bob", "10.0.0.2")
+	login.CreatedAt = now
+
+	tests := []struct {
+		filter *AuditFilter
+		entry  *AuditEntry
+		result bool
+	}{
+		// empty filter matches everything
+		{&AuditFilter{}, entry, true},
+		{&AuditFilter{}, login, true},
+
+		// user
+		{&AuditFilter{User: "alice"}, entry, true},
+		{&AuditFilter{User: "bob"}, entry, false},
+
+		// time range (both ends inclusive)
+		{&AuditFilter{From: now, To: now}, entry, true},
+		{&AuditFilter{From: now.Add(time.Second)}, entry, false},
+		{&AuditFilter{To: now.Add(-time.Second)}, entry, false},
+
+		// namespace and kind should match the same object
+		{&AuditFilter{Namespace: "main"}, entry, true},
+		{&AuditFilter{Kind: lang.ContractObject.Kind}, entry, true},
+		{&AuditFilter{Namespace: "main", Kind: lang.ServiceObject.Kind}, entry, true},
+		{&AuditFilter{Namespace: "main", Kind: lang.ContractObject.Kind}, entry, false},
+		{&AuditFilter{Namespace: "unknown"}, entry, false},
+
+		// entries without objects never match namespace or kind
+		{&AuditFilter{Namespace: "main"}, login, false},
+		{&AuditFilter{User: "bob", Kind: lang.ServiceObject.Kind}, login, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.result, test.filter.Matches(test.entry), "Audit filter %+v should return %t for entry %+v", test.filter, test.result, test.entry)
+	}
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DependencyStatusObject is Info for DependencyStatus
+var DependencyStatusObject = &runtime.Info{
+	Kind:        "dependency-status",
+	Storable:    true,
+	Versioned:   false,
+	Constructor: func() runtime.Object { return &DependencyStatus{} },
+}
+
+// DependencyStatusKey returns a key of the DependencyStatus object for a given dependency key
+func DependencyStatusKey(dependencyKey string) string {
+	return runtime.KeyFromParts(runtime.SystemNS, DependencyStatusObject.Kind, dependencyKey)
+}
+
+const (
+	// DependencyPhaseError means that dependency could not be resolved, or actions for it failed to apply
+	DependencyPhaseError = "error"
+
+	// DependencyPhaseResolved means that dependency has been resolved, but actions for it are held back (e.g. the
+	// enforcer is paused) or revision has been cancelled before they got applied
+	DependencyPhaseResolved = "resolved"
+
+	// DependencyPhaseDeploying means that dependency has been resolved and actions for it are being applied
+	DependencyPhaseDeploying = "deploying"
+
+	// DependencyPhaseReady means that dependency has been resolved and deployed (i.e. actual state matches desired
+	// state for all of its component instances)
+	DependencyPhaseReady = "ready"
+)
+
+// DependencyStatus holds the status of a single dependency, as recorded by the enforcer. Unlike resolution errors,
+// which are only available in the resolve log of a revision, status is kept per dependency and gets updated on
+// every enforcement cycle
+type DependencyStatus struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// DependencyKey is the key of the dependency
+	DependencyKey string
+
+	// Phase is the phase dependency is in (error, resolved, deploying or ready)
+	Phase string
+
+	// LastTransitionTime is when dependency moved into its current phase
+	LastTransitionTime time.Time
+
+	// Message is a human-readable explanation of the current phase (e.g. the reason why dependency is failing)
+	Message string `yaml:",omitempty"`
+
+	// Contract is the name of the contract, which dependency got resolved with
+	Contract string `yaml:",omitempty"`
+
+	// ContractVersion is the version of the contract, which dependency got resolved with (empty if contract has no
+	// versions)
+	ContractVersion string `yaml:",omitempty"`
+
+	// Context is the name of the contract context, which dependency got resolved into
+	Context string `yaml:",omitempty"`
+
+	// Cluster is the name of the cluster, which dependency got resolved into
+	Cluster string `yaml:",omitempty"`
+
+	// ComponentKeys is a sorted list of keys of component instances, which have been allocated for the dependency
+	ComponentKeys []string `yaml:",omitempty"`
+}
+
+// NewDependencyStatus creates a new DependencyStatus object for a given dependency key
+func NewDependencyStatus(dependencyKey string) *DependencyStatus {
+	return &DependencyStatus{
+		TypeKind:      DependencyStatusObject.GetTypeKind(),
+		DependencyKey: dependencyKey,
+	}
+}
+
+// GetName returns object name
+func (status *DependencyStatus) GetName() string {
+	return status.DependencyKey
+}
+
+// GetNamespace returns object namespace
+func (status *DependencyStatus) GetNamespace() string {
+	return runtime.SystemNS
+}
+
+// Update copies phase and details from the next status. Transition time gets set to a given time only if the phase
+// changes. It returns true if anything has changed, so that status only needs to be saved when it gets changed
+func (status *DependencyStatus) Update(next *DependencyStatus, now time.Time) bool {
+	changed := status.Phase != next.Phase ||
+		status.Message != next.Message ||
+		status.Contract != next.Contract ||
+		status.ContractVersion != next.ContractVersion ||
+		status.Context != next.Context ||
+		status.Cluster != next.Cluster ||
+		!equalStrings(status.ComponentKeys, next.ComponentKeys)
+
+	if status.Phase != next.Phase {
+		status.LastTransitionTime = now
+	}
+	status.Phase = next.Phase
+	status.Message = next.Message
+	status.Contract = next.Contract
+	status.ContractVersion = next.ContractVersion
+	status.Context = next.Context
+	status.Cluster = next.Cluster
+	status.ComponentKeys = next.ComponentKeys
+	return changed
+}
+
+// DependencyStatuses is a map from dependency key to its status
+type DependencyStatuses map[string]*DependencyStatus
+
+// NewDependencyStatuses calculates statuses of all dependencies in the desired state. Dependencies which haven't been
+// resolved end up in the error phase, while all resolved dependencies are ready until they get marked otherwise.
+// Resolution errors get passed through a given function, so that secret values can be masked
+func NewDependencyStatuses(desiredState *resolve.PolicyResolution, mask func(string) string) DependencyStatuses {
+	// collect component instances for every dependency
+	componentKeys := make(map[string][]string)
+	for key, instance := range desiredState.ComponentInstanceMap {
+		for dKey := range instance.DependencyKeys {
+			componentKeys[dKey] = append(componentKeys[dKey], key)
+		}
+	}
+
+	result := make(DependencyStatuses)
+	for dKey, dResolution := range desiredState.GetDependencyInstanceMap() {
+		status := NewDependencyStatus(dKey)
+		result[dKey] = status
+		if !dResolution.Resolved {
+			status.Phase = DependencyPhaseError
+			status.Message = mask(firstLine(dResolution.Error))
+			continue
+		}
+
+		status.Phase = DependencyPhaseReady
+		status.ContractVersion = dResolution.ContractVersion
+		if instance := desiredState.ComponentInstanceMap[dResolution.ComponentInstanceKey]; instance != nil {
+			status.Contract = instance.Metadata.Key.ContractName
+			status.Context = instance.Metadata.Key.ContextNameWithKeys
+			status.Cluster = instance.Metadata.Key.ClusterName
+		}
+		status.ComponentKeys = componentKeys[dKey]
+		sort.Strings(status.ComponentKeys)
+	}
+	return result
+}
+
+// Mark moves given resolved dependencies into a given phase. Dependencies which are in the error phase already (i.e.
+// haven't been resolved) stay there
+func (statuses DependencyStatuses) Mark(dependencyKeys map[string]bool, phase string, message string) {
+	for dKey := range dependencyKeys {
+		status := statuses[dKey]
+		if status == nil || status.Phase == DependencyPhaseError {
+			continue
+		}
+		status.Phase = phase
+		status.Message = message
+	}
+}
+
+// Only the first line of an error is shown in the status (e.g. errors caused by panics include the stack trace)
+func firstLine(message string) string {
+	return strings.SplitN(message, "\n", 2)[0]
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDependencyStatuses(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// service which can be resolved
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContract(service, b.CriteriaTrue())
+	dOk := b.AddDependency(b.AddUser(), contract)
+
+	// contract with a context which never matches
+	serviceFailed := b.AddService()
+	contractFailed := b.AddContract(serviceFailed, b.Criteria("false", "true", "false"))
+	dFailed := b.AddDependency(b.AddUser(), contractFailed)
+
+	desiredState := resolve.NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).ResolveAllDependencies()
+	statuses := NewDependencyStatuses(desiredState, strings.ToUpper)
+
+	// resolved dependency should be ready and point to where it got resolved into
+	statusOk := statuses[runtime.KeyForStorable(dOk)]
+	if assert.NotNil(t, statusOk, "Status should be calculated for resolved dependency") {
+		assert.Equal(t, DependencyPhaseReady, statusOk.Phase, "Resolved dependency should be ready")
+		assert.Equal(t, contract.Name, statusOk.Contract, "Contract should be recorded in status")
+		assert.Equal(t, cluster.Name, statusOk.Cluster, "Cluster should be recorded in status")
+		assert.NotEmpty(t, statusOk.Context, "Context should be recorded in status")
+		assert.Len(t, statusOk.ComponentKeys, 2, "Service and its component should be recorded in status")
+	}
+
+	// failed dependency should be in error phase with the masked resolution error
+	statusFailed := statuses[runtime.KeyForStorable(dFailed)]
+	if assert.NotNil(t, statusFailed, "Status should be calculated for failed dependency") {
+		assert.Equal(t, DependencyPhaseError, statusFailed.Phase, "Failed dependency should be in error phase")
+		assert.NotEmpty(t, statusFailed.Message, "Failed dependency should have a message")
+		assert.Equal(t, strings.ToUpper(statusFailed.Message), statusFailed.Message, "Message should be passed through the mask function")
+		assert.Empty(t, statusFailed.ComponentKeys, "Failed dependency should have no component instances")
+	}
+
+	// marking dependencies should not affect the ones which have failed
+	statuses.Mark(map[string]bool{runtime.KeyForStorable(dOk): true, runtime.KeyForStorable(dFailed): true}, DependencyPhaseDeploying, "deploying")
+	assert.Equal(t, DependencyPhaseDeploying, statusOk.Phase, "Resolved dependency should be marked")
+	assert.Equal(t, "deploying", statusOk.Message, "Resolved dependency should be marked with a message")
+	assert.Equal(t, DependencyPhaseError, statusFailed.Phase, "Failed dependency should stay in error phase")
+}
+
+func TestDependencyStatusUpdate(t *testing.T) {
+	status := NewDependencyStatus("main/dependency/test")
+	start := time.Now()
+
+	// moving into a new phase should set transition time
+	next := &DependencyStatus{Phase: DependencyPhaseDeploying, ComponentKeys: []string{"a"}}
+	assert.True(t, status.Update(next, start), "Status should change when phase changes")
+	assert.Equal(t, start, status.LastTransitionTime, "Transition time should be set when phase changes")
+
+	// same status should not be changed
+	later := start.Add(time.Minute)
+	assert.False(t, status.Update(&DependencyStatus{Phase: DependencyPhaseDeploying, ComponentKeys: []string{"a"}}, later), "Status should not change")
+
+	// changing details within the same phase should keep transition time
+	assert.True(t, status.Update(&DependencyStatus{Phase: DependencyPhaseDeploying, ComponentKeys: []string{"a", "b"}}, later), "Status should change when component keys change")
+	assert.Equal(t, start, status.LastTransitionTime, "Transition time should be kept when phase stays the same")
+
+	// moving into another phase should update transition time
+	assert.True(t, status.Update(&DependencyStatus{Phase: DependencyPhaseReady}, later), "Status should change when phase changes")
+	assert.Equal(t, later, status.LastTransitionTime, "Transition time should be updated when phase changes")
+	assert.Equal(t, DependencyPhaseReady, status.Phase, "Phase should be updated")
+}
@@ -0,0 +1,34 @@
+package diff
+
+import (
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+)
+
+// AffectedDependencies returns a set of keys of dependencies, which are affected by actions in a given plan (i.e.
+// dependencies which won't be deployed until these actions get applied). Attaching and detaching a dependency only
+// affects that particular dependency, while any other action on a component instance affects all dependencies which
+// use it, either in prev (actual) or next (desired) state
+func AffectedDependencies(plan *action.Plan, next *resolve.PolicyResolution, prev *resolve.PolicyResolution) map[string]bool {
+	result := make(map[string]bool)
+	for key, node := range plan.NodeMap {
+		for _, act := range node.Actions {
+			switch act := act.(type) {
+			case *component.AttachDependencyAction:
+				result[act.DependencyID] = true
+			case *component.DetachDependencyAction:
+				result[act.DependencyID] = true
+			default:
+				for _, state := range []*resolve.PolicyResolution{prev, next} {
+					if instance := state.ComponentInstanceMap[key]; instance != nil {
+						for dKey := range instance.DependencyKeys {
+							result[dKey] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,177 @@
+package diff
+
+import (
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"strings"
+)
+
+// Kinds of changes, which impact of policy changes is grouped by
+const (
+	// ImpactCreate means that dependencies get resolved and component instances get created
+	ImpactCreate = "create"
+
+	// ImpactUpdate means that dependencies stay resolved, but the component instances they use get changed
+	ImpactUpdate = "update"
+
+	// ImpactDelete means that dependencies are no longer resolved (i.e. they get broken) and component instances get
+	// deleted
+	ImpactDelete = "delete"
+)
+
+// ImpactGroup is a set of dependencies, users, component instances and clusters, which are impacted by policy changes
+// in the same way (i.e. create, update or delete)
+type ImpactGroup struct {
+	// Change is the kind of change (create, update or delete)
+	Change string
+
+	// Dependencies is a sorted list of keys of impacted dependencies
+	Dependencies []string
+
+	// Users is a sorted list of names of users, who own impacted dependencies
+	Users []string
+
+	// ComponentInstances is a sorted list of keys of impacted component instances
+	ComponentInstances []string
+
+	// Clusters is a sorted list of names of clusters, which impacted component instances belong to
+	Clusters []string
+}
+
+// GetDefaultColumns returns default set of columns to be displayed
+func (group *ImpactGroup) GetDefaultColumns() []string {
+	return []string{"Change", "Dependencies", "Users", "Component Instances", "Clusters"}
+}
+
+// AsColumns returns ImpactGroup representation as columns
+func (group *ImpactGroup) AsColumns() map[string]string {
+	return map[string]string{
+		"Change":              group.Change,
+		"Dependencies":        joinOrNone(group.Dependencies),
+		"Users":               joinOrNone(group.Users),
+		"Component Instances": joinOrNone(group.ComponentInstances),
+		"Clusters":            joinOrNone(group.Clusters),
+	}
+}
+
+// IsEmpty returns true if nothing is impacted
+func (group *ImpactGroup) IsEmpty() bool {
+	return len(group.Dependencies) <= 0 && len(group.ComponentInstances) <= 0
+}
+
+func joinOrNone(values []string) string {
+	if len(values) <= 0 {
+		return "(none)"
+	}
+	return strings.Join(values, "\n")
+}
+
+// Impact returns dependencies, users, component instances and clusters which are impacted by the difference between
+// prev and next states, grouped by create, update and delete. Both states must be desired states, i.e. resolution of
+// the policy before and after the change. Policies are used to look up users who own the dependencies.
+//
+// Component instances are impacted if they get created, updated or deleted. Dependencies are impacted if they get
+// resolved, if they are no longer resolved, or if they stay resolved but the component instances they use are affected
+// by actions (i.e. joining action plan with dependency keys of component instances)
+func (diff *PolicyResolutionDiff) Impact(policyNext *lang.Policy, policyPrev *lang.Policy) []*ImpactGroup {
+	dependencies := map[string]map[string]bool{ImpactCreate: {}, ImpactUpdate: {}, ImpactDelete: {}}
+	instances := map[string]map[string]bool{ImpactCreate: {}, ImpactUpdate: {}, ImpactDelete: {}}
+	clusters := map[string]map[string]bool{ImpactCreate: {}, ImpactUpdate: {}, ImpactDelete: {}}
+
+	// component instances, which get created, updated or deleted
+	for key, node := range diff.ActionPlan.NodeMap {
+		for _, act := range node.Actions {
+			switch act.(type) {
+			case *component.CreateAction:
+				instances[ImpactCreate][key] = true
+				diff.addCluster(clusters[ImpactCreate], key)
+			case *component.UpdateAction:
+				instances[ImpactUpdate][key] = true
+				diff.addCluster(clusters[ImpactUpdate], key)
+			case *component.DeleteAction:
+				instances[ImpactDelete][key] = true
+				diff.addCluster(clusters[ImpactDelete], key)
+			}
+		}
+	}
+
+	// dependencies, which get resolved, broken or affected by actions
+	affected := AffectedDependencies(diff.ActionPlan, diff.Next, diff.Prev)
+	dMapNext := diff.Next.GetDependencyInstanceMap()
+	dMapPrev := diff.Prev.GetDependencyInstanceMap()
+	for _, dKey := range util.GetSortedStringKeys(mergeDependencyMaps(dMapNext, dMapPrev)) {
+		next, prev := dMapNext[dKey], dMapPrev[dKey]
+		resolvedNext := next != nil && next.Resolved
+		resolvedPrev := prev != nil && prev.Resolved
+		switch {
+		case resolvedNext && !resolvedPrev:
+			dependencies[ImpactCreate][dKey] = true
+			diff.addCluster(clusters[ImpactCreate], next.ComponentInstanceKey)
+		case !resolvedNext && resolvedPrev:
+			dependencies[ImpactDelete][dKey] = true
+			diff.addCluster(clusters[ImpactDelete], prev.ComponentInstanceKey)
+		case resolvedNext && resolvedPrev:
+			if affected[dKey] || next.ComponentInstanceKey != prev.ComponentInstanceKey || next.ContractVersion != prev.ContractVersion {
+				dependencies[ImpactUpdate][dKey] = true
+				diff.addCluster(clusters[ImpactUpdate], next.ComponentInstanceKey)
+				diff.addCluster(clusters[ImpactUpdate], prev.ComponentInstanceKey)
+			}
+		}
+	}
+
+	// users, who own impacted dependencies
+	owners := dependencyOwners(policyPrev)
+	for dKey, user := range dependencyOwners(policyNext) {
+		owners[dKey] = user
+	}
+
+	result := []*ImpactGroup{}
+	for _, change := range []string{ImpactCreate, ImpactUpdate, ImpactDelete} {
+		users := make(map[string]bool)
+		for dKey := range dependencies[change] {
+			if user, ok := owners[dKey]; ok {
+				users[user] = true
+			}
+		}
+		result = append(result, &ImpactGroup{
+			Change:             change,
+			Dependencies:       util.GetSortedStringKeys(dependencies[change]),
+			Users:              util.GetSortedStringKeys(users),
+			ComponentInstances: util.GetSortedStringKeys(instances[change]),
+			Clusters:           util.GetSortedStringKeys(clusters[change]),
+		})
+	}
+	return result
+}
+
+// Adds the cluster of a given component instance (looked up in both states) to the set of clusters
+func (diff *PolicyResolutionDiff) addCluster(clusters map[string]bool, key string) {
+	for _, state := range []*resolve.PolicyResolution{diff.Next, diff.Prev} {
+		if instance := state.ComponentInstanceMap[key]; instance != nil {
+			clusters[instance.GetCluster()] = true
+			return
+		}
+	}
+}
+
+func mergeDependencyMaps(maps ...map[string]*resolve.DependencyResolution) map[string]bool {
+	result := make(map[string]bool)
+	for _, dMap := range maps {
+		for dKey := range dMap {
+			result[dKey] = true
+		}
+	}
+	return result
+}
+
+// Returns a map from dependency key to the name of the user who owns the dependency
+func dependencyOwners(policy *lang.Policy) map[string]string {
+	result := make(map[string]string)
+	for _, obj := range policy.GetObjectsByKind(lang.DependencyObject.Kind) {
+		result[runtime.KeyForStorable(obj)] = obj.(*lang.Dependency).User
+	}
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/util"
 )
 
@@ -17,6 +18,9 @@ type PolicyResolutionDiff struct {
 
 	// Plan is a plan of actions to transform Prev to Next
 	ActionPlan *action.Plan
+
+	// Warnings is a list of instances of sticky services, which would move if they were not sticky
+	Warnings []string
 }
 
 // NewPolicyResolutionDiff calculates difference between prev and next policy resolution structs (actual and desired states).
@@ -32,9 +36,18 @@ func NewPolicyResolutionDiff(next *resolve.PolicyResolution, prev *resolve.Polic
 		ActionPlan: action.NewPlan(),
 	}
 	result.compareAndProduceActions()
+	result.collectWarnings()
 	return result
 }
 
+// Collect "would move" warnings for instances of sticky services, which were kept in place
+func (diff *PolicyResolutionDiff) collectWarnings() {
+	dMap := diff.Next.GetDependencyInstanceMap()
+	for _, dKey := range util.GetSortedStringKeys(dMap) {
+		diff.Warnings = append(diff.Warnings, dMap[dKey].WouldMove...)
+	}
+}
+
 // Produce a list of actions
 func (diff *PolicyResolutionDiff) compareAndProduceActions() {
 	// Produce a map of all component instances
@@ -98,7 +111,9 @@ func (diff *PolicyResolutionDiff) buildActions(key string) {
 
 	// See if a component needs to be instantiated
 	if len(depKeysPrev) <= 0 && len(depKeysNext) > 0 {
+		addHookActions(node, nextInstance, lang.HookPreCreate)
 		node.AddAction(component.NewCreateAction(key, nextInstance.CalculatedCodeParams), true)
+		addHookActions(node, nextInstance, lang.HookPostCreate)
 		endpointsAction = true
 	}
 
@@ -106,7 +121,9 @@ func (diff *PolicyResolutionDiff) buildActions(key string) {
 	if len(depKeysPrev) > 0 && len(depKeysNext) > 0 && isCodeComponent {
 		sameParams := prevInstance.CalculatedCodeParams.DeepEqual(nextInstance.CalculatedCodeParams)
 		if !sameParams {
+			addHookActions(node, nextInstance, lang.HookPreUpdate)
 			node.AddAction(component.NewUpdateAction(key, prevInstance.CalculatedCodeParams, nextInstance.CalculatedCodeParams), true)
+			addHookActions(node, nextInstance, lang.HookPostUpdate)
 
 			// indicate that a parent service component instance gets updated as well
 			// this is required for adjusting update/creation times of a service with changed component
@@ -115,6 +132,10 @@ func (diff *PolicyResolutionDiff) buildActions(key string) {
 			serviceNode := diff.ActionPlan.GetActionGraphNode(serviceKey)
 			serviceNode.AddAction(component.NewUpdateAction(serviceKey, util.NestedParameterMap{}, util.NestedParameterMap{}), true)
 
+			endpointsAction = true
+		} else if prevInstance.NotReady {
+			// component instance didn't become ready during the previous run, so it should be waited for again
+			node.AddAction(component.NewWaitForReadyAction(key), true)
 			endpointsAction = true
 		}
 	}
@@ -135,7 +156,9 @@ func (diff *PolicyResolutionDiff) buildActions(key string) {
 
 	// See if a component needs to be destructed
 	if len(depKeysPrev) > 0 && len(depKeysNext) <= 0 {
+		addHookActions(node, prevInstance, lang.HookPreDelete)
 		node.AddAction(component.NewDeleteAction(key, prevInstance.CalculatedCodeParams), true)
+		addHookActions(node, prevInstance, lang.HookPostDelete)
 		endpointsAction = false
 	}
 
@@ -144,3 +167,11 @@ func (diff *PolicyResolutionDiff) buildActions(key string) {
 		node.AddAction(component.NewEndpointsAction(key), true)
 	}
 }
+
+// Adds actions for lifecycle hooks of a component instance in a given phase. Since actions of a node are executed
+// sequentially, hooks will run right before or right after the action they have been added next to
+func addHookActions(node *action.GraphNode, instance *resolve.ComponentInstance, phase string) {
+	for _, hook := range instance.CalculatedHooks[phase] {
+		node.AddAction(component.NewHookAction(instance, phase, hook), true)
+	}
+}
@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
@@ -8,9 +9,11 @@ import (
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"sort"
 	"testing"
 )
 
@@ -100,6 +103,131 @@ func TestDiffComponentWithServiceSharing(t *testing.T) {
 	verifyDiff(t, diff, 7, 0, 0, 9, 0, 0)
 }
 
+func TestDiffActionPlanSplit(t *testing.T) {
+	b := makePolicyBuilderWithServiceSharing()
+	resolvedNext := resolvePolicy(t, b)
+	resolvedEmpty := resolvePolicy(t, builder.NewPolicyBuilder())
+	diff := NewPolicyResolutionDiff(resolvedNext, resolvedEmpty)
+	total := diff.ActionPlan.NumberOfActions()
+
+	// holding back the shared service should hold back everything, as all other instances depend on it
+	// (shared service has no components, so it's the only instance without outgoing edges)
+	isShared := func(instance *resolve.ComponentInstance) bool {
+		return instance.Metadata.Key.IsService() && len(instance.EdgesOut) <= 0
+	}
+	runnable, held := diff.ActionPlan.Split(func(key string) bool {
+		return isShared(resolvedNext.ComponentInstanceMap[key])
+	})
+	assert.Equal(t, uint32(0), runnable.NumberOfActions(), "Split: no actions should be runnable when shared service is held back")
+	assert.Equal(t, total, held.NumberOfActions(), "Split: all actions should be held back when shared service is held back")
+
+	// holding back a single instance of the first service should not affect the rest of the plan
+	var heldKey string
+	for key, instance := range resolvedNext.ComponentInstanceMap {
+		if instance.Metadata.Key.IsService() && !isShared(instance) {
+			heldKey = key
+			break
+		}
+	}
+	runnable, held = diff.ActionPlan.Split(func(key string) bool {
+		return key == heldKey
+	})
+	assert.True(t, runnable.NumberOfActions() > 0, "Split: some actions should be runnable")
+	assert.True(t, held.NumberOfActions() > 0, "Split: some actions should be held back")
+	assert.Equal(t, total, runnable.NumberOfActions()+held.NumberOfActions(), "Split: no actions should be lost")
+	for key := range held.NodeMap {
+		assert.Equal(t, heldKey, key, "Split: only the held instance should be held back")
+	}
+}
+
+func TestDiffActionPlanPartition(t *testing.T) {
+	resolvedEmpty := resolvePolicy(t, builder.NewPolicyBuilder())
+
+	// independent services in different namespaces should end up in different partitions
+	resolvedNext := resolvePolicy(t, makePolicyBuilderWithTwoNamespaces(false))
+	diff := NewPolicyResolutionDiff(resolvedNext, resolvedEmpty)
+	partitions := diff.ActionPlan.Partition(func(key string) string {
+		return resolvedNext.ComponentInstanceMap[key].Metadata.Key.Namespace
+	})
+	if assert.Equal(t, 2, len(partitions), "Partition: independent namespaces should be applied separately") {
+		assert.Equal(t, diff.ActionPlan.NumberOfActions(), partitions["a"].NumberOfActions()+partitions["b"].NumberOfActions(), "Partition: no actions should be lost")
+	}
+
+	// once service in one namespace depends on a service in another namespace, partitions should get merged
+	resolvedNext = resolvePolicy(t, makePolicyBuilderWithTwoNamespaces(true))
+	diff = NewPolicyResolutionDiff(resolvedNext, resolvedEmpty)
+	partitions = diff.ActionPlan.Partition(func(key string) string {
+		return resolvedNext.ComponentInstanceMap[key].Metadata.Key.Namespace
+	})
+	if assert.Equal(t, 1, len(partitions), "Partition: dependent namespaces should be applied together") {
+		assert.Equal(t, diff.ActionPlan.NumberOfActions(), partitions["a,b"].NumberOfActions(), "Partition: no actions should be lost")
+	}
+}
+
+func TestDiffAffectedDependencies(t *testing.T) {
+	b := makePolicyBuilder()
+	resolvedPrev := resolvePolicy(t, b)
+
+	// add two dependencies, each getting its own component instances
+	contract := b.Policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract)
+	contract.Contexts[0].Allocation.Keys = []string{"{{ .Dependency.ID }}"}
+	contract.Contexts[0].Criteria = b.Criteria("param != 'broken'", "true", "false")
+	d1 := b.AddDependency(b.AddUser(), contract)
+	d1.Labels["param"] = "value1"
+	d2 := b.AddDependency(b.AddUser(), contract)
+	d2.Labels["param"] = "value2"
+	resolvedNext := resolvePolicy(t, b)
+
+	// both dependencies should be affected by creation of their components
+	diff := NewPolicyResolutionDiff(resolvedNext, resolvedPrev)
+	affected := AffectedDependencies(diff.ActionPlan, resolvedNext, resolvedPrev)
+	assert.Equal(t, map[string]bool{runtime.KeyForStorable(d1): true, runtime.KeyForStorable(d2): true}, affected, "Both dependencies should be affected")
+
+	// update the first dependency, only it should be affected
+	d1.Labels["param"] = "value3"
+	resolvedNextAgain := resolvePolicy(t, b)
+	diffAgain := NewPolicyResolutionDiff(resolvedNextAgain, resolvedNext)
+	affected = AffectedDependencies(diffAgain.ActionPlan, resolvedNextAgain, resolvedNext)
+	assert.Equal(t, map[string]bool{runtime.KeyForStorable(d1): true}, affected, "Only updated dependency should be affected")
+
+	// nothing should be affected by an empty plan
+	diffEmpty := NewPolicyResolutionDiff(resolvedNextAgain, resolvedNextAgain)
+	assert.Empty(t, AffectedDependencies(diffEmpty.ActionPlan, resolvedNextAgain, resolvedNextAgain), "No dependencies should be affected by an empty plan")
+}
+
+func TestDiffImpact(t *testing.T) {
+	b := makePolicyBuilder()
+	cluster := b.Policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)
+	resolvedPrev := resolvePolicy(t, b)
+
+	// add two dependencies, each getting its own component instances
+	contract := b.Policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract)
+	contract.Contexts[0].Allocation.Keys = []string{"{{ .Dependency.ID }}"}
+	contract.Contexts[0].Criteria = b.Criteria("param != 'broken'", "true", "false")
+	d1 := b.AddDependency(b.AddUser(), contract)
+	d1.Labels["param"] = "value1"
+	d2 := b.AddDependency(b.AddUser(), contract)
+	d2.Labels["param"] = "value2"
+	resolvedNext := resolvePolicy(t, b)
+
+	// both dependencies and all of their instances should be created
+	impact := NewPolicyResolutionDiff(resolvedNext, resolvedPrev).Impact(b.Policy(), b.Policy())
+	verifyImpact(t, impact[0], ImpactCreate, []string{runtime.KeyForStorable(d1), runtime.KeyForStorable(d2)}, []string{d1.User, d2.User}, 4, []string{cluster.Name})
+	verifyImpact(t, impact[1], ImpactUpdate, nil, nil, 0, nil)
+	verifyImpact(t, impact[2], ImpactDelete, nil, nil, 0, nil)
+
+	// update the first dependency and break the second one, so that it no longer matches the context
+	d1.Labels["param"] = "value3"
+	d2.Labels["param"] = "broken"
+	resolvedNextAgain := resolve.NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).ResolveAllDependencies()
+
+	// the first dependency should be updated, while the second one should be deleted along with its instances
+	impact = NewPolicyResolutionDiff(resolvedNextAgain, resolvedNext).Impact(b.Policy(), b.Policy())
+	verifyImpact(t, impact[0], ImpactCreate, nil, nil, 0, nil)
+	verifyImpact(t, impact[1], ImpactUpdate, []string{runtime.KeyForStorable(d1)}, []string{d1.User}, 2, []string{cluster.Name})
+	verifyImpact(t, impact[2], ImpactDelete, []string{runtime.KeyForStorable(d2)}, []string{d2.User}, 2, []string{cluster.Name})
+}
+
 /*
 	Helpers
 */
@@ -149,6 +277,34 @@ func makePolicyBuilderWithServiceSharing() *builder.PolicyBuilder {
 	return b
 }
 
+func makePolicyBuilderWithTwoNamespaces(dependent bool) *builder.PolicyBuilder {
+	b := builder.NewPolicyBuilderWithNS("a")
+	clusterObj := b.AddCluster()
+
+	// create a service in namespace 'b'
+	b.SwitchNamespace("b")
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, clusterObj.Name)))
+	serviceB := b.AddService()
+	b.AddServiceComponent(serviceB, b.CodeComponent(nil, nil))
+	contractB := b.AddContract(serviceB, b.CriteriaTrue())
+
+	// create a service in namespace 'a', which may depend on the service in namespace 'b'
+	b.SwitchNamespace("a")
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, clusterObj.Name)))
+	serviceA := b.AddService()
+	b.AddServiceComponent(serviceA, b.CodeComponent(nil, nil))
+	if dependent {
+		b.AddServiceComponent(serviceA, b.ContractComponent(contractB))
+	}
+	contractA := b.AddContract(serviceA, b.CriteriaTrue())
+
+	// add dependencies
+	b.AddDependency(b.AddUser(), contractA)
+	b.AddDependency(b.AddUser(), contractB)
+
+	return b
+}
+
 func resolvePolicy(t *testing.T, builder *builder.PolicyBuilder) *resolve.PolicyResolution {
 	t.Helper()
 	eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
@@ -195,7 +351,7 @@ func verifyDiff(t *testing.T, diff *PolicyResolutionDiff, componentInstantiate i
 		return nil
 	}
 
-	_ = diff.ActionPlan.Apply(action.WrapSequential(fn), action.NewApplyResultUpdaterImpl())
+	_ = diff.ActionPlan.Apply(context.Background(), action.WrapSequential(fn), action.NewApplyResultUpdaterImpl())
 
 	ok := assert.Equal(t, componentInstantiate, cnt.create, "Diff: component instantiations")
 	ok = ok && assert.Equal(t, componentDestruct, cnt.delete, "Diff: component destructions")
@@ -209,3 +365,19 @@ func verifyDiff(t *testing.T, diff *PolicyResolutionDiff, componentInstantiate i
 		t.FailNow()
 	}
 }
+
+func verifyImpact(t *testing.T, group *ImpactGroup, change string, dependencies []string, users []string, instances int, clusters []string) {
+	t.Helper()
+	assert.Equal(t, change, group.Change, "Impact group should have the right kind of change")
+	assert.Equal(t, sortedCopy(dependencies), sortedCopy(group.Dependencies), "Impacted dependencies for '%s'", change)
+	assert.Equal(t, sortedCopy(users), sortedCopy(group.Users), "Impacted users for '%s'", change)
+	assert.Len(t, group.ComponentInstances, instances, "Impacted component instances for '%s'", change)
+	assert.Equal(t, sortedCopy(clusters), sortedCopy(group.Clusters), "Impacted clusters for '%s'", change)
+}
+
+func sortedCopy(values []string) []string {
+	result := make([]string, len(values))
+	copy(result, values)
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// DependencyMigrationsObject is Info for DependencyMigrations
+var DependencyMigrationsObject = &runtime.Info{
+	Kind:        "dependency-migrations",
+	Storable:    true,
+	Versioned:   false,
+	Constructor: func() runtime.Object { return &DependencyMigrations{} },
+}
+
+// DependencyMigrationsKey is the default key for the DependencyMigrations object (there is only one such object)
+var DependencyMigrationsKey = runtime.KeyFromParts(runtime.SystemNS, DependencyMigrationsObject.Kind, runtime.EmptyName)
+
+// DependencyMigrations holds dependencies, which have been requested to move to a new placement. Dependencies on
+// sticky services keep their placement (context, cluster and allocation keys) from the actual state, even if rules or
+// context criteria change. They only move once a migration has been requested and processed by the enforcer
+type DependencyMigrations struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Requested is a map from dependency key to the name of the user who requested migration
+	Requested map[string]string
+}
+
+// NewDependencyMigrations creates a new empty DependencyMigrations object
+func NewDependencyMigrations() *DependencyMigrations {
+	return &DependencyMigrations{
+		TypeKind:  DependencyMigrationsObject.GetTypeKind(),
+		Requested: make(map[string]string),
+	}
+}
+
+// GetName returns object name
+func (migrations *DependencyMigrations) GetName() string {
+	return runtime.EmptyName
+}
+
+// GetNamespace returns object namespace
+func (migrations *DependencyMigrations) GetNamespace() string {
+	return runtime.SystemNS
+}
+
+// GetDependencyKeys returns a set of keys of dependencies which have been requested to migrate
+func (migrations *DependencyMigrations) GetDependencyKeys() map[string]bool {
+	result := make(map[string]bool)
+	for dKey := range migrations.Requested {
+		result[dKey] = true
+	}
+	return result
+}
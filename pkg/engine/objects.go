@@ -15,12 +15,19 @@ var (
 		component.AttachDependencyActionObject,
 		component.DetachDependencyActionObject,
 		component.EndpointsActionObject,
+		component.HookActionObject,
+		component.WaitForReadyActionObject,
 	}
 
 	// Objects is the list of informational objects for all objects in the engine
 	Objects = runtime.AppendAll([]*runtime.Info{
 		PolicyDataObject,
 		RevisionObject,
+		AuditEntryObject,
+		DependencyMigrationsObject,
+		EnforcerPauseObject,
+		PasswordSeedObject,
+		DependencyStatusObject,
 		resolve.ComponentInstanceObject,
 	}, ActionObjects)
 )
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"time"
+)
+
+// PasswordSeedObject is Info for PasswordSeed
+var PasswordSeedObject = &runtime.Info{
+	Kind:        "password-seed",
+	Storable:    true,
+	Versioned:   false,
+	Constructor: func() runtime.Object { return &PasswordSeed{} },
+}
+
+// PasswordSeedKey is the default key for the PasswordSeed object (there is only one such object)
+var PasswordSeedKey = runtime.KeyFromParts(runtime.SystemNS, PasswordSeedObject.Kind, runtime.EmptyName)
+
+// PasswordSeed holds a secret key, which gets mixed into all values produced by random functions in templates
+// (e.g. generated passwords). It gets generated once, on the first run of Aptomi server, and must never change,
+// otherwise all generated values will change as well
+type PasswordSeed struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Key is the secret key
+	Key string
+
+	// CreatedAt is when the key was generated
+	CreatedAt time.Time
+}
+
+// GetName returns object name
+func (seed *PasswordSeed) GetName() string {
+	return runtime.EmptyName
+}
+
+// GetNamespace returns object namespace
+func (seed *PasswordSeed) GetNamespace() string {
+	return runtime.SystemNS
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"time"
+)
+
+// EnforcerPauseObject is Info for EnforcerPause
+var EnforcerPauseObject = &runtime.Info{
+	Kind:        "enforcer-pause",
+	Storable:    true,
+	Versioned:   false,
+	Constructor: func() runtime.Object { return &EnforcerPause{} },
+}
+
+// EnforcerPauseKey is the default key for the EnforcerPause object (there is only one such object)
+var EnforcerPauseKey = runtime.KeyFromParts(runtime.SystemNS, EnforcerPauseObject.Kind, runtime.EmptyName)
+
+const (
+	// PauseScopeAll represents pause of the enforcer for all component instances
+	PauseScopeAll = "all"
+	// PauseScopeCluster represents pause of the enforcer for component instances in a given cluster
+	PauseScopeCluster = "cluster"
+	// PauseScopeNamespace represents pause of the enforcer for component instances in a given namespace
+	PauseScopeNamespace = "namespace"
+)
+
+// EnforcerPause holds the paused state of the enforcer. While paused, the enforcer keeps resolving policy and
+// calculating actions, but doesn't apply actions on component instances in the paused scopes. Instead, it records the
+// number of actions which have been held back
+type EnforcerPause struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Paused is a map from pause scope key (e.g. 'all', 'cluster/<name>' or 'namespace/<name>') to the pause record
+	Paused map[string]*PauseRecord
+
+	// PendingActions is the number of actions, which have been held back during the last enforcement cycle
+	PendingActions uint32
+
+	// PendingInstances is the list of keys of component instances, which have been held back during the last
+	// enforcement cycle
+	PendingInstances []string
+
+	// CheckedAt is when the enforcer recorded pending actions last time
+	CheckedAt time.Time
+}
+
+// PauseRecord describes who paused the enforcer and when
+type PauseRecord struct {
+	// User is the name of the user who paused the enforcer
+	User string
+
+	// PausedAt is when the enforcer was paused
+	PausedAt time.Time
+}
+
+// NewEnforcerPause creates a new EnforcerPause object, which has nothing paused
+func NewEnforcerPause() *EnforcerPause {
+	return &EnforcerPause{
+		TypeKind: EnforcerPauseObject.GetTypeKind(),
+		Paused:   make(map[string]*PauseRecord),
+	}
+}
+
+// GetName returns object name
+func (pause *EnforcerPause) GetName() string {
+	return runtime.EmptyName
+}
+
+// GetNamespace returns object namespace
+func (pause *EnforcerPause) GetNamespace() string {
+	return runtime.SystemNS
+}
+
+// PauseScopeKey returns a key for the given pause scope and name (cluster or namespace name). Name is ignored for
+// PauseScopeAll. It returns an error if the scope is unknown or name is missing
+func PauseScopeKey(scope string, name string) (string, error) {
+	switch scope {
+	case PauseScopeAll:
+		return PauseScopeAll, nil
+	case PauseScopeCluster, PauseScopeNamespace:
+		if len(name) <= 0 {
+			return "", fmt.Errorf("name must be specified for pause scope '%s'", scope)
+		}
+		return scope + "/" + name, nil
+	default:
+		return "", fmt.Errorf("unknown pause scope '%s'", scope)
+	}
+}
+
+// IsPaused returns true if the enforcer is paused for at least one scope
+func (pause *EnforcerPause) IsPaused() bool {
+	return len(pause.Paused) > 0
+}
+
+// IsPausedFor returns true if the enforcer is paused for component instances in a given cluster and namespace
+func (pause *EnforcerPause) IsPausedFor(cluster string, namespace string) bool {
+	if pause.Paused[PauseScopeAll] != nil {
+		return true
+	}
+	if pause.Paused[PauseScopeCluster+"/"+cluster] != nil {
+		return true
+	}
+	return pause.Paused[PauseScopeNamespace+"/"+namespace] != nil
+}
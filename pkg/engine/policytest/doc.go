@@ -0,0 +1,4 @@
+// Package policytest allows policy authors to test their policy locally, by running declarative test cases through
+// policy resolution. Every test case describes a user, a dependency declared by that user, and expected results
+// of resolving that dependency (context, cluster, components and their code params, or an expected rejection).
+package policytest
@@ -0,0 +1,40 @@
+package policytest
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// PrintResults prints test case results in the same format 'go test -v' does. If verbose is true, policy resolution
+// log will be printed for every failed test case. Returns true if all test cases have passed
+func PrintResults(out io.Writer, results []*Result, verbose bool) bool {
+	passed := true
+	var total time.Duration
+	for _, result := range results {
+		total += result.Duration
+		fmt.Fprintf(out, "=== RUN   %s\n", result.TestCase.Name) // nolint: errcheck
+		if result.Passed() {
+			fmt.Fprintf(out, "--- PASS: %s (%.2fs)\n", result.TestCase.Name, result.Duration.Seconds()) // nolint: errcheck
+			continue
+		}
+
+		passed = false
+		fmt.Fprintf(out, "--- FAIL: %s (%.2fs)\n", result.TestCase.Name, result.Duration.Seconds()) // nolint: errcheck
+		for _, failure := range result.Failures {
+			fmt.Fprintf(out, "    %s\n", failure) // nolint: errcheck
+		}
+		if verbose {
+			for _, apiEvent := range result.EventLog.AsAPIEvents() {
+				fmt.Fprintf(out, "        [%s] %s\n", apiEvent.LogLevel, apiEvent.Message) // nolint: errcheck
+			}
+		}
+	}
+
+	if passed {
+		fmt.Fprintf(out, "PASS\nok\t%d test cases\t%.3fs\n", len(results), total.Seconds()) // nolint: errcheck
+	} else {
+		fmt.Fprintf(out, "FAIL\n") // nolint: errcheck
+	}
+	return passed
+}
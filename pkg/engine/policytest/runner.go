@@ -0,0 +1,233 @@
+package policytest
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/external"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
+	"github.com/Aptomi/aptomi/pkg/external/users"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/Sirupsen/logrus"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TestDependencyName is the name of the dependency which gets added to the policy for every test case
+const TestDependencyName = "policy-test"
+
+// Result is the result of running a single test case
+type Result struct {
+	// TestCase which has been run
+	TestCase *TestCase
+
+	// Failures is a list of failed expectations (empty if test case passed)
+	Failures []string
+
+	// Duration is how long it took to run the test case
+	Duration time.Duration
+
+	// EventLog is the policy resolution log
+	EventLog *event.Log
+}
+
+// Passed returns true if all expectations of the test case have been met
+func (result *Result) Passed() bool {
+	return len(result.Failures) == 0
+}
+
+func (result *Result) failf(format string, args ...interface{}) {
+	result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+}
+
+// Run runs all test cases against the policy, consisting of the given objects
+func Run(objects []lang.Base, testCases []*TestCase) []*Result {
+	result := []*Result{}
+	for _, testCase := range testCases {
+		result = append(result, RunTestCase(objects, testCase))
+	}
+	return result
+}
+
+// RunTestCase runs a single test case against the policy, consisting of the given objects. Dependencies declared in
+// the policy are ignored, only the dependency from the test case gets resolved
+func RunTestCase(objects []lang.Base, testCase *TestCase) *Result {
+	start := time.Now()
+	result := &Result{
+		TestCase: testCase,
+		EventLog: event.NewLog(logrus.DebugLevel, "policy-test"),
+	}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	// build policy with the test dependency
+	policy := lang.NewPolicy()
+	for _, obj := range objects {
+		if obj.GetKind() == lang.DependencyObject.Kind {
+			continue
+		}
+		err := policy.AddObject(obj)
+		if err != nil {
+			result.failf("error while adding object '%s' into the policy: %s", runtime.KeyForStorable(obj), err)
+			return result
+		}
+	}
+
+	dependency := testCase.newDependency()
+	err := policy.AddObject(dependency)
+	if err != nil {
+		result.failf("error while adding test dependency into the policy: %s", err)
+		return result
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		result.failf("policy is invalid: %s", err)
+		return result
+	}
+
+	// resolve policy with the test user and its secrets
+	resolver := resolve.NewPolicyResolver(policy, testCase.newExternalData(), result.EventLog)
+	resolution := resolver.ResolveAllDependencies()
+
+	testCase.Expect.check(result, resolution, runtime.KeyForStorable(dependency))
+	return result
+}
+
+// Creates dependency for the test case
+func (testCase *TestCase) newDependency() *lang.Dependency {
+	namespace := testCase.Dependency.Namespace
+	if len(namespace) <= 0 {
+		namespace = "main"
+	}
+	return &lang.Dependency{
+		TypeKind: lang.DependencyObject.GetTypeKind(),
+		Metadata: lang.Metadata{
+			Namespace: namespace,
+			Name:      TestDependencyName,
+		},
+		User:     testCase.User.Name,
+		Contract: testCase.Dependency.Contract,
+		Version:  testCase.Dependency.Version,
+		Labels:   testCase.Dependency.Labels,
+		Params:   testCase.Dependency.Params,
+	}
+}
+
+// Creates external data with mock user loader and secret loader, which know about the test user only
+func (testCase *TestCase) newExternalData() *external.Data {
+	userLoader := users.NewUserLoaderMock()
+	userLoader.AddUser(&lang.User{
+		Name:        testCase.User.Name,
+		Labels:      testCase.User.Labels,
+		DomainAdmin: testCase.User.DomainAdmin,
+	})
+
+	secretLoader := secrets.NewSecretLoaderMock()
+	for name, value := range testCase.User.Secrets {
+		secretLoader.AddSecret(testCase.User.Name, name, value)
+	}
+
+	return external.NewData(userLoader, secretLoader)
+}
+
+// Checks that resolution of the dependency meets expectations
+func (expect *Expectation) check(result *Result, resolution *resolve.PolicyResolution, dependencyKey string) {
+	dResolution := resolution.GetDependencyInstanceMap()[dependencyKey]
+	resolved := dResolution != nil && dResolution.Resolved
+
+	if expect.Reject || len(expect.Error) > 0 {
+		if resolved {
+			result.failf("expected dependency to be rejected, but it got resolved")
+			return
+		}
+		if len(expect.Error) > 0 {
+			verifier := event.NewLogVerifier(expect.Error, true)
+			result.EventLog.Save(verifier)
+			if verifier.MatchedErrorsCount() <= 0 {
+				result.failf("expected dependency to be rejected with error containing '%s', but it got rejected with a different error", expect.Error)
+			}
+		}
+		return
+	}
+
+	if !resolved {
+		result.failf("expected dependency to be resolved, but it got rejected")
+		return
+	}
+
+	root := resolution.ComponentInstanceMap[dResolution.ComponentInstanceKey]
+	if len(expect.Context) > 0 && expect.Context != root.Metadata.Key.ContextName {
+		result.failf("expected context '%s', got '%s'", expect.Context, root.Metadata.Key.ContextName)
+	}
+	if len(expect.Cluster) > 0 && expect.Cluster != root.Metadata.Key.ClusterName {
+		result.failf("expected cluster '%s', got '%s'", expect.Cluster, root.Metadata.Key.ClusterName)
+	}
+
+	// find code components of the service, which dependency got resolved to
+	components := make(map[string]*resolve.ComponentInstance)
+	for _, instance := range resolution.ComponentInstanceMap {
+		key := instance.Metadata.Key
+		if key.IsComponent() && instance.IsCode && key.GetParentServiceKey().GetKey() == root.Metadata.Key.GetKey() {
+			components[key.ComponentName] = instance
+		}
+	}
+
+	if expect.Components != nil {
+		expected := append([]string{}, expect.Components...)
+		actual := []string{}
+		for name := range components {
+			actual = append(actual, name)
+		}
+		sort.Strings(expected)
+		sort.Strings(actual)
+		if strings.Join(expected, ",") != strings.Join(actual, ",") {
+			result.failf("expected components %s, got %s", expected, actual)
+		}
+	}
+
+	for _, name := range util.GetSortedStringKeys(expect.Params) {
+		instance, ok := components[name]
+		if !ok {
+			result.failf("expected code params for component '%s', but component is not instantiated", name)
+			continue
+		}
+		for _, mismatch := range matchParams("", expect.Params[name], instance.CalculatedCodeParams) {
+			result.failf("component '%s': %s", name, mismatch)
+		}
+	}
+}
+
+// Checks that actual params contain all expected params. Scalar values are compared as strings, as code params
+// are usually produced by text templates
+func matchParams(prefix string, expected util.NestedParameterMap, actual util.NestedParameterMap) []string {
+	result := []string{}
+	for _, key := range util.GetSortedStringKeys(expected) {
+		path := key
+		if len(prefix) > 0 {
+			path = prefix + "." + key
+		}
+
+		actualValue, ok := actual[key]
+		if !ok {
+			result = append(result, fmt.Sprintf("expected param '%s' to be set, but it's missing", path))
+			continue
+		}
+
+		expectedMap, expectedIsMap := expected[key].(util.NestedParameterMap)
+		actualMap, actualIsMap := actualValue.(util.NestedParameterMap)
+		if expectedIsMap && actualIsMap {
+			result = append(result, matchParams(path, expectedMap, actualMap)...)
+			continue
+		}
+
+		if fmt.Sprintf("%v", expected[key]) != fmt.Sprintf("%v", actualValue) {
+			result = append(result, fmt.Sprintf("expected param '%s' to be '%v', got '%v'", path, expected[key], actualValue))
+		}
+	}
+	return result
+}
@@ -0,0 +1,226 @@
+package policytest
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunTestCases(t *testing.T) {
+	objects := makePolicyObjects()
+
+	testCases := []*TestCase{
+		{
+			Name:       "prod context",
+			User:       TestUser{Name: "alice", Labels: map[string]string{"team": "platform"}},
+			Dependency: TestDependency{Contract: "db", Labels: map[string]string{"env": "prod"}},
+			Expect: Expectation{
+				Context:    "prod",
+				Cluster:    "cluster-us-east",
+				Components: []string{"database"},
+				Params: map[string]util.NestedParameterMap{
+					"database": {"replicas": 3, "nested": util.NestedParameterMap{"owner": "alice"}},
+				},
+			},
+		},
+		{
+			Name:       "rejected",
+			User:       TestUser{Name: "bob"},
+			Dependency: TestDependency{Contract: "db", Labels: map[string]string{"env": "unknown"}},
+			Expect:     Expectation{Reject: true, Error: "unable to find matching context"},
+		},
+		{
+			Name:       "wrong expectations",
+			User:       TestUser{Name: "carol", Labels: map[string]string{"team": "platform"}},
+			Dependency: TestDependency{Contract: "db", Labels: map[string]string{"env": "dev"}},
+			Expect: Expectation{
+				Context:    "prod",
+				Components: []string{"database", "cache"},
+				Params: map[string]util.NestedParameterMap{
+					"database": {"replicas": 3, "missing": "value"},
+				},
+			},
+		},
+		{
+			Name:       "expected rejection",
+			User:       TestUser{Name: "dave", DomainAdmin: true},
+			Dependency: TestDependency{Contract: "db", Labels: map[string]string{"env": "dev"}},
+			Expect:     Expectation{Reject: true},
+		},
+	}
+
+	results := Run(objects, testCases)
+	if !assert.Equal(t, 4, len(results), "All test cases should be run") {
+		t.FailNow()
+	}
+
+	assert.True(t, results[0].Passed(), "Test case should pass: %v", results[0].Failures)
+	assert.True(t, results[1].Passed(), "Test case should pass: %v", results[1].Failures)
+	assert.Equal(t, []string{
+		"expected context 'prod', got 'dev'",
+		"expected components [cache database], got [database]",
+		"component 'database': expected param 'missing' to be set, but it's missing",
+		"component 'database': expected param 'replicas' to be '3', got '1'",
+	}, results[2].Failures, "Test case should fail with all mismatched expectations")
+	assert.Equal(t, []string{"expected dependency to be rejected, but it got resolved"}, results[3].Failures, "Test case should fail")
+}
+
+func TestRunIgnoresPolicyDependencies(t *testing.T) {
+	objects := makePolicyObjects()
+	objects = append(objects, &lang.Dependency{
+		TypeKind: lang.DependencyObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: "main", Name: "broken"},
+		User:     "unknown-user",
+		Contract: "db",
+	})
+
+	result := RunTestCase(objects, &TestCase{
+		Name:       "dev",
+		User:       TestUser{Name: "alice", Labels: map[string]string{"team": "platform"}, Secrets: map[string]string{"token": "secret"}},
+		Dependency: TestDependency{Contract: "main/db", Labels: map[string]string{"env": "dev"}},
+		Expect:     Expectation{Context: "dev"},
+	})
+	assert.True(t, result.Passed(), "Test case should pass: %v", result.Failures)
+
+	// user without consumer role should be rejected
+	result = RunTestCase(objects, &TestCase{
+		Name:       "acl",
+		User:       TestUser{Name: "eve"},
+		Dependency: TestDependency{Contract: "db", Labels: map[string]string{"env": "dev"}},
+		Expect:     Expectation{Error: "doesn't have ACL permissions to consume service"},
+	})
+	assert.True(t, result.Passed(), "Test case should pass: %v", result.Failures)
+}
+
+func TestLoadTestCases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policytest")
+	if !assert.NoError(t, err, "Temp dir should be created") {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	data := `
+- name: prod context
+  user:
+    name: alice
+    domain-admin: true
+    labels:
+      team: platform
+  dependency:
+    contract: db
+    labels:
+      env: prod
+  expect:
+    context: prod
+    components: [database]
+    params:
+      database:
+        replicas: 3
+        nested:
+          owner: alice
+- user:
+    name: bob
+  dependency:
+    contract: db
+  expect:
+    reject: true
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "tests.yaml"), []byte(data), 0644)
+	if !assert.NoError(t, err, "Test case file should be written") {
+		t.FailNow()
+	}
+
+	testCases, err := LoadTestCases([]string{dir})
+	if !assert.NoError(t, err, "Test cases should be loaded") {
+		t.FailNow()
+	}
+	assert.Equal(t, 2, len(testCases), "Both test cases should be loaded")
+	assert.Equal(t, "prod context", testCases[0].Name)
+	assert.Equal(t, "platform", testCases[0].User.Labels["team"])
+	assert.True(t, testCases[0].User.DomainAdmin)
+	assert.Equal(t, "prod", testCases[0].Expect.Context)
+	assert.Equal(t, 3, testCases[0].Expect.Params["database"]["replicas"])
+	assert.Equal(t, "alice", testCases[0].Expect.Params["database"]["nested"].(util.NestedParameterMap)["owner"])
+	assert.Equal(t, filepath.Join(dir, "tests.yaml")+" #2", testCases[1].Name)
+	assert.True(t, testCases[1].Expect.Reject)
+
+	// test case without contract is invalid
+	err = ioutil.WriteFile(filepath.Join(dir, "tests.yaml"), []byte("- user:\n    name: alice\n"), 0644)
+	if !assert.NoError(t, err, "Test case file should be written") {
+		t.FailNow()
+	}
+	_, err = LoadTestCases([]string{dir})
+	assert.Error(t, err, "Test case without contract should not be loaded")
+}
+
+func makePolicyObjects() []lang.Base {
+	cluster := &lang.Cluster{
+		TypeKind: lang.ClusterObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: runtime.SystemNS, Name: "cluster-us-east"},
+		Type:     "kubernetes",
+		Config: struct {
+			Namespace string
+		}{
+			Namespace: "default",
+		},
+	}
+
+	service := &lang.Service{
+		TypeKind: lang.ServiceObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: "main", Name: "db"},
+		Components: []*lang.ServiceComponent{
+			{
+				Name: "database",
+				Code: &lang.Code{
+					Type: "helm",
+					Params: util.NestedParameterMap{
+						"replicas": "{{ .Labels.replicas }}",
+						"nested":   util.NestedParameterMap{"owner": "{{ .User.Name }}"},
+					},
+				},
+			},
+		},
+	}
+
+	contract := &lang.Contract{
+		TypeKind: lang.ContractObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: "main", Name: "db"},
+		Contexts: []*lang.Context{
+			{
+				Name:         "prod",
+				Criteria:     &lang.Criteria{RequireAll: []string{"env == 'prod'"}},
+				Allocation:   &lang.Allocation{Service: "db"},
+				ChangeLabels: lang.NewLabelOperationsSetSingleLabel("replicas", "3"),
+			},
+			{
+				Name:         "dev",
+				Criteria:     &lang.Criteria{RequireAll: []string{"env == 'dev'"}},
+				Allocation:   &lang.Allocation{Service: "db"},
+				ChangeLabels: lang.NewLabelOperationsSetSingleLabel("replicas", "1"),
+			},
+		},
+	}
+
+	rule := &lang.Rule{
+		TypeKind: lang.RuleObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: "main", Name: "cluster"},
+		Weight:   10,
+		Criteria: &lang.Criteria{RequireAny: []string{"true"}},
+		Actions:  &lang.RuleActions{ChangeLabels: lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)},
+	}
+
+	aclRule := &lang.ACLRule{
+		TypeKind: lang.ACLRuleObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: runtime.SystemNS, Name: "platform_consumers"},
+		Weight:   10,
+		Criteria: &lang.Criteria{RequireAll: []string{"team == 'platform'"}},
+		Actions:  &lang.RuleActions{AddRole: map[string]string{lang.ServiceConsumer.ID: "main"}},
+	}
+
+	return []lang.Base{cluster, service, contract, rule, aclRule}
+}
@@ -0,0 +1,112 @@
+package policytest
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+)
+
+// TestCase is a single declarative test case for the policy
+type TestCase struct {
+	// Name is a human-readable name of the test case
+	Name string
+
+	// User is the user who declares the dependency
+	User TestUser
+
+	// Dependency is the dependency which will be resolved
+	Dependency TestDependency
+
+	// Expect defines expected results of resolving the dependency
+	Expect Expectation
+}
+
+// TestUser is a user with labels and secrets, which will be served by a mock user loader and secret loader
+type TestUser struct {
+	Name    string
+	Labels  map[string]string
+	Secrets map[string]string
+
+	// DomainAdmin marks user as domain admin, so it can consume services regardless of ACL rules
+	DomainAdmin bool `yaml:"domain-admin"`
+}
+
+// TestDependency is a dependency on a contract, which will be added to the policy
+type TestDependency struct {
+	// Namespace where dependency is declared ('main' if not specified)
+	Namespace string
+
+	// Contract which is being requested ('contractName' or 'namespace/contractName')
+	Contract string
+
+	// Labels which are provided by the user
+	Labels map[string]string
+
+	// Version of the contract which is being requested (the latest if not specified)
+	Version string
+
+	// Params which are provided by the user
+	Params map[string]interface{}
+}
+
+// Expectation defines expected results of resolving a dependency. Empty fields are not checked
+type Expectation struct {
+	// Reject means that dependency is expected not to be resolved
+	Reject bool
+
+	// Error is a part of error message which is expected when dependency gets rejected
+	Error string
+
+	// Context is the name of the context which is expected to be picked within the contract
+	Context string
+
+	// Cluster is the name of the cluster which the service is expected to be deployed to
+	Cluster string
+
+	// Components is a list of code components of the service, which are expected to be instantiated
+	Components []string
+
+	// Params is a map from component name to a subset of code params expected for that component
+	Params map[string]util.NestedParameterMap
+}
+
+// LoadTestCases loads test cases from YAML files, which are found in the given files/dirs
+func LoadTestCases(paths []string) ([]*TestCase, error) {
+	files, err := util.FindYamlFiles(paths)
+	if err != nil {
+		return nil, fmt.Errorf("error while searching for test case files: %s", err)
+	}
+	sort.Strings(files)
+
+	result := []*TestCase{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("can't read test case file %s: %s", file, err)
+		}
+
+		testCases := []*TestCase{}
+		err = yaml.Unmarshal(data, &testCases)
+		if err != nil {
+			return nil, fmt.Errorf("can't unmarshal test case file %s: %s", file, err)
+		}
+
+		for idx, testCase := range testCases {
+			if len(testCase.Name) <= 0 {
+				testCase.Name = fmt.Sprintf("%s #%d", file, idx+1)
+			}
+			if len(testCase.User.Name) <= 0 || len(testCase.Dependency.Contract) <= 0 {
+				return nil, fmt.Errorf("test case '%s' in file %s should have user name and dependency contract specified", testCase.Name, file)
+			}
+		}
+		result = append(result, testCases...)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no test cases found in %s", paths)
+	}
+
+	return result, nil
+}
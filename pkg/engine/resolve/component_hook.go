@@ -0,0 +1,51 @@
+package resolve
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"time"
+)
+
+// ComponentHook is a lifecycle hook of a component instance with calculated code parameters. It gets executed by
+// the apply engine as a separate action, before or after the corresponding action on the component instance
+type ComponentHook struct {
+	// Name is a hook name, as defined in the service component
+	Name string
+
+	// Type is a code type of the hook, which determines the plugin used to execute it
+	Type string
+
+	// Params is a set of calculated code parameters for the hook
+	Params util.NestedParameterMap
+
+	// WaitForReady defines how long to wait for hook code to become ready, before it gets destroyed
+	WaitForReady *lang.WaitForReady `yaml:",omitempty"`
+
+	// Timeout is the maximum time which hook execution can take, or zero if there is no limit
+	Timeout time.Duration `yaml:",omitempty"`
+}
+
+// Helper to calculate code parameters for all lifecycle hooks of the component, keyed by lifecycle phase
+func (node *resolutionNode) calculateHooks() (map[string][]*ComponentHook, error) {
+	result := make(map[string][]*ComponentHook)
+	if node.component.Hooks == nil {
+		return result, nil
+	}
+	data := node.getContextualDataForCodeDiscoveryTemplate()
+	for _, phase := range lang.HookPhases {
+		for _, hook := range node.component.Hooks.GetHooks(phase) {
+			params, err := util.ProcessParameterTree(hook.Code.Params, data, node.resolver.templateCache, util.ModeEvaluate)
+			if err != nil {
+				return nil, err
+			}
+			result[phase] = append(result[phase], &ComponentHook{
+				Name:         hook.Name,
+				Type:         hook.Code.Type,
+				Params:       params,
+				WaitForReady: hook.Code.WaitForReady,
+				Timeout:      hook.Code.GetTimeout(),
+			})
+		}
+	}
+	return result, nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
+	"reflect"
 	"strconv"
 	"time"
 )
@@ -63,6 +64,9 @@ type ComponentInstance struct {
 	// CalculatedCodeParams is a set of calculated code parameters for the component (non-conflicting over all uses of this component)
 	CalculatedCodeParams util.NestedParameterMap
 
+	// CalculatedHooks is a set of calculated lifecycle hooks for the component, keyed by lifecycle phase (non-conflicting over all uses of this component)
+	CalculatedHooks map[string][]*ComponentHook
+
 	// EdgesIn is a set of incoming graph edges ('key' -> true) into this component instance. Storing for observability and reporting, so we can reconstruct the graph
 	EdgesIn map[string]bool
 
@@ -84,6 +88,16 @@ type ComponentInstance struct {
 
 	// Endpoints represents all URLs that could be used to access deployed service
 	Endpoints map[string]string
+
+	// NotReady is set when component instance has been deployed, but hasn't become ready yet. Such instance will be
+	// waited for again during the next enforcement cycles
+	NotReady bool `yaml:",omitempty"`
+
+	// NotReadyAttempts is how many times component instance has been waited for without becoming ready
+	NotReadyAttempts int `yaml:",omitempty"`
+
+	// NotReadyCheckedAt is the last time when component instance has been waited for without becoming ready
+	NotReadyCheckedAt time.Time `yaml:",omitempty"`
 }
 
 // Creates a new component instance
@@ -95,6 +109,7 @@ func newComponentInstance(cik *ComponentInstanceKey) *ComponentInstance {
 		CalculatedLabels:     lang.NewLabelSet(make(map[string]string)),
 		CalculatedDiscovery:  util.NestedParameterMap{},
 		CalculatedCodeParams: util.NestedParameterMap{},
+		CalculatedHooks:      make(map[string][]*ComponentHook),
 		EdgesIn:              make(map[string]bool),
 		EdgesOut:             make(map[string]bool),
 		DataForPlugins:       make(map[string]string),
@@ -162,6 +177,23 @@ func (instance *ComponentInstance) addCodeParams(codeParams util.NestedParameter
 	return nil
 }
 
+func (instance *ComponentInstance) addHooks(hooks map[string][]*ComponentHook) error {
+	if len(instance.CalculatedHooks) == 0 {
+		// Record hooks
+		instance.CalculatedHooks = hooks
+	} else if !reflect.DeepEqual(instance.CalculatedHooks, hooks) {
+		// Same component instance, different hooks
+		return errors.NewErrorWithDetails(
+			fmt.Sprintf("conflicting hooks for component instance: %s", instance.GetKey()),
+			errors.Details{
+				"hooks_existing": instance.CalculatedHooks,
+				"hooks_new":      hooks,
+			},
+		)
+	}
+	return nil
+}
+
 func (instance *ComponentInstance) addDiscoveryParams(discoveryParams util.NestedParameterMap) error {
 	if len(instance.CalculatedDiscovery) == 0 {
 		// Record discovery parameters
@@ -232,6 +264,12 @@ func (instance *ComponentInstance) appendData(ops *ComponentInstance) error {
 		return err
 	}
 
+	// Combine hooks
+	err = instance.addHooks(ops.CalculatedHooks)
+	if err != nil {
+		return err
+	}
+
 	// Incoming and outgoing graph edges (instance: key -> true) as we are traversing the graph
 	for key := range ops.EdgesIn {
 		instance.addEdgeIn(key)
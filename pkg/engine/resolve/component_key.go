@@ -23,7 +23,9 @@ const componentRootName = "root"
 // Currently, component keys are formed from multiple parameters as follows.
 // Cluster gets included as a part of the key (components running on different clusters must have different keys).
 // Namespace gets included as a part of the key (components from different namespaces must have different keys).
-// Contract, Context (with allocation keys), Service get included as a part of the key (Service must be within the same namespace as Contract).
+// Contract, Contract version, Context (with allocation keys), Service get included as a part of the key (Service must be within the same namespace as Contract).
+// Contract version is included for all versions except the first one, since contexts with the same name may exist in different versions.
+// The first version is treated the same way as a contract without versions, so instances keep their keys when versions get introduced.
 // ComponentName gets included as a part of the key. For service-level component instances, ComponentName is
 // set to componentRootName, while for all component instances within a service an actual Component.Name is used.
 type ComponentInstanceKey struct {
@@ -34,6 +36,7 @@ type ComponentInstanceKey struct {
 	ClusterName         string // mandatory
 	Namespace           string // determined from the contract
 	ContractName        string // mandatory
+	ContractVersion     string // empty if contract has no versions (or instance was created before contract got versions)
 	ContextName         string // mandatory
 	KeysResolved        string // mandatory
 	ContextNameWithKeys string // calculated
@@ -42,10 +45,14 @@ type ComponentInstanceKey struct {
 }
 
 // NewComponentInstanceKey creates a new ComponentInstanceKey
-func NewComponentInstanceKey(cluster *lang.Cluster, contract *lang.Contract, context *lang.Context, allocationKeysResolved []string, service *lang.Service, component *lang.ServiceComponent) *ComponentInstanceKey {
+func NewComponentInstanceKey(cluster *lang.Cluster, contract *lang.Contract, version *lang.ContractVersion, context *lang.Context, allocationKeysResolved []string, service *lang.Service, component *lang.ServiceComponent) *ComponentInstanceKey {
+	contractVersion := getContractVersionNameUnsafe(version)
 	contextName := getContextNameUnsafe(context)
 	keysResolved := strings.Join(allocationKeysResolved, componentInstanceKeySeparator)
 	contextNameWithKeys := contextName
+	if !isFirstContractVersion(contract, version) {
+		contextNameWithKeys = strings.Join([]string{contractVersion, contextNameWithKeys}, componentInstanceKeySeparator)
+	}
 	if len(keysResolved) > 0 {
 		contextNameWithKeys = strings.Join([]string{contextNameWithKeys, keysResolved}, componentInstanceKeySeparator)
 	}
@@ -53,6 +60,7 @@ func NewComponentInstanceKey(cluster *lang.Cluster, contract *lang.Contract, con
 		ClusterName:         getClusterNameUnsafe(cluster),
 		Namespace:           getContractNamespaceUnsafe(contract),
 		ContractName:        getContractNameUnsafe(contract),
+		ContractVersion:     contractVersion,
 		ContextName:         contextName,
 		KeysResolved:        keysResolved,
 		ContextNameWithKeys: contextNameWithKeys,
@@ -67,6 +75,7 @@ func (cik *ComponentInstanceKey) MakeCopy() *ComponentInstanceKey {
 		ClusterName:         cik.ClusterName,
 		Namespace:           cik.Namespace,
 		ContractName:        cik.ContractName,
+		ContractVersion:     cik.ContractVersion,
 		ContextName:         cik.ContextName,
 		KeysResolved:        cik.KeysResolved,
 		ContextNameWithKeys: cik.ContextNameWithKeys,
@@ -115,8 +124,19 @@ var (
 
 // GetDeployName returns a string that could be used as name for deployment inside the cluster
 func (cik ComponentInstanceKey) GetDeployName() string {
+	return deployNameForKey(cik.GetKey())
+}
+
+// GetHookDeployName returns a string that could be used as name for deployment of the given component hook inside
+// the cluster. It never clashes with the deploy name of the component instance itself
+func (cik ComponentInstanceKey) GetHookDeployName(hookName string) string {
+	return deployNameForKey(cik.GetKey() + componentInstanceKeySeparator + "hook" + componentInstanceKeySeparator + hookName)
+}
+
+// Helper to generate a short name, which is safe to use in the cluster, from an arbitrary key
+func deployNameForKey(key string) string {
 	h := fnv.New64a()
-	_, err := h.Write([]byte(cik.GetKey()))
+	_, err := h.Write([]byte(key))
 	if err != nil {
 		panic(err)
 	}
@@ -152,6 +172,33 @@ func getContractNamespaceUnsafe(contract *lang.Contract) string {
 	return contract.Namespace
 }
 
+// If contract version has not been resolved yet or contract has no versions, return empty string
+// Otherwise use version name
+func getContractVersionNameUnsafe(version *lang.ContractVersion) string {
+	if version == nil {
+		return ""
+	}
+	return version.Name
+}
+
+// Returns true if version is the first version of a contract (or contract has no versions). The first version doesn't
+// get included into the key, so that existing instances keep their keys when a contract gets converted to versions
+func isFirstContractVersion(contract *lang.Contract, version *lang.ContractVersion) bool {
+	if contract == nil || version == nil {
+		return true
+	}
+	return contract.GetVersions()[0].Name == version.Name
+}
+
+// Returns true if component instance belongs to a given version of a contract. Instances, which have been created
+// before contract got versions, don't have a version recorded and belong to the first version
+func (cik *ComponentInstanceKey) isContractVersion(contract *lang.Contract, version *lang.ContractVersion) bool {
+	if len(cik.ContractVersion) <= 0 {
+		return isFirstContractVersion(contract, version)
+	}
+	return version != nil && cik.ContractVersion == version.Name
+}
+
 // If context has not been resolved yet and we need a key, generate one
 // Otherwise use context name
 func getContextNameUnsafe(context *lang.Context) string {
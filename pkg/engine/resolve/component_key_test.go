@@ -65,6 +65,7 @@ func makeKey(root bool) *ComponentInstanceKey {
 	key := NewComponentInstanceKey(
 		b.AddCluster(),
 		contract,
+		nil,
 		contract.Contexts[0],
 		[]string{"x", "y", "z"},
 		service,
@@ -81,5 +82,6 @@ func makeKeyUnsafe() *ComponentInstanceKey {
 		nil,
 		nil,
 		nil,
+		nil,
 	)
 }
@@ -1,5 +1,9 @@
 package resolve
 
+import (
+	"github.com/Aptomi/aptomi/pkg/lang"
+)
+
 // DependencyResolution contains resolution status for a given dependency
 type DependencyResolution struct {
 	// Resolved indicates whether or not dependency has been resolved. If it has been resolved,
@@ -9,18 +13,54 @@ type DependencyResolution struct {
 
 	// ComponentInstanceKey holds the reference to component instance, to which dependency got resolved
 	ComponentInstanceKey string
+
+	// ContractVersion is the name of the contract version, which dependency got resolved with (empty if contract
+	// has no versions)
+	ContractVersion string `yaml:",omitempty"`
+
+	// Deprecated is the deprecation message of the contract version, which dependency got resolved with (empty if
+	// version is not deprecated)
+	Deprecated string `yaml:",omitempty"`
+
+	// Outputs contains values, which the service delivered back to the consumer (in the order they are declared in
+	// the service). Values of sensitive outputs are registered in the secret masker of policy resolution
+	Outputs []*DependencyOutput `yaml:",omitempty"`
+
+	// WouldMove contains warnings about instances of sticky services, which would have moved to a different
+	// placement if they were not sticky. They stay in place until dependency migration is requested
+	WouldMove []string `yaml:",omitempty"`
+
+	// Error is the reason why dependency could not be resolved (empty if it has been resolved)
+	Error string `yaml:",omitempty"`
+}
+
+// DependencyOutput is a calculated value of a service output for a given dependency
+type DependencyOutput struct {
+	// Name of the output
+	Name string
+
+	// Value of the output
+	Value string
+
+	// Sensitive indicates that the value should only be shown to the user who owns the dependency
+	Sensitive bool `yaml:",omitempty"`
 }
 
 // Creates a new dependency resolution
-func newDependencyResolution(resolveErr error, key *ComponentInstanceKey) *DependencyResolution {
+func newDependencyResolution(resolveErr error, key *ComponentInstanceKey, version *lang.ContractVersion, outputs []*DependencyOutput, wouldMove []string) *DependencyResolution {
 	if resolveErr != nil {
 		return &DependencyResolution{
 			Resolved: false,
+			Error:    resolveErr.Error(),
 		}
 	}
 
 	return &DependencyResolution{
 		Resolved:             true,
 		ComponentInstanceKey: key.GetKey(),
+		ContractVersion:      version.Name,
+		Deprecated:           version.Deprecated,
+		Outputs:              outputs,
+		WouldMove:            wouldMove,
 	}
 }
@@ -0,0 +1,39 @@
+package resolve
+
+import (
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"sync"
+)
+
+// LastResolution keeps the most recent desired state calculated by the enforcer, along with the generation of the
+// policy it has been calculated for. It allows to serve data derived from the desired state (e.g. quota usage)
+// without resolving the policy again. It's safe for concurrent use
+type LastResolution struct {
+	mutex      sync.RWMutex
+	policyGen  runtime.Generation
+	resolution *PolicyResolution
+}
+
+// NewLastResolution creates a new LastResolution, which doesn't hold any desired state yet
+func NewLastResolution() *LastResolution {
+	return &LastResolution{}
+}
+
+// Set records the desired state calculated for a given generation of the policy
+func (last *LastResolution) Set(policyGen runtime.Generation, resolution *PolicyResolution) {
+	last.mutex.Lock()
+	defer last.mutex.Unlock()
+	last.policyGen = policyGen
+	last.resolution = resolution
+}
+
+// Get returns the desired state calculated for a given generation of the policy, or nil if the last recorded desired
+// state has been calculated for another generation (or nothing has been recorded yet)
+func (last *LastResolution) Get(policyGen runtime.Generation) *PolicyResolution {
+	last.mutex.RLock()
+	defer last.mutex.RUnlock()
+	if last.resolution == nil || last.policyGen != policyGen {
+		return nil
+	}
+	return last.resolution
+}
@@ -2,6 +2,7 @@ package resolve
 
 import (
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
@@ -21,6 +22,12 @@ type PolicyResolution struct {
 
 	// Resolved dependencies: dependencyID -> dependency resolution
 	dependencyInstanceMap map[string]*DependencyResolution
+
+	// Quota usage: quotaKey -> quota usage
+	quotaUsageMap map[string]*QuotaUsage
+
+	// Secret values exposed to the policy during resolution, so they can be masked in logs and API responses
+	secretMasker *secrets.Masker
 }
 
 // NewPolicyResolution creates new empty PolicyResolution, given a flag indicating whether it's a
@@ -30,9 +37,16 @@ func NewPolicyResolution(isDesired bool) *PolicyResolution {
 		isDesired:             isDesired,
 		ComponentInstanceMap:  make(map[string]*ComponentInstance),
 		dependencyInstanceMap: make(map[string]*DependencyResolution),
+		quotaUsageMap:         make(map[string]*QuotaUsage),
+		secretMasker:          secrets.NewMasker(nil),
 	}
 }
 
+// GetSecretMasker returns masker, which is aware of all secret values exposed to the policy during resolution
+func (resolution *PolicyResolution) GetSecretMasker() *secrets.Masker {
+	return resolution.secretMasker
+}
+
 // GetComponentInstanceEntry retrieves a component instance entry by key, or creates an new entry if it doesn't exist
 func (resolution *PolicyResolution) GetComponentInstanceEntry(cik *ComponentInstanceKey) *ComponentInstance {
 	key := cik.GetKey()
@@ -56,6 +70,11 @@ func (resolution *PolicyResolution) RecordCodeParams(cik *ComponentInstanceKey,
 	return instance.addCodeParams(codeParams)
 }
 
+// RecordHooks stores calculated lifecycle hooks for component instance
+func (resolution *PolicyResolution) RecordHooks(cik *ComponentInstanceKey, hooks map[string][]*ComponentHook) error {
+	return resolution.GetComponentInstanceEntry(cik).addHooks(hooks)
+}
+
 // RecordDiscoveryParams stores calculated discovery params for component instance
 func (resolution *PolicyResolution) RecordDiscoveryParams(cik *ComponentInstanceKey, discoveryParams util.NestedParameterMap) error {
 	return resolution.GetComponentInstanceEntry(cik).addDiscoveryParams(discoveryParams)
@@ -77,11 +96,18 @@ func (resolution *PolicyResolution) StoreEdge(src *ComponentInstanceKey, dst *Co
 
 // AppendData appends data to the current PolicyResolution record by aggregating data over component instances.
 // If there is a conflict (e.g. components have different code parameters), then an error will be reported.
+// Component instances from the given PolicyResolution don't get modified, so it can be appended more than once.
 func (resolution *PolicyResolution) AppendData(ops *PolicyResolution) error {
 	for key, instance := range ops.ComponentInstanceMap {
 		// if component doesn't exist, copy it over
 		if _, ok := resolution.ComponentInstanceMap[key]; !ok {
-			resolution.ComponentInstanceMap[key] = instance
+			instanceCopy := newComponentInstance(instance.Metadata.Key)
+			instanceCopy.IsCode = instance.IsCode
+			err := instanceCopy.appendData(instance)
+			if err != nil {
+				return err
+			}
+			resolution.ComponentInstanceMap[key] = instanceCopy
 		} else { // otherwise, update data
 			err := resolution.ComponentInstanceMap[key].appendData(instance)
 			if err != nil {
@@ -100,6 +126,14 @@ func (resolution *PolicyResolution) GetDependencyInstanceMap() map[string]*Depen
 	return resolution.dependencyInstanceMap
 }
 
+// GetQuotaUsageMap returns map which contains usage for every quota defined in the policy
+func (resolution *PolicyResolution) GetQuotaUsageMap() map[string]*QuotaUsage {
+	if !resolution.isDesired {
+		panic("attempting to get quota usage map for actual state")
+	}
+	return resolution.quotaUsageMap
+}
+
 // SetDependencyInstanceMap overrides existing dependencyInstanceMap
 func (resolution *PolicyResolution) SetDependencyInstanceMap(dMap map[string]*DependencyResolution) {
 	// TODO: we actually need to start saving dependencyInstanceMap into the store. after that we can delete this method
@@ -120,10 +154,23 @@ func (resolution *PolicyResolution) Validate(policy *lang.Policy) error {
 			return fmt.Errorf("contract '%s/%s' can only be deleted after it's no longer in use. still used by: %s", componentKey.Namespace, componentKey.ContractName, componentKey.GetKey())
 		}
 
-		// verify that context within a contract exists
+		// verify that contract version exists (contract without versions has a single unnamed version)
 		contract := contractObj.(*lang.Contract)
+		var version *lang.ContractVersion
+		for _, v := range contract.GetVersions() {
+			if componentKey.isContractVersion(contract, v) {
+				version = v
+				break
+			}
+		}
+		if version == nil {
+			// component instance points to non-existing version of a contract, meaning this component instance is now orphan
+			return fmt.Errorf("version '%s/%s/%s' can only be deleted after it's no longer in use. still used by: %s", componentKey.Namespace, componentKey.ContractName, componentKey.ContractVersion, componentKey.GetKey())
+		}
+
+		// verify that context within a contract version exists
 		contextExists := false
-		for _, context := range contract.Contexts {
+		for _, context := range version.Contexts {
 			if context.Name == componentKey.ContextName {
 				contextExists = true
 				break
@@ -131,7 +178,7 @@ func (resolution *PolicyResolution) Validate(policy *lang.Policy) error {
 		}
 		if !contextExists {
 			// component instance points to non-existing context within a contract, meaning this component instance is now orphan
-			return fmt.Errorf("context '%s/%s/%s' can only be deleted after it's no longer in use. still used by: %s", componentKey.Namespace, componentKey.ContractName, componentKey.ContextName, componentKey.GetKey())
+			return fmt.Errorf("context '%s/%s/%s' can only be deleted after it's no longer in use. still used by: %s", componentKey.Namespace, componentKey.ContractName, componentKey.ContextNameWithKeys, componentKey.GetKey())
 		}
 
 		// verify that service exists
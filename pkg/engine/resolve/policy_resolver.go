@@ -11,6 +11,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/util"
 	sysruntime "runtime"
 	"runtime/debug"
+	"sort"
 	"sync"
 )
 
@@ -32,6 +33,9 @@ type PolicyResolver struct {
 	// External data
 	externalData *external.Data
 
+	// Placement of sticky services from the actual state (nil, if placement doesn't need to be kept)
+	sticky *stickyPlacement
+
 	/*
 		Cache
 	*/
@@ -42,6 +46,16 @@ type PolicyResolver struct {
 	// Template cache
 	templateCache *template.Cache
 
+	// Secret key for random functions in templates
+	randomKey string
+
+	// Cache of dependency resolution results between runs of the resolver (nil, if results don't need to be reused)
+	cache *ResolutionCache
+
+	// Fingerprints of inputs other than policy objects, calculated during this run of the resolver
+	fingerprintMutex sync.Mutex
+	fingerprints     map[inputRef]string
+
 	/*
 		Calculated objects (aggregated over all dependencies)
 	*/
@@ -69,11 +83,43 @@ func NewPolicyResolver(policy *lang.Policy, externalData *external.Data, eventLo
 		externalData:    externalData,
 		expressionCache: expression.NewCache(),
 		templateCache:   template.NewCache(),
-		resolution:      NewPolicyResolution(true),
+		fingerprints:    make(map[inputRef]string),
+		resolution:      newPolicyResolutionWithQuotas(policy),
 		eventLog:        eventLog,
 	}
 }
 
+// Creates a new PolicyResolution with empty usage recorded for every quota defined in the policy
+func newPolicyResolutionWithQuotas(policy *lang.Policy) *PolicyResolution {
+	resolution := NewPolicyResolution(true)
+	for _, quotaObj := range policy.GetObjectsByKind(lang.QuotaObject.Kind) {
+		quota := quotaObj.(*lang.Quota)
+		resolution.quotaUsageMap[runtime.KeyForStorable(quota)] = newQuotaUsage(quota)
+	}
+	return resolution
+}
+
+// byDependencyKey sorts dependencies along with their resolution results by dependency key
+type byDependencyKey struct {
+	dependencies []lang.Base
+	nodes        []*resolutionNode
+	resolveErrs  []error
+}
+
+func (s byDependencyKey) Len() int {
+	return len(s.dependencies)
+}
+
+func (s byDependencyKey) Swap(i, j int) {
+	s.dependencies[i], s.dependencies[j] = s.dependencies[j], s.dependencies[i]
+	s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i]
+	s.resolveErrs[i], s.resolveErrs[j] = s.resolveErrs[j], s.resolveErrs[i]
+}
+
+func (s byDependencyKey) Less(i, j int) bool {
+	return runtime.KeyForStorable(s.dependencies[i]) < runtime.KeyForStorable(s.dependencies[j])
+}
+
 // ResolveAllDependencies takes policy as input and calculates PolicyResolution (desired state) as output.
 //
 // The method resolves all recorded claims for consuming contracts ("instantiate <contract> with <labels>"), calculating
@@ -86,24 +132,43 @@ func (resolver *PolicyResolver) ResolveAllDependencies() *PolicyResolution {
 	var semaphore = make(chan int, MaxConcurrentGoRoutines)
 	var wg sync.WaitGroup
 	dependencies := resolver.policy.GetObjectsByKind(lang.DependencyObject.Kind)
+	nodes := make([]*resolutionNode, len(dependencies))
+	resolveErrs := make([]error, len(dependencies))
 
 	// Resolve every declared dependency
-	for _, d := range dependencies {
+	for idx, d := range dependencies {
 		// Start go routine for resolving a given dependency
 		wg.Add(1)
 		semaphore <- 1
-		go func(d *lang.Dependency) {
+		go func(idx int, d *lang.Dependency) {
 			defer wg.Done()
-			node, resolveErr := resolver.resolveDependency(d)
-			resolver.combineData(node, resolveErr)
+			nodes[idx], resolveErrs[idx] = resolver.resolveDependency(d)
 			<-semaphore
-		}(d.(*lang.Dependency))
+		}(idx, d.(*lang.Dependency))
 	}
 
 	// Wait for all go routines to end
 	wg.Wait()
 
-	// Once all components are resolved, print information about them into event log
+	// Forget results for dependencies which have been removed from the policy
+	if resolver.cache != nil {
+		resolver.cache.retain(dependencies)
+	}
+
+	// Combine data in the order of dependency keys, so quotas always get consumed by the same dependencies
+	sort.Sort(byDependencyKey{dependencies, nodes, resolveErrs})
+	for idx := range dependencies {
+		resolver.combineData(nodes[idx], resolveErrs[idx])
+	}
+
+	// Once all components are resolved, make sure their sensitive code params will be masked
+	for _, instance := range resolver.resolution.ComponentInstanceMap {
+		if instance.Metadata.Key.IsComponent() {
+			resolver.registerSensitiveCodeParams(instance)
+		}
+	}
+
+	// Print information about resolved components into event log
 	for _, instance := range resolver.resolution.ComponentInstanceMap {
 		if instance.Metadata.Key.IsComponent() {
 			resolver.logComponentCodeParams(instance)
@@ -111,16 +176,62 @@ func (resolver *PolicyResolver) ResolveAllDependencies() *PolicyResolution {
 		}
 	}
 
+	// Warn about expressions which referred to missing parameters (e.g. labels with typos in their names)
+	resolver.logMissingParameters()
+
 	return resolver.resolution
 }
 
+// Makes secret masker aware of sensitive code params of the component instance (either explicitly marked as
+// sensitive in the service definition, or having keys which match sensitive key patterns)
+func (resolver *PolicyResolver) registerSensitiveCodeParams(instance *ComponentInstance) {
+	serviceObj, err := resolver.policy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err != nil {
+		panic(fmt.Sprintf("error while getting service '%s/%s' from the policy: %s", instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace, err))
+	}
+	code := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName].Code
+	if code != nil {
+		resolver.resolution.secretMasker.AddSensitiveKeys(instance.GetKey(), code.Sensitive...)
+		resolver.resolution.secretMasker.AddSensitiveParams(instance.GetKey(), instance.CalculatedCodeParams)
+	}
+}
+
+// RegisterSensitiveParams makes secret masker of the policy resolution aware of sensitive code params of component
+// instances from another state (e.g. actual state, which is about to be changed), so their values get masked in logs
+// as well. Services of these instances may no longer exist in the policy, in which case only keys matching sensitive
+// key patterns are taken into account
+func (resolver *PolicyResolver) RegisterSensitiveParams(state *PolicyResolution) {
+	masker := resolver.resolution.secretMasker
+	for _, instance := range state.ComponentInstanceMap {
+		if !instance.Metadata.Key.IsComponent() {
+			continue
+		}
+		serviceObj, err := resolver.policy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+		if err == nil && serviceObj != nil {
+			component := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName]
+			if component != nil && component.Code != nil {
+				masker.AddSensitiveKeys(instance.GetKey(), component.Code.Sensitive...)
+			}
+		}
+		masker.AddSensitiveParams(instance.GetKey(), instance.CalculatedCodeParams)
+	}
+}
+
 // Resolves a single dependency and returns an error if it cannot be resolved
 func (resolver *PolicyResolver) resolveDependency(d *lang.Dependency) (node *resolutionNode, resolveErr error) {
+	// reuse the previous result, if none of the inputs it has been calculated from have changed
+	if resolver.cache != nil {
+		if entry := resolver.cache.get(resolver, d); entry != nil {
+			return resolver.newResolutionNodeFromCache(d, entry), entry.resolveErr
+		}
+	}
+
 	// make sure we are converting panics into errors
 	defer func() {
 		if err := recover(); err != nil {
 			resolveErr = fmt.Errorf("panic: %s\n%s", err, string(debug.Stack()))
 			node.eventLog.NewEntry().Error(resolveErr)
+			resolver.expressionCache.AddMissingParameters(node.expressionCache.GetMissingParameters())
 		}
 	}()
 
@@ -132,6 +243,11 @@ func (resolver *PolicyResolver) resolveDependency(d *lang.Dependency) (node *res
 
 	// resolve it
 	resolveErr = resolver.resolveNode(node)
+
+	// store the result in the cache (results of resolution which ended up with a panic don't get here)
+	if resolver.cache != nil {
+		return resolver.newResolutionNodeFromCache(d, resolver.cache.put(node, resolveErr)), resolveErr
+	}
 	return node, resolveErr
 }
 
@@ -150,6 +266,15 @@ func (resolver *PolicyResolver) combineData(node *resolutionNode, resolutionErr
 		resolver.combineMutex.Unlock()
 	}()
 
+	// if there was no resolution error, check that dependency fits into quotas
+	if resolutionErr == nil {
+		quotaErr := resolver.checkAndConsumeQuotas(node)
+		if quotaErr != nil {
+			node.eventLog.NewEntry().Error(quotaErr)
+			resolutionErr = quotaErr
+		}
+	}
+
 	// if there was no resolution error, combine component data
 	if resolutionErr == nil {
 		// aggregate component instance data
@@ -163,7 +288,48 @@ func (resolver *PolicyResolver) combineData(node *resolutionNode, resolutionErr
 	}
 
 	// add a record for dependency resolution
-	resolver.resolution.dependencyInstanceMap[runtime.KeyForStorable(node.dependency)] = newDependencyResolution(resolutionErr, node.serviceKey)
+	resolver.resolution.dependencyInstanceMap[runtime.KeyForStorable(node.dependency)] = newDependencyResolution(resolutionErr, node.serviceKey, node.contractVersion, node.outputs, node.wouldMove)
+}
+
+// Checks that the resolved dependency fits into all quotas which apply to it. If it does, then dependency gets
+// counted against those quotas. Otherwise an error is returned
+func (resolver *PolicyResolver) checkAndConsumeQuotas(node *resolutionNode) error {
+	policyNS := resolver.policy.Namespace[node.dependency.Namespace]
+	if policyNS == nil || len(policyNS.Quotas) <= 0 {
+		return nil
+	}
+
+	// quotas are matched against the initial set of labels (dependency labels + user labels)
+	labels := lang.NewLabelSet(node.dependency.Labels)
+	labels.AddLabels(node.user.Labels)
+	params := expression.NewParams(labels.Labels, map[string]interface{}{})
+
+	dependencyKey := runtime.KeyForStorable(node.dependency)
+	usages := []*QuotaUsage{}
+	for _, name := range util.GetSortedStringKeys(policyNS.Quotas) {
+		quota := policyNS.Quotas[name]
+		matches, err := quota.Matches(params, resolver.expressionCache)
+		if err != nil {
+			return node.errorWhenTestingQuota(quota, err)
+		}
+		node.logTestedQuotaMatch(quota, matches)
+		if !matches {
+			continue
+		}
+
+		usage := resolver.resolution.quotaUsageMap[runtime.KeyForStorable(quota)]
+		err = usage.check(dependencyKey, node.resolution)
+		if err != nil {
+			return node.errorQuotaExceeded(quota, err)
+		}
+		usages = append(usages, usage)
+	}
+
+	// dependency fits into all quotas, so let's count it
+	for _, usage := range usages {
+		usage.add(dependencyKey, node.resolution)
+	}
+	return nil
 }
 
 // Evaluate evaluates and resolves a single dependency ("<user> needs <service> with <labels>") and calculates component allocations
@@ -202,12 +368,26 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 	node.namespace = node.contract.Namespace
 	node.objectResolved(node.contract)
 
+	// Pick the version of the contract
+	node.contractVersion, err = node.getContractVersion()
+	if err != nil {
+		return err
+	}
+
+	// Resolve contract params
+	node.params, err = node.resolveParams()
+	if err != nil {
+		return err
+	}
+	node.logParamsResolved()
+
 	// Process service and transform labels
 	node.transformLabels(node.labels, node.contract.ChangeLabels)
 
-	// Match the context
+	// Match the context (dependencies on sticky services keep the context they have been placed into)
+	node.stickyKey = node.getStickyKey()
 	node.context, err = node.getMatchedContext(resolver.policy)
-	if err != nil {
+	if !node.keepStickyContext() && err != nil {
 		return err
 	}
 
@@ -226,17 +406,21 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 	if err != nil {
 		return err
 	}
+	node.keepStickyAllocationKeys()
 
 	// Process global rules before processing service key and dependent component keys
 	ruleResult, err := node.processRules()
 	if err != nil {
 		return err
 	}
+	node.keepStickyCluster()
+
 	// Create service key
 	node.serviceKey, err = node.createComponentKey(nil)
 	if err != nil {
 		return err
 	}
+	node.checkStickyPlacement()
 
 	// Check if we've been there already and therefore hit a service cycle
 	cycle := util.ContainsString(node.path, node.serviceKey.GetKey())
@@ -308,8 +492,9 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 			// Resolve dependency on another contract recursively
 			err := resolver.resolveNode(nodeNext)
 
-			// Combine event logs first
+			// Combine event logs and warnings first
 			node.eventLogsCombined = append(node.eventLogsCombined, nodeNext.eventLogsCombined...)
+			node.wouldMove = append(node.wouldMove, nodeNext.wouldMove...)
 
 			// Then return an error, if there was one
 			if err != nil {
@@ -323,6 +508,14 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 		node.resolution.RecordResolved(node.componentKey, node.dependency, ruleResult)
 	}
 
+	// Calculate outputs, which are delivered back to the consumer (only for the service which consumer has requested)
+	if node.depth == 0 {
+		node.outputs, err = node.calculateOutputs()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Mark note as resolved and record usage of a given service instance
 	node.logInstanceSuccessfullyResolved(node.serviceKey)
 	node.resolution.RecordResolved(node.serviceKey, node.dependency, ruleResult)
@@ -0,0 +1,309 @@
+package resolve
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"strings"
+	"sync"
+)
+
+// ResolutionCache keeps results of dependency resolution between runs of the policy resolver, so that only
+// dependencies affected by changes get re-resolved. Every result is stored along with the inputs which have been read
+// while resolving the dependency (generations of policy objects, user labels, secrets, placement of sticky services).
+// The result gets reused only if all of these inputs are still the same.
+//
+// Changes to policy objects are detected by their generations, so the cache must only be used with policies loaded
+// from the store, where every change to an object increments its generation
+type ResolutionCache struct {
+	mutex   sync.Mutex
+	entries map[string]*resolutionCacheEntry
+}
+
+// NewResolutionCache creates a new empty cache of dependency resolution results
+func NewResolutionCache() *ResolutionCache {
+	return &ResolutionCache{
+		entries: make(map[string]*resolutionCacheEntry),
+	}
+}
+
+// SetCache makes the resolver reuse results of dependency resolution from the given cache, if the inputs they depend
+// on haven't changed. Newly calculated results get stored in the cache
+func (resolver *PolicyResolver) SetCache(cache *ResolutionCache) *PolicyResolver {
+	resolver.cache = cache
+	return resolver
+}
+
+// Result of resolving a single dependency, along with the inputs it has been calculated from
+type resolutionCacheEntry struct {
+	inputs *resolutionInputs
+
+	resolution      *PolicyResolution
+	eventLogs       []*event.Log
+	serviceKey      *ComponentInstanceKey
+	contractVersion *lang.ContractVersion
+	outputs         []*DependencyOutput
+	wouldMove       []string
+	resolveErr      error
+	missingParams   []*expression.MissingParameterError
+}
+
+// Returns a cached result for a given dependency, or nil if there is no result or its inputs have changed
+func (cache *ResolutionCache) get(resolver *PolicyResolver, dependency *lang.Dependency) *resolutionCacheEntry {
+	dKey := runtime.KeyForStorable(dependency)
+	cache.mutex.Lock()
+	entry := cache.entries[dKey]
+	cache.mutex.Unlock()
+
+	if entry == nil || !entry.inputs.upToDate(resolver, dKey) {
+		return nil
+	}
+	return entry
+}
+
+// Stores result of resolving a given dependency in the cache
+func (cache *ResolutionCache) put(node *resolutionNode, resolveErr error) *resolutionCacheEntry {
+	entry := &resolutionCacheEntry{
+		inputs:          node.inputs,
+		resolution:      node.resolution,
+		eventLogs:       node.eventLogsCombined,
+		serviceKey:      node.serviceKey,
+		contractVersion: node.contractVersion,
+		outputs:         node.outputs,
+		wouldMove:       node.wouldMove,
+		resolveErr:      resolveErr,
+		missingParams:   node.expressionCache.GetMissingParameters(),
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.entries[runtime.KeyForStorable(node.dependency)] = entry
+	return entry
+}
+
+// Removes results for all dependencies which are no longer present in the policy
+func (cache *ResolutionCache) retain(dependencies []lang.Base) {
+	present := make(map[string]bool)
+	for _, d := range dependencies {
+		present[runtime.KeyForStorable(d)] = true
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for dKey := range cache.entries {
+		if !present[dKey] {
+			delete(cache.entries, dKey)
+		}
+	}
+}
+
+// Creates a resolution node from the cached result, so it can be combined into the overall state of the world.
+// Node gets a fresh event log, so that errors found while combining don't end up in the cache
+func (resolver *PolicyResolver) newResolutionNodeFromCache(dependency *lang.Dependency, entry *resolutionCacheEntry) *resolutionNode {
+	node := resolver.newResolutionNode()
+	node.dependency = dependency
+	node.user = resolver.externalData.UserLoader.LoadUserByName(dependency.User)
+	node.objectResolved(dependency)
+
+	node.resolution = entry.resolution
+	node.eventLogsCombined = append(append([]*event.Log{}, entry.eventLogs...), node.eventLog)
+	node.serviceKey = entry.serviceKey
+	node.contractVersion = entry.contractVersion
+	node.outputs = entry.outputs
+	node.wouldMove = entry.wouldMove
+
+	// secret values, which have been exposed to the policy, still need to be masked
+	resolver.resolution.secretMasker.AddSecrets(entry.inputs.secretValues)
+
+	// expressions aren't evaluated again, so missing parameters need to be reported as if they were
+	resolver.expressionCache.AddMissingParameters(entry.missingParams)
+	return node
+}
+
+// Kinds of inputs other than policy objects, which can be read while resolving a dependency
+const (
+	inputUser        = "user"
+	inputUserSecrets = "user-secrets"
+	inputSecrets     = "secrets"
+	inputRules       = "rules"
+	inputACL         = "acl"
+)
+
+// Reference to a policy object, as it has been looked up while resolving a dependency
+type objectRef struct {
+	kind      string
+	locator   string
+	namespace string
+}
+
+// State of a policy object (whether it exists and which generation it is)
+type objectState struct {
+	found      bool
+	generation runtime.Generation
+}
+
+// Reference to an input other than policy object
+type inputRef struct {
+	kind  string
+	name  string
+	scope secrets.Scope
+}
+
+// Set of inputs which have been read while resolving a dependency. It gets shared by all nodes of the dependency
+type resolutionInputs struct {
+	// state of every policy object which has been looked up
+	objects map[objectRef]objectState
+
+	// fingerprints of users, secrets and rules which have been read
+	fingerprints map[inputRef]string
+
+	// placement of sticky services which has been looked up, keyed by contract key
+	sticky map[string]string
+
+	// secret values exposed to the policy
+	secretValues map[string]string
+}
+
+// Creates a new empty set of inputs
+func newResolutionInputs() *resolutionInputs {
+	return &resolutionInputs{
+		objects:      make(map[objectRef]objectState),
+		fingerprints: make(map[inputRef]string),
+		sticky:       make(map[string]string),
+		secretValues: make(map[string]string),
+	}
+}
+
+// Checks whether all inputs are still the same, as seen by a given resolver
+func (inputs *resolutionInputs) upToDate(resolver *PolicyResolver, dKey string) bool {
+	for ref, state := range inputs.objects {
+		if resolver.objectState(ref) != state {
+			return false
+		}
+	}
+	for ref, fingerprint := range inputs.fingerprints {
+		if resolver.fingerprint(ref) != fingerprint {
+			return false
+		}
+	}
+	for contractKey, placement := range inputs.sticky {
+		if resolver.stickyPlacement(dKey, contractKey) != placement {
+			return false
+		}
+	}
+	return true
+}
+
+// Looks up an object in the policy and records it as an input of the dependency which is being resolved
+func (node *resolutionNode) getObject(kind string, locator string, namespace string) (runtime.Object, error) {
+	obj, err := node.resolver.policy.GetObject(kind, locator, namespace)
+	if node.inputs != nil {
+		node.inputs.objects[objectRef{kind, locator, namespace}] = stateOf(obj, err)
+	}
+	return obj, err
+}
+
+// Records an input other than policy object, which has been read while resolving the dependency
+func (node *resolutionNode) inputRead(kind string, name string, scope secrets.Scope) {
+	if node.inputs != nil {
+		ref := inputRef{kind, name, scope}
+		node.inputs.fingerprints[ref] = node.resolver.fingerprint(ref)
+	}
+}
+
+// Returns the current state of a referenced policy object
+func (resolver *PolicyResolver) objectState(ref objectRef) objectState {
+	return stateOf(resolver.policy.GetObject(ref.kind, ref.locator, ref.namespace))
+}
+
+func stateOf(obj runtime.Object, err error) objectState {
+	if err != nil || obj == nil {
+		return objectState{}
+	}
+	if versioned, ok := obj.(runtime.Versioned); ok {
+		return objectState{found: true, generation: versioned.GetGeneration()}
+	}
+	return objectState{found: true}
+}
+
+// Returns a fingerprint of the current value of a referenced input. Fingerprints are calculated once per resolver,
+// as the same inputs get read by many dependencies
+func (resolver *PolicyResolver) fingerprint(ref inputRef) string {
+	resolver.fingerprintMutex.Lock()
+	defer resolver.fingerprintMutex.Unlock()
+
+	if result, ok := resolver.fingerprints[ref]; ok {
+		return result
+	}
+
+	var result string
+	switch ref.kind {
+	case inputUser:
+		result = fingerprintOfExternalData(func() string {
+			user := resolver.externalData.UserLoader.LoadUserByName(ref.name)
+			if user == nil {
+				return ""
+			}
+			return fmt.Sprintf("%s|%t|%s", user.Name, user.DomainAdmin, fingerprintOfMap(user.Labels))
+		})
+	case inputUserSecrets:
+		result = fingerprintOfExternalData(func() string {
+			return fingerprintOfSecrets(resolver.externalData.SecretLoader.LoadSecretsByUserName(ref.name))
+		})
+	case inputSecrets:
+		result = fingerprintOfExternalData(func() string {
+			return fingerprintOfSecrets(resolver.externalData.SecretLoader.LoadSecretsByScope(ref.scope))
+		})
+	case inputRules:
+		if policyNS := resolver.policy.Namespace[ref.name]; policyNS != nil {
+			result = fingerprintOfRules(policyNS.Rules)
+		}
+	case inputACL:
+		if policyNS := resolver.policy.Namespace[runtime.SystemNS]; policyNS != nil {
+			result = fingerprintOfRules(policyNS.ACLRules)
+		}
+	default:
+		panic(fmt.Sprintf("unknown kind of resolution input: %s", ref.kind))
+	}
+
+	resolver.fingerprints[ref] = result
+	return result
+}
+
+// Loaders of external data panic if data can't be loaded (e.g. vault is not available), so the error becomes the
+// fingerprint. It never matches a fingerprint of actual data, so the cached result won't be reused and dependency will
+// be resolved again (and fail)
+func fingerprintOfExternalData(load func() string) (result string) {
+	defer func() {
+		if err := recover(); err != nil {
+			result = fmt.Sprintf("error: %s", err)
+		}
+	}()
+	return load()
+}
+
+func fingerprintOfMap(values map[string]string) string {
+	result := []string{}
+	for _, k := range util.GetSortedStringKeys(values) {
+		result = append(result, k+"="+values[k])
+	}
+	return strings.Join(result, ",")
+}
+
+// Secret values don't get stored in fingerprints, only their hash does
+func fingerprintOfSecrets(values map[string]string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fingerprintOfMap(values))))
+}
+
+func fingerprintOfRules(rules map[string]*lang.Rule) string {
+	result := []string{}
+	for _, name := range util.GetSortedStringKeys(rules) {
+		result = append(result, fmt.Sprintf("%s@%s", name, rules[name].GetGeneration()))
+	}
+	return strings.Join(result, ",")
+}
@@ -3,7 +3,9 @@ package resolve
 import (
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 )
@@ -37,6 +39,14 @@ type resolutionNode struct {
 	contractName string
 	contract     *lang.Contract
 
+	// requested version of the contract (empty means the latest one) and the version we are currently resolving
+	contractVersionName string
+	contractVersion     *lang.ContractVersion
+
+	// param values supplied for the contract and the full set of params, resolved according to the contract
+	paramValues map[string]interface{}
+	params      map[string]interface{}
+
 	// reference to the current set of labels
 	labels *lang.LabelSet
 
@@ -65,11 +75,37 @@ type resolutionNode struct {
 
 	// path that we traveled so far (to detect cycles)
 	path []string
+
+	// outputs which service delivers back to the consumer
+	outputs []*DependencyOutput
+
+	// previous placement of the service instance, which has to be kept because the service is sticky
+	stickyKey *ComponentInstanceKey
+
+	// where service instance would move to if it wasn't sticky, and resulting warnings for the whole subtree
+	wouldMoveTo []string
+	wouldMove   []string
+
+	// inputs read while resolving the dependency, shared by all nodes (nil, if results don't get cached)
+	inputs *resolutionInputs
+
+	// cache of expressions used by the node, which records missing parameters encountered while resolving the
+	// dependency, shared by all nodes
+	expressionCache *expression.Cache
+
+	// secrets of scopes, which have been loaded by the node so far (they get loaded only when referred to)
+	scopedSecrets map[secrets.Scope]map[string]string
 }
 
 // Creates a new empty resolution node
 func (resolver *PolicyResolver) newResolutionNode() *resolutionNode {
 	eventLog := event.NewLog(resolver.eventLog.GetLevel(), resolver.eventLog.GetScope())
+	var inputs *resolutionInputs
+	expressionCache := resolver.expressionCache
+	if resolver.cache != nil {
+		inputs = newResolutionInputs()
+		expressionCache = resolver.expressionCache.NewScope()
+	}
 	return &resolutionNode{
 		resolver:          resolver,
 		eventLog:          eventLog,
@@ -84,6 +120,10 @@ func (resolver *PolicyResolver) newResolutionNode() *resolutionNode {
 
 		// empty path
 		path: []string{},
+
+		inputs: inputs,
+
+		expressionCache: expressionCache,
 	}
 }
 
@@ -92,12 +132,16 @@ func (resolver *PolicyResolver) newResolutionNode() *resolutionNode {
 func (resolver *PolicyResolver) initResolutionNode(node *resolutionNode, dependency *lang.Dependency) {
 	// populate user, dependency
 	node.dependency = dependency
+	node.getObject(lang.DependencyObject.Kind, dependency.Name, dependency.Namespace)
 	user := resolver.externalData.UserLoader.LoadUserByName(dependency.User)
 	node.user = user
+	node.inputRead(inputUser, dependency.User, secrets.Scope{})
 
 	// start with the namespace & contract specified in the dependency
 	node.namespace = dependency.Namespace
 	node.contractName = dependency.Contract
+	node.contractVersionName = dependency.Version
+	node.paramValues = dependency.Params
 
 	// create a starting set of labels, combining user labels and dependency labels
 	node.labels = lang.NewLabelSet(dependency.Labels)
@@ -135,6 +179,12 @@ func (node *resolutionNode) createChildNode() *resolutionNode {
 
 		// copy path
 		path: util.CopySliceOfStrings(node.path),
+
+		// share inputs with the parent node
+		inputs: node.inputs,
+
+		// share expression cache with the parent node
+		expressionCache: node.expressionCache,
 	}
 }
 
@@ -154,7 +204,7 @@ func (node *resolutionNode) checkUserExists() error {
 
 // Helper to get a contract
 func (node *resolutionNode) getContract(policy *lang.Policy) *lang.Contract {
-	contractObj, err := policy.GetObject(lang.ContractObject.Kind, node.contractName, node.namespace)
+	contractObj, err := node.getObject(lang.ContractObject.Kind, node.contractName, node.namespace)
 	if contractObj == nil || err != nil {
 		panic(fmt.Sprintf("Can't get contract '%s/%s': %s", node.namespace, node.contractName, err))
 	}
@@ -163,6 +213,30 @@ func (node *resolutionNode) getContract(policy *lang.Policy) *lang.Contract {
 	return contract
 }
 
+// Helper to get a requested version of the contract
+func (node *resolutionNode) getContractVersion() (*lang.ContractVersion, error) {
+	version := node.contract.GetVersion(node.contractVersionName)
+	if version == nil {
+		return nil, node.errorContractVersionDoesNotExist()
+	}
+	if len(version.Name) > 0 {
+		node.logContractVersionFound(version)
+	}
+	if len(version.Deprecated) > 0 {
+		node.logContractVersionDeprecated(version)
+	}
+	return version, nil
+}
+
+// Helper to resolve contract params (contract components of services don't supply values, so only defaults are used)
+func (node *resolutionNode) resolveParams() (map[string]interface{}, error) {
+	params, err := node.contract.ResolveParams(node.paramValues)
+	if err != nil {
+		return nil, node.errorWhenResolvingParams(err)
+	}
+	return params, nil
+}
+
 // Helper to get a matched context
 func (node *resolutionNode) getMatchedContext(policy *lang.Policy) (*lang.Context, error) {
 	// Locate the list of contexts for service
@@ -171,14 +245,14 @@ func (node *resolutionNode) getMatchedContext(policy *lang.Policy) (*lang.Contex
 	// Find matching context
 	contextualData := node.getContextualDataForContextExpression()
 	var contextMatched *lang.Context
-	for _, context := range node.contract.Contexts {
+	for _, context := range node.contractVersion.Contexts {
 		// Check if context matches (based on criteria)
-		matched, err := context.Matches(contextualData, node.resolver.expressionCache)
+		matched, branch, err := context.MatchesBranch(contextualData, node.expressionCache)
 		if err != nil {
 			// Propagate error up
 			return nil, node.errorWhenTestingContext(context, err)
 		}
-		node.logTestedContextCriteria(context, matched)
+		node.logTestedContextCriteria(context, matched, branch)
 		if matched {
 			contextMatched = context
 			break
@@ -195,7 +269,7 @@ func (node *resolutionNode) getMatchedContext(policy *lang.Policy) (*lang.Contex
 
 // Helper to get a matched service
 func (node *resolutionNode) getMatchedService(policy *lang.Policy) (*lang.Service, error) {
-	serviceObj, err := policy.GetObject(lang.ServiceObject.Kind, node.context.Allocation.Service, node.namespace)
+	serviceObj, err := node.getObject(lang.ServiceObject.Kind, node.context.Allocation.Service, node.namespace)
 	if serviceObj == nil || err != nil {
 		panic(fmt.Sprintf("Can't get service '%s/%s': %s", node.namespace, node.context.Allocation.Service, err))
 	}
@@ -208,6 +282,7 @@ func (node *resolutionNode) getMatchedService(policy *lang.Policy) (*lang.Servic
 	}
 
 	// User should have access to consume the service according to the ACL
+	node.inputRead(inputACL, "", secrets.Scope{})
 	userView := node.resolver.policy.View(node.user)
 	canConsume, err := userView.CanConsume(service)
 	if !canConsume {
@@ -233,7 +308,7 @@ func (node *resolutionNode) resolveAllocationKeys(policy *lang.Policy) ([]string
 // checks if component criteria holds or not (i.e. whether component should be included or excluded from processing)
 func (node *resolutionNode) componentMatches(component *lang.ServiceComponent) (bool, error) {
 	contextualData := node.getContextualDataForComponentCriteria()
-	matched, err := component.Matches(contextualData, node.resolver.expressionCache)
+	matched, err := component.Matches(contextualData, node.expressionCache)
 	if err != nil {
 		// Propagate error up
 		return false, node.errorWhenTestingComponent(component, err)
@@ -247,7 +322,7 @@ func (node *resolutionNode) componentMatches(component *lang.ServiceComponent) (
 // createComponentKey creates a component key
 func (node *resolutionNode) createComponentKey(component *lang.ServiceComponent) (*ComponentInstanceKey, error) {
 	clusterName := node.labels.Labels[lang.LabelCluster]
-	clusterObj, err := node.resolver.policy.GetObject(lang.ClusterObject.Kind, clusterName, runtime.SystemNS)
+	clusterObj, err := node.getObject(lang.ClusterObject.Kind, clusterName, runtime.SystemNS)
 	if err != nil || clusterObj == nil {
 		return nil, node.errorClusterDoesNotExist(clusterName)
 	}
@@ -255,6 +330,7 @@ func (node *resolutionNode) createComponentKey(component *lang.ServiceComponent)
 	return NewComponentInstanceKey(
 		clusterObj.(*lang.Cluster),
 		node.contract,
+		node.contractVersion,
 		node.context,
 		node.allocationKeysResolved,
 		node.service,
@@ -277,11 +353,11 @@ func (node *resolutionNode) processRulesWithinNamespace(policyNamespace *lang.Po
 	rules := lang.GetRulesSortedByWeight(policyNamespace.Rules)
 	contextualData := node.getContextualDataForRuleExpression()
 	for _, rule := range rules {
-		matched, err := rule.Matches(contextualData, node.resolver.expressionCache)
+		matched, branch, err := rule.MatchesBranch(contextualData, node.expressionCache)
 		if err != nil {
 			return node.errorWhenProcessingRule(rule, err)
 		}
-		node.logTestedRuleMatch(rule, matched)
+		node.logTestedRuleMatch(rule, matched, branch)
 		if matched {
 			rule.ApplyActions(result)
 
@@ -302,6 +378,10 @@ func (node *resolutionNode) processRulesWithinNamespace(policyNamespace *lang.Po
 func (node *resolutionNode) processRules() (*lang.RuleActionResult, error) {
 	result := lang.NewRuleActionResult(node.labels)
 
+	// rules can change the outcome, even if they don't match now
+	node.inputRead(inputRules, node.namespace, secrets.Scope{})
+	node.inputRead(inputRules, runtime.SystemNS, secrets.Scope{})
+
 	// process rules within the current namespace
 	var err = node.processRulesWithinNamespace(node.resolver.policy.Namespace[node.namespace], result)
 	if err != nil {
@@ -327,6 +407,17 @@ func (node *resolutionNode) calculateAndStoreCodeParams() error {
 		return node.errorWhenProcessingCodeParams(err)
 	}
 
+	// Hooks get the same contextual data as the component code
+	hooks, err := node.calculateHooks()
+	if err != nil {
+		return node.errorWhenProcessingCodeParams(err)
+	}
+
+	err = node.resolution.RecordHooks(node.componentKey, hooks)
+	if err != nil {
+		return node.errorWhenProcessingCodeParams(err)
+	}
+
 	return nil
 }
 
@@ -349,3 +440,31 @@ func (node *resolutionNode) calculateAndStoreDiscoveryParams() error {
 
 	return nil
 }
+
+// Helper to calculate outputs of the service, which are delivered back to the consumer
+func (node *resolutionNode) calculateOutputs() ([]*DependencyOutput, error) {
+	result := []*DependencyOutput{}
+	if len(node.service.Outputs) <= 0 {
+		return result, nil
+	}
+	params := node.getContextualDataForOutputTemplate()
+	for _, output := range node.service.Outputs {
+		value, err := node.resolver.templateCache.Evaluate(output.Value, params)
+		if err != nil {
+			return nil, node.errorWhenProcessingOutput(output, err)
+		}
+
+		// make sure sensitive outputs don't show up in logs and API responses
+		if output.Sensitive {
+			node.loadSecrets(map[string]string{output.Name: value})
+		}
+
+		result = append(result, &DependencyOutput{
+			Name:      output.Name,
+			Value:     value,
+			Sensitive: output.Sensitive,
+		})
+	}
+	node.logOutputsCalculated(result)
+	return result, nil
+}
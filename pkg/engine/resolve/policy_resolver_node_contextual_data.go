@@ -2,6 +2,7 @@ package resolve
 
 import (
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/lang/template"
@@ -18,7 +19,9 @@ import (
 func (node *resolutionNode) getContextualDataForContextExpression() *expression.Parameters {
 	return expression.NewParams(
 		node.labels.Labels,
-		map[string]interface{}{},
+		map[string]interface{}{
+			"Params": node.params,
+		},
 	)
 }
 
@@ -27,7 +30,9 @@ func (node *resolutionNode) getContextualDataForContextExpression() *expression.
 func (node *resolutionNode) getContextualDataForComponentCriteria() *expression.Parameters {
 	return expression.NewParams(
 		node.labels.Labels,
-		map[string]interface{}{},
+		map[string]interface{}{
+			"Params": node.params,
+		},
 	)
 }
 
@@ -59,28 +64,56 @@ func (node *resolutionNode) getContextualDataForContextAllocationTemplate() *tem
 			User       interface{}
 			Dependency interface{}
 			Labels     interface{}
+			Params     interface{}
 		}{
 			User:       node.proxyUser(node.user),
 			Dependency: node.proxyDependency(node.dependency),
 			Labels:     node.labels.Labels,
+			Params:     node.params,
 		},
 	)
 }
 
 // This method defines which contextual information will be exposed to the template engine (for evaluating all templates - discovery, code params, etc)
 // Be careful about what gets exposed through this method. User can refer to structs and their methods from the policy
+// Random functions in templates are seeded with the component key and the secret key of the resolver, so they produce
+// stable values for the component
 func (node *resolutionNode) getContextualDataForCodeDiscoveryTemplate() *template.Parameters {
-	return template.NewParams(
+	return template.NewParamsWithSeed(
 		struct {
 			User      interface{}
 			Labels    interface{}
 			Discovery interface{}
 			Cluster   interface{}
+			Secrets   interface{}
+			Params    interface{}
 		}{
 			User:      node.proxyUser(node.user),
 			Labels:    node.labels.Labels,
 			Discovery: node.proxyDiscovery(node.discoveryTreeNode, node.componentKey),
 			Cluster:   node.proxyCluster(node.labels.Labels[lang.LabelCluster]),
+			Secrets:   node.proxySecrets(node.service, node.labels.Labels[lang.LabelCluster]),
+			Params:    node.params,
+		},
+		node.componentKey.GetKey(),
+		node.resolver.randomKey,
+	)
+}
+
+// This method defines which contextual information will be exposed to the template engine (for evaluating service outputs)
+// Be careful about what gets exposed through this method. User can refer to structs and their methods from the policy
+func (node *resolutionNode) getContextualDataForOutputTemplate() *template.Parameters {
+	return template.NewParams(
+		struct {
+			User      interface{}
+			Labels    interface{}
+			Discovery interface{}
+			Params    interface{}
+		}{
+			User:      node.proxyUser(node.user),
+			Labels:    node.labels.Labels,
+			Discovery: node.proxyDiscovery(node.discoveryTreeNode, node.serviceKey),
+			Params:    node.params,
 		},
 	)
 }
@@ -109,8 +142,70 @@ func (node *resolutionNode) proxyUser(user *lang.User) interface{} {
 	}{
 		Name:    user.Name,
 		Labels:  user.Labels,
-		Secrets: node.resolver.externalData.SecretLoader.LoadSecretsByUserName(user.Name),
+		Secrets: node.loadUserSecrets(user.Name),
+	}
+}
+
+// How secrets of the service, its namespace and the cluster are visible from the policy language. Every scope gets
+// loaded only when template refers to it
+func (node *resolutionNode) proxySecrets(service *lang.Service, cluster string) interface{} {
+	return &secretsProxy{node: node, service: service, cluster: cluster}
+}
+
+// secretsProxy exposes scoped secrets to templates via methods, so they get loaded lazily
+type secretsProxy struct {
+	node    *resolutionNode
+	service *lang.Service
+	cluster string
+}
+
+// Namespace returns secrets shared by all services in the namespace of the service
+func (proxy *secretsProxy) Namespace() map[string]string {
+	return proxy.node.loadScopedSecrets(secrets.NamespaceScope(proxy.service.Namespace))
+}
+
+// Service returns secrets of the service
+func (proxy *secretsProxy) Service() map[string]string {
+	return proxy.node.loadScopedSecrets(secrets.ServiceScope(proxy.service.Namespace, proxy.service.Name))
+}
+
+// Cluster returns secrets of the cluster, which code gets deployed to
+func (proxy *secretsProxy) Cluster() map[string]string {
+	return proxy.node.loadScopedSecrets(secrets.ClusterScope(proxy.cluster))
+}
+
+// Loads secrets of a given user and exposes them to the policy
+func (node *resolutionNode) loadUserSecrets(userName string) map[string]string {
+	node.inputRead(inputUserSecrets, userName, secrets.Scope{})
+	return node.loadSecrets(node.resolver.externalData.SecretLoader.LoadSecretsByUserName(userName))
+}
+
+// Loads secrets of a given scope and exposes them to the policy. Secrets get loaded once per node
+func (node *resolutionNode) loadScopedSecrets(scope secrets.Scope) map[string]string {
+	if scope.IsEmpty() {
+		return make(map[string]string)
+	}
+	if result, ok := node.scopedSecrets[scope]; ok {
+		return result
+	}
+	node.inputRead(inputSecrets, "", scope)
+	result := node.loadSecrets(node.resolver.externalData.SecretLoader.LoadSecretsByScope(scope))
+	if node.scopedSecrets == nil {
+		node.scopedSecrets = make(map[secrets.Scope]map[string]string)
+	}
+	node.scopedSecrets[scope] = result
+	return result
+}
+
+// Registers secret values exposed to the policy, so they get masked in logs and API responses
+func (node *resolutionNode) loadSecrets(values map[string]string) map[string]string {
+	node.resolver.resolution.secretMasker.AddSecrets(values)
+	if node.inputs != nil {
+		for k, v := range values {
+			node.inputs.secretValues[k] = v
+		}
 	}
+	return values
 }
 
 // How dependency is visible from the policy language
@@ -129,7 +224,7 @@ func (node *resolutionNode) proxyDependency(dependency *lang.Dependency) interfa
 func (node *resolutionNode) proxyCluster(name string) interface{} {
 	// make cluster available
 	clusterName := node.labels.Labels[lang.LabelCluster]
-	clusterObj, err := node.resolver.policy.GetObject(lang.ClusterObject.Kind, clusterName, runtime.SystemNS)
+	clusterObj, err := node.getObject(lang.ClusterObject.Kind, clusterName, runtime.SystemNS)
 	if err != nil || clusterObj == nil {
 		panic(fmt.Sprintf("cluster not set for component instance '%s'", node.componentKey))
 	}
@@ -3,9 +3,12 @@ package resolve
 import (
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/errors"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/davecgh/go-spew/spew"
+	"sort"
+	"strings"
 )
 
 /*
@@ -40,6 +43,14 @@ func (node *resolutionNode) errorWhenTestingContext(context *lang.Context, cause
 	return fmt.Errorf("error while trying to match context '%s' for contract '%s': %s", context.Name, node.contract.Name, node.printCauseDetailsOnDebug(cause))
 }
 
+func (node *resolutionNode) errorContractVersionDoesNotExist() error {
+	return fmt.Errorf("version '%s' doesn't exist in contract '%s'", node.contractVersionName, node.contract.Name)
+}
+
+func (node *resolutionNode) errorWhenResolvingParams(cause error) error {
+	return fmt.Errorf("error while resolving params for contract '%s': %s", node.contract.Name, cause)
+}
+
 func (node *resolutionNode) errorContextNotMatched() error {
 	return fmt.Errorf("unable to find matching context within contract: '%s'", node.contract.Name)
 }
@@ -64,6 +75,18 @@ func (node *resolutionNode) errorWhenProcessingDiscoveryParams(cause error) erro
 	return fmt.Errorf("error when processing discovery params for service '%s', contract '%s', context '%s', component '%s': %s", node.service.Name, node.contract.Name, node.context.Name, node.component.Name, node.printCauseDetailsOnDebug(cause))
 }
 
+func (node *resolutionNode) errorWhenProcessingOutput(output *lang.ServiceOutput, cause error) error {
+	return fmt.Errorf("error when processing output '%s' for service '%s', contract '%s', context '%s': %s", output.Name, node.service.Name, node.contract.Name, node.context.Name, node.printCauseDetailsOnDebug(cause))
+}
+
+func (node *resolutionNode) errorWhenTestingQuota(quota *lang.Quota, cause error) error {
+	return fmt.Errorf("error while checking if quota '%s' applies to dependency '%s/%s': %s", runtime.KeyForStorable(quota), node.dependency.Namespace, node.dependency.Name, node.printCauseDetailsOnDebug(cause))
+}
+
+func (node *resolutionNode) errorQuotaExceeded(quota *lang.Quota, cause error) error {
+	return fmt.Errorf("dependency '%s/%s' exceeds quota '%s': %s", node.dependency.Namespace, node.dependency.Name, runtime.KeyForStorable(quota), cause)
+}
+
 func (node *resolutionNode) errorServiceCycleDetected() error {
 	return fmt.Errorf("error when processing policy, service cycle detected: %s", node.path)
 }
@@ -96,13 +119,35 @@ func (node *resolutionNode) logContractFound(contract *lang.Contract) {
 	node.eventLog.NewEntry().Debugf("Contract found in policy: '%s'", contract.Name)
 }
 
+func (node *resolutionNode) logContractVersionFound(version *lang.ContractVersion) {
+	node.eventLog.NewEntry().Debugf("Using version '%s' of contract '%s'", version.Name, node.contract.Name)
+}
+
+func (node *resolutionNode) logParamsResolved() {
+	node.eventLog.NewEntry().Debugf("Resolved params for contract '%s': %v", node.contract.Name, node.params)
+}
+
+func (node *resolutionNode) logOutputsCalculated(outputs []*DependencyOutput) {
+	for _, output := range outputs {
+		value := output.Value
+		if output.Sensitive {
+			value = secrets.MaskedValue
+		}
+		node.eventLog.NewEntry().Debugf("Output '%s' of service '%s' for dependency '%s/%s': %s", output.Name, node.service.Name, node.dependency.Namespace, node.dependency.Name, value)
+	}
+}
+
+func (node *resolutionNode) logContractVersionDeprecated(version *lang.ContractVersion) {
+	node.eventLog.NewEntry().Warningf("Dependency '%s/%s' uses deprecated version '%s' of contract '%s': %s", node.dependency.Namespace, node.dependency.Name, version.Name, node.contract.Name, version.Deprecated)
+}
+
 func (node *resolutionNode) logServiceFound(service *lang.Service) {
 	node.eventLog.NewEntry().Debugf("Service found in policy: '%s'", service.Name)
 }
 
 func (node *resolutionNode) logStartMatchingContexts() {
 	contextNames := []string{}
-	for _, context := range node.contract.Contexts {
+	for _, context := range node.contractVersion.Contexts {
 		contextNames = append(contextNames, context.Name)
 	}
 	node.eventLog.NewEntry().Infof("Picking context within contract '%s'. Trying contexts: %s", node.contract.Name, contextNames)
@@ -116,7 +161,11 @@ func (node *resolutionNode) logComponentNotMatched(component *lang.ServiceCompon
 	node.eventLog.NewEntry().Infof("Component criteria evaluated to 'false', excluding it from processing: service '%s', component '%s'", node.service.Name, node.component.Name)
 }
 
-func (node *resolutionNode) logTestedContextCriteria(context *lang.Context, matched bool) {
+func (node *resolutionNode) logTestedContextCriteria(context *lang.Context, matched bool, branch string) {
+	if matched && len(branch) > 0 {
+		node.eventLog.NewEntry().Debugf("Trying context '%s' within contract '%s'. Matched = %t (branch: %s)", context.Name, node.contract.Name, matched, branch)
+		return
+	}
 	node.eventLog.NewEntry().Debugf("Trying context '%s' within contract '%s'. Matched = %t", context.Name, node.contract.Name, matched)
 }
 
@@ -124,10 +173,18 @@ func (node *resolutionNode) logRulesProcessingResult(policyNamespace *lang.Polic
 	node.eventLog.NewEntry().Debugf("Rules processed within namespace '%s' for context '%s' within contract '%s'", policyNamespace.Name, node.context.Name, node.contract.Name)
 }
 
-func (node *resolutionNode) logTestedRuleMatch(rule *lang.Rule, match bool) {
+func (node *resolutionNode) logTestedRuleMatch(rule *lang.Rule, match bool, branch string) {
+	if match && len(branch) > 0 {
+		node.eventLog.NewEntry().Debugf("Testing if rule '%s' applies in context '%s' within contract '%s'. Result: %t (branch: %s)", rule.Name, node.context.Name, node.contract.Name, match, branch)
+		return
+	}
 	node.eventLog.NewEntry().Debugf("Testing if rule '%s' applies in context '%s' within contract '%s'. Result: %t", rule.Name, node.context.Name, node.contract.Name, match)
 }
 
+func (node *resolutionNode) logTestedQuotaMatch(quota *lang.Quota, match bool) {
+	node.eventLog.NewEntry().Debugf("Testing if quota '%s' applies to dependency '%s/%s'. Result: %t", runtime.KeyForStorable(quota), node.dependency.Namespace, node.dependency.Name, match)
+}
+
 func (node *resolutionNode) logAllocationKeysSuccessfullyResolved(resolvedKeys []string) {
 	if len(resolvedKeys) > 0 {
 		node.eventLog.NewEntry().Infof("Allocation keys successfully resolved for context '%s' within contract '%s': %s", node.context.Name, node.contract.Name, resolvedKeys)
@@ -167,6 +224,36 @@ func (node *resolutionNode) logCannotResolveInstance() {
 	}
 }
 
+func (node *resolutionNode) logStickyContextKept(context *lang.Context) {
+	if node.context == nil {
+		node.wouldMoveTo = append(node.wouldMoveTo, "no context matches")
+		node.eventLog.NewEntry().Infof("Keeping context '%s' within contract '%s', because service '%s' is sticky", context.Name, node.contract.Name, node.stickyKey.ServiceName)
+		return
+	}
+	node.wouldMoveTo = append(node.wouldMoveTo, fmt.Sprintf("to context '%s'", node.context.Name))
+	node.eventLog.NewEntry().Infof("Keeping context '%s' instead of '%s' within contract '%s', because service '%s' is sticky", context.Name, node.context.Name, node.contract.Name, node.stickyKey.ServiceName)
+}
+
+func (node *resolutionNode) logStickyAllocationKeysKept(keys []string) {
+	node.wouldMoveTo = append(node.wouldMoveTo, fmt.Sprintf("to allocation keys %s", node.allocationKeysResolved))
+	node.eventLog.NewEntry().Infof("Keeping allocation keys %s instead of %s for context '%s' within contract '%s', because service '%s' is sticky", keys, node.allocationKeysResolved, node.context.Name, node.contract.Name, node.stickyKey.ServiceName)
+}
+
+func (node *resolutionNode) logStickyClusterKept(clusterName string) {
+	node.wouldMoveTo = append(node.wouldMoveTo, fmt.Sprintf("to cluster '%s'", node.labels.Labels[lang.LabelCluster]))
+	node.eventLog.NewEntry().Infof("Keeping cluster '%s' instead of '%s' within contract '%s', because service '%s' is sticky", clusterName, node.labels.Labels[lang.LabelCluster], node.contract.Name, node.stickyKey.ServiceName)
+}
+
+func (node *resolutionNode) logStickyPlacementKept() string {
+	message := fmt.Sprintf("Dependency '%s/%s' would move instance '%s' (%s), but it was kept in place because service '%s' is sticky. Use 'aptomictl dependency migrate' to move it", node.dependency.Namespace, node.dependency.Name, node.stickyKey.GetKey(), strings.Join(node.wouldMoveTo, ", "), node.stickyKey.ServiceName)
+	node.eventLog.NewEntry().Warning(message)
+	return message
+}
+
+func (node *resolutionNode) logStickyPlacementNotKept() {
+	node.eventLog.NewEntry().Warningf("Dependency '%s/%s' can't keep instance '%s' of sticky service '%s' in place, it moves to '%s'", node.dependency.Namespace, node.dependency.Name, node.stickyKey.GetKey(), node.stickyKey.ServiceName, node.serviceKey.GetKey())
+}
+
 func (resolver *PolicyResolver) logComponentCodeParams(instance *ComponentInstance) {
 	serviceObj, err := resolver.policy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
 	if err != nil {
@@ -175,7 +262,8 @@ func (resolver *PolicyResolver) logComponentCodeParams(instance *ComponentInstan
 	code := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName].Code
 	if code != nil {
 		cs := spew.ConfigState{Indent: "\t"}
-		resolver.eventLog.NewEntry().Debugf("Calculated final code params for component '%s': %s", instance.Metadata.Key.GetKey(), cs.Sdump(instance.CalculatedCodeParams))
+		codeParams := resolver.resolution.secretMasker.MaskParams(instance.GetKey(), instance.CalculatedCodeParams)
+		resolver.eventLog.NewEntry().Debugf("Calculated final code params for component '%s': %s", instance.Metadata.Key.GetKey(), cs.Sdump(codeParams))
 	}
 }
 
@@ -191,12 +279,55 @@ func (resolver *PolicyResolver) logComponentDiscoveryParams(instance *ComponentI
 	}
 }
 
+func (resolver *PolicyResolver) logMissingParameters() {
+	for _, missingErr := range resolver.expressionCache.GetMissingParameters() {
+		resolver.eventLog.NewEntry().Warningf("%s (used in %s)", missingErr, strings.Join(resolver.findExpressionLocations(missingErr.Expression), ", "))
+	}
+}
+
+// Returns locations of policy objects, which use the given expression in their criteria
+func (resolver *PolicyResolver) findExpressionLocations(expressionStr string) []string {
+	result := []string{}
+	for _, kind := range []string{lang.RuleObject.Kind, lang.ACLRuleObject.Kind} {
+		for _, obj := range resolver.policy.GetObjectsByKind(kind) {
+			rule := obj.(*lang.Rule)
+			if rule.Criteria.Contains(expressionStr) {
+				result = append(result, fmt.Sprintf("%s '%s'", kind, runtime.KeyForStorable(rule)))
+			}
+		}
+	}
+	for _, obj := range resolver.policy.GetObjectsByKind(lang.ContractObject.Kind) {
+		contract := obj.(*lang.Contract)
+		for _, context := range contract.GetAllContexts() {
+			if context.Criteria.Contains(expressionStr) {
+				result = append(result, fmt.Sprintf("contract '%s', context '%s'", runtime.KeyForStorable(contract), context.Name))
+			}
+		}
+	}
+	for _, obj := range resolver.policy.GetObjectsByKind(lang.ServiceObject.Kind) {
+		service := obj.(*lang.Service)
+		for _, component := range service.Components {
+			if component.Criteria.Contains(expressionStr) {
+				result = append(result, fmt.Sprintf("service '%s', component '%s'", runtime.KeyForStorable(service), component.Name))
+			}
+		}
+	}
+	for _, obj := range resolver.policy.GetObjectsByKind(lang.QuotaObject.Kind) {
+		quota := obj.(*lang.Quota)
+		if quota.Criteria.Contains(expressionStr) {
+			result = append(result, fmt.Sprintf("quota '%s'", runtime.KeyForStorable(quota)))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
 // if the given argument is ErrorWithDetails, it logs its details on debug mode
 func (node *resolutionNode) printCauseDetailsOnDebug(err error) error {
 	errWithDetails, isErrorWithDetails := err.(*errors.ErrorWithDetails)
 	if isErrorWithDetails {
 		cs := spew.ConfigState{Indent: "\t"}
-		node.eventLog.NewEntry().Debug(cs.Sdump(errWithDetails.Details()))
+		node.eventLog.NewEntry().Debug(node.resolver.resolution.secretMasker.MaskString(cs.Sdump(errWithDetails.Details())))
 	}
 	return err
 }
@@ -0,0 +1,35 @@
+package resolve
+
+import (
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"regexp"
+)
+
+// Options is a set of server-wide settings, which define how policy gets resolved. Zero value means default settings
+type Options struct {
+	// SensitiveKeyPatterns is a list of patterns for keys of code params, values of which are considered sensitive
+	// and get masked in logs and API responses. If nil, default patterns will be used
+	SensitiveKeyPatterns []*regexp.Regexp
+
+	// MissingParamsMode defines what happens when expression refers to a parameter which doesn't exist (e.g. a
+	// label with a typo in its name). Empty value means that missing parameters are silently ignored
+	MissingParamsMode expression.MissingParamsMode
+
+	// RandomKey is a secret key, which gets mixed into values produced by random functions in templates (e.g.
+	// generated passwords), so they can't be guessed from the component key alone
+	RandomKey string
+}
+
+// SetOptions applies server-wide settings to the resolver. It should be called before resolving the policy
+func (resolver *PolicyResolver) SetOptions(options *Options) *PolicyResolver {
+	if options == nil {
+		return resolver
+	}
+	resolver.resolution.secretMasker = secrets.NewMasker(options.SensitiveKeyPatterns)
+	if len(options.MissingParamsMode) > 0 {
+		resolver.expressionCache = expression.NewCacheWithMode(options.MissingParamsMode)
+	}
+	resolver.randomKey = options.RandomKey
+	return resolver
+}
@@ -0,0 +1,159 @@
+package resolve
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"strings"
+)
+
+// stickyPlacement holds placement of services from the actual state, so that dependencies on sticky services can keep
+// their context, allocation keys and cluster when rules or context criteria change
+type stickyPlacement struct {
+	// map from dependency key -> contract key ('namespace/contractName') -> service instance key in actual state.
+	// if dependency has been placed into more than one service instance of the same contract, then it is nil
+	placement map[string]map[string]*ComponentInstanceKey
+
+	// set of dependency keys, which have been requested to migrate to a new placement
+	migrate map[string]bool
+}
+
+// SetStickyPlacement makes the resolver keep placement of existing dependencies on sticky services, as recorded in
+// the given actual state. Dependencies with keys from 'migrate' are resolved from scratch and allowed to move
+func (resolver *PolicyResolver) SetStickyPlacement(actualState *PolicyResolution, migrate map[string]bool) *PolicyResolver {
+	sticky := &stickyPlacement{
+		placement: make(map[string]map[string]*ComponentInstanceKey),
+		migrate:   migrate,
+	}
+	for _, instance := range actualState.ComponentInstanceMap {
+		key := instance.Metadata.Key
+		if !key.IsService() {
+			continue
+		}
+		contractKey := runtime.KeyFromParts(key.Namespace, lang.ContractObject.Kind, key.ContractName)
+		for dKey := range instance.DependencyKeys {
+			if sticky.placement[dKey] == nil {
+				sticky.placement[dKey] = make(map[string]*ComponentInstanceKey)
+			}
+			if _, exists := sticky.placement[dKey][contractKey]; exists {
+				// ambiguous placement, don't pin it
+				sticky.placement[dKey][contractKey] = nil
+				continue
+			}
+			sticky.placement[dKey][contractKey] = key
+		}
+	}
+	resolver.sticky = sticky
+	return resolver
+}
+
+// Returns previous placement of the service instance which the node is resolving, if the dependency needs to keep it.
+// Returns nil if placement is unknown, ambiguous, the service is not sticky, or the dependency is being migrated
+func (node *resolutionNode) getStickyKey() *ComponentInstanceKey {
+	dKey := runtime.KeyForStorable(node.dependency)
+	contractKey := runtime.KeyForStorable(node.contract)
+	if node.inputs != nil {
+		node.inputs.sticky[contractKey] = node.resolver.stickyPlacement(dKey, contractKey)
+	}
+
+	sticky := node.resolver.sticky
+	if sticky == nil {
+		return nil
+	}
+	if sticky.migrate[dKey] {
+		return nil
+	}
+	key := sticky.placement[dKey][contractKey]
+	if key == nil {
+		return nil
+	}
+
+	// placement is kept only if the service, which was previously placed, is still sticky
+	serviceObj, err := node.getObject(lang.ServiceObject.Kind, key.ServiceName, key.Namespace)
+	if err != nil || serviceObj == nil || !serviceObj.(*lang.Service).Sticky {
+		return nil
+	}
+	return key
+}
+
+// Returns previous placement of the service instance for a given dependency and contract, as a string which can be
+// compared between runs of the resolver
+func (resolver *PolicyResolver) stickyPlacement(dKey string, contractKey string) string {
+	sticky := resolver.sticky
+	if sticky == nil {
+		return ""
+	}
+	if sticky.migrate[dKey] {
+		return "migrate"
+	}
+	key, exists := sticky.placement[dKey][contractKey]
+	if !exists {
+		return ""
+	}
+	if key == nil {
+		return "ambiguous"
+	}
+	return key.GetKey()
+}
+
+// Helper to keep the context from the previous placement of a sticky service (if it still exists in the contract).
+// Returns true if the node ended up with the context of the previous placement
+func (node *resolutionNode) keepStickyContext() bool {
+	if node.stickyKey == nil || !node.stickyKey.isContractVersion(node.contract, node.contractVersion) {
+		return false
+	}
+	if node.context != nil && node.context.Name == node.stickyKey.ContextName {
+		return true
+	}
+	for _, context := range node.contractVersion.Contexts {
+		if context.Name == node.stickyKey.ContextName {
+			node.logStickyContextKept(context)
+			node.context = context
+			return true
+		}
+	}
+	return false
+}
+
+// Helper to keep the allocation keys from the previous placement of a sticky service
+func (node *resolutionNode) keepStickyAllocationKeys() {
+	if node.stickyKey == nil || !node.stickyKey.isContractVersion(node.contract, node.contractVersion) || node.context.Name != node.stickyKey.ContextName {
+		return
+	}
+	if strings.Join(node.allocationKeysResolved, componentInstanceKeySeparator) == node.stickyKey.KeysResolved {
+		return
+	}
+	keys := []string{}
+	if len(node.stickyKey.KeysResolved) > 0 {
+		keys = strings.Split(node.stickyKey.KeysResolved, componentInstanceKeySeparator)
+	}
+	node.logStickyAllocationKeysKept(keys)
+	node.allocationKeysResolved = keys
+}
+
+// Helper to keep the cluster from the previous placement of a sticky service (if the cluster still exists)
+func (node *resolutionNode) keepStickyCluster() {
+	if node.stickyKey == nil || node.labels.Labels[lang.LabelCluster] == node.stickyKey.ClusterName {
+		return
+	}
+	clusterObj, err := node.getObject(lang.ClusterObject.Kind, node.stickyKey.ClusterName, runtime.SystemNS)
+	if err != nil || clusterObj == nil {
+		return
+	}
+	node.logStickyClusterKept(node.stickyKey.ClusterName)
+	node.labels.Labels[lang.LabelCluster] = node.stickyKey.ClusterName
+}
+
+// Helper to check whether the service instance stayed in its previous placement. If it moved, it means that
+// placement couldn't be kept (e.g. context, service or cluster has been removed from the policy)
+func (node *resolutionNode) checkStickyPlacement() {
+	if node.stickyKey == nil {
+		return
+	}
+	if node.serviceKey.GetKey() != node.stickyKey.GetKey() {
+		node.logStickyPlacementNotKept()
+		return
+	}
+	if len(node.wouldMoveTo) > 0 {
+		node.wouldMove = append(node.wouldMove, node.logStickyPlacementKept())
+	}
+}
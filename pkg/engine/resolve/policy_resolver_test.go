@@ -3,8 +3,10 @@ package resolve
 import (
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Sirupsen/logrus"
@@ -51,6 +53,205 @@ func TestPolicyResolverSimple(t *testing.T) {
 	assert.Equal(t, 1, len(instance2.DependencyKeys), "Instance should be referenced by one dependency")
 }
 
+func TestPolicyResolverNestedCriteria(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a context, which has nested criteria
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContract(service, &lang.Criteria{
+		RequireAnyOf: []*lang.Criteria{
+			{RequireAll: []string{"team == 'a'", "zone == 'us'"}},
+			{RequireAll: []string{"team == 'b'"}, RequireAny: []string{"zone == 'eu'", "zone == 'asia'"}},
+		},
+	})
+
+	// add rule to set cluster
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add dependency (should be resolved via the second group)
+	d1 := b.AddDependency(b.AddUser(), contract)
+	d1.Labels["team"] = "b"
+	d1.Labels["zone"] = "asia"
+
+	// policy resolution should be completed successfully and matched branch should be logged
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Matched = true (branch: require-any-of[1] > require-any[1] 'zone == 'asia'')")
+	assert.True(t, resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(d1)].Resolved, "Dependency should be successfully resolved")
+}
+
+func TestPolicyResolverContractVersions(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a contract, which has two versions (first one is deprecated)
+	service := b.AddService()
+	component := b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContractMultipleContexts(service, b.CriteriaTrue(), b.CriteriaTrue())
+	contract.Versions = []*lang.ContractVersion{
+		{Name: "v1", Deprecated: "please use v2", Contexts: contract.Contexts[:1]},
+		{Name: "v2", Contexts: contract.Contexts[1:]},
+	}
+	contract.Contexts = nil
+
+	// add rule to set cluster
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add dependency pinned to the old version (should be resolved via v1 context)
+	d1 := b.AddDependency(b.AddUser(), contract)
+	d1.Version = "v1"
+
+	// add dependency without version (should be resolved via the latest version)
+	d2 := b.AddDependency(b.AddUser(), contract)
+
+	// policy resolution should be completed successfully and usage of deprecated version should be logged
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "uses deprecated version 'v1'")
+
+	r1 := resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(d1)]
+	assert.Equal(t, "v1", r1.ContractVersion, "Dependency should be resolved with pinned contract version")
+	assert.Equal(t, "please use v2", r1.Deprecated, "Dependency should be marked as using deprecated version")
+	instance1 := getInstanceByParams(t, cluster, contract, contract.Versions[0].Contexts[0], nil, service, component, resolution)
+	assert.Equal(t, 1, len(instance1.DependencyKeys), "Instance of v1 should be referenced by one dependency")
+
+	r2 := resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(d2)]
+	assert.Equal(t, "v2", r2.ContractVersion, "Dependency should be resolved with the latest contract version")
+	assert.Empty(t, r2.Deprecated, "Dependency should not be marked as using deprecated version")
+	instance2 := getInstanceByParams(t, cluster, contract, contract.Versions[1].Contexts[0], nil, service, component, resolution)
+	assert.Equal(t, 1, len(instance2.DependencyKeys), "Instance of v2 should be referenced by one dependency")
+}
+
+func TestPolicyResolverContractVersionsSameContextName(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a contract with two versions, which have contexts with the same name
+	service := b.AddService()
+	component := b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContractMultipleContexts(service, b.CriteriaTrue(), b.CriteriaTrue())
+	contract.Contexts[1].Name = contract.Contexts[0].Name
+	contract.Versions = []*lang.ContractVersion{
+		{Name: "v1", Contexts: contract.Contexts[:1]},
+		{Name: "v2", Contexts: contract.Contexts[1:]},
+	}
+	contract.Contexts = nil
+
+	// add rule to set cluster
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add dependencies on both versions
+	d1 := b.AddDependency(b.AddUser(), contract)
+	d1.Version = "v1"
+	d2 := b.AddDependency(b.AddUser(), contract)
+	d2.Version = "v2"
+
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+
+	// dependencies should not share the same instance, even though context names are the same
+	instance1 := getInstanceByParams(t, cluster, contract, contract.Versions[0].Contexts[0], nil, service, component, resolution)
+	instance2 := getInstanceByParams(t, cluster, contract, contract.Versions[1].Contexts[0], nil, service, component, resolution)
+	assert.NotEqual(t, instance1.GetKey(), instance2.GetKey(), "Instances of different contract versions should have different keys")
+	assert.Equal(t, 1, len(instance1.DependencyKeys), "Instance of v1 should be referenced by one dependency")
+	assert.Equal(t, 1, len(instance2.DependencyKeys), "Instance of v2 should be referenced by one dependency")
+	assert.Equal(t, "v1", instance1.Metadata.Key.ContractVersion, "Contract version should be recorded in the instance key")
+
+	// resolution should be valid against the policy
+	policy := b.Policy()
+	assert.NoError(t, resolution.Validate(policy), "Resolution should be valid")
+
+	// removing the first version should not be allowed while its instance is in use, even though the context with
+	// the same name still exists in the second version
+	contract.Versions = contract.Versions[1:]
+	assert.Error(t, resolution.Validate(policy), "Removing contract version, which is in use, should not be allowed")
+}
+
+func TestPolicyResolverContractVersionsUpgrade(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a sticky service and a contract without versions, which has two contexts
+	service := b.AddService()
+	service.Sticky = true
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContractMultipleContexts(service,
+		b.Criteria("label1 == 'value1'", "true", "false"),
+		b.Criteria("label1 == 'value2'", "true", "false"),
+	)
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add dependency, which gets placed into the first context
+	d := b.AddDependency(b.AddUser(), contract)
+	d.Labels["label1"] = "value1"
+	actualState := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+	instanceKey := getInstanceByDependencyKey(t, runtime.KeyForStorable(d), actualState).GetKey()
+
+	// convert contract to versions, so that existing contexts become the first version
+	contract.Versions = []*lang.ContractVersion{{Name: "v1", Contexts: contract.Contexts}}
+	contract.Contexts = nil
+	assert.NoError(t, actualState.Validate(b.Policy()), "Deployed instances should stay valid once contract gets converted to versions")
+
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+	assert.Equal(t, instanceKey, getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution).GetKey(), "Instance should keep its key once contract gets converted to versions")
+
+	// sticky placement from the time contract had no versions should still be kept
+	d.Labels["label1"] = "value2"
+	eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
+	resolution = NewPolicyResolver(b.Policy(), b.External(), eventLog).SetStickyPlacement(actualState, nil).ResolveAllDependencies()
+	assert.Equal(t, instanceKey, getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution).GetKey(), "Sticky service instance should stay in place once contract gets converted to versions")
+
+	// add the second version with the same contexts. dependency pinned to the first version should keep its instance
+	v2Contexts := []*lang.Context{}
+	for _, context := range contract.Versions[0].Contexts {
+		v2Context := *context
+		v2Contexts = append(v2Contexts, &v2Context)
+	}
+	contract.Versions = append(contract.Versions, &lang.ContractVersion{Name: "v2", Contexts: v2Contexts})
+	d.Labels["label1"] = "value1"
+	d.Version = "v1"
+	resolution = resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+	assert.Equal(t, instanceKey, getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution).GetKey(), "Instance of the first version should keep its key once the second version gets added")
+
+	// once dependency moves to the second version, it should get a new instance
+	d.Version = ""
+	resolution = resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+	assert.NotEqual(t, instanceKey, getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution).GetKey(), "Instance of the second version should have a different key")
+}
+
+func TestPolicyResolverContractParams(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a contract, which picks context based on the supplied params
+	service := b.AddService()
+	component := b.AddServiceComponent(service, b.CodeComponent(util.NestedParameterMap{"replicas": "{{ .Params.replicas }}"}, nil))
+	contract := b.AddContractMultipleContexts(service,
+		&lang.Criteria{RequireAll: []string{"Params.size == 'large'"}},
+		b.CriteriaTrue(),
+	)
+	contract.Params = []*lang.ContractParam{
+		{Name: "size", Default: "small", Allowed: []interface{}{"small", "large"}},
+		{Name: "replicas", Type: lang.ParamTypeInt, Default: 1},
+	}
+
+	// add rule to set cluster
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add dependency with params (should be resolved to the first context)
+	d1 := b.AddDependency(b.AddUser(), contract)
+	d1.Params = map[string]interface{}{"size": "large", "replicas": 3}
+
+	// add dependency without params (should be resolved to the second context with default params)
+	b.AddDependency(b.AddUser(), contract)
+
+	// policy resolution should be completed successfully
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+
+	// check that params are available in criteria and templates
+	instance1 := getInstanceByParams(t, cluster, contract, contract.Contexts[0], nil, service, component, resolution)
+	assert.Equal(t, util.NestedParameterMap{"replicas": "3"}, instance1.CalculatedCodeParams, "Code params should be calculated using supplied params")
+	instance2 := getInstanceByParams(t, cluster, contract, contract.Contexts[1], nil, service, component, resolution)
+	assert.Equal(t, util.NestedParameterMap{"replicas": "1"}, instance2.CalculatedCodeParams, "Code params should be calculated using default params")
+}
+
 func TestPolicyResolverComponentWithCriteria(t *testing.T) {
 	b := builder.NewPolicyBuilder()
 
@@ -274,6 +475,320 @@ func TestPolicyResolverCodeAndDiscoveryParams(t *testing.T) {
 	assert.Equal(t, 5, instance2.CalculatedCodeParams.GetNestedMap("nested").GetNestedMap("param")["nameInt"], "Code parameter should be calculated correctly (int)")
 }
 
+func TestPolicyResolverScopedSecrets(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a component, which takes secrets in its code params
+	service := b.AddService()
+	component := b.CodeComponent(
+		util.NestedParameterMap{
+			"adminPassword": "{{ .Secrets.Service.adminPassword }}",
+			"url":           "mysql://root:{{ .Secrets.Service.adminPassword }}@db",
+			"token":         "{{ .Secrets.Namespace.monitoringToken }}",
+			"registry":      "{{ .Secrets.Cluster.registryPassword }}",
+		},
+		nil,
+	)
+	b.AddServiceComponent(service, component)
+
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+	b.AddDependency(b.AddUser(), contract)
+
+	b.AddScopedSecret(secrets.ServiceScope(service.Namespace, service.Name), "adminPassword", "mysqladminpassword")
+	b.AddScopedSecret(secrets.NamespaceScope(service.Namespace), "monitoringToken", "maintoken")
+	b.AddScopedSecret(secrets.ClusterScope(cluster.Name), "registryPassword", "registrypassword")
+
+	// policy should be resolved successfully
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+
+	// code params should have actual secret values
+	instance := getInstanceByParams(t, cluster, contract, contract.Contexts[0], nil, service, component, resolution)
+	assert.Equal(t, "mysqladminpassword", instance.CalculatedCodeParams["adminPassword"], "Service secret should be available in code params")
+	assert.Equal(t, "maintoken", instance.CalculatedCodeParams["token"], "Namespace secret should be available in code params")
+	assert.Equal(t, "registrypassword", instance.CalculatedCodeParams["registry"], "Cluster secret should be available in code params")
+
+	// secret values should be masked
+	masked := resolution.GetSecretMasker().MaskParams(instance.GetKey(), instance.CalculatedCodeParams)
+	assert.Equal(t, secrets.MaskedValue, masked["adminPassword"], "Secret value should be masked")
+	assert.Equal(t, "mysql://root:"+secrets.MaskedValue+"@db", masked["url"], "Secret value should be masked")
+	assert.Equal(t, "mysqladminpassword", instance.CalculatedCodeParams["adminPassword"], "Masking should not modify code params")
+}
+
+func TestPolicyResolverScopedSecretsLoadedLazily(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with two components, which only refer to service secrets
+	service := b.AddService()
+	for i := 0; i < 2; i++ {
+		b.AddServiceComponent(service, b.CodeComponent(
+			util.NestedParameterMap{
+				"user":     "root",
+				"password": "{{ .Secrets.Service.adminPassword }}",
+				"url":      "mysql://root:{{ .Secrets.Service.adminPassword }}@db",
+			},
+			nil,
+		))
+	}
+
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+	b.AddDependency(b.AddUser(), contract)
+	b.AddScopedSecret(secrets.ServiceScope(service.Namespace, service.Name), "adminPassword", "mysqladminpassword")
+
+	// policy should be resolved successfully
+	resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+
+	// service secrets should be loaded once, while secrets which are not referred to should not be loaded at all
+	loader := b.External().SecretLoader.(*secrets.SecretLoaderMock)
+	assert.Equal(t, 1, loader.GetScopedLoads(secrets.ServiceScope(service.Namespace, service.Name)), "Service secrets should be loaded once per node")
+	assert.Equal(t, 0, loader.GetScopedLoads(secrets.NamespaceScope(service.Namespace)), "Namespace secrets should not be loaded")
+	assert.Equal(t, 0, loader.GetScopedLoads(secrets.ClusterScope(cluster.Name)), "Cluster secrets should not be loaded")
+}
+
+func TestPolicyResolverServiceOutputs(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a component, which exposes discovery params, and outputs which are calculated from them
+	service := b.AddService()
+	component := b.CodeComponent(
+		nil,
+		util.NestedParameterMap{
+			"host":     "db-{{ .Labels.team }}",
+			"password": "pwd-{{ .Labels.team }}",
+		},
+	)
+	b.AddServiceComponent(service, component)
+	service.Outputs = []*lang.ServiceOutput{
+		{Name: "url", Value: "mysql://{{ .Discovery." + component.Name + ".host }}:3306"},
+		{Name: "password", Value: "{{ .Discovery." + component.Name + ".password }}", Sensitive: true},
+	}
+
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+	d := b.AddDependency(b.AddUser(), contract)
+	d.Labels["team"] = "platform"
+
+	// policy should be resolved successfully
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Output 'password' of service")
+
+	// outputs should be calculated for dependency
+	outputs := resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(d)].Outputs
+	if assert.Len(t, outputs, 2, "Dependency should have outputs") {
+		assert.Equal(t, &DependencyOutput{Name: "url", Value: "mysql://db-platform:3306"}, outputs[0], "Output should be calculated from discovery params")
+		assert.Equal(t, &DependencyOutput{Name: "password", Value: "pwd-platform", Sensitive: true}, outputs[1], "Sensitive output should be calculated from discovery params")
+	}
+
+	// sensitive output value should be masked
+	assert.Equal(t, secrets.MaskedValue, resolution.GetSecretMasker().MaskString("pwd-platform"), "Sensitive output value should be masked")
+	assert.Equal(t, "mysql://db-platform:3306", resolution.GetSecretMasker().MaskString("mysql://db-platform:3306"), "Non-sensitive output value should not be masked")
+}
+
+func TestPolicyResolverSensitiveCodeParams(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a component, which has sensitive code params
+	service := b.AddService()
+	component := b.CodeComponent(
+		util.NestedParameterMap{
+			"rootPassword": "qwerty123",
+			"license":      "license-{{ .Labels.cluster }}",
+			"name":         "mysql",
+		},
+		nil,
+	)
+	component.Code.Sensitive = []string{"license"}
+	b.AddServiceComponent(service, component)
+
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+	b.AddDependency(b.AddUser(), contract)
+
+	// policy should be resolved successfully
+	resolution := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+	instance := getInstanceByParams(t, cluster, contract, contract.Contexts[0], nil, service, component, resolution)
+
+	// sensitive params should be masked both by keys and by values
+	masker := resolution.GetSecretMasker()
+	masked := masker.MaskParams(instance.GetKey(), instance.CalculatedCodeParams)
+	assert.Equal(t, secrets.MaskedValue, masked["rootPassword"], "Param matching sensitive key pattern should be masked")
+	assert.Equal(t, secrets.MaskedValue, masked["license"], "Param marked as sensitive should be masked")
+	assert.Equal(t, "mysql", masked["name"], "Regular param should not be masked")
+	assert.Equal(t, "password ******", masker.MaskString("password qwerty123"), "Sensitive value should be masked in text")
+	assert.Equal(t, "license ******", masker.MaskString("license license-"+cluster.Name), "Sensitive value should be masked in text")
+}
+
+func TestPolicyResolverSensitiveCodeParamsOfAnotherState(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a component, which has sensitive code params
+	service := b.AddService()
+	component := b.CodeComponent(
+		util.NestedParameterMap{
+			"rootPassword": "qwerty123",
+			"license":      "license-old",
+		},
+		nil,
+	)
+	component.Code.Sensitive = []string{"license"}
+	b.AddServiceComponent(service, component)
+
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+	b.AddDependency(b.AddUser(), contract)
+	actualState := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+
+	// change sensitive params, so their old values are present only in the actual state
+	component.Code.Params = util.NestedParameterMap{
+		"rootPassword": "qwerty456",
+		"license":      "license-new",
+	}
+	resolver := NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.WarnLevel, "test-resolve"))
+	resolution := resolver.ResolveAllDependencies()
+	assert.True(t, resolution.AllDependenciesResolvedSuccessfully(), "All dependencies should be resolved successfully")
+
+	masker := resolution.GetSecretMasker()
+	assert.Equal(t, "password qwerty123", masker.MaskString("password qwerty123"), "Value from another state should not be known before it gets registered")
+
+	// once actual state gets registered, its sensitive values should be masked as well
+	resolver.RegisterSensitiveParams(actualState)
+	assert.Equal(t, "password ******", masker.MaskString("password qwerty123"), "Param matching sensitive key pattern should be masked")
+	assert.Equal(t, "license ******", masker.MaskString("license license-old"), "Param marked as sensitive should be masked")
+	assert.Equal(t, "password ******", masker.MaskString("password qwerty456"), "Param of the resolution itself should still be masked")
+}
+
+func TestPolicyResolverSensitiveCodeParamsScopedToInstance(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// two services have a param with the same key, but it's marked as sensitive only in one of them
+	params := util.NestedParameterMap{"license": "license-{{ .Labels.cluster }}", "apiKey": "key-{{ .Labels.cluster }}"}
+	serviceSensitive := b.AddService()
+	componentSensitive := b.CodeComponent(params, nil)
+	componentSensitive.Code.Sensitive = []string{"license"}
+	b.AddServiceComponent(serviceSensitive, componentSensitive)
+	contractSensitive := b.AddContract(serviceSensitive, b.CriteriaTrue())
+	b.AddDependency(b.AddUser(), contractSensitive)
+
+	serviceRegular := b.AddService()
+	componentRegular := b.CodeComponent(util.NestedParameterMap{"license": "gpl", "apiKey": "key"}, nil)
+	b.AddServiceComponent(serviceRegular, componentRegular)
+	contractRegular := b.AddContract(serviceRegular, b.CriteriaTrue())
+	b.AddDependency(b.AddUser(), contractRegular)
+
+	// resolve with custom key patterns
+	patterns, err := secrets.CompileSensitiveKeyPatterns([]string{"(?i)^apikey$"})
+	assert.NoError(t, err, "Key patterns should be compiled")
+	resolution := NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).
+		SetOptions(&Options{SensitiveKeyPatterns: patterns}).
+		ResolveAllDependencies()
+	assert.True(t, resolution.AllDependenciesResolvedSuccessfully(), "All dependencies should be resolved successfully")
+
+	masker := resolution.GetSecretMasker()
+	instanceSensitive := getInstanceByParams(t, cluster, contractSensitive, contractSensitive.Contexts[0], nil, serviceSensitive, componentSensitive, resolution)
+	instanceRegular := getInstanceByParams(t, cluster, contractRegular, contractRegular.Contexts[0], nil, serviceRegular, componentRegular, resolution)
+
+	masked := masker.MaskParams(instanceSensitive.GetKey(), instanceSensitive.CalculatedCodeParams)
+	assert.Equal(t, secrets.MaskedValue, masked["license"], "Param marked as sensitive should be masked")
+	assert.Equal(t, secrets.MaskedValue, masked["apiKey"], "Param matching custom key pattern should be masked")
+
+	masked = masker.MaskParams(instanceRegular.GetKey(), instanceRegular.CalculatedCodeParams)
+	assert.Equal(t, "gpl", masked["license"], "Param marked as sensitive in another service should not be masked")
+	assert.Equal(t, secrets.MaskedValue, masked["apiKey"], "Param matching custom key pattern should be masked")
+	assert.Equal(t, "license gpl", masker.MaskString("license gpl"), "Regular value should not be masked in text")
+}
+
+func TestPolicyResolverTemplateFunctions(t *testing.T) {
+	makePolicy := func() *builder.PolicyBuilder {
+		b := builder.NewPolicyBuilder()
+		service := b.AddService()
+		b.AddServiceComponent(service,
+			b.CodeComponent(
+				util.NestedParameterMap{
+					"name":     "{{ .Labels.cluster | upper | b64enc }}",
+					"password": "{{ randAlphaNum 12 }}",
+				},
+				nil,
+			),
+		)
+		contract := b.AddContract(service, b.CriteriaTrue())
+		cluster := b.AddCluster()
+		b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+		b.AddDependency(b.AddUser(), contract)
+		return b
+	}
+
+	// random values in code params should be the same every time policy gets resolved
+	passwords := []interface{}{}
+	for i := 0; i < 2; i++ {
+		resolution := resolvePolicy(t, makePolicy(), ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+		for _, instance := range resolution.ComponentInstanceMap {
+			if instance.Metadata.Key.IsComponent() {
+				assert.Len(t, instance.CalculatedCodeParams["password"], 12, "Random password should be generated")
+				passwords = append(passwords, instance.CalculatedCodeParams["password"])
+			}
+		}
+	}
+	if assert.Len(t, passwords, 2, "Component instance should be resolved twice") {
+		assert.Equal(t, passwords[0], passwords[1], "Random password should be stable")
+	}
+
+	// random values should depend on the secret key of the resolver
+	resolution := resolvePolicyWithOptions(t, makePolicy(), &Options{RandomKey: "secret"}, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+	for _, instance := range resolution.ComponentInstanceMap {
+		if instance.Metadata.Key.IsComponent() {
+			assert.NotEqual(t, passwords[0], instance.CalculatedCodeParams["password"], "Random password should depend on the key")
+		}
+	}
+}
+
+func TestPolicyResolverMissingParams(t *testing.T) {
+	makePolicy := func() (*builder.PolicyBuilder, *lang.Rule) {
+		b := builder.NewPolicyBuilder()
+		service := b.AddService()
+		b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+		contract := b.AddContract(service, b.CriteriaTrue())
+		cluster := b.AddCluster()
+		b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+		// rule with a typo in the label name
+		rule := b.AddRule(&lang.Criteria{RequireAll: []string{"tem == 'dev'"}}, b.RuleActions(lang.NewLabelOperationsSetSingleLabel("team", "dev")))
+		b.AddDependency(b.AddUser(), contract)
+		return b, rule
+	}
+
+	// by default, missing parameters are silently ignored
+	b, _ := makePolicy()
+	resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+
+	// in warn mode, policy gets resolved, but a warning with the expression and its location should be logged
+	b, rule := makePolicy()
+	resolvePolicyWithOptions(t, b, &Options{MissingParamsMode: expression.MissingParamsWarn}, ResAllDependenciesResolvedSuccessfully, fmt.Sprintf("expression 'tem == 'dev'' refers to a missing parameter: No parameter/field/method with name 'tem' found in 'global list of parameters' (used in rule '%s')", runtime.KeyForStorable(rule)))
+
+	// in warn mode, the warning should be logged on every run, even if dependency resolution is taken from the cache
+	b, rule = makePolicy()
+	policy := b.Policy()
+	cache := NewResolutionCache()
+	for i := 0; i < 2; i++ {
+		eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
+		resolution := NewPolicyResolver(policy, b.External(), eventLog).SetOptions(&Options{MissingParamsMode: expression.MissingParamsWarn}).SetCache(cache).ResolveAllDependencies()
+		assert.True(t, resolution.AllDependenciesResolvedSuccessfully(), "All dependencies should be resolved successfully")
+		verifier := event.NewLogVerifier(fmt.Sprintf("expression 'tem == 'dev'' refers to a missing parameter: No parameter/field/method with name 'tem' found in 'global list of parameters' (used in rule '%s')", runtime.KeyForStorable(rule)), false)
+		eventLog.Save(verifier)
+		assert.Equal(t, 1, verifier.MatchedErrorsCount(), "Missing parameter should be reported once on run #%d", i+1)
+	}
+
+	// in error mode, dependency should fail to resolve
+	b, rule = makePolicy()
+	resolvePolicyWithOptions(t, b, &Options{MissingParamsMode: expression.MissingParamsError}, ResSomeDependenciesFailed, fmt.Sprintf("error while processing rule '%s'", rule.Name))
+}
+
 func TestPolicyResolverDependencyWithNonExistingUser(t *testing.T) {
 	b := builder.NewPolicyBuilder()
 	service := b.AddService()
@@ -401,6 +916,92 @@ func TestPolicyResolverPickClusterViaRules(t *testing.T) {
 	assert.Equal(t, cluster2.Name, instance2.CalculatedLabels.Labels[lang.LabelCluster], "Cluster should be set correctly via rules")
 }
 
+func TestPolicyResolverStickyPlacement(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a sticky service, which can be deployed to different clusters
+	service := b.AddService()
+	service.Sticky = true
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContract(service, b.CriteriaTrue())
+
+	// add rules, which say to deploy to different clusters based on label value
+	cluster1 := b.AddCluster()
+	cluster2 := b.AddCluster()
+	b.AddRule(b.Criteria("target == 'one'", "true", "false"), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster1.Name)))
+	b.AddRule(b.Criteria("target == 'two'", "true", "false"), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster2.Name)))
+
+	// add dependency, which gets placed into the first cluster
+	d := b.AddDependency(b.AddUser(), contract)
+	d.Labels["target"] = "one"
+	actualState := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+	instance := getInstanceByDependencyKey(t, runtime.KeyForStorable(d), actualState)
+	assert.Equal(t, cluster1.Name, instance.Metadata.Key.ClusterName, "Dependency should be placed into the first cluster")
+
+	// change labels, so that rules point dependency to the second cluster
+	d.Labels["target"] = "two"
+	resolveSticky := func(migrate map[string]bool) *PolicyResolution {
+		eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
+		resolution := NewPolicyResolver(b.Policy(), b.External(), eventLog).SetStickyPlacement(actualState, migrate).ResolveAllDependencies()
+		assert.True(t, resolution.AllDependenciesResolvedSuccessfully(), "All dependencies should be resolved successfully")
+		return resolution
+	}
+
+	// sticky service instance should stay in the first cluster, with a warning that it would move
+	resolution := resolveSticky(nil)
+	instance = getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution)
+	assert.Equal(t, cluster1.Name, instance.Metadata.Key.ClusterName, "Sticky service instance should stay in the first cluster")
+	assert.Equal(t, cluster1.Name, instance.CalculatedLabels.Labels[lang.LabelCluster], "Sticky service instance should keep cluster label")
+	wouldMove := resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(d)].WouldMove
+	if assert.Len(t, wouldMove, 1, "Dependency should have a warning that it would move") {
+		assert.Contains(t, wouldMove[0], "to cluster '"+cluster2.Name+"'", "Warning should say where dependency would move")
+	}
+
+	// once migration is requested, service instance should move to the second cluster
+	resolution = resolveSticky(map[string]bool{runtime.KeyForStorable(d): true})
+	instance = getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution)
+	assert.Equal(t, cluster2.Name, instance.Metadata.Key.ClusterName, "Migrated service instance should move to the second cluster")
+	assert.Empty(t, resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(d)].WouldMove, "Migrated dependency should have no warnings")
+
+	// if service is not sticky, service instance should move to the second cluster right away
+	service.Sticky = false
+	resolution = resolveSticky(nil)
+	instance = getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution)
+	assert.Equal(t, cluster2.Name, instance.Metadata.Key.ClusterName, "Non-sticky service instance should move to the second cluster")
+}
+
+func TestPolicyResolverStickyContext(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a sticky service and a contract with two contexts
+	service := b.AddService()
+	service.Sticky = true
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContractMultipleContexts(service,
+		b.Criteria("label1 == 'value1'", "true", "false"),
+		b.Criteria("label1 == 'value2'", "true", "false"),
+	)
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add dependency, which gets placed into the first context
+	d := b.AddDependency(b.AddUser(), contract)
+	d.Labels["label1"] = "value1"
+	actualState := resolvePolicy(t, b, ResAllDependenciesResolvedSuccessfully, "Successfully resolved")
+
+	// change labels, so that dependency matches the second context. it should stay in the first context
+	d.Labels["label1"] = "value2"
+	eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
+	resolution := NewPolicyResolver(b.Policy(), b.External(), eventLog).SetStickyPlacement(actualState, nil).ResolveAllDependencies()
+	instance := getInstanceByDependencyKey(t, runtime.KeyForStorable(d), resolution)
+	assert.Equal(t, contract.Contexts[0].Name, instance.Metadata.Key.ContextName, "Sticky service instance should stay in the first context")
+
+	// event log should contain a warning
+	verifier := event.NewLogVerifier("it was kept in place because service '"+service.Name+"' is sticky", false)
+	eventLog.Save(verifier)
+	assert.True(t, verifier.MatchedErrorsCount() > 0, "Event log should have a warning that dependency would move")
+}
+
 func TestPolicyResolverInternalPanic(t *testing.T) {
 	b := builder.NewPolicyBuilder()
 	b.PanicWhenLoadingUsers()
@@ -469,6 +1070,185 @@ func TestPolicyResolverAllocationKeys(t *testing.T) {
 	}
 }
 
+func TestPolicyResolverQuotas(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service, where every user gets its own instance
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContract(service, b.CriteriaTrue())
+	contract.Contexts[0].Allocation.Keys = b.AllocationKeys("{{ .User.Name }}")
+
+	// add rule to set cluster
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add quota, which allows only 2 instances of the service for dev team
+	quota := b.AddQuota(b.Criteria("team == 'dev'", "true", "false"))
+	quota.MaxInstancesPerService = 2
+
+	// add dependencies for dev team (only 2 of them should be resolved) and for another team (should be resolved)
+	dDev := []*lang.Dependency{}
+	for i := 0; i < 3; i++ {
+		d := b.AddDependency(b.AddUser(), contract)
+		d.Labels["team"] = "dev"
+		dDev = append(dDev, d)
+	}
+	dOther := b.AddDependency(b.AddUser(), contract)
+	dOther.Labels["team"] = "other"
+
+	// policy resolution should fail for one dependency
+	resolution := resolvePolicy(t, b, ResSomeDependenciesFailed, "exceeds quota")
+	resolvedDev := 0
+	for _, d := range dDev {
+		if resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(d)].Resolved {
+			resolvedDev++
+		}
+	}
+	assert.Equal(t, 2, resolvedDev, "Only 2 dependencies should be resolved for dev team")
+	assert.True(t, resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(dOther)].Resolved, "Dependency for another team should be successfully resolved")
+
+	// check quota usage
+	usage := resolution.GetQuotaUsageMap()[runtime.KeyForStorable(quota)]
+	if assert.NotNil(t, usage, "Quota usage should be present in policy resolution") {
+		assert.Equal(t, 2, len(usage.Dependencies), "Quota should be consumed by 2 dependencies")
+		assert.Equal(t, 2, len(usage.InstancesPerService[runtime.KeyForStorable(service)]), "Quota should be consumed by 2 service instances")
+		assert.Equal(t, 2, len(usage.InstancesPerCluster[cluster.Name]), "Quota should be consumed by 2 component instances in the cluster")
+	}
+}
+
+func TestPolicyResolverCache(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service, which takes user labels and a secret
+	service := b.AddService()
+	component := b.AddServiceComponent(service, b.CodeComponent(
+		util.NestedParameterMap{"password": "{{ .Secrets.Service.password }}"},
+		nil,
+	))
+	contract := b.AddContract(service, b.CriteriaTrue())
+	contract.Contexts[0].Allocation.Keys = b.AllocationKeys("{{ .User.Name }}")
+	b.AddScopedSecret(secrets.ServiceScope(service.Namespace, service.Name), "password", "secret1")
+
+	// add rule to set cluster
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add two dependencies
+	user1 := b.AddUser()
+	user1.Labels["team"] = "a"
+	d1 := b.AddDependency(user1, contract)
+	user2 := b.AddUser()
+	d2 := b.AddDependency(user2, contract)
+	d1Key, d2Key := runtime.KeyForStorable(d1), runtime.KeyForStorable(d2)
+
+	policy := b.Policy()
+	cache := NewResolutionCache()
+	resolveWithCache := func() *PolicyResolution {
+		t.Helper()
+		resolution := NewPolicyResolver(policy, b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).SetCache(cache).ResolveAllDependencies()
+		if !assert.True(t, resolution.AllDependenciesResolvedSuccessfully(), "All dependencies should be resolved successfully") {
+			t.FailNow()
+		}
+		return resolution
+	}
+
+	// first run should fill the cache
+	resolveWithCache()
+	e1, e2 := cache.entries[d1Key], cache.entries[d2Key]
+	assert.NotNil(t, e1, "Result for dependency should be cached")
+	assert.NotNil(t, e2, "Result for dependency should be cached")
+
+	// second run should reuse both results and produce the same state
+	resolution := resolveWithCache()
+	assert.True(t, e1 == cache.entries[d1Key], "Result for dependency should be reused")
+	assert.True(t, e2 == cache.entries[d2Key], "Result for dependency should be reused")
+	instance := getInstanceByDependencyKey(t, d1Key, resolution)
+	assert.Equal(t, 1, len(instance.DependencyKeys), "Instance should be referenced by one dependency")
+	assert.Equal(t, "a", instance.CalculatedLabels.Labels["team"], "Instance should have user labels")
+	assert.Equal(t, secrets.MaskedValue, resolution.GetSecretMasker().MaskString("secret1"), "Secret from cached result should be masked")
+
+	// change of user labels should only affect dependency of that user
+	user1.Labels["team"] = "b"
+	resolution = resolveWithCache()
+	assert.False(t, e1 == cache.entries[d1Key], "Dependency should be re-resolved after change of user labels")
+	assert.True(t, e2 == cache.entries[d2Key], "Result for dependency should be reused")
+	assert.Equal(t, "b", getInstanceByDependencyKey(t, d1Key, resolution).CalculatedLabels.Labels["team"], "Instance should have updated user labels")
+	e1 = cache.entries[d1Key]
+
+	// change of secret value should affect both dependencies
+	b.AddScopedSecret(secrets.ServiceScope(service.Namespace, service.Name), "password", "secret2")
+	resolution = resolveWithCache()
+	assert.False(t, e1 == cache.entries[d1Key], "Dependency should be re-resolved after change of secrets")
+	assert.False(t, e2 == cache.entries[d2Key], "Dependency should be re-resolved after change of secrets")
+	assert.Equal(t, "secret2", getInstanceByParams(t, cluster, contract, contract.Contexts[0], []string{user2.Name}, service, component, resolution).CalculatedCodeParams["password"], "Instance should have updated secret")
+	e1, e2 = cache.entries[d1Key], cache.entries[d2Key]
+
+	// new generation of a service should affect both dependencies
+	service.Generation++
+	component.Code.Params["version"] = "2"
+	resolution = resolveWithCache()
+	assert.False(t, e1 == cache.entries[d1Key], "Dependency should be re-resolved after change of service")
+	assert.False(t, e2 == cache.entries[d2Key], "Dependency should be re-resolved after change of service")
+	assert.Equal(t, "2", getInstanceByParams(t, cluster, contract, contract.Contexts[0], []string{user1.Name}, service, component, resolution).CalculatedCodeParams["version"], "Instance should have updated code params")
+
+	// removed dependency should be removed from the cache
+	policy.RemoveObject(d2)
+	resolveWithCache()
+	assert.NotContains(t, cache.entries, d2Key, "Result for removed dependency should be removed from the cache")
+}
+
+func BenchmarkPolicyResolver(b *testing.B) {
+	benchmarkPolicyResolver(b, nil)
+}
+
+func BenchmarkPolicyResolverWithCache(b *testing.B) {
+	benchmarkPolicyResolver(b, NewResolutionCache())
+}
+
+// Runs policy resolution for a policy with many dependencies, when nothing changes between runs
+func benchmarkPolicyResolver(b *testing.B, cache *ResolutionCache) {
+	pb := builder.NewPolicyBuilder()
+
+	// create a service with two components, where every user gets its own instance
+	service := pb.AddService()
+	pb.AddServiceComponent(service, pb.CodeComponent(util.NestedParameterMap{"user": "{{ .User.Name }}", "team": "{{ .Labels.team }}"}, nil))
+	pb.AddServiceComponent(service, pb.CodeComponent(util.NestedParameterMap{"replicas": "2"}, util.NestedParameterMap{"url": "http://{{ .Discovery.Instance }}"}))
+	contract := pb.AddContract(service, pb.Criteria("team != 'none'", "true", "false"))
+	contract.Contexts[0].Allocation.Keys = pb.AllocationKeys("{{ .User.Name }}")
+
+	// add rule to set cluster
+	cluster := pb.AddCluster()
+	pb.AddRule(pb.CriteriaTrue(), pb.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, cluster.Name)))
+
+	// add dependencies
+	for i := 0; i < 1000; i++ {
+		d := pb.AddDependency(pb.AddUser(), contract)
+		d.Labels["team"] = fmt.Sprintf("team%d", i%10)
+	}
+
+	policy := pb.Policy()
+	newResolver := func() *PolicyResolver {
+		return NewPolicyResolver(policy, pb.External(), event.NewLog(logrus.WarnLevel, "bench-resolve")).SetCache(cache)
+	}
+
+	// results get cached on the first run
+	newResolver().ResolveAllDependencies()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// only measure resolution, but not policy validation when resolver gets created
+		b.StopTimer()
+		resolver := newResolver()
+		b.StartTimer()
+
+		resolution := resolver.ResolveAllDependencies()
+		if !resolution.AllDependenciesResolvedSuccessfully() {
+			b.Fatal("All dependencies should be resolved successfully")
+		}
+	}
+}
+
 /*
 	Helpers
 */
@@ -479,9 +1259,14 @@ const (
 )
 
 func resolvePolicy(t *testing.T, builder *builder.PolicyBuilder, expectedResult int, expectedLogMessage string) *PolicyResolution {
+	t.Helper()
+	return resolvePolicyWithOptions(t, builder, nil, expectedResult, expectedLogMessage)
+}
+
+func resolvePolicyWithOptions(t *testing.T, builder *builder.PolicyBuilder, options *Options, expectedResult int, expectedLogMessage string) *PolicyResolution {
 	t.Helper()
 	eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
-	resolver := NewPolicyResolver(builder.Policy(), builder.External(), eventLog)
+	resolver := NewPolicyResolver(builder.Policy(), builder.External(), eventLog).SetOptions(options)
 	result := resolver.ResolveAllDependencies()
 
 	if !assert.Equal(t, expectedResult != ResSomeDependenciesFailed, result.AllDependenciesResolvedSuccessfully(), "All dependencies should be resolved successfully") {
@@ -520,7 +1305,15 @@ func getInstanceByDependencyKey(t *testing.T, dependencyID string, resolution *P
 
 func getInstanceByParams(t *testing.T, cluster *lang.Cluster, contract *lang.Contract, context *lang.Context, allocationKeysResolved []string, service *lang.Service, component *lang.ServiceComponent, resolution *PolicyResolution) *ComponentInstance {
 	t.Helper()
-	key := NewComponentInstanceKey(cluster, contract, context, allocationKeysResolved, service, component)
+	var version *lang.ContractVersion
+	for _, v := range contract.Versions {
+		for _, c := range v.Contexts {
+			if c == context {
+				version = v
+			}
+		}
+	}
+	key := NewComponentInstanceKey(cluster, contract, version, context, allocationKeysResolved, service, component)
 	instance, ok := resolution.ComponentInstanceMap[key.GetKey()]
 	if !assert.True(t, ok, "Component instance '%s' should be present in resolution data", key.GetKey()) {
 		t.FailNow()
@@ -0,0 +1,105 @@
+package resolve
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// QuotaUsage contains information about how much of a given quota is consumed by dependencies
+type QuotaUsage struct {
+	// Quota is the key of the quota object in the policy
+	Quota string
+
+	// Limits copied from the quota (0 means no limit)
+	MaxDependencies        int
+	MaxInstancesPerCluster int
+	MaxInstancesPerService int
+
+	// Dependencies is a set of dependency keys ('key' -> true), which are counted against the quota
+	Dependencies map[string]bool
+
+	// InstancesPerCluster is a set of code component instance keys per cluster (cluster -> 'key' -> true)
+	InstancesPerCluster map[string]map[string]bool
+
+	// InstancesPerService is a set of service instance keys per service (service key -> 'key' -> true)
+	InstancesPerService map[string]map[string]bool
+}
+
+// Creates a new empty quota usage for a given quota
+func newQuotaUsage(quota *lang.Quota) *QuotaUsage {
+	return &QuotaUsage{
+		Quota:                  runtime.KeyForStorable(quota),
+		MaxDependencies:        quota.MaxDependencies,
+		MaxInstancesPerCluster: quota.MaxInstancesPerCluster,
+		MaxInstancesPerService: quota.MaxInstancesPerService,
+		Dependencies:           make(map[string]bool),
+		InstancesPerCluster:    make(map[string]map[string]bool),
+		InstancesPerService:    make(map[string]map[string]bool),
+	}
+}
+
+// Counts instances from a given resolution which are not counted against the quota yet, grouped by cluster and by service
+func (usage *QuotaUsage) countNewInstances(resolution *PolicyResolution) (perCluster map[string]int, perService map[string]int) {
+	perCluster = make(map[string]int)
+	perService = make(map[string]int)
+	for key, instance := range resolution.ComponentInstanceMap {
+		cik := instance.Metadata.Key
+		if instance.IsCode && !usage.InstancesPerCluster[cik.ClusterName][key] {
+			perCluster[cik.ClusterName]++
+		}
+		if cik.IsService() {
+			serviceKey := runtime.KeyFromParts(cik.Namespace, lang.ServiceObject.Kind, cik.ServiceName)
+			if !usage.InstancesPerService[serviceKey][key] {
+				perService[serviceKey]++
+			}
+		}
+	}
+	return perCluster, perService
+}
+
+// check returns an error if adding dependency with a given resolution would exceed the quota
+func (usage *QuotaUsage) check(dependencyKey string, resolution *PolicyResolution) error {
+	if usage.MaxDependencies > 0 && !usage.Dependencies[dependencyKey] && len(usage.Dependencies)+1 > usage.MaxDependencies {
+		return fmt.Errorf("max number of dependencies reached (%d)", usage.MaxDependencies)
+	}
+
+	perCluster, perService := usage.countNewInstances(resolution)
+	if usage.MaxInstancesPerCluster > 0 {
+		for _, cluster := range util.GetSortedStringKeys(perCluster) {
+			if len(usage.InstancesPerCluster[cluster])+perCluster[cluster] > usage.MaxInstancesPerCluster {
+				return fmt.Errorf("max number of component instances in cluster '%s' reached (%d)", cluster, usage.MaxInstancesPerCluster)
+			}
+		}
+	}
+	if usage.MaxInstancesPerService > 0 {
+		for _, serviceKey := range util.GetSortedStringKeys(perService) {
+			if len(usage.InstancesPerService[serviceKey])+perService[serviceKey] > usage.MaxInstancesPerService {
+				return fmt.Errorf("max number of instances of service '%s' reached (%d)", serviceKey, usage.MaxInstancesPerService)
+			}
+		}
+	}
+	return nil
+}
+
+// add counts dependency with a given resolution against the quota
+func (usage *QuotaUsage) add(dependencyKey string, resolution *PolicyResolution) {
+	usage.Dependencies[dependencyKey] = true
+	for key, instance := range resolution.ComponentInstanceMap {
+		cik := instance.Metadata.Key
+		if instance.IsCode {
+			if _, ok := usage.InstancesPerCluster[cik.ClusterName]; !ok {
+				usage.InstancesPerCluster[cik.ClusterName] = make(map[string]bool)
+			}
+			usage.InstancesPerCluster[cik.ClusterName][key] = true
+		}
+		if cik.IsService() {
+			serviceKey := runtime.KeyFromParts(cik.Namespace, lang.ServiceObject.Kind, cik.ServiceName)
+			if _, ok := usage.InstancesPerService[serviceKey]; !ok {
+				usage.InstancesPerService[serviceKey] = make(map[string]bool)
+			}
+			usage.InstancesPerService[serviceKey][key] = true
+		}
+	}
+}
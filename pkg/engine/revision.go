@@ -27,6 +27,8 @@ const (
 	RevisionStatusCompleted = "completed"
 	// RevisionStatusError represents Revision status when a critical error happened (we should rarely see those)
 	RevisionStatusError = "error"
+	// RevisionStatusCancelled represents Revision status when apply has been cancelled by user
+	RevisionStatusCancelled = "cancelled"
 )
 
 // Revision is a "milestone" in applying policy changes
@@ -42,6 +44,10 @@ type Revision struct {
 
 	Result *action.ApplyResult
 
+	// Partitions is a map from partition name (namespace, or a list of namespaces which depend on each other) to the
+	// status of applying actions in that partition. Partitions get applied independently from each other
+	Partitions map[string]*RevisionPartition
+
 	ResolveLog []*event.APIEvent
 	ApplyLog   []*event.APIEvent
 }
@@ -59,6 +65,14 @@ func NewRevision(gen runtime.Generation, policyGen runtime.Generation) *Revision
 	}
 }
 
+// RevisionPartition holds status of applying actions in a single partition of the revision
+type RevisionPartition struct {
+	Status    string
+	AppliedAt time.Time
+
+	Result *action.ApplyResult
+}
+
 // GetName returns Revision name
 func (revision *Revision) GetName() string {
 	return runtime.EmptyName
@@ -19,6 +19,16 @@ func (eventLog *Log) AsAPIEvents() []*APIEvent {
 	return saver.events
 }
 
+// AsMaskedAPIEvents takes all buffered event log entries and saves them as APIEvents, passing every message
+// through the mask function (e.g. to hide secret values)
+func (eventLog *Log) AsMaskedAPIEvents(mask func(string) string) []*APIEvent {
+	result := eventLog.AsAPIEvents()
+	for _, apiEvent := range result {
+		apiEvent.Message = mask(apiEvent.Message)
+	}
+	return result
+}
+
 // HookAPIEvents saves all events as APIEvents that holds only time, level and message
 type HookAPIEvents struct {
 	events []*APIEvent
@@ -2,11 +2,13 @@ package event
 
 import (
 	"github.com/Sirupsen/logrus"
+	"sync"
 )
 
 // HookMemory implements event log hook, which buffers all event log entries in hookMemory
 type HookMemory struct {
 	entries []*logrus.Entry
+	mutex   sync.Mutex
 }
 
 // Levels defines on which log levels this hook should be fired
@@ -16,6 +18,8 @@ func (buf *HookMemory) Levels() []logrus.Level {
 
 // Fire processes a single log entry
 func (buf *HookMemory) Fire(e *logrus.Entry) error {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
 	buf.entries = append(buf.entries, e)
 	return nil
 }
@@ -1,2 +1,2 @@
-// Package secrets implements support for retrieving user Secrets from external sources (File).
+// Package secrets implements support for retrieving user Secrets from external sources (File, Vault).
 package secrets
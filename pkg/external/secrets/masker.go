@@ -0,0 +1,263 @@
+package secrets
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaskedValue is what secret values get replaced with, when they are about to be displayed or logged
+const MaskedValue = "******"
+
+// DefaultSensitiveKeyPatterns is a list of regular expressions for parameter keys, values of which are considered
+// sensitive and get masked by default
+var DefaultSensitiveKeyPatterns = []string{
+	"(?i)passw(or)?d",
+	"(?i)secret",
+	"(?i)token",
+	"(?i)credential",
+	"(?i)private[-_]?key",
+	"(?i)api[-_]?key",
+}
+
+// MinMaskedValueLength is the minimum length of a secret value, for it to get masked in free-form text. Shorter values
+// (e.g. "true" or "1") would mask unrelated text, so they only get masked by parameter keys
+const MinMaskedValueLength = 6
+
+// CompileSensitiveKeyPatterns compiles a list of regular expressions for parameter keys, values of which are
+// considered sensitive
+func CompileSensitiveKeyPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for sensitive keys '%s': %s", pattern, err)
+		}
+		result = append(result, compiled)
+	}
+	return result, nil
+}
+
+var defaultSensitiveKeyPatterns = func() []*regexp.Regexp {
+	result, err := CompileSensitiveKeyPatterns(DefaultSensitiveKeyPatterns)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}()
+
+// Masker remembers secret values which have been exposed to the policy, as well as keys of parameters which have
+// been marked as sensitive for every component instance. It allows to replace them with MaskedValue in strings and
+// parameter maps before they get logged or returned from the API
+type Masker struct {
+	keyPatterns   []*regexp.Regexp
+	values        map[string]bool
+	sensitiveKeys map[string]map[string]bool
+	mutex         sync.RWMutex
+}
+
+// NewMasker returns new Masker with no secret values, which treats parameters with keys matching given patterns as
+// sensitive. If patterns are nil, default patterns will be used
+func NewMasker(keyPatterns []*regexp.Regexp) *Masker {
+	if keyPatterns == nil {
+		keyPatterns = defaultSensitiveKeyPatterns
+	}
+	return &Masker{
+		keyPatterns:   keyPatterns,
+		values:        make(map[string]bool),
+		sensitiveKeys: make(map[string]map[string]bool),
+	}
+}
+
+// AddSensitiveKeys makes masker treat values of parameters of a given component instance as sensitive, in addition
+// to the keys matching sensitive key patterns. Keys are paths in the parameter tree, with nested keys separated by
+// dots (e.g. "db.password")
+func (masker *Masker) AddSensitiveKeys(instanceKey string, keys ...string) {
+	masker.mutex.Lock()
+	defer masker.mutex.Unlock()
+	if _, ok := masker.sensitiveKeys[instanceKey]; !ok {
+		masker.sensitiveKeys[instanceKey] = make(map[string]bool)
+	}
+	for _, key := range keys {
+		masker.sensitiveKeys[instanceKey][key] = true
+	}
+}
+
+// IsSensitiveKey returns true if parameter of a given component instance is either marked as sensitive by its path,
+// or its key matches sensitive key patterns
+func (masker *Masker) IsSensitiveKey(instanceKey string, path string) bool {
+	masker.mutex.RLock()
+	marked := masker.sensitiveKeys[instanceKey][path]
+	masker.mutex.RUnlock()
+	if marked {
+		return true
+	}
+
+	key := path[strings.LastIndex(path, ".")+1:]
+	for _, pattern := range masker.keyPatterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSensitiveParams walks the parameter tree of a given component instance and makes masker aware of values of all
+// sensitive parameters, so they will get masked everywhere (including free-form log messages)
+func (masker *Masker) AddSensitiveParams(instanceKey string, params util.NestedParameterMap) {
+	masker.addSensitiveParams(instanceKey, "", params)
+}
+
+func (masker *Masker) addSensitiveParams(instanceKey string, prefix string, params util.NestedParameterMap) {
+	for key, value := range params {
+		path := joinPath(prefix, key)
+		if masker.IsSensitiveKey(instanceKey, path) {
+			masker.addSensitiveValue(value)
+		} else {
+			masker.addSensitiveParamsFromValue(instanceKey, path, value)
+		}
+	}
+}
+
+// Looks for sensitive params in nested maps, including maps inside lists (list items share the path of the list)
+func (masker *Masker) addSensitiveParamsFromValue(instanceKey string, path string, value interface{}) {
+	if nested, ok := toNestedMap(value); ok {
+		masker.addSensitiveParams(instanceKey, path, nested)
+	} else if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			masker.addSensitiveParamsFromValue(instanceKey, path, item)
+		}
+	}
+}
+
+func (masker *Masker) addSensitiveValue(value interface{}) {
+	switch v := value.(type) {
+	case string:
+		masker.addValue(v)
+	case []interface{}:
+		for _, item := range v {
+			masker.addSensitiveValue(item)
+		}
+	default:
+		if nested, ok := toNestedMap(value); ok {
+			for _, nestedValue := range nested {
+				masker.addSensitiveValue(nestedValue)
+			}
+		}
+	}
+}
+
+func joinPath(prefix string, key string) string {
+	if len(prefix) <= 0 {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func toNestedMap(value interface{}) (util.NestedParameterMap, bool) {
+	switch v := value.(type) {
+	case util.NestedParameterMap:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// AddSecrets makes masker aware of the given secret values, so they will get masked
+func (masker *Masker) AddSecrets(secrets map[string]string) {
+	for _, value := range secrets {
+		masker.addValue(value)
+	}
+}
+
+// Registers value for masking in free-form text, unless it's too short to be masked safely
+func (masker *Masker) addValue(value string) {
+	if len(value) < MinMaskedValueLength {
+		return
+	}
+	masker.mutex.Lock()
+	defer masker.mutex.Unlock()
+	masker.values[value] = true
+}
+
+// MaskString replaces all occurrences of known secret values in a string with MaskedValue
+func (masker *Masker) MaskString(s string) string {
+	masker.mutex.RLock()
+	defer masker.mutex.RUnlock()
+	if len(masker.values) <= 0 {
+		return s
+	}
+
+	// replace longer values first, so that a secret which is a part of another secret doesn't leave the rest exposed
+	values := make([]string, 0, len(masker.values))
+	for value := range masker.values {
+		values = append(values, value)
+	}
+	sort.Sort(byLengthDesc(values))
+
+	for _, value := range values {
+		s = strings.Replace(s, value, MaskedValue, -1)
+	}
+	return s
+}
+
+// MaskParams returns a copy of the parameter tree of a given component instance, where values of sensitive
+// parameters, as well as known secret values are replaced with MaskedValue
+func (masker *Masker) MaskParams(instanceKey string, params util.NestedParameterMap) util.NestedParameterMap {
+	return masker.maskParams(instanceKey, "", params)
+}
+
+func (masker *Masker) maskParams(instanceKey string, prefix string, params util.NestedParameterMap) util.NestedParameterMap {
+	if params == nil {
+		return nil
+	}
+	result := util.NestedParameterMap{}
+	for key, value := range params {
+		path := joinPath(prefix, key)
+		if masker.IsSensitiveKey(instanceKey, path) {
+			result[key] = MaskedValue
+		} else {
+			result[key] = masker.maskValue(instanceKey, path, value)
+		}
+	}
+	return result
+}
+
+func (masker *Masker) maskValue(instanceKey string, path string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return masker.MaskString(v)
+	case util.NestedParameterMap:
+		return masker.maskParams(instanceKey, path, v)
+	case map[string]interface{}:
+		return masker.maskParams(instanceKey, path, v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for idx, item := range v {
+			result[idx] = masker.maskValue(instanceKey, path, item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// byLengthDesc sorts strings by their length, longest first
+type byLengthDesc []string
+
+func (s byLengthDesc) Len() int {
+	return len(s)
+}
+
+func (s byLengthDesc) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s byLengthDesc) Less(i, j int) bool {
+	return len(s[i]) > len(s[j])
+}
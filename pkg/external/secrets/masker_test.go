@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMasker(t *testing.T) {
+	masker := NewMasker(nil)
+	masker.AddSecrets(map[string]string{
+		"password":     "secret",
+		"longPassword": "secretsecret2",
+		"empty":        "",
+		"short":        "1",
+	})
+
+	assert.Equal(t, "url: mysql://root:******@db", masker.MaskString("url: mysql://root:secret@db"))
+	assert.Equal(t, "key=******", masker.MaskString("key=secretsecret2"))
+	assert.Equal(t, "nothing to mask", masker.MaskString("nothing to mask"))
+	assert.Equal(t, "replicas: 1", masker.MaskString("replicas: 1"), "Short values should not be masked in text")
+
+	params := util.NestedParameterMap{
+		"password": "secret",
+		"port":     3306,
+		"nested": util.NestedParameterMap{
+			"url":   "mysql://root:secret@db",
+			"hosts": []interface{}{"db", "secret"},
+		},
+	}
+	masked := masker.MaskParams("instance", params)
+	assert.Equal(t, "******", masked["password"])
+	assert.Equal(t, 3306, masked["port"])
+	assert.Equal(t, "mysql://root:******@db", masked.GetNestedMap("nested")["url"])
+	assert.Equal(t, []interface{}{"db", "******"}, masked.GetNestedMap("nested")["hosts"])
+
+	// original params should not be modified
+	assert.Equal(t, "secret", params["password"])
+}
+
+func TestMaskerSensitiveKeys(t *testing.T) {
+	masker := NewMasker(nil)
+	masker.AddSensitiveKeys("instance", "dbConnection", "db.user", "enabled")
+
+	params := util.NestedParameterMap{
+		"adminPassword": "qwerty123",
+		"dbConnection":  "mysql://db:3306",
+		"name":          "wordpress",
+		"enabled":       "true",
+		"db": util.NestedParameterMap{
+			"user":         "wpadmin",
+			"dbConnection": "not sensitive",
+		},
+		"nested": util.NestedParameterMap{
+			"apiToken": "abcdef",
+			"replicas": 3,
+		},
+		"env": []interface{}{
+			util.NestedParameterMap{"name": "HOST", "secretKey": "s3cr3tkey"},
+		},
+	}
+	masker.AddSensitiveParams("instance", params)
+
+	// values should be masked by key paths and key patterns
+	masked := masker.MaskParams("instance", params)
+	assert.Equal(t, MaskedValue, masked["adminPassword"])
+	assert.Equal(t, MaskedValue, masked["dbConnection"])
+	assert.Equal(t, MaskedValue, masked["enabled"])
+	assert.Equal(t, "wordpress", masked["name"])
+	assert.Equal(t, MaskedValue, masked.GetNestedMap("db")["user"], "Nested key should be masked by its path")
+	assert.Equal(t, "not sensitive", masked.GetNestedMap("db")["dbConnection"], "Nested key with the same name as sensitive key should not be masked")
+	assert.Equal(t, MaskedValue, masked.GetNestedMap("nested")["apiToken"])
+	assert.Equal(t, 3, masked.GetNestedMap("nested")["replicas"])
+	assert.Equal(t, MaskedValue, masked["env"].([]interface{})[0].(util.NestedParameterMap)["secretKey"])
+	assert.Equal(t, "HOST", masked["env"].([]interface{})[0].(util.NestedParameterMap)["name"])
+
+	// values of sensitive params should be masked in free-form text as well, unless they are too short
+	assert.Equal(t, "connecting to ****** with ******", masker.MaskString("connecting to mysql://db:3306 with qwerty123"))
+	assert.Equal(t, "deploying wordpress", masker.MaskString("deploying wordpress"))
+	assert.Equal(t, "using key ******", masker.MaskString("using key s3cr3tkey"), "Sensitive values inside lists should be masked in text")
+	assert.Equal(t, "user ******", masker.MaskString("user wpadmin"))
+	assert.Equal(t, "enabled: true", masker.MaskString("enabled: true"), "Short sensitive values should not be masked in text")
+
+	// keys marked as sensitive for one instance should not be masked for another one
+	masked = masker.MaskParams("another", util.NestedParameterMap{"dbConnection": "mysql://another:3306", "adminPassword": "pwd"})
+	assert.Equal(t, "mysql://another:3306", masked["dbConnection"], "Sensitive keys should be scoped to the instance")
+	assert.Equal(t, MaskedValue, masked["adminPassword"], "Key patterns should apply to all instances")
+}
+
+func TestMaskerSensitiveKeyPatterns(t *testing.T) {
+	_, err := CompileSensitiveKeyPatterns([]string{"(invalid"})
+	assert.Error(t, err, "Invalid pattern should not be compiled")
+
+	patterns, err := CompileSensitiveKeyPatterns([]string{"^license$"})
+	assert.NoError(t, err, "Pattern should be compiled")
+
+	masker := NewMasker(patterns)
+	assert.True(t, masker.IsSensitiveKey("instance", "license"))
+	assert.True(t, masker.IsSensitiveKey("instance", "nested.license"), "Patterns should be matched against the last key in the path")
+	assert.False(t, masker.IsSensitiveKey("instance", "password"))
+	assert.True(t, NewMasker(nil).IsSensitiveKey("instance", "password"), "Default patterns should not be changed")
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"fmt"
+)
+
+// Scope defines what a set of secrets belongs to, when secrets don't belong to a particular user. It can be
+// a namespace (only Namespace is set), a service within a namespace (Namespace and Service are set), or a cluster
+// (only Cluster is set)
+type Scope struct {
+	Namespace string
+	Service   string
+	Cluster   string
+}
+
+// NamespaceScope returns a scope for secrets shared by all services within a namespace
+func NamespaceScope(namespace string) Scope {
+	return Scope{Namespace: namespace}
+}
+
+// ServiceScope returns a scope for secrets of a service within a namespace
+func ServiceScope(namespace string, service string) Scope {
+	return Scope{Namespace: namespace, Service: service}
+}
+
+// ClusterScope returns a scope for secrets of a cluster
+func ClusterScope(cluster string) Scope {
+	return Scope{Cluster: cluster}
+}
+
+// IsEmpty returns true if scope doesn't point to anything
+func (scope Scope) IsEmpty() bool {
+	return len(scope.Namespace) <= 0 && len(scope.Service) <= 0 && len(scope.Cluster) <= 0
+}
+
+// String returns a human-readable representation of the scope, e.g. 'service/main/db'
+func (scope Scope) String() string {
+	switch {
+	case len(scope.Cluster) > 0:
+		return fmt.Sprintf("cluster/%s", scope.Cluster)
+	case len(scope.Service) > 0:
+		return fmt.Sprintf("service/%s/%s", scope.Namespace, scope.Service)
+	default:
+		return fmt.Sprintf("namespace/%s", scope.Namespace)
+	}
+}
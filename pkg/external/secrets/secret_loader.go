@@ -1,8 +1,11 @@
 package secrets
 
-// SecretLoader is an interface which allows aptomi to load secrets for users
-// from different sources (e.g. file, external store, etc)
+// SecretLoader is an interface which allows aptomi to load secrets for users, as well as secrets scoped to
+// namespaces, services and clusters from different sources (e.g. file, external store, etc)
 type SecretLoader interface {
 	// LoadSecretsByUserName should load a set of secrets for a given user
 	LoadSecretsByUserName(string) map[string]string
+
+	// LoadSecretsByScope should load a set of secrets for a given scope (namespace, service or cluster)
+	LoadSecretsByScope(Scope) map[string]string
 }
@@ -13,18 +13,26 @@ import (
 	"time"
 )
 
-// SecretLoaderFromDir allows to load secrets for users from a given directory
+// SecretLoaderFromDir allows to load secrets for users and scopes (namespaces, services, clusters) from a given directory
 type SecretLoaderFromDir struct {
 	baseDir string
 	cache   *cache.Cache
 }
 
-// UserSecrets represents a single user secret (user name and a map of secrets)
+// UserSecrets represents a single user secret (user name and a map of secrets). If user is not set, then secrets
+// belong to the scope (namespace, service within a namespace, or cluster) instead
 type UserSecrets struct {
 	User    string
+	Scope   `yaml:",inline"`
 	Secrets map[string]string
 }
 
+// allSecrets holds all secrets loaded from the directory
+type allSecrets struct {
+	users  map[string]map[string]string
+	scopes map[Scope]map[string]string
+}
+
 // NewSecretLoaderFromDir returns new UserLoaderFromDir, given a directory where files should be read from
 func NewSecretLoaderFromDir(baseDir string) SecretLoader {
 	return &SecretLoaderFromDir{
@@ -33,12 +41,17 @@ func NewSecretLoaderFromDir(baseDir string) SecretLoader {
 	}
 }
 
-// LoadSecretsAll loads all secrets
+// LoadSecretsAll loads all user secrets
 func (loader *SecretLoaderFromDir) LoadSecretsAll() map[string]map[string]string {
+	return loader.loadAll().users
+}
+
+// Loads all user and scoped secrets
+func (loader *SecretLoaderFromDir) loadAll() *allSecrets {
 	// this can be called concurrently by the engine, so it needs to be thread safe
 	cachedSecrets, _ := loader.cache.Get("secrets")
 	if cachedSecrets != nil {
-		return cachedSecrets.(map[string]map[string]string)
+		return cachedSecrets.(*allSecrets)
 	}
 
 	// synchronize and retrieve secrets
@@ -47,7 +60,10 @@ func (loader *SecretLoaderFromDir) LoadSecretsAll() map[string]map[string]string
 	defer func() { mutex.Unlock() }()
 
 	// retrieve secrets
-	result := make(map[string]map[string]string)
+	result := &allSecrets{
+		users:  make(map[string]map[string]string),
+		scopes: make(map[Scope]map[string]string),
+	}
 
 	if len(loader.baseDir) <= 0 {
 		// log.Warnf("Skip loading secrets because baseDir not specified")
@@ -64,7 +80,11 @@ func (loader *SecretLoaderFromDir) LoadSecretsAll() map[string]map[string]string
 	for _, f := range files {
 		secrets := loadUserSecretsFromFile(f)
 		for _, secret := range secrets {
-			result[strings.ToLower(secret.User)] = secret.Secrets
+			if len(secret.User) > 0 {
+				result.users[strings.ToLower(secret.User)] = secret.Secrets
+			} else if !secret.Scope.IsEmpty() {
+				result.scopes[secret.Scope] = secret.Secrets
+			}
 		}
 	}
 
@@ -77,6 +97,14 @@ func (loader *SecretLoaderFromDir) LoadSecretsByUserName(user string) map[string
 	return loader.LoadSecretsAll()[strings.ToLower(user)]
 }
 
+// LoadSecretsByScope loads secrets for a single scope (namespace, service or cluster)
+func (loader *SecretLoaderFromDir) LoadSecretsByScope(scope Scope) map[string]string {
+	if scope.IsEmpty() {
+		return make(map[string]string)
+	}
+	return loader.loadAll().scopes[scope]
+}
+
 // Loads secrets from file
 func loadUserSecretsFromFile(fileName string) []*UserSecrets {
 	log.Debugf("Loading secrets from file: %s", fileName)
@@ -34,3 +34,32 @@ func TestLoadSecrets(t *testing.T) {
 		assert.Equal(t, "bigsecretvalue", secrets["bigsecret"])
 	}
 }
+
+func TestLoadScopedSecrets(t *testing.T) {
+	secretLoader := NewSecretLoaderFromDir("../../testdata/unittests")
+
+	{
+		secrets := secretLoader.LoadSecretsByScope(NamespaceScope("main"))
+		assert.Equal(t, 1, len(secrets))
+		assert.Equal(t, "maintoken", secrets["monitoringToken"])
+	}
+
+	{
+		secrets := secretLoader.LoadSecretsByScope(ServiceScope("main", "mysql"))
+		assert.Equal(t, 1, len(secrets))
+		assert.Equal(t, "mysqladminpassword", secrets["adminPassword"])
+	}
+
+	{
+		secrets := secretLoader.LoadSecretsByScope(ClusterScope("cluster-us-east"))
+		assert.Equal(t, 1, len(secrets))
+		assert.Equal(t, "registrypassword", secrets["registryPassword"])
+	}
+
+	{
+		// scoped secrets should not be mixed with user secrets
+		assert.Equal(t, 0, len(secretLoader.LoadSecretsByScope(ServiceScope("main", "twitter"))))
+		assert.Equal(t, 0, len(secretLoader.LoadSecretsByScope(ClusterScope("cluster-us-west"))))
+		assert.Equal(t, 3, len(secretLoader.(*SecretLoaderFromDir).LoadSecretsAll()))
+	}
+}
@@ -0,0 +1,298 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/Sirupsen/logrus"
+	"github.com/patrickmn/go-cache"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// SecretLoaderFromVault allows to load secrets for users and scopes (namespaces, services, clusters) from
+// Vault-compatible KV (version 2) secrets engine
+type SecretLoaderFromVault struct {
+	cfg                   config.Vault
+	pathTemplate          *template.Template
+	namespacePathTemplate *template.Template
+	servicePathTemplate   *template.Template
+	clusterPathTemplate   *template.Template
+	httpClient            *http.Client
+	cache                 *cache.Cache
+
+	// last successfully loaded secrets, served if Vault is not available
+	lastKnown     map[string]map[string]string
+	lastKnownLock sync.Mutex
+
+	// token used to talk to Vault (either static one or obtained via AppRole login)
+	token          string
+	tokenExpiresAt time.Time
+	tokenLock      sync.Mutex
+}
+
+// NewSecretLoaderFromVault returns new SecretLoaderFromVault, given Vault config
+func NewSecretLoaderFromVault(cfg config.Vault) SecretLoader {
+	if len(cfg.Token) <= 0 && cfg.AppRole == nil {
+		panic("either token or approle must be specified to load secrets from vault")
+	}
+
+	return &SecretLoaderFromVault{
+		cfg:                   cfg,
+		pathTemplate:          parsePathTemplate(cfg.PathTemplate),
+		namespacePathTemplate: parsePathTemplate(cfg.NamespacePathTemplate),
+		servicePathTemplate:   parsePathTemplate(cfg.ServicePathTemplate),
+		clusterPathTemplate:   parsePathTemplate(cfg.ClusterPathTemplate),
+		httpClient:            &http.Client{Timeout: cfg.GetTimeout()},
+		cache:                 cache.New(cfg.GetCacheTTL(), cfg.GetCacheTTL()),
+		lastKnown:             make(map[string]map[string]string),
+		token:                 cfg.Token,
+	}
+}
+
+// Parses path template, returns nil if template is empty
+func parsePathTemplate(text string) *template.Template {
+	if len(text) <= 0 {
+		return nil
+	}
+	result, err := template.New("path").Option("missingkey=error").Parse(text)
+	if err != nil {
+		panic(fmt.Sprintf("invalid path template for loading secrets from vault '%s': %s", text, err))
+	}
+	return result
+}
+
+// vaultPathParams is what gets exposed to path templates
+type vaultPathParams struct {
+	User      string
+	Namespace string
+	Service   string
+	Cluster   string
+}
+
+// LoadSecretsByUserName loads secrets for a single user
+func (loader *SecretLoaderFromVault) LoadSecretsByUserName(user string) map[string]string {
+	user = strings.ToLower(user)
+	return loader.load("user/"+user, fmt.Sprintf("user '%s'", user), loader.pathTemplate, &vaultPathParams{User: user})
+}
+
+// LoadSecretsByScope loads secrets for a single scope (namespace, service or cluster)
+func (loader *SecretLoaderFromVault) LoadSecretsByScope(scope Scope) map[string]string {
+	if scope.IsEmpty() {
+		// e.g. cluster scope for a service which doesn't have a cluster assigned
+		return make(map[string]string)
+	}
+
+	var pathTemplate *template.Template
+	switch {
+	case len(scope.Cluster) > 0:
+		pathTemplate = loader.clusterPathTemplate
+	case len(scope.Service) > 0:
+		pathTemplate = loader.servicePathTemplate
+	default:
+		pathTemplate = loader.namespacePathTemplate
+	}
+	if pathTemplate == nil {
+		// secrets for this kind of scope are not stored in vault
+		return make(map[string]string)
+	}
+
+	params := &vaultPathParams{Namespace: scope.Namespace, Service: scope.Service, Cluster: scope.Cluster}
+	return loader.load(scope.String(), fmt.Sprintf("'%s'", scope), pathTemplate, params)
+}
+
+// Loads secrets from cache or from vault, falling back to last known secrets if vault is not available. If secrets
+// have never been loaded, it panics instead of serving no secrets, so that dependencies relying on them fail to resolve
+func (loader *SecretLoaderFromVault) load(key string, description string, pathTemplate *template.Template, params *vaultPathParams) map[string]string {
+	// this can be called concurrently by the engine, so it needs to be thread safe
+	cachedSecrets, found := loader.cache.Get(key)
+	if found {
+		return cachedSecrets.(map[string]string)
+	}
+
+	result, err := loader.loadSecrets(description, pathTemplate, params)
+	if err != nil {
+		// if vault is not available, serve the last known secrets instead of failing policy resolution
+		loader.lastKnownLock.Lock()
+		defer loader.lastKnownLock.Unlock()
+		if lastKnown, ok := loader.lastKnown[key]; ok {
+			log.Warnf("Error while loading secrets for %s from vault, using last known secrets: %s", description, err)
+			return lastKnown
+		}
+		// there is nothing to fall back to. serving no secrets would look like a legitimate change of secrets, so
+		// let's fail instead (secrets will be loaded once vault is back)
+		panic(fmt.Errorf("error while loading secrets for %s from vault and there are no last known secrets: %s", description, err))
+	}
+
+	loader.lastKnownLock.Lock()
+	loader.lastKnown[key] = result
+	loader.lastKnownLock.Unlock()
+
+	loader.cache.Set(key, result, cache.DefaultExpiration)
+	return result
+}
+
+// Loads secrets from vault, given path template and parameters for it
+func (loader *SecretLoaderFromVault) loadSecrets(description string, pathTemplate *template.Template, params *vaultPathParams) (map[string]string, error) {
+	path, err := secretPath(pathTemplate, params)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}{}
+
+	found, err := loader.request(http.MethodGet, fmt.Sprintf("/v1/%s/data/%s", strings.Trim(loader.cfg.Mount, "/"), path), nil, response, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	if !found {
+		// there are no secrets in vault
+		return result, nil
+	}
+
+	for key, value := range response.Data.Data {
+		result[key] = fmt.Sprintf("%v", value)
+	}
+
+	log.Debugf("Loaded %d secrets for %s from vault", len(result), description)
+	return result, nil
+}
+
+// Returns path of secrets, by evaluating path template. Every parameter gets escaped, so it always ends up as a
+// single path segment and can't be used to point to secrets of another user or scope
+func secretPath(pathTemplate *template.Template, params *vaultPathParams) (string, error) {
+	escaped := &vaultPathParams{}
+	for _, p := range []struct {
+		value  string
+		result *string
+	}{
+		{params.User, &escaped.User},
+		{params.Namespace, &escaped.Namespace},
+		{params.Service, &escaped.Service},
+		{params.Cluster, &escaped.Cluster},
+	} {
+		if p.value == "." || p.value == ".." {
+			return "", fmt.Errorf("invalid path segment: '%s'", p.value)
+		}
+		*p.result = url.PathEscape(p.value)
+	}
+
+	var buf bytes.Buffer
+	err := pathTemplate.Execute(&buf, escaped)
+	if err != nil {
+		return "", fmt.Errorf("error while evaluating path template: %s", err)
+	}
+	return strings.Trim(buf.String(), "/"), nil
+}
+
+// Returns token for talking to vault. If AppRole auth is configured, it will log in when token is absent or expired
+func (loader *SecretLoaderFromVault) getToken(forceLogin bool) (string, error) {
+	loader.tokenLock.Lock()
+	defer loader.tokenLock.Unlock()
+
+	if loader.cfg.AppRole == nil {
+		return loader.token, nil
+	}
+
+	if !forceLogin && len(loader.token) > 0 && (loader.tokenExpiresAt.IsZero() || time.Now().Before(loader.tokenExpiresAt)) {
+		return loader.token, nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"role_id":   loader.cfg.AppRole.RoleID,
+		"secret_id": loader.cfg.AppRole.SecretID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response := &struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}{}
+
+	found, err := loader.doRequest(http.MethodPost, fmt.Sprintf("/v1/auth/%s/login", strings.Trim(loader.cfg.AppRole.GetAppRoleMount(), "/")), "", body, response)
+	if err != nil {
+		return "", fmt.Errorf("error while logging in to vault using approle: %s", err)
+	}
+	if !found || len(response.Auth.ClientToken) <= 0 {
+		return "", fmt.Errorf("error while logging in to vault using approle: no token returned")
+	}
+
+	loader.token = response.Auth.ClientToken
+	loader.tokenExpiresAt = time.Time{}
+	if response.Auth.LeaseDuration > 0 {
+		// renew token a bit earlier than it actually expires
+		loader.tokenExpiresAt = time.Now().Add(time.Duration(response.Auth.LeaseDuration) * time.Second * 9 / 10)
+	}
+
+	return loader.token, nil
+}
+
+// Makes an authenticated request to vault. If token got rejected and AppRole auth is configured, it will log in again and retry once
+func (loader *SecretLoaderFromVault) request(method string, path string, body []byte, result interface{}, retry bool) (bool, error) {
+	token, err := loader.getToken(false)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := loader.doRequest(method, path, token, body, result)
+	if err == errVaultPermissionDenied && retry && loader.cfg.AppRole != nil {
+		_, err = loader.getToken(true)
+		if err != nil {
+			return false, err
+		}
+		return loader.request(method, path, body, result, false)
+	}
+	return found, err
+}
+
+var errVaultPermissionDenied = fmt.Errorf("permission denied")
+
+// Makes a request to vault and decodes JSON response into result. Returns false if nothing was found
+func (loader *SecretLoaderFromVault) doRequest(method string, path string, token string, body []byte, result interface{}) (bool, error) {
+	req, err := http.NewRequest(method, strings.TrimRight(loader.cfg.Address, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	if len(token) > 0 {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := loader.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode == http.StatusForbidden:
+		return false, errVaultPermissionDenied
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return false, fmt.Errorf("unexpected response status from vault: %s", resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return false, fmt.Errorf("error while decoding response from vault: %s", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,201 @@
+package secrets
+
+import (
+	"encoding/json"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// vaultStub is a minimal Vault-compatible KV v2 HTTP API with token and AppRole auth
+type vaultStub struct {
+	secrets  map[string]map[string]interface{}
+	token    string
+	requests int32
+	logins   int32
+}
+
+func (stub *vaultStub) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path == "/v1/auth/approle/login" {
+		atomic.AddInt32(&stub.logins, 1)
+		body := map[string]string{}
+		_ = json.NewDecoder(request.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(writer).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": stub.token, "lease_duration": 3600},
+		})
+		return
+	}
+
+	atomic.AddInt32(&stub.requests, 1)
+	if request.Header.Get("X-Vault-Token") != stub.token {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+	data, ok := stub.secrets[path.Clean(request.URL.EscapedPath())]
+	if !ok {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(map[string]interface{}{
+		"data": map[string]interface{}{"data": data},
+	})
+}
+
+func newVaultStub() *vaultStub {
+	return &vaultStub{
+		secrets: map[string]map[string]interface{}{
+			"/v1/secret/data/aptomi/users/alice": {
+				"twitterAppKey": "aliceappkey",
+				"port":          8080,
+			},
+			"/v1/secret/data/aptomi/services/main/mysql": {
+				"adminPassword": "mysqladminpassword",
+			},
+			"/v1/secret/data/aptomi/clusters/cluster-us-east": {
+				"registryPassword": "registrypassword",
+			},
+		},
+		token: "s.token",
+	}
+}
+
+func TestLoadSecretsFromVaultWithToken(t *testing.T) {
+	stub := newVaultStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	secretLoader := NewSecretLoaderFromVault(config.Vault{
+		Address:      server.URL,
+		Mount:        "secret",
+		PathTemplate: "aptomi/users/{{ .User }}",
+		Token:        "s.token",
+	})
+
+	// secrets should be loaded for existing user
+	secrets := secretLoader.LoadSecretsByUserName("Alice")
+	assert.Equal(t, 2, len(secrets))
+	assert.Equal(t, "aliceappkey", secrets["twitterAppKey"])
+	assert.Equal(t, "8080", secrets["port"])
+
+	// secrets should be empty for a user without secrets
+	assert.Equal(t, 0, len(secretLoader.LoadSecretsByUserName("bob")))
+
+	// secrets should be cached
+	secretLoader.LoadSecretsByUserName("alice")
+	secretLoader.LoadSecretsByUserName("bob")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stub.requests), "Secrets should be served from cache")
+}
+
+func TestLoadScopedSecretsFromVault(t *testing.T) {
+	stub := newVaultStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	secretLoader := NewSecretLoaderFromVault(config.Vault{
+		Address:             server.URL,
+		Mount:               "secret",
+		PathTemplate:        "aptomi/users/{{ .User }}",
+		ServicePathTemplate: "aptomi/services/{{ .Namespace }}/{{ .Service }}",
+		ClusterPathTemplate: "aptomi/clusters/{{ .Cluster }}",
+		Token:               "s.token",
+	})
+
+	assert.Equal(t, "mysqladminpassword", secretLoader.LoadSecretsByScope(ServiceScope("main", "mysql"))["adminPassword"])
+	assert.Equal(t, "registrypassword", secretLoader.LoadSecretsByScope(ClusterScope("cluster-us-east"))["registryPassword"])
+	assert.Equal(t, 0, len(secretLoader.LoadSecretsByScope(ServiceScope("main", "kafka"))))
+
+	// namespace path template is not set, so vault should not be queried for namespace secrets
+	assert.Equal(t, 0, len(secretLoader.LoadSecretsByScope(NamespaceScope("main"))))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&stub.requests))
+
+	// empty scope (e.g. service without a cluster) should not be queried, even if namespace path template is set
+	secretLoader = NewSecretLoaderFromVault(config.Vault{
+		Address:               server.URL,
+		Mount:                 "secret",
+		NamespacePathTemplate: "aptomi/namespaces/{{ .Namespace }}",
+		ClusterPathTemplate:   "aptomi/clusters/{{ .Cluster }}",
+		Token:                 "s.token",
+	})
+	assert.Equal(t, 0, len(secretLoader.LoadSecretsByScope(ClusterScope(""))))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&stub.requests))
+}
+
+func TestLoadSecretsFromVaultWithAppRole(t *testing.T) {
+	stub := newVaultStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	secretLoader := NewSecretLoaderFromVault(config.Vault{
+		Address:      server.URL,
+		Mount:        "secret",
+		PathTemplate: "aptomi/users/{{ .User }}",
+		AppRole: &config.VaultAppRole{
+			RoleID:   "role",
+			SecretID: "secret",
+		},
+	})
+
+	secrets := secretLoader.LoadSecretsByUserName("alice")
+	assert.Equal(t, "aliceappkey", secrets["twitterAppKey"])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&stub.logins), "Loader should log in using AppRole once")
+
+	// if token gets revoked, loader should log in again
+	stub.token = "s.newtoken"
+	assert.Equal(t, 0, len(secretLoader.LoadSecretsByUserName("bob")))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stub.logins), "Loader should log in again once token is rejected")
+}
+
+func TestLoadSecretsFromVaultUnavailable(t *testing.T) {
+	stub := newVaultStub()
+	server := httptest.NewServer(stub)
+
+	secretLoader := NewSecretLoaderFromVault(config.Vault{
+		Address:      server.URL,
+		Mount:        "secret",
+		PathTemplate: "aptomi/users/{{ .User }}",
+		Token:        "s.token",
+		CacheTTL:     time.Millisecond,
+	})
+
+	// load secrets and shut down vault
+	assert.Equal(t, "aliceappkey", secretLoader.LoadSecretsByUserName("alice")["twitterAppKey"])
+	server.Close()
+	time.Sleep(5 * time.Millisecond)
+
+	// last known secrets should be served
+	assert.Equal(t, "aliceappkey", secretLoader.LoadSecretsByUserName("alice")["twitterAppKey"])
+
+	// loader should fail for unknown user, instead of serving no secrets
+	assert.Panics(t, func() {
+		secretLoader.LoadSecretsByUserName("bob")
+	}, "Loader should fail if secrets have never been loaded")
+}
+
+func TestLoadSecretsFromVaultPathEscaping(t *testing.T) {
+	stub := newVaultStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	secretLoader := NewSecretLoaderFromVault(config.Vault{
+		Address:             server.URL,
+		Mount:               "secret",
+		PathTemplate:        "aptomi/users/{{ .User }}",
+		ClusterPathTemplate: "aptomi/clusters/{{ .Cluster }}",
+		Token:               "s.token",
+	})
+
+	// parameters should not be able to point to secrets of another user or scope
+	assert.Equal(t, 0, len(secretLoader.LoadSecretsByUserName("../services/main/mysql")))
+	assert.Panics(t, func() { secretLoader.LoadSecretsByUserName("..") }, "Invalid path segment should not be served as no secrets")
+	assert.Equal(t, 0, len(secretLoader.LoadSecretsByScope(ClusterScope("../users/alice"))))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stub.requests), "Invalid path segments should not be requested from vault")
+}
@@ -1,22 +1,42 @@
 package secrets
 
+import "sync"
+
 // SecretLoaderMock allows to mock secret loader and use in-memory user storage
 type SecretLoaderMock struct {
-	secrets map[string]map[string]string
+	secrets       map[string]map[string]string
+	scopedSecrets map[Scope]map[string]string
+
+	// how many times secrets of every scope have been loaded
+	scopedLoads     map[Scope]int
+	scopedLoadsLock sync.Mutex
 }
 
 // NewSecretLoaderMock returns new SecretLoaderMock
 func NewSecretLoaderMock() *SecretLoaderMock {
 	return &SecretLoaderMock{
-		secrets: make(map[string]map[string]string),
+		secrets:       make(map[string]map[string]string),
+		scopedSecrets: make(map[Scope]map[string]string),
+		scopedLoads:   make(map[Scope]int),
 	}
 }
 
 // AddSecret adds a secret for a given user
 func (loader *SecretLoaderMock) AddSecret(userName string, secretName string, secretValue string) {
+	if _, ok := loader.secrets[userName]; !ok {
+		loader.secrets[userName] = make(map[string]string)
+	}
 	loader.secrets[userName][secretName] = secretValue
 }
 
+// AddScopedSecret adds a secret for a given scope (namespace, service or cluster)
+func (loader *SecretLoaderMock) AddScopedSecret(scope Scope, secretName string, secretValue string) {
+	if _, ok := loader.scopedSecrets[scope]; !ok {
+		loader.scopedSecrets[scope] = make(map[string]string)
+	}
+	loader.scopedSecrets[scope][secretName] = secretValue
+}
+
 // LoadSecretsAll loads all secrets
 func (loader *SecretLoaderMock) LoadSecretsAll() map[string]map[string]string {
 	return loader.secrets
@@ -26,3 +46,18 @@ func (loader *SecretLoaderMock) LoadSecretsAll() map[string]map[string]string {
 func (loader *SecretLoaderMock) LoadSecretsByUserName(userName string) map[string]string {
 	return loader.secrets[userName]
 }
+
+// LoadSecretsByScope loads secrets for a single scope (namespace, service or cluster)
+func (loader *SecretLoaderMock) LoadSecretsByScope(scope Scope) map[string]string {
+	loader.scopedLoadsLock.Lock()
+	loader.scopedLoads[scope]++
+	loader.scopedLoadsLock.Unlock()
+	return loader.scopedSecrets[scope]
+}
+
+// GetScopedLoads returns how many times secrets of a given scope have been loaded
+func (loader *SecretLoaderMock) GetScopedLoads(scope Scope) int {
+	loader.scopedLoadsLock.Lock()
+	defer loader.scopedLoadsLock.Unlock()
+	return loader.scopedLoads[scope]
+}
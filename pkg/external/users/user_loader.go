@@ -20,3 +20,11 @@ type UserLoader interface {
 	// Summary returns summary for the loader as string
 	Summary() string
 }
+
+// Refresher is an optional interface for user loaders, which cache user data loaded from an external source
+// (e.g. LDAP). It allows to pick up changes in user data without waiting for cache to expire
+type Refresher interface {
+	// Refresh should load user data from the source again, replacing cached data. If data can't be loaded,
+	// cached data should be kept and an error should be returned
+	Refresh() error
+}
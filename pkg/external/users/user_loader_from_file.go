@@ -17,6 +17,9 @@ type UserLoaderFromFile struct {
 	fileName             string
 	cache                *cache.Cache
 	domainAdminOverrides map[string]bool
+
+	// mutex synchronizes loading of users into the cache
+	mutex sync.Mutex
 }
 
 // NewUserLoaderFromFile returns new UserLoaderFromFile
@@ -36,11 +39,30 @@ func (loader *UserLoaderFromFile) LoadUsersAll() *lang.GlobalUsers {
 		return cachedUsers.(*lang.GlobalUsers)
 	}
 
-	// synchronize and retrieve users
-	mutex := sync.Mutex{}
-	mutex.Lock()
-	defer func() { mutex.Unlock() }()
+	// synchronize and retrieve users, unless they have been retrieved while we were waiting for the lock
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	cachedUsers, _ = loader.cache.Get("users")
+	if cachedUsers != nil {
+		return cachedUsers.(*lang.GlobalUsers)
+	}
+
+	result := loader.loadUsers()
+	loader.cache.Set("users", result, cache.DefaultExpiration)
+	return result
+}
+
+// Refresh loads users from the file again, replacing cached users
+func (loader *UserLoaderFromFile) Refresh() error {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
 
+	loader.cache.Set("users", loader.loadUsers(), cache.DefaultExpiration)
+	return nil
+}
+
+// Loads all users from the file, bypassing cache
+func (loader *UserLoaderFromFile) loadUsers() *lang.GlobalUsers {
 	result := &lang.GlobalUsers{Users: make(map[string]*lang.User)}
 	userList := loadUsersFromFile(loader.fileName)
 	for _, u := range userList {
@@ -49,7 +71,6 @@ func (loader *UserLoaderFromFile) LoadUsersAll() *lang.GlobalUsers {
 			u.DomainAdmin = true
 		}
 	}
-	loader.cache.Set("users", result, cache.DefaultExpiration)
 	return result
 }
 
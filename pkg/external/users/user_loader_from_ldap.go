@@ -18,6 +18,9 @@ type UserLoaderFromLDAP struct {
 	cfg                  config.LDAP
 	cache                *cache.Cache
 	domainAdminOverrides map[string]bool
+
+	// mutex synchronizes loading of users into the cache
+	mutex sync.Mutex
 }
 
 // NewUserLoaderFromLDAP returns new UserLoaderFromLDAP, given location with LDAP configuration file (with host/port and mapping)
@@ -37,25 +40,50 @@ func (loader *UserLoaderFromLDAP) LoadUsersAll() *lang.GlobalUsers {
 		return cachedUsers.(*lang.GlobalUsers)
 	}
 
-	// synchronize and retrieve users
-	mutex := sync.Mutex{}
-	mutex.Lock()
-	defer func() { mutex.Unlock() }()
+	// synchronize and retrieve users, unless they have been retrieved while we were waiting for the lock
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	cachedUsers, _ = loader.cache.Get("ldapUsers")
+	if cachedUsers != nil {
+		return cachedUsers.(*lang.GlobalUsers)
+	}
 
-	result := &lang.GlobalUsers{Users: make(map[string]*lang.User)}
-	ldapUsers, err := loader.ldapSearch()
+	result, err := loader.loadUsers()
 	if err != nil {
 		// we need user data, but they cannot be loaded from LDAP. for now, let's panic
 		panic(err)
 	}
+	loader.cache.Set("ldapUsers", result, cache.DefaultExpiration)
+	return result
+}
+
+// Refresh loads users from LDAP again, replacing cached users. If users can't be loaded, cached users are kept
+func (loader *UserLoaderFromLDAP) Refresh() error {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	result, err := loader.loadUsers()
+	if err != nil {
+		return err
+	}
+	loader.cache.Set("ldapUsers", result, cache.DefaultExpiration)
+	return nil
+}
+
+// Loads all users from LDAP, bypassing cache
+func (loader *UserLoaderFromLDAP) loadUsers() (*lang.GlobalUsers, error) {
+	result := &lang.GlobalUsers{Users: make(map[string]*lang.User)}
+	ldapUsers, err := loader.ldapSearch()
+	if err != nil {
+		return nil, err
+	}
 	for _, u := range ldapUsers {
 		result.Users[strings.ToLower(u.Name)] = u
 		if _, exist := loader.domainAdminOverrides[strings.ToLower(u.Name)]; exist {
 			u.DomainAdmin = true
 		}
 	}
-	loader.cache.Set("ldapUsers", result, cache.DefaultExpiration)
-	return result
+	return result, nil
 }
 
 // LoadUserByName loads a single user by name
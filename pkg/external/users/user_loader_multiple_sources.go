@@ -54,6 +54,19 @@ func (loader *UserLoaderMultipleSources) Authenticate(name, password string) (*l
 	return nil, fmt.Errorf("user '%s' does not exist", name)
 }
 
+// Refresh refreshes user data in all sources, which cache it
+func (loader *UserLoaderMultipleSources) Refresh() error {
+	for _, l := range loader.loaders {
+		if refresher, ok := l.(Refresher); ok {
+			err := refresher.Refresh()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Summary returns summary as string
 func (loader *UserLoaderMultipleSources) Summary() string {
 	return strconv.Itoa(len(loader.LoadUsersAll().Users)) + " (multiple sources)"
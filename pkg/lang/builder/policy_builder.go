@@ -167,6 +167,25 @@ func (builder *PolicyBuilder) AddRule(criteria *lang.Criteria, actions *lang.Rul
 	return result
 }
 
+// AddQuota creates a new quota and adds it to the policy
+func (builder *PolicyBuilder) AddQuota(criteria *lang.Criteria) *lang.Quota {
+	result := &lang.Quota{
+		TypeKind: lang.QuotaObject.GetTypeKind(),
+		Metadata: lang.Metadata{
+			Namespace: builder.namespace,
+			Name:      util.RandomID(builder.random, idLength),
+		},
+		Criteria: criteria,
+	}
+	builder.addObject(builder.domainAdminView, result)
+	return result
+}
+
+// AddScopedSecret adds a secret for a given scope (namespace, service or cluster)
+func (builder *PolicyBuilder) AddScopedSecret(scope secrets.Scope, name string, value string) {
+	builder.secrets.AddScopedSecret(scope, name, value)
+}
+
 // AddCluster creates a new cluster and adds it to the policy
 func (builder *PolicyBuilder) AddCluster() *lang.Cluster {
 	result := &lang.Cluster{
@@ -28,11 +28,66 @@ type Contract struct {
 	// the contract gets matched
 	ChangeLabels LabelOperations `yaml:"change-labels,omitempty" validate:"labelOperations"`
 
+	// Params defines typed input parameters of the contract, which consumers can supply in their dependencies
+	Params []*ContractParam `yaml:"params,omitempty" validate:"dive"`
+
 	// Contexts contains an ordered list of contexts within a contract. When allocating an instance, Aptomi will pick
 	// and instantiate the first context which matches the criteria
+	Contexts []*Context `yaml:"contexts,omitempty" validate:"dive"`
+
+	// Versions contains an ordered list of contract versions, each with its own list of contexts. The last version in
+	// the list is the latest one. Dependencies can pin a specific version, otherwise they will use the latest. If
+	// versions are defined, contexts should not be specified directly in the contract
+	Versions []*ContractVersion `yaml:"versions,omitempty" validate:"dive"`
+}
+
+// ContractVersion is a named version of a contract with its own list of contexts. It allows service owners to evolve
+// the contract without breaking consumers, which depend on the older version
+type ContractVersion struct {
+	// Name defines version name in the policy
+	Name string `validate:"identifier"`
+
+	// Deprecated, if not empty, marks the version as deprecated. It's a message which will be shown to consumers of
+	// this version (e.g. explaining which version to migrate to)
+	Deprecated string `yaml:"deprecated,omitempty"`
+
+	// Contexts contains an ordered list of contexts within a contract version
 	Contexts []*Context `validate:"dive"`
 }
 
+// GetVersions returns the list of contract versions. Contract without versions is considered to have a single
+// unnamed version with all contract contexts
+func (contract *Contract) GetVersions() []*ContractVersion {
+	if len(contract.Versions) <= 0 {
+		return []*ContractVersion{{Contexts: contract.Contexts}}
+	}
+	return contract.Versions
+}
+
+// GetVersion returns contract version by its name. If name is empty, the latest version is returned. If version
+// with a given name doesn't exist, nil is returned
+func (contract *Contract) GetVersion(name string) *ContractVersion {
+	versions := contract.GetVersions()
+	if len(name) <= 0 {
+		return versions[len(versions)-1]
+	}
+	for _, version := range versions {
+		if version.Name == name {
+			return version
+		}
+	}
+	return nil
+}
+
+// GetAllContexts returns contexts from all versions of the contract
+func (contract *Contract) GetAllContexts() []*Context {
+	result := []*Context{}
+	for _, version := range contract.GetVersions() {
+		result = append(result, version.Contexts...)
+	}
+	return result
+}
+
 // Context represents a single context within a service contract.
 // It's essentially a service instance for a given of class of use cases, a given set of consumers, etc.
 type Context struct {
@@ -77,6 +132,15 @@ func (context *Context) Matches(params *expression.Parameters, cache *expression
 	return context.Criteria.allows(params, cache)
 }
 
+// MatchesBranch checks if context criteria is satisfied, and also returns a description of the criteria branch
+// which got matched (empty if there are no alternatives in the criteria)
+func (context *Context) MatchesBranch(params *expression.Parameters, cache *expression.Cache) (bool, string, error) {
+	if context.Criteria == nil {
+		return true, "", nil
+	}
+	return context.Criteria.match(params, cache)
+}
+
 // ResolveKeys resolves dynamic allocation keys, which later get added to component instance key
 func (context *Context) ResolveKeys(params *template.Parameters, cache *template.Cache) ([]string, error) {
 	if cache == nil {
@@ -0,0 +1,153 @@
+package lang
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"math"
+	"strconv"
+)
+
+// Types of contract params
+const (
+	ParamTypeString = "string"
+	ParamTypeInt    = "int"
+	ParamTypeFloat  = "float"
+	ParamTypeBool   = "bool"
+)
+
+// ContractParam is a typed input parameter of a contract. Consumers supply param values via Dependency.Params, and
+// those values become available in context criteria, component criteria and templates as 'Params'
+type ContractParam struct {
+	// Name defines param name
+	Name string `validate:"identifier"`
+
+	// Type of the param (one of string, int, float, bool). If not specified, string is assumed
+	Type string `yaml:"type,omitempty" validate:"omitempty,paramtype"`
+
+	// Default is a default value of the param, which is used when consumer doesn't supply the value
+	Default interface{} `yaml:"default,omitempty"`
+
+	// Required indicates that consumer must supply the value (if there is no default value)
+	Required bool `yaml:"required,omitempty"`
+
+	// Allowed is an optional list of values, which the param is allowed to take
+	Allowed []interface{} `yaml:"allowed,omitempty"`
+}
+
+// GetType returns param type, defaulting to string
+func (param *ContractParam) GetType() string {
+	if len(param.Type) <= 0 {
+		return ParamTypeString
+	}
+	return param.Type
+}
+
+// Convert checks that the given value conforms to the param type and its list of allowed values, and returns the
+// value converted to the param type (string, int, float64 or bool)
+func (param *ContractParam) Convert(value interface{}) (interface{}, error) {
+	result, err := convertParamValue(param.GetType(), value)
+	if err != nil {
+		return nil, fmt.Errorf("param '%s': %s", param.Name, err)
+	}
+
+	if len(param.Allowed) > 0 {
+		for _, allowedValue := range param.Allowed {
+			allowed, err := convertParamValue(param.GetType(), allowedValue)
+			if err == nil && allowed == result {
+				return result, nil
+			}
+		}
+		return nil, fmt.Errorf("param '%s': value '%v' is not allowed, must be in %v", param.Name, value, param.Allowed)
+	}
+
+	return result, nil
+}
+
+// Converts value to the given param type
+func convertParamValue(paramType string, value interface{}) (interface{}, error) {
+	switch paramType {
+	case ParamTypeString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int, int64, float64, bool:
+			return fmt.Sprintf("%v", v), nil
+		}
+	case ParamTypeInt:
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return int(v), nil
+			}
+		case string:
+			if vInt, err := strconv.Atoi(v); err == nil {
+				return vInt, nil
+			}
+		}
+	case ParamTypeFloat:
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			if vFloat, err := strconv.ParseFloat(v, 64); err == nil {
+				return vFloat, nil
+			}
+		}
+	case ParamTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if vBool, err := strconv.ParseBool(v); err == nil {
+				return vBool, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown type '%s'", paramType)
+	}
+	return nil, fmt.Errorf("value '%v' is not of type '%s'", value, paramType)
+}
+
+// ResolveParams takes param values supplied by a consumer and returns the full set of contract params. It applies
+// default values, converts values to their declared types, and returns an error if a param is not declared in the
+// contract, a required param is missing, or a value is not of the declared type or not allowed
+func (contract *Contract) ResolveParams(values map[string]interface{}) (map[string]interface{}, error) {
+	declared := make(map[string]*ContractParam)
+	for _, param := range contract.Params {
+		declared[param.Name] = param
+	}
+	for _, name := range util.GetSortedStringKeys(values) {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("param '%s' is not declared in contract '%s'", name, contract.Name)
+		}
+	}
+
+	result := make(map[string]interface{})
+	for _, param := range contract.Params {
+		value, ok := values[param.Name]
+		if !ok || value == nil {
+			value = param.Default
+		}
+		if value == nil {
+			if param.Required {
+				return nil, fmt.Errorf("param '%s' is required by contract '%s'", param.Name, contract.Name)
+			}
+			continue
+		}
+
+		converted, err := param.Convert(value)
+		if err != nil {
+			return nil, err
+		}
+		result[param.Name] = converted
+	}
+	return result, nil
+}
@@ -195,6 +195,55 @@ func TestServiceContextEmptyCriteria(t *testing.T) {
 	matchContext(t, context, paramsMatch, nil, nil)
 }
 
+func TestServiceContextNestedCriteria(t *testing.T) {
+	// (env == 'prod') && ((team == 'a' && zone == 'us') || team == 'b' || !(zone == 'eu' || zone == 'asia'))
+	context := &Context{
+		Name: "nested",
+		Criteria: &Criteria{
+			RequireAll: []string{"env == 'prod'"},
+			RequireAnyOf: []*Criteria{
+				{RequireAll: []string{"team == 'a'", "zone == 'us'"}},
+				{RequireAny: []string{"team == 'b'"}},
+				{RequireNoneOf: []*Criteria{{RequireAny: []string{"zone == 'eu'", "zone == 'asia'"}}}},
+			},
+		},
+	}
+	paramsMatch := []*expression.Parameters{
+		expression.NewParams(map[string]string{"env": "prod", "team": "a", "zone": "us"}, nil),
+		expression.NewParams(map[string]string{"env": "prod", "team": "b", "zone": "eu"}, nil),
+		expression.NewParams(map[string]string{"env": "prod", "team": "c", "zone": "africa"}, nil),
+	}
+	paramsDoesntMatch := []*expression.Parameters{
+		expression.NewParams(map[string]string{"env": "dev", "team": "a", "zone": "us"}, nil),
+		expression.NewParams(map[string]string{"env": "prod", "team": "a", "zone": "eu"}, nil),
+		expression.NewParams(map[string]string{"env": "prod", "team": "c", "zone": "asia"}, nil),
+	}
+	matchContext(t, context, paramsMatch, paramsDoesntMatch, nil)
+
+	// check which branch got matched
+	branches := []string{
+		"require-any-of[0]",
+		"require-any-of[1] > require-any[0] 'team == 'b''",
+		"require-any-of[2]",
+	}
+	for idx, params := range paramsMatch {
+		matched, branch, err := context.MatchesBranch(params, nil)
+		assert.NoError(t, err, "Context should be matched without errors")
+		assert.True(t, matched, "Context should be matched")
+		assert.Equal(t, branches[idx], branch, "Matched branch should be returned")
+	}
+
+	// criteria without alternatives has no branches
+	matched, branch, err := (&Context{Criteria: &Criteria{RequireAll: []string{"true"}}}).MatchesBranch(paramsMatch[0], nil)
+	assert.NoError(t, err, "Context should be matched without errors")
+	assert.True(t, matched, "Context should be matched")
+	assert.Empty(t, branch, "Matched branch should be empty")
+
+	// nested groups are checked when looking for an expression
+	assert.True(t, context.Criteria.Contains("zone == 'asia'"), "Criteria should contain nested expression")
+	assert.False(t, context.Criteria.Contains("zone == 'africa'"), "Criteria should not contain unknown expression")
+}
+
 func makeInvalidContexts() []*Context {
 	return []*Context{
 		{
@@ -216,6 +265,12 @@ func makeInvalidContexts() []*Context {
 				RequireNone: []string{"specialname + '789')((("},
 			},
 		},
+		{
+			Name: "special-invalid-context-nested",
+			Criteria: &Criteria{
+				RequireAnyOf: []*Criteria{{RequireAll: []string{"specialname + '000')((("}}},
+			},
+		},
 	}
 }
 
@@ -286,3 +341,62 @@ func TestServiceContextKeyResolution(t *testing.T) {
 	evalKeys(t, context, paramFailure, true, nil, nil)
 	evalKeys(t, context, paramFailure, true, nil, cache)
 }
+
+func TestContractVersions(t *testing.T) {
+	// contract without versions has a single unnamed version
+	contract := &Contract{
+		Contexts: []*Context{{Name: "context"}},
+	}
+	assert.Len(t, contract.GetVersions(), 1, "Contract without versions should have a single implicit version")
+	assert.Equal(t, "", contract.GetVersion("").Name, "Implicit version should be unnamed")
+	assert.Len(t, contract.GetVersion("").Contexts, 1, "Implicit version should contain contract contexts")
+	assert.Nil(t, contract.GetVersion("v1"), "Named version should not exist in contract without versions")
+
+	// contract with versions
+	contract = &Contract{
+		Versions: []*ContractVersion{
+			{Name: "v1", Deprecated: "use v2", Contexts: []*Context{{Name: "a"}, {Name: "b"}}},
+			{Name: "v2", Contexts: []*Context{{Name: "c"}}},
+		},
+	}
+	assert.Equal(t, "v2", contract.GetVersion("").Name, "Latest version should be returned when name is empty")
+	assert.Equal(t, "v1", contract.GetVersion("v1").Name, "Version should be returned by name")
+	assert.Equal(t, "use v2", contract.GetVersion("v1").Deprecated, "Deprecation message should be preserved")
+	assert.Nil(t, contract.GetVersion("v3"), "Unknown version should not be returned")
+	assert.Len(t, contract.GetAllContexts(), 3, "Contexts from all versions should be returned")
+}
+
+func TestContractResolveParams(t *testing.T) {
+	contract := &Contract{
+		Metadata: Metadata{Name: "database"},
+		Params: []*ContractParam{
+			{Name: "size", Default: "small", Allowed: []interface{}{"small", "large"}},
+			{Name: "replicas", Type: ParamTypeInt, Default: 1},
+			{Name: "ratio", Type: ParamTypeFloat},
+			{Name: "backup", Type: ParamTypeBool, Required: true},
+		},
+	}
+
+	// defaults should be applied and values should be converted to declared types
+	params, err := contract.ResolveParams(map[string]interface{}{"backup": "true", "ratio": 2})
+	assert.NoError(t, err, "Params should be resolved")
+	assert.Equal(t, map[string]interface{}{"size": "small", "replicas": 1, "ratio": 2.0, "backup": true}, params, "Params should be resolved with defaults")
+
+	params, err = contract.ResolveParams(map[string]interface{}{"backup": false, "size": "large", "replicas": 3.0})
+	assert.NoError(t, err, "Params should be resolved")
+	assert.Equal(t, map[string]interface{}{"size": "large", "replicas": 3, "backup": false}, params, "Params should be resolved with supplied values")
+
+	// invalid values should be rejected
+	invalid := []map[string]interface{}{
+		nil,
+		{"backup": true, "size": "medium"},
+		{"backup": true, "replicas": 1.5},
+		{"backup": true, "replicas": "many"},
+		{"backup": "maybe"},
+		{"backup": true, "unknown": "value"},
+	}
+	for _, values := range invalid {
+		_, err = contract.ResolveParams(values)
+		assert.Error(t, err, "Params should not be resolved: %v", values)
+	}
+}
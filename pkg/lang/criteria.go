@@ -1,21 +1,24 @@
 package lang
 
 import (
+	"fmt"
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"strings"
 )
 
 // Criteria is a structure which allows users to define complex matching expressions in the policy. Criteria
 // expressions can refer to labels through variables. It supports require-all, require-any and require-none clauses,
-// with a list of expressions under each clause.
+// with a list of expressions under each clause. Each clause can also contain a list of nested criteria groups
+// (require-all-of, require-any-of, require-none-of), which allows to combine all/any/none logic recursively.
 //
 // Criteria gets evaluated to true only when
-// (1) All RequireAll expression evaluate to true,
-// (2) At least one of RequireAny expressions evaluates to true,
-// (3) None of RequireNone expressions evaluate to true.
+// (1) All RequireAll expressions and all RequireAllOf groups evaluate to true,
+// (2) At least one of RequireAny expressions or RequireAnyOf groups evaluates to true,
+// (3) None of RequireNone expressions and none of RequireNoneOf groups evaluate to true.
 //
-// If any of RequireAll, RequireAny, RequireNone are absent, the corresponding clause will be skipped. So it's
-// perfectly fine to have a criteria with fewer than 3 clauses (e.g. just RequireAll), or with no sections at all. Empty
-// criteria without any clauses always evaluates to true
+// If any of the clauses are absent, the corresponding clause will be skipped. So it's perfectly fine to have a
+// criteria with fewer than 3 clauses (e.g. just RequireAll), or with no sections at all. Empty criteria without any
+// clauses always evaluates to true
 type Criteria struct {
 	// RequireAll follows 'AND' logic
 	RequireAll []string `yaml:"require-all,omitempty" validate:"dive,expression"`
@@ -25,19 +28,51 @@ type Criteria struct {
 
 	// RequireNone follows 'AND NOT'
 	RequireNone []string `yaml:"require-none,omitempty" validate:"dive,expression"`
+
+	// RequireAllOf is a list of nested criteria groups, following 'AND' logic together with RequireAll
+	RequireAllOf []*Criteria `yaml:"require-all-of,omitempty" validate:"omitempty,dive"`
+
+	// RequireAnyOf is a list of nested criteria groups, following 'OR' logic together with RequireAny
+	RequireAnyOf []*Criteria `yaml:"require-any-of,omitempty" validate:"omitempty,dive"`
+
+	// RequireNoneOf is a list of nested criteria groups, following 'AND NOT' logic together with RequireNone
+	RequireNoneOf []*Criteria `yaml:"require-none-of,omitempty" validate:"omitempty,dive"`
 }
 
 // Returns whether criteria evaluates to "true", given a set of parameters for its expressions and a cache
 func (criteria *Criteria) allows(params *expression.Parameters, cache *expression.Cache) (bool, error) {
+	result, _, err := criteria.match(params, cache)
+	return result, err
+}
+
+// Returns whether criteria evaluates to "true", given a set of parameters for its expressions and a cache. If it does,
+// it also returns a human-readable description of the branch which got matched, i.e. which of require-any expressions
+// and groups evaluated to true (e.g. "require-any-of[1] > require-any[0] 'team == "dev"'"). Description is empty if
+// criteria has no require-any clauses
+func (criteria *Criteria) match(params *expression.Parameters, cache *expression.Cache) (bool, string, error) {
+	branch := []string{}
+
 	// Make sure all "require-all" criteria evaluate to true
 	for _, exprShouldBeTrue := range criteria.RequireAll {
 		result, err := criteria.evaluateBool(exprShouldBeTrue, params, cache)
 		if err != nil {
 			// propagate expression error up, if happened
-			return false, err
+			return false, "", err
+		}
+		if !result {
+			return false, "", nil
+		}
+	}
+	for idx, groupShouldBeTrue := range criteria.RequireAllOf {
+		result, groupBranch, err := groupShouldBeTrue.match(params, cache)
+		if err != nil {
+			return false, "", err
 		}
 		if !result {
-			return false, nil
+			return false, "", nil
+		}
+		if len(groupBranch) > 0 {
+			branch = append(branch, fmt.Sprintf("require-all-of[%d] > %s", idx, groupBranch))
 		}
 	}
 
@@ -46,32 +81,60 @@ func (criteria *Criteria) allows(params *expression.Parameters, cache *expressio
 		result, err := criteria.evaluateBool(exprShouldBeFalse, params, cache)
 		if err != nil {
 			// propagate expression error up, if happened
-			return false, err
+			return false, "", err
+		}
+		if result {
+			return false, "", nil
+		}
+	}
+	for _, groupShouldBeFalse := range criteria.RequireNoneOf {
+		result, _, err := groupShouldBeFalse.match(params, cache)
+		if err != nil {
+			return false, "", err
 		}
 		if result {
-			return false, nil
+			return false, "", nil
 		}
 	}
 
 	// Make sure at least one "require-any" criteria evaluates to true
-	if len(criteria.RequireAny) > 0 {
-		for _, exprShouldBeTrue := range criteria.RequireAny {
+	if len(criteria.RequireAny) > 0 || len(criteria.RequireAnyOf) > 0 {
+		matched := false
+		for idx, exprShouldBeTrue := range criteria.RequireAny {
 			result, err := criteria.evaluateBool(exprShouldBeTrue, params, cache)
 			if err != nil {
 				// propagate expression error up, if happened
-				return false, err
+				return false, "", err
+			}
+			if result {
+				branch = append(branch, fmt.Sprintf("require-any[%d] '%s'", idx, exprShouldBeTrue))
+				matched = true
+				break
+			}
+		}
+		for idx := 0; !matched && idx < len(criteria.RequireAnyOf); idx++ {
+			result, groupBranch, err := criteria.RequireAnyOf[idx].match(params, cache)
+			if err != nil {
+				return false, "", err
 			}
 			if result {
-				return true, nil
+				if len(groupBranch) > 0 {
+					branch = append(branch, fmt.Sprintf("require-any-of[%d] > %s", idx, groupBranch))
+				} else {
+					branch = append(branch, fmt.Sprintf("require-any-of[%d]", idx))
+				}
+				matched = true
 			}
 		}
 
 		// If no criteria got evaluated to true, return false
-		return false, nil
+		if !matched {
+			return false, "", nil
+		}
 	}
 
-	// Everything is fine and "require-any" is empty, let's return true
-	return true, nil
+	// Everything is fine, let's return true
+	return true, strings.Join(branch, ", "), nil
 }
 
 // Evaluates bool expression, given a set of parameters and a cache. If cache is nil, it will still be evaluated
@@ -82,3 +145,25 @@ func (criteria *Criteria) evaluateBool(expressionStr string, params *expression.
 	}
 	return cache.EvaluateAsBool(expressionStr, params)
 }
+
+// Contains returns true if the given expression is used in any of the criteria clauses
+func (criteria *Criteria) Contains(expressionStr string) bool {
+	if criteria == nil {
+		return false
+	}
+	for _, clause := range [][]string{criteria.RequireAll, criteria.RequireAny, criteria.RequireNone} {
+		for _, expr := range clause {
+			if expr == expressionStr {
+				return true
+			}
+		}
+	}
+	for _, groups := range [][]*Criteria{criteria.RequireAllOf, criteria.RequireAnyOf, criteria.RequireNoneOf} {
+		for _, group := range groups {
+			if group.Contains(expressionStr) {
+				return true
+			}
+		}
+	}
+	return false
+}
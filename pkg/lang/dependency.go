@@ -28,6 +28,14 @@ type Dependency struct {
 	// namespace.
 	Contract string `validate:"required"`
 
+	// Version of the contract that is being requested. If not specified, the latest version of the contract will
+	// be used
+	Version string `yaml:"version,omitempty"`
+
 	// Labels which are provided by the user.
 	Labels map[string]string `yaml:"labels,omitempty" validate:"omitempty,labels"`
+
+	// Params which are provided by the user. They have to be declared in the contract, and will be checked against
+	// the types declared there
+	Params map[string]interface{} `yaml:"params,omitempty"`
 }
@@ -1,15 +1,62 @@
 package expression
 
-import "sync"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MissingParamsMode defines what happens when expression refers to a parameter which has not been supplied for
+// evaluation (e.g. a label with a typo in its name)
+type MissingParamsMode string
+
+const (
+	// MissingParamsIgnore means that expressions with missing parameters silently evaluate to false
+	MissingParamsIgnore MissingParamsMode = "ignore"
+
+	// MissingParamsWarn means that expressions with missing parameters evaluate to false, but get recorded in the
+	// cache so they can be reported as warnings
+	MissingParamsWarn MissingParamsMode = "warn"
+
+	// MissingParamsError means that expressions with missing parameters fail to evaluate with MissingParameterError
+	MissingParamsError MissingParamsMode = "error"
+)
+
+// ParseMissingParamsMode parses and validates mode for handling missing parameters. Empty string means MissingParamsIgnore
+func ParseMissingParamsMode(value string) (MissingParamsMode, error) {
+	mode := MissingParamsMode(value)
+	if len(mode) <= 0 {
+		return MissingParamsIgnore, nil
+	}
+	if mode != MissingParamsIgnore && mode != MissingParamsWarn && mode != MissingParamsError {
+		return MissingParamsIgnore, fmt.Errorf("invalid mode for handling missing parameters in expressions '%s', must be in %s", mode, []MissingParamsMode{MissingParamsIgnore, MissingParamsWarn, MissingParamsError})
+	}
+	return mode, nil
+}
 
 // Cache is a thread-safe cache of compiled expressions
 type Cache struct {
-	eCache sync.Map
+	eCache *sync.Map
+
+	// how missing parameters are handled and which ones were encountered (in MissingParamsWarn mode)
+	mode          MissingParamsMode
+	missingParams sync.Map
 }
 
-// NewCache creates a new thread-safe Cache
+// NewCache creates a new thread-safe Cache, which silently ignores missing parameters
 func NewCache() *Cache {
-	return &Cache{eCache: sync.Map{}}
+	return NewCacheWithMode(MissingParamsIgnore)
+}
+
+// NewCacheWithMode creates a new thread-safe Cache, which handles missing parameters according to the given mode
+func NewCacheWithMode(mode MissingParamsMode) *Cache {
+	return &Cache{eCache: &sync.Map{}, mode: mode}
+}
+
+// NewScope creates a new thread-safe Cache, which shares compiled expressions and the mode of handling missing
+// parameters with the given cache, but records missing parameters separately (e.g. per resolved dependency)
+func (cache *Cache) NewScope() *Cache {
+	return &Cache{eCache: cache.eCache, mode: cache.mode}
 }
 
 // EvaluateAsBool evaluates boolean expression given a set of parameters.
@@ -35,5 +82,52 @@ func (cache *Cache) EvaluateAsBool(expressionStr string, params *Parameters) (bo
 
 	// Evaluate expression
 	// This seems to be thread safe
-	return expression.EvaluateAsBool(params)
+	result, err := expression.evaluateAsBool(params)
+	if missingErr, ok := err.(*MissingParameterError); ok {
+		switch cache.mode {
+		case MissingParamsError:
+			return false, missingErr
+		case MissingParamsWarn:
+			cache.missingParams.Store(missingErr.Error(), missingErr)
+		}
+		return false, nil
+	}
+	return result, err
+}
+
+// GetMissingParameters returns a sorted list of errors for expressions, which referred to missing parameters
+// while being evaluated (only recorded in MissingParamsWarn mode)
+func (cache *Cache) GetMissingParameters() []*MissingParameterError {
+	result := []*MissingParameterError{}
+	cache.missingParams.Range(func(key, value interface{}) bool {
+		result = append(result, value.(*MissingParameterError))
+		return true
+	})
+	sort.Sort(missingParamsSorter(result))
+	return result
+}
+
+// AddMissingParameters records errors for expressions, which referred to missing parameters while being evaluated
+// elsewhere (e.g. in another scope). They only get recorded in MissingParamsWarn mode
+func (cache *Cache) AddMissingParameters(missingErrs []*MissingParameterError) {
+	if cache.mode != MissingParamsWarn {
+		return
+	}
+	for _, missingErr := range missingErrs {
+		cache.missingParams.Store(missingErr.Error(), missingErr)
+	}
+}
+
+type missingParamsSorter []*MissingParameterError
+
+func (s missingParamsSorter) Len() int {
+	return len(s)
+}
+
+func (s missingParamsSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s missingParamsSorter) Less(i, j int) bool {
+	return s[i].Error() < s[j].Error()
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/errors"
 	"github.com/ralekseenkov/govaluate"
+	"sort"
 )
 
 // Expression struct contains expression string as well as its compiled version
@@ -14,40 +15,96 @@ type Expression struct {
 
 // NewExpression compiles an expression and returns the result in Expression struct
 // Parameter expressionStr must follow syntax defined by https://github.com/Knetic/govaluate
+// In addition to that, functions from the Aptomi function library can be used (see functions.go)
 func NewExpression(expressionStr string) (*Expression, error) {
-	functions := map[string]govaluate.ExpressionFunction{
-		"in": func(args ...interface{}) (interface{}, error) {
-			if len(args) == 0 {
-				return nil, fmt.Errorf("can't evaluate in() function when zero arguments supplied")
-			}
-			v := args[0]
-			for i := 1; i < len(args); i++ {
-				if v == args[i] {
-					return true, nil
-				}
-			}
-			return false, nil
-		},
+	expressionCompiled, err := govaluate.NewEvaluableExpressionWithFunctions(expressionStr, functionMap)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile expression '%s': %s", expressionStr, err)
 	}
 
-	expressionCompiled, err := govaluate.NewEvaluableExpressionWithFunctions(expressionStr, functions)
+	// check function calls and pass parameters into functions which need them
+	tokens, changed, err := processFunctionCalls(expressionCompiled.Tokens())
 	if err != nil {
 		return nil, fmt.Errorf("unable to compile expression '%s': %s", expressionStr, err)
 	}
+	if changed {
+		expressionCompiled, err = govaluate.NewEvaluableExpressionFromTokens(tokens)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile expression '%s': %s", expressionStr, err)
+		}
+	}
+
 	return &Expression{
 		expressionStr:      expressionStr,
 		expressionCompiled: expressionCompiled,
 	}, nil
 }
 
-// EvaluateAsBool evaluates a compiled boolean expression given a set of named parameters
+// MissingParameterError is returned when expression refers to a parameter, which has not been supplied for evaluation
+type MissingParameterError struct {
+	// Expression is the expression being evaluated
+	Expression string
+
+	// Cause is the underlying error, which contains the name of the missing parameter
+	Cause error
+}
+
+func (err *MissingParameterError) Error() string {
+	return fmt.Sprintf("expression '%s' refers to a missing parameter: %s", err.Expression, err.Cause)
+}
+
+// StructRefs returns a sorted list of unique variable names, which are accessed as structs by the expression
+// (e.g. 'Service' for 'Service.Name == "foo"')
+func (expression *Expression) StructRefs() []string {
+	refs := make(map[string]bool)
+	for _, token := range expression.expressionCompiled.Tokens() {
+		if token.Kind == govaluate.ACCESSOR {
+			refs[token.Value.([]string)[0]] = true
+		}
+	}
+	result := []string{}
+	for ref := range refs {
+		result = append(result, ref)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Vars returns a sorted list of unique variable names, which are referred to by the expression (e.g. 'team' for
+// 'team == "dev"'). Variables accessed as structs are not included, see StructRefs
+func (expression *Expression) Vars() []string {
+	refs := make(map[string]bool)
+	for _, token := range expression.expressionCompiled.Tokens() {
+		if token.Kind == govaluate.VARIABLE && token.Value != paramsVariable {
+			refs[token.Value.(string)] = true
+		}
+	}
+	result := []string{}
+	for ref := range refs {
+		result = append(result, ref)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// EvaluateAsBool evaluates a compiled boolean expression given a set of named parameters. If expression refers
+// to a missing parameter, it will be evaluated to false
 func (expression *Expression) EvaluateAsBool(params *Parameters) (bool, error) {
+	result, err := expression.evaluateAsBool(params)
+	if _, ok := err.(*MissingParameterError); ok {
+		return false, nil
+	}
+	return result, err
+}
+
+// Evaluates a compiled boolean expression given a set of named parameters. If expression refers to a missing
+// parameter, MissingParameterError will be returned
+func (expression *Expression) evaluateAsBool(params *Parameters) (bool, error) {
 	// Evaluate
-	result, err := expression.expressionCompiled.Evaluate(*params)
+	result, err := expression.expressionCompiled.Eval(evalParameters{params})
 	if err != nil {
-		// Return false and swallow the error if we encountered a missing parameter
 		if _, ok := err.(*govaluate.MissingParameterError); ok {
-			return false, nil
+			return false, &MissingParameterError{Expression: expression.expressionStr, Cause: err}
 		}
 		return false, errors.NewErrorWithDetails(
 			fmt.Sprintf("unable to evaluate expression '%s': %s", expression.expressionStr, err),
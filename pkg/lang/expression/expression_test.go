@@ -126,3 +126,151 @@ func TestExpressions(t *testing.T) {
 		evaluateWithCache(t, test.expression, params, test.result, cache)
 	}
 }
+
+func TestExpressionFunctions(t *testing.T) {
+	params := NewParams(
+		map[string]string{
+			"team":    "Platform-Team",
+			"region":  "us-east-1",
+			"version": "1.10.2",
+			"old":     "v1.9",
+			"zones":   "a,b,c",
+			"ip":      "10.0.3.17",
+			"replica": "3",
+		},
+		nil,
+	)
+
+	tests := []struct {
+		expression string
+		result     int
+	}{
+		// label existence
+		{"has('team')", ResTrue},
+		{"has('missing')", ResFalse},
+		{"!has('missing') && lower(team) == 'platform-team'", ResTrue},
+		{"has(replica)", ResEvalError},
+
+		// string functions
+		{"matches(region, '^us-(east|west)-[0-9]+$')", ResTrue},
+		{"matches(region, '^eu-')", ResFalse},
+		{"matches(region, '(')", ResCompileError},
+		{"hasPrefix(region, 'us-')", ResTrue},
+		{"hasSuffix(region, '-2')", ResFalse},
+		{"contains(team, 'Team')", ResTrue},
+		{"upper(region) == 'US-EAST-1'", ResTrue},
+		{"trim('  x ') == 'x'", ResTrue},
+		{"hasPrefix(replica, '3')", ResTrue},
+
+		// lists
+		{"len(split(zones, ',')) == 3", ResTrue},
+		{"len(split(team, ',')) == 1", ResTrue},
+		{"len(region) == 9", ResTrue},
+		{"in('b', split(zones, ','))", ResTrue},
+		{"contains(split(zones, ','), 'd')", ResFalse},
+
+		// semantic versions
+		{"semverGE(version, '1.9.0')", ResTrue},
+		{"semverGT(version, old)", ResTrue},
+		{"semverLT('1.0.0-beta', '1.0.0')", ResTrue},
+		{"semverLT('1.0.0-rc.2', '1.0.0-rc.10')", ResTrue},
+		{"semverLT('1.0.0-alpha', '1.0.0-alpha.1')", ResTrue},
+		{"semverLT('1.0.0-alpha.1', '1.0.0-alpha.beta')", ResTrue},
+		{"semverLT('1.0.0-beta.11', '1.0.0-rc.1')", ResTrue},
+		{"semverGE('1.0.0-rc.1', '1.0.0-rc.1+build')", ResTrue},
+		{"semverLE(version, '1.10.2+build')", ResTrue},
+		{"semverGE(version, 'latest')", ResEvalError},
+
+		// networks
+		{"inCIDR(ip, '10.0.0.0/16')", ResTrue},
+		{"inCIDR(ip, '192.168.0.0/16')", ResFalse},
+		{"inCIDR(ip, '10.0.0.0/33')", ResEvalError},
+
+		// wrong number of arguments and unknown functions
+		{"matches(region)", ResCompileError},
+		{"lower(team, region) == 'x'", ResCompileError},
+		{"has()", ResCompileError},
+		{"unknownFunc(team)", ResCompileError},
+	}
+
+	for _, test := range tests {
+		evaluate(t, test.expression, params, test.result)
+	}
+
+	cache := NewCache()
+	for _, test := range tests {
+		evaluateWithCache(t, test.expression, params, test.result, cache)
+	}
+}
+
+func TestExpressionMissingParams(t *testing.T) {
+	params := NewParams(
+		map[string]string{
+			"team": "dev",
+		},
+		map[string]interface{}{
+			"Service": struct {
+				Name string
+			}{
+				"myservicename",
+			},
+		},
+	)
+
+	expressions := []string{
+		"tem == 'dev'",
+		"Service.Nme == 'myservicename'",
+		"team == 'dev' && Servce.Name == 'myservicename'",
+	}
+
+	// missing parameters are silently ignored
+	cache := NewCacheWithMode(MissingParamsIgnore)
+	for _, expressionStr := range expressions {
+		evaluateWithCache(t, expressionStr, params, ResFalse, cache)
+	}
+	assert.Empty(t, cache.GetMissingParameters(), "Missing parameters should not be recorded in ignore mode")
+
+	// missing parameters are recorded as warnings
+	cache = NewCacheWithMode(MissingParamsWarn)
+	for _, expressionStr := range expressions {
+		evaluateWithCache(t, expressionStr, params, ResFalse, cache)
+	}
+	evaluateWithCache(t, "!has('tem') || tem == 'dev'", params, ResTrue, cache)
+	missing := cache.GetMissingParameters()
+	if assert.Equal(t, len(expressions), len(missing), "Every expression with a missing parameter should be recorded once") {
+		assert.Equal(t, "Service.Nme == 'myservicename'", missing[0].Expression, "Missing parameters should be sorted")
+	}
+
+	// missing parameters result in errors
+	cache = NewCacheWithMode(MissingParamsError)
+	for _, expressionStr := range expressions {
+		_, err := cache.EvaluateAsBool(expressionStr, params)
+		if assert.IsType(t, &MissingParameterError{}, err, "Missing parameter should result in an error: %s", expressionStr) {
+			assert.Equal(t, expressionStr, err.(*MissingParameterError).Expression, "Error should contain the expression")
+		}
+	}
+	evaluateWithCache(t, "!has('tem') || tem == 'dev'", params, ResTrue, cache)
+
+	// invalid mode
+	_, err := ParseMissingParamsMode("strict")
+	assert.Error(t, err, "Invalid mode should not be accepted")
+
+	// empty mode
+	mode, err := ParseMissingParamsMode("")
+	assert.NoError(t, err, "Empty mode should be accepted")
+	assert.Equal(t, MissingParamsIgnore, mode, "Empty mode should mean that missing parameters are ignored")
+}
+
+func TestExpressionStructRefs(t *testing.T) {
+	expr, err := NewExpression("Service.Name == 'a' && (Dependency.ID == 'b' || Service.Labels.x == 'c') && team == 'd'")
+	if assert.NoError(t, err, "Expression should be compiled") {
+		assert.Equal(t, []string{"Dependency", "Service"}, expr.StructRefs(), "Struct references should be returned")
+		assert.Equal(t, []string{"team"}, expr.Vars(), "Variables should be returned")
+	}
+
+	expr, err = NewExpression("has('owner') && (zone == 'a' || team == 'b' || zone == 'c')")
+	if assert.NoError(t, err, "Expression should be compiled") {
+		assert.Equal(t, []string{"team", "zone"}, expr.Vars(), "Variables should be returned, hidden function params should be skipped")
+		assert.Equal(t, []string{}, expr.StructRefs(), "Struct references should be empty")
+	}
+}
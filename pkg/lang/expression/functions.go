@@ -0,0 +1,465 @@
+package expression
+
+import (
+	"fmt"
+	"github.com/ralekseenkov/govaluate"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// paramsVariable is a name of the hidden variable, which gets passed as the first argument into functions which need
+// access to the whole set of parameters (e.g. has). It can't be referred to from the policy, as it's not a valid
+// variable name for the expression parser
+const paramsVariable = "$params"
+
+// function is a function available in expressions
+type function struct {
+	// name of the function, as it should be called from the expression
+	name string
+
+	// minArgs and maxArgs define how many arguments function expects (maxArgs < 0 means unlimited)
+	minArgs int
+	maxArgs int
+
+	// withParams indicates that function needs expression parameters passed as its first argument
+	withParams bool
+
+	// impl is the actual implementation of the function
+	impl govaluate.ExpressionFunction
+}
+
+// functions is a list of all functions available in expressions
+var functions = []*function{
+	{name: "in", minArgs: 0, maxArgs: -1, impl: funcIn},
+	{name: "has", minArgs: 1, maxArgs: 1, withParams: true, impl: funcHas},
+	{name: "matches", minArgs: 2, maxArgs: 2, impl: funcMatches},
+	{name: "hasPrefix", minArgs: 2, maxArgs: 2, impl: funcHasPrefix},
+	{name: "hasSuffix", minArgs: 2, maxArgs: 2, impl: funcHasSuffix},
+	{name: "contains", minArgs: 2, maxArgs: 2, impl: funcContains},
+	{name: "lower", minArgs: 1, maxArgs: 1, impl: funcLower},
+	{name: "upper", minArgs: 1, maxArgs: 1, impl: funcUpper},
+	{name: "trim", minArgs: 1, maxArgs: 1, impl: funcTrim},
+	{name: "split", minArgs: 2, maxArgs: 2, impl: funcSplit},
+	{name: "len", minArgs: 1, maxArgs: 1, impl: funcLen},
+	{name: "semverGT", minArgs: 2, maxArgs: 2, impl: funcSemverGT},
+	{name: "semverGE", minArgs: 2, maxArgs: 2, impl: funcSemverGE},
+	{name: "semverLT", minArgs: 2, maxArgs: 2, impl: funcSemverLT},
+	{name: "semverLE", minArgs: 2, maxArgs: 2, impl: funcSemverLE},
+	{name: "inCIDR", minArgs: 2, maxArgs: 2, impl: funcInCIDR},
+}
+
+// functionMap is a map of functions to be passed to the expression parser
+var functionMap = func() map[string]govaluate.ExpressionFunction {
+	result := make(map[string]govaluate.ExpressionFunction)
+	for _, f := range functions {
+		result[f.name] = f.impl
+	}
+	return result
+}()
+
+// functionByImpl allows to look up function by its implementation, since parsed tokens don't hold function names
+var functionByImpl = func() map[uintptr]*function {
+	result := make(map[uintptr]*function)
+	for _, f := range functions {
+		result[reflect.ValueOf(f.impl).Pointer()] = f
+	}
+	return result
+}()
+
+// List is a list of values returned by functions (e.g. split). It's a separate type, so that the expression
+// parser doesn't expand it into multiple function arguments
+type List []interface{}
+
+// Post-processes function calls in parsed tokens: checks the number of arguments, validates literal arguments
+// and passes parameters into functions which need them. Returns modified tokens and whether they have been changed
+func processFunctionCalls(tokens []govaluate.ExpressionToken) ([]govaluate.ExpressionToken, bool, error) {
+	changed := false
+	result := make([]govaluate.ExpressionToken, 0, len(tokens))
+	for idx := 0; idx < len(tokens); idx++ {
+		token := tokens[idx]
+		result = append(result, token)
+		if token.Kind != govaluate.FUNCTION {
+			continue
+		}
+
+		f, ok := functionByImpl[reflect.ValueOf(token.Value).Pointer()]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown function")
+		}
+
+		// function is always followed by its arguments in parentheses
+		if idx+1 >= len(tokens) || tokens[idx+1].Kind != govaluate.CLAUSE {
+			return nil, false, fmt.Errorf("function '%s' must be followed by its arguments in parentheses", f.name)
+		}
+		args := functionArgs(tokens[idx+2:])
+		if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+			return nil, false, fmt.Errorf("function '%s' called with %d arguments, expected %s", f.name, len(args), describeArgsCount(f))
+		}
+
+		// regular expressions which are defined as literals can be checked right away
+		if f.name == "matches" && len(args[1]) == 1 && args[1][0].Kind == govaluate.STRING {
+			_, err := compileRegexp(args[1][0].Value.(string))
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		if f.withParams {
+			result = append(result, tokens[idx+1], govaluate.ExpressionToken{Kind: govaluate.VARIABLE, Value: paramsVariable})
+			if len(args) > 0 {
+				result = append(result, govaluate.ExpressionToken{Kind: govaluate.SEPARATOR, Value: ","})
+			}
+			idx++
+			changed = true
+		}
+	}
+	return result, changed, nil
+}
+
+// Splits tokens into function arguments, given tokens which follow the opening parenthesis of the function call
+func functionArgs(tokens []govaluate.ExpressionToken) [][]govaluate.ExpressionToken {
+	result := [][]govaluate.ExpressionToken{}
+	current := []govaluate.ExpressionToken{}
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.Kind == govaluate.CLAUSE:
+			depth++
+		case token.Kind == govaluate.CLAUSE_CLOSE && depth == 0:
+			if len(current) > 0 || len(result) > 0 {
+				result = append(result, current)
+			}
+			return result
+		case token.Kind == govaluate.CLAUSE_CLOSE:
+			depth--
+		case token.Kind == govaluate.SEPARATOR && depth == 0:
+			result = append(result, current)
+			current = []govaluate.ExpressionToken{}
+			continue
+		}
+		current = append(current, token)
+	}
+	return result
+}
+
+func describeArgsCount(f *function) string {
+	switch {
+	case f.maxArgs < 0:
+		return fmt.Sprintf("at least %d", f.minArgs)
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d", f.minArgs)
+	default:
+		return fmt.Sprintf("from %d to %d", f.minArgs, f.maxArgs)
+	}
+}
+
+// evalParameters exposes expression parameters to the expression parser, including the hidden variable with all
+// parameters for functions which need them
+type evalParameters struct {
+	params *Parameters
+}
+
+func (p evalParameters) Get(name string) (interface{}, error) {
+	if name == paramsVariable {
+		return p.params, nil
+	}
+	return govaluate.MapParameters(*p.params).Get(name)
+}
+
+/*
+	Function implementations
+*/
+
+// in(value, a, b, ...) returns true if value is equal to one of the following arguments. Lists get expanded
+func funcIn(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("can't evaluate in() function when zero arguments supplied")
+	}
+	v := args[0]
+	for i := 1; i < len(args); i++ {
+		if list, ok := args[i].(List); ok {
+			for _, item := range list {
+				if v == item {
+					return true, nil
+				}
+			}
+		} else if v == args[i] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// has('name') returns true if parameter (e.g. label) with a given name is defined
+func funcHas(args ...interface{}) (interface{}, error) {
+	name, err := stringArg("has", args[1])
+	if err != nil {
+		return nil, err
+	}
+	_, ok := (*args[0].(*Parameters))[name]
+	return ok, nil
+}
+
+// matches(value, 'regexp') returns true if value matches regular expression
+func funcMatches(args ...interface{}) (interface{}, error) {
+	pattern, err := stringArg("matches", args[1])
+	if err != nil {
+		return nil, err
+	}
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(toString(args[0])), nil
+}
+
+// hasPrefix(value, prefix) returns true if value starts with prefix
+func funcHasPrefix(args ...interface{}) (interface{}, error) {
+	return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+}
+
+// hasSuffix(value, suffix) returns true if value ends with suffix
+func funcHasSuffix(args ...interface{}) (interface{}, error) {
+	return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+}
+
+// contains(value, substring) returns true if value contains substring. If value is a list, it returns true if list
+// contains an element equal to the second argument
+func funcContains(args ...interface{}) (interface{}, error) {
+	if list, ok := args[0].(List); ok {
+		for _, item := range list {
+			if item == args[1] {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return strings.Contains(toString(args[0]), toString(args[1])), nil
+}
+
+// lower(value) returns value in lower case
+func funcLower(args ...interface{}) (interface{}, error) {
+	return strings.ToLower(toString(args[0])), nil
+}
+
+// upper(value) returns value in upper case
+func funcUpper(args ...interface{}) (interface{}, error) {
+	return strings.ToUpper(toString(args[0])), nil
+}
+
+// trim(value) returns value with leading and trailing whitespace removed
+func funcTrim(args ...interface{}) (interface{}, error) {
+	return strings.TrimSpace(toString(args[0])), nil
+}
+
+// split(value, separator) splits value into a list of strings
+func funcSplit(args ...interface{}) (interface{}, error) {
+	result := List{}
+	for _, item := range strings.Split(toString(args[0]), toString(args[1])) {
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// len(value) returns length of a string or a list
+func funcLen(args ...interface{}) (interface{}, error) {
+	if list, ok := args[0].(List); ok {
+		return float64(len(list)), nil
+	}
+	return float64(len(toString(args[0]))), nil
+}
+
+// semverGT(a, b) returns true if version a is greater than version b
+func funcSemverGT(args ...interface{}) (interface{}, error) {
+	cmp, err := compareSemverArgs("semverGT", args)
+	return cmp > 0, err
+}
+
+// semverGE(a, b) returns true if version a is greater than or equal to version b
+func funcSemverGE(args ...interface{}) (interface{}, error) {
+	cmp, err := compareSemverArgs("semverGE", args)
+	return cmp >= 0, err
+}
+
+// semverLT(a, b) returns true if version a is less than version b
+func funcSemverLT(args ...interface{}) (interface{}, error) {
+	cmp, err := compareSemverArgs("semverLT", args)
+	return cmp < 0, err
+}
+
+// semverLE(a, b) returns true if version a is less than or equal to version b
+func funcSemverLE(args ...interface{}) (interface{}, error) {
+	cmp, err := compareSemverArgs("semverLE", args)
+	return cmp <= 0, err
+}
+
+// inCIDR(ip, cidr) returns true if IP address belongs to a network defined in CIDR notation
+func funcInCIDR(args ...interface{}) (interface{}, error) {
+	ip := net.ParseIP(toString(args[0]))
+	if ip == nil {
+		return nil, fmt.Errorf("inCIDR() got invalid IP address '%s'", toString(args[0]))
+	}
+	_, network, err := net.ParseCIDR(toString(args[1]))
+	if err != nil {
+		return nil, fmt.Errorf("inCIDR() got invalid CIDR '%s': %s", toString(args[1]), err)
+	}
+	return network.Contains(ip), nil
+}
+
+/*
+	Helpers
+*/
+
+// Converts value to string. Label values which look like numbers are passed into expressions as float64, so they
+// have to be converted back without the fractional part
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func stringArg(funcName string, value interface{}) (string, error) {
+	result, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s() expects a string, got '%v'", funcName, value)
+	}
+	return result, nil
+}
+
+var (
+	regexpCache     = make(map[string]*regexp.Regexp)
+	regexpCacheLock sync.RWMutex
+)
+
+// Compiles regular expression, caching the result
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexpCacheLock.RLock()
+	re, ok := regexpCache[pattern]
+	regexpCacheLock.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression '%s': %s", pattern, err)
+	}
+
+	regexpCacheLock.Lock()
+	regexpCache[pattern] = re
+	regexpCacheLock.Unlock()
+	return re, nil
+}
+
+func compareSemverArgs(funcName string, args []interface{}) (int, error) {
+	a, err := parseSemver(toString(args[0]))
+	if err != nil {
+		return 0, fmt.Errorf("%s(): %s", funcName, err)
+	}
+	b, err := parseSemver(toString(args[1]))
+	if err != nil {
+		return 0, fmt.Errorf("%s(): %s", funcName, err)
+	}
+	return a.compare(b), nil
+}
+
+// semver is a parsed semantic version (major.minor.patch-prerelease). Build metadata is ignored
+type semver struct {
+	numbers    [3]int
+	prerelease string
+}
+
+// Parses semantic version. A leading 'v' is allowed, and missing minor/patch numbers are treated as zeros
+func parseSemver(value string) (*semver, error) {
+	version := strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if idx := strings.Index(version, "+"); idx >= 0 {
+		version = version[:idx]
+	}
+
+	result := &semver{}
+	if idx := strings.Index(version, "-"); idx >= 0 {
+		result.prerelease = version[idx+1:]
+		version = version[:idx]
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid semantic version '%s'", value)
+	}
+	for idx, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("invalid semantic version '%s'", value)
+		}
+		result.numbers[idx] = number
+	}
+	return result, nil
+}
+
+// Compares two versions, returns -1, 0 or 1. Pre-release versions have lower precedence than release versions
+func (v *semver) compare(that *semver) int {
+	for idx := range v.numbers {
+		if v.numbers[idx] != that.numbers[idx] {
+			if v.numbers[idx] < that.numbers[idx] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.prerelease == that.prerelease:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(that.prerelease) == 0:
+		return -1
+	default:
+		return comparePrerelease(v.prerelease, that.prerelease)
+	}
+}
+
+// Compares two pre-release versions, returns -1, 0 or 1. Dot-separated identifiers get compared one by one: numeric
+// identifiers numerically, others in ASCII order, and numeric identifiers have lower precedence than others (semver 11).
+// If all identifiers are equal, the one with more identifiers has higher precedence
+func comparePrerelease(a string, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for idx := 0; idx < len(partsA) && idx < len(partsB); idx++ {
+		numA, errA := strconv.ParseUint(partsA[idx], 10, 64)
+		numB, errB := strconv.ParseUint(partsB[idx], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		case partsA[idx] != partsB[idx]:
+			if partsA[idx] < partsB[idx] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(partsA) < len(partsB):
+		return -1
+	case len(partsA) > len(partsB):
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,71 @@
+package lang
+
+// Lifecycle phases of component instance, in which hooks get executed
+const (
+	HookPreCreate  = "pre-create"
+	HookPostCreate = "post-create"
+	HookPreUpdate  = "pre-update"
+	HookPostUpdate = "post-update"
+	HookPreDelete  = "pre-delete"
+	HookPostDelete = "post-delete"
+)
+
+// HookPhases is the list of all lifecycle phases of component instance, in which hooks get executed
+var HookPhases = []string{HookPreCreate, HookPostCreate, HookPreUpdate, HookPostUpdate, HookPreDelete, HookPostDelete}
+
+// ComponentHooks defines lifecycle hooks of a code component. Every hook gets executed as a separate action, right
+// before or right after the corresponding create, update or delete action of the component instance. If a hook fails,
+// the rest of the actions for the component instance are not executed (e.g. a failed 'pre-update' schema migration
+// prevents the component from being upgraded)
+type ComponentHooks struct {
+	// PreCreate hooks are executed before component instance gets created
+	PreCreate []*Hook `yaml:"pre-create,omitempty" validate:"dive"`
+
+	// PostCreate hooks are executed after component instance got created
+	PostCreate []*Hook `yaml:"post-create,omitempty" validate:"dive"`
+
+	// PreUpdate hooks are executed before component instance gets updated (e.g. schema migrations)
+	PreUpdate []*Hook `yaml:"pre-update,omitempty" validate:"dive"`
+
+	// PostUpdate hooks are executed after component instance got updated
+	PostUpdate []*Hook `yaml:"post-update,omitempty" validate:"dive"`
+
+	// PreDelete hooks are executed before component instance gets deleted (e.g. backups)
+	PreDelete []*Hook `yaml:"pre-delete,omitempty" validate:"dive"`
+
+	// PostDelete hooks are executed after component instance got deleted
+	PostDelete []*Hook `yaml:"post-delete,omitempty" validate:"dive"`
+}
+
+// Hook is a piece of code (e.g. a helm chart or a raw Kubernetes manifest with a Job), which gets deployed by the
+// code plugin when the hook is executed. Once hook code becomes ready, it gets destroyed
+type Hook struct {
+	// Name is a user-defined hook name
+	Name string `validate:"identifier"`
+
+	// Code is the code which gets instantiated when the hook is executed. Its params follow text template syntax and
+	// can refer to the same data as params of the component code
+	Code *Code `validate:"required"`
+}
+
+// GetHooks returns hooks for the given lifecycle phase
+func (hooks *ComponentHooks) GetHooks(phase string) []*Hook {
+	if hooks == nil {
+		return nil
+	}
+	switch phase {
+	case HookPreCreate:
+		return hooks.PreCreate
+	case HookPostCreate:
+		return hooks.PostCreate
+	case HookPreUpdate:
+		return hooks.PreUpdate
+	case HookPostUpdate:
+		return hooks.PostUpdate
+	case HookPreDelete:
+		return hooks.PreDelete
+	case HookPostDelete:
+		return hooks.PostDelete
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+// Package lint provides semantic checks for Aptomi policy, finding objects which are well-formed but will never
+// be used during policy resolution (unreachable contexts, unused contracts, rules which can never match, etc).
+package lint
@@ -0,0 +1,470 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// CheckShadowedContext is reported for a context which will never be picked, because an earlier context within
+	// the same contract has broader criteria
+	CheckShadowedContext = "shadowed-context"
+
+	// CheckUnusedContract is reported for a contract which is not used by any dependency or service component
+	CheckUnusedContract = "unused-contract"
+
+	// CheckUnallocatedService is reported for a service which is not allocated by any contract context
+	CheckUnallocatedService = "unallocated-service"
+
+	// CheckRuleNeverMatches is reported for a rule which criteria refer to labels that are never set
+	CheckRuleNeverMatches = "rule-never-matches"
+
+	// CheckUnusedDiscovery is reported for a component which exposes discovery params, but nobody refers to them
+	CheckUnusedDiscovery = "unused-discovery"
+
+	// CheckConflictingLabels is reported for change-labels operations which conflict with each other
+	CheckConflictingLabels = "conflicting-change-labels"
+)
+
+// Warning is a single problem found in the policy
+type Warning struct {
+	// Check is the name of the check which reported the problem
+	Check string
+
+	// Object is the key of the policy object which has the problem
+	Object string
+
+	// Message describes the problem
+	Message string
+}
+
+func (warning *Warning) String() string {
+	return fmt.Sprintf("%s: %s (%s)", warning.Object, warning.Message, warning.Check)
+}
+
+// Linter runs semantic checks against the policy. Unlike PolicyValidator, it doesn't look for errors, but rather
+// for objects and expressions which are valid, but most likely are a mistake
+type Linter struct {
+	policy         *lang.Policy
+	knownLabelKeys map[string]bool
+	warnings       []*Warning
+}
+
+// NewLinter creates a new Linter for the given policy. Labels which are set in the policy (by dependencies, services
+// and change-labels) are known automatically, while the keys of labels supplied from outside of the policy (i.e. user
+// labels) should be passed in knownLabelKeys
+func NewLinter(policy *lang.Policy, knownLabelKeys []string) *Linter {
+	linter := &Linter{
+		policy:         policy,
+		knownLabelKeys: map[string]bool{lang.LabelCluster: true},
+	}
+	for _, key := range knownLabelKeys {
+		linter.knownLabelKeys[key] = true
+	}
+	linter.collectKnownLabelKeys()
+	return linter
+}
+
+// Lint runs all checks and returns the list of warnings, sorted by object key
+func (linter *Linter) Lint() []*Warning {
+	linter.warnings = []*Warning{}
+
+	linter.checkShadowedContexts()
+	linter.checkUnusedContracts()
+	linter.checkUnallocatedServices()
+	linter.checkRulesNeverMatch()
+	linter.checkUnusedDiscovery()
+	linter.checkConflictingLabels()
+
+	sort.Stable(warningsSorter(linter.warnings))
+	return linter.warnings
+}
+
+func (linter *Linter) warnf(check string, obj lang.Base, format string, args ...interface{}) {
+	linter.warnings = append(linter.warnings, &Warning{
+		Check:   check,
+		Object:  runtime.KeyForStorable(obj),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Collects keys of all labels which can be set by the policy itself
+func (linter *Linter) collectKnownLabelKeys() {
+	addKeys := func(labels map[string]string) {
+		for key := range labels {
+			linter.knownLabelKeys[key] = true
+		}
+	}
+
+	for _, obj := range linter.policy.GetObjectsByKind(lang.DependencyObject.Kind) {
+		addKeys(obj.(*lang.Dependency).Labels)
+	}
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ServiceObject.Kind) {
+		addKeys(obj.(*lang.Service).Labels)
+	}
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ContractObject.Kind) {
+		contract := obj.(*lang.Contract)
+		addKeys(contract.ChangeLabels["set"])
+		for _, context := range contract.GetAllContexts() {
+			addKeys(context.ChangeLabels["set"])
+		}
+	}
+	for _, obj := range linter.policy.GetObjectsByKind(lang.RuleObject.Kind) {
+		rule := obj.(*lang.Rule)
+		if rule.Actions != nil {
+			addKeys(rule.Actions.ChangeLabels["set"])
+		}
+	}
+}
+
+// Reports contexts, which can't be picked because an earlier context in the same contract matches whenever they do
+func (linter *Linter) checkShadowedContexts() {
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ContractObject.Kind) {
+		contract := obj.(*lang.Contract)
+		for _, version := range contract.GetVersions() {
+			contexts := version.Contexts
+			for j := 1; j < len(contexts); j++ {
+				for i := 0; i < j; i++ {
+					if implies(contexts[j].Criteria, contexts[i].Criteria) {
+						linter.warnf(CheckShadowedContext, contract, "context '%s'%s will never be picked, it's shadowed by earlier context '%s' with broader criteria", contexts[j].Name, inVersion(version), contexts[i].Name)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// Reports contracts, which are not referred to by any dependency or service component
+func (linter *Linter) checkUnusedContracts() {
+	used := make(map[string]bool)
+	markUsed := func(locator string, namespace string) {
+		obj, err := linter.policy.GetObject(lang.ContractObject.Kind, locator, namespace)
+		if contract, ok := obj.(*lang.Contract); err == nil && ok && contract != nil {
+			used[runtime.KeyForStorable(contract)] = true
+		}
+	}
+
+	for _, obj := range linter.policy.GetObjectsByKind(lang.DependencyObject.Kind) {
+		dependency := obj.(*lang.Dependency)
+		markUsed(dependency.Contract, dependency.Namespace)
+	}
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ServiceObject.Kind) {
+		service := obj.(*lang.Service)
+		for _, component := range service.Components {
+			if len(component.Contract) > 0 {
+				markUsed(component.Contract, service.Namespace)
+			}
+		}
+	}
+
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ContractObject.Kind) {
+		if !used[runtime.KeyForStorable(obj)] {
+			linter.warnf(CheckUnusedContract, obj, "contract is not used by any dependency or service")
+		}
+	}
+}
+
+// Reports services, which are not allocated by any context
+func (linter *Linter) checkUnallocatedServices() {
+	allocated := make(map[string]bool)
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ContractObject.Kind) {
+		contract := obj.(*lang.Contract)
+		for _, context := range contract.GetAllContexts() {
+			if context.Allocation == nil {
+				continue
+			}
+			obj, err := linter.policy.GetObject(lang.ServiceObject.Kind, context.Allocation.Service, contract.Namespace)
+			if service, ok := obj.(*lang.Service); err == nil && ok && service != nil {
+				allocated[runtime.KeyForStorable(service)] = true
+			}
+		}
+	}
+
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ServiceObject.Kind) {
+		if !allocated[runtime.KeyForStorable(obj)] {
+			linter.warnf(CheckUnallocatedService, obj, "service is not allocated by any context")
+		}
+	}
+}
+
+// Reports rules, which criteria can't evaluate to true, because they refer to labels that are never set. Expressions
+// which refer to missing labels always evaluate to false
+func (linter *Linter) checkRulesNeverMatch() {
+	for _, obj := range linter.policy.GetObjectsByKind(lang.RuleObject.Kind) {
+		rule := obj.(*lang.Rule)
+		if rule.Criteria == nil {
+			continue
+		}
+
+		for _, expr := range rule.Criteria.RequireAll {
+			if unknown := linter.unknownLabels(expr); len(unknown) > 0 {
+				linter.warnf(CheckRuleNeverMatches, rule, "rule will never match, require-all expression '%s' refers to labels which are never set: %s", expr, strings.Join(unknown, ", "))
+			}
+		}
+
+		// nested groups are not checked, so rule is only reported if it has no alternatives in require-any-of
+		if len(rule.Criteria.RequireAny) > 0 && len(rule.Criteria.RequireAnyOf) <= 0 {
+			unknown := make(map[string]bool)
+			for _, expr := range rule.Criteria.RequireAny {
+				exprUnknown := linter.unknownLabels(expr)
+				if len(exprUnknown) <= 0 {
+					unknown = nil
+					break
+				}
+				for _, name := range exprUnknown {
+					unknown[name] = true
+				}
+			}
+			if len(unknown) > 0 {
+				linter.warnf(CheckRuleNeverMatches, rule, "rule will never match, all require-any expressions refer to labels which are never set: %s", strings.Join(util.GetSortedStringKeys(unknown), ", "))
+			}
+		}
+	}
+}
+
+// Returns labels which are referred to by the expression, but never set
+func (linter *Linter) unknownLabels(exprStr string) []string {
+	expr, err := expression.NewExpression(exprStr)
+	if err != nil {
+		// invalid expressions are reported by policy validation
+		return nil
+	}
+	result := []string{}
+	for _, name := range expr.Vars() {
+		if !linter.knownLabelKeys[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// discoveryRef matches references to discovery params in templates, e.g. '.Discovery.component.url'
+var discoveryRef = regexp.MustCompile(`\.Discovery((?:\.[\w-]+)*)`)
+
+// Reports code components, which expose discovery params that are not referred to by any template in the policy
+func (linter *Linter) checkUnusedDiscovery() {
+	referred := make(map[string]bool)
+	referredAll := false
+	collectRefs := func(str string) {
+		for _, match := range discoveryRef.FindAllStringSubmatch(str, -1) {
+			if len(match[1]) <= 0 {
+				// the whole discovery tree is used
+				referredAll = true
+			}
+			for _, name := range strings.Split(strings.Trim(match[1], "."), ".") {
+				referred[name] = true
+			}
+		}
+	}
+
+	services := linter.policy.GetObjectsByKind(lang.ServiceObject.Kind)
+	for _, obj := range services {
+		for _, component := range obj.(*lang.Service).Components {
+			if component.Code != nil {
+				walkStrings(component.Code.Params, collectRefs)
+			}
+			walkStrings(component.Discovery, collectRefs)
+		}
+	}
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ContractObject.Kind) {
+		for _, context := range obj.(*lang.Contract).GetAllContexts() {
+			if context.Allocation != nil {
+				for _, key := range context.Allocation.Keys {
+					collectRefs(key)
+				}
+			}
+		}
+	}
+	if referredAll {
+		return
+	}
+
+	for _, obj := range services {
+		for _, component := range obj.(*lang.Service).Components {
+			if len(component.Discovery) > 0 && !referred[component.Name] {
+				linter.warnf(CheckUnusedDiscovery, obj, "discovery params of component '%s' are not used by any template", component.Name)
+			}
+		}
+	}
+}
+
+// Calls the given function for every string value in the nested parameter tree
+func walkStrings(value interface{}, fn func(string)) {
+	switch v := value.(type) {
+	case string:
+		fn(v)
+	case util.NestedParameterMap:
+		for _, key := range util.GetSortedStringKeys(v) {
+			walkStrings(v[key], fn)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkStrings(item, fn)
+		}
+	}
+}
+
+// Reports label operations, which set and remove the same label, as well as rules with the same criteria, which
+// set the same label to different values (so the rule with lower weight has no effect)
+func (linter *Linter) checkConflictingLabels() {
+	checkOps := func(obj lang.Base, ops lang.LabelOperations, where string) {
+		for _, key := range util.GetSortedStringKeys(ops["set"]) {
+			if _, ok := ops["remove"][key]; ok {
+				linter.warnf(CheckConflictingLabels, obj, "%s both sets and removes label '%s'", where, key)
+			}
+		}
+	}
+
+	for _, obj := range linter.policy.GetObjectsByKind(lang.ContractObject.Kind) {
+		contract := obj.(*lang.Contract)
+		checkOps(contract, contract.ChangeLabels, "contract")
+		for _, version := range contract.GetVersions() {
+			for _, context := range version.Contexts {
+				checkOps(contract, context.ChangeLabels, fmt.Sprintf("context '%s'%s", context.Name, inVersion(version)))
+			}
+		}
+	}
+
+	for _, namespace := range util.GetSortedStringKeys(linter.policy.Namespace) {
+		rules := lang.GetRulesSortedByWeight(linter.policy.Namespace[namespace].Rules)
+		for j, rule := range rules {
+			if rule.Actions == nil {
+				continue
+			}
+			checkOps(rule, rule.Actions.ChangeLabels, "rule")
+
+			for i := 0; i < j; i++ {
+				prev := rules[i]
+				if prev.Actions == nil || criteriaKey(prev.Criteria) != criteriaKey(rule.Criteria) {
+					continue
+				}
+				for _, key := range util.GetSortedStringKeys(rule.Actions.ChangeLabels["set"]) {
+					value, ok := prev.Actions.ChangeLabels["set"][key]
+					if ok && value != rule.Actions.ChangeLabels["set"][key] {
+						linter.warnf(CheckConflictingLabels, rule, "rule overrides label '%s' set by rule '%s' with the same criteria", key, prev.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Returns a suffix for messages about contexts, which are defined in a named contract version
+func inVersion(version *lang.ContractVersion) string {
+	if len(version.Name) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" in version '%s'", version.Name)
+}
+
+// Returns true if criteria 'broader' is always satisfied when criteria 'narrower' is satisfied. It only looks at
+// expressions as strings, so it may return false for criteria which are equivalent, but written differently
+func implies(narrower *lang.Criteria, broader *lang.Criteria) bool {
+	if broader == nil {
+		return true
+	}
+	if narrower == nil {
+		narrower = &lang.Criteria{}
+	}
+	if len(broader.RequireAllOf) > 0 || len(broader.RequireAnyOf) > 0 || len(broader.RequireNoneOf) > 0 {
+		// nested groups are only compared as a whole
+		return criteriaKey(narrower) == criteriaKey(broader)
+	}
+
+	for _, expr := range broader.RequireAll {
+		if !isTrue(expr) && !contains(narrower.RequireAll, expr) {
+			return false
+		}
+	}
+
+	for _, expr := range broader.RequireNone {
+		if !isFalse(expr) && !contains(narrower.RequireNone, expr) {
+			return false
+		}
+	}
+
+	if len(broader.RequireAny) <= 0 {
+		return true
+	}
+	for _, expr := range broader.RequireAny {
+		if isTrue(expr) || contains(narrower.RequireAll, expr) {
+			return true
+		}
+	}
+	if len(narrower.RequireAny) <= 0 || len(narrower.RequireAnyOf) > 0 {
+		return false
+	}
+	for _, expr := range narrower.RequireAny {
+		if !contains(broader.RequireAny, expr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a string which is the same for criteria with the same sets of expressions
+func criteriaKey(criteria *lang.Criteria) string {
+	if criteria == nil {
+		return ""
+	}
+	if len(criteria.RequireAllOf) <= 0 && len(criteria.RequireAnyOf) <= 0 && len(criteria.RequireNoneOf) <= 0 && implies(nil, criteria) {
+		// criteria always evaluates to true
+		return ""
+	}
+	clauses := []string{}
+	for _, clause := range [][]string{criteria.RequireAll, criteria.RequireAny, criteria.RequireNone} {
+		exprs := []string{}
+		for _, expr := range clause {
+			exprs = append(exprs, strings.TrimSpace(expr))
+		}
+		sort.Strings(exprs)
+		clauses = append(clauses, strings.Join(exprs, "\n"))
+	}
+	for _, groups := range [][]*lang.Criteria{criteria.RequireAllOf, criteria.RequireAnyOf, criteria.RequireNoneOf} {
+		keys := []string{}
+		for _, group := range groups {
+			keys = append(keys, "("+criteriaKey(group)+")")
+		}
+		sort.Strings(keys)
+		clauses = append(clauses, strings.Join(keys, "\n"))
+	}
+	return strings.Join(clauses, "\n--\n")
+}
+
+func contains(exprs []string, expr string) bool {
+	for _, e := range exprs {
+		if strings.TrimSpace(e) == strings.TrimSpace(expr) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTrue(expr string) bool {
+	return strings.TrimSpace(expr) == "true"
+}
+
+func isFalse(expr string) bool {
+	return strings.TrimSpace(expr) == "false"
+}
+
+type warningsSorter []*Warning
+
+func (ws warningsSorter) Len() int {
+	return len(ws)
+}
+
+func (ws warningsSorter) Swap(i, j int) {
+	ws[i], ws[j] = ws[j], ws[i]
+}
+
+func (ws warningsSorter) Less(i, j int) bool {
+	return ws[i].Object < ws[j].Object
+}
@@ -0,0 +1,179 @@
+package lint
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLinter(t *testing.T) {
+	policy := makePolicy()
+
+	warnings := NewLinter(policy, []string{"team"}).Lint()
+	assert.Equal(t, []string{
+		"main/contract/cache: contract is not used by any dependency or service (unused-contract)",
+		"main/contract/db: context 'staging' will never be picked, it's shadowed by earlier context 'prod' with broader criteria (shadowed-context)",
+		"main/contract/db: context 'fallback-2' will never be picked, it's shadowed by earlier context 'fallback' with broader criteria (shadowed-context)",
+		"main/contract/db: context 'fallback' both sets and removes label 'tier' (conflicting-change-labels)",
+		"main/rule/cluster-2: rule overrides label 'cluster' set by rule 'cluster-1' with the same criteria (conflicting-change-labels)",
+		"main/rule/unknown: rule will never match, require-all expression 'zone == \"us\"' refers to labels which are never set: zone (rule-never-matches)",
+		"main/rule/unknown-any: rule will never match, all require-any expressions refer to labels which are never set: region, zone (rule-never-matches)",
+		"main/service/db: discovery params of component 'unused' are not used by any template (unused-discovery)",
+		"main/service/orphan: service is not allocated by any context (unallocated-service)",
+	}, warningStrings(warnings), "Linter should report all problems in the policy")
+
+	// without user label keys, rules referring to user labels will be reported
+	warnings = NewLinter(policy, nil).Lint()
+	assert.Contains(t, warningStrings(warnings), "main/rule/team: rule will never match, require-all expression 'team == \"dev\"' refers to labels which are never set: team (rule-never-matches)")
+}
+
+func TestImplies(t *testing.T) {
+	testCases := []struct {
+		narrower *lang.Criteria
+		broader  *lang.Criteria
+		result   bool
+	}{
+		{nil, nil, true},
+		{nil, &lang.Criteria{RequireAny: []string{"true"}}, true},
+		{nil, &lang.Criteria{RequireAll: []string{"a"}}, false},
+		{&lang.Criteria{RequireAll: []string{"a", "b"}}, &lang.Criteria{RequireAll: []string{"a"}}, true},
+		{&lang.Criteria{RequireAll: []string{"a"}}, &lang.Criteria{RequireAll: []string{"a", "b"}}, false},
+		{&lang.Criteria{RequireNone: []string{"a", "b"}}, &lang.Criteria{RequireNone: []string{"a"}}, true},
+		{&lang.Criteria{RequireNone: []string{"a"}}, &lang.Criteria{RequireNone: []string{"a", "b"}}, false},
+		{&lang.Criteria{RequireAll: []string{"a"}}, &lang.Criteria{RequireAny: []string{"a", "b"}}, true},
+		{&lang.Criteria{RequireAny: []string{"a"}}, &lang.Criteria{RequireAny: []string{"a", "b"}}, true},
+		{&lang.Criteria{RequireAny: []string{"a", "c"}}, &lang.Criteria{RequireAny: []string{"a", "b"}}, false},
+		{&lang.Criteria{RequireAll: []string{"c"}}, &lang.Criteria{RequireAny: []string{"a", "b"}}, false},
+		{&lang.Criteria{RequireAnyOf: []*lang.Criteria{{RequireAll: []string{"c"}}}}, &lang.Criteria{RequireAny: []string{"a"}}, false},
+		{&lang.Criteria{RequireAnyOf: []*lang.Criteria{{RequireAll: []string{"a"}}}}, &lang.Criteria{RequireAnyOf: []*lang.Criteria{{RequireAll: []string{"a"}}}}, true},
+		{&lang.Criteria{RequireAll: []string{"a"}}, &lang.Criteria{RequireAnyOf: []*lang.Criteria{{RequireAll: []string{"a"}}}}, false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.result, implies(tc.narrower, tc.broader), "Criteria %v should imply %v: %t", tc.narrower, tc.broader, tc.result)
+	}
+}
+
+func warningStrings(warnings []*Warning) []string {
+	result := []string{}
+	for _, warning := range warnings {
+		result = append(result, warning.String())
+	}
+	return result
+}
+
+func makePolicy() *lang.Policy {
+	policy := lang.NewPolicy()
+	objects := []lang.Base{
+		&lang.Service{
+			TypeKind: lang.ServiceObject.GetTypeKind(),
+			Metadata: lang.Metadata{Namespace: "main", Name: "db"},
+			Components: []*lang.ServiceComponent{
+				{
+					Name:      "database",
+					Code:      &lang.Code{Type: "helm", Params: util.NestedParameterMap{"url": "{{ .Discovery.settings.url }}"}},
+					Discovery: util.NestedParameterMap{"url": "db:5432"},
+				},
+				{
+					Name:      "settings",
+					Code:      &lang.Code{Type: "helm"},
+					Discovery: util.NestedParameterMap{"url": "settings:80"},
+				},
+				{
+					Name:      "unused",
+					Code:      &lang.Code{Type: "helm"},
+					Discovery: util.NestedParameterMap{"port": "9000"},
+				},
+			},
+		},
+		&lang.Service{
+			TypeKind: lang.ServiceObject.GetTypeKind(),
+			Metadata: lang.Metadata{Namespace: "main", Name: "app"},
+			Components: []*lang.ServiceComponent{
+				{
+					Name:     "db",
+					Contract: "db",
+				},
+				{
+					Name: "app",
+					Code: &lang.Code{Type: "helm", Params: util.NestedParameterMap{"db": []interface{}{"{{ .Discovery.db.database.url }}"}}},
+				},
+			},
+		},
+		&lang.Service{
+			TypeKind: lang.ServiceObject.GetTypeKind(),
+			Metadata: lang.Metadata{Namespace: "main", Name: "orphan"},
+		},
+		&lang.Contract{
+			TypeKind: lang.ContractObject.GetTypeKind(),
+			Metadata: lang.Metadata{Namespace: "main", Name: "db"},
+			Contexts: []*lang.Context{
+				{
+					Name:       "prod",
+					Criteria:   &lang.Criteria{RequireAll: []string{"env == 'prod'"}},
+					Allocation: &lang.Allocation{Service: "db"},
+				},
+				{
+					Name:       "staging",
+					Criteria:   &lang.Criteria{RequireAll: []string{"env == 'prod'", "team == 'staging'"}},
+					Allocation: &lang.Allocation{Service: "db"},
+				},
+				{
+					Name:         "fallback",
+					Allocation:   &lang.Allocation{Service: "main/db"},
+					ChangeLabels: lang.NewLabelOperations(map[string]string{"tier": "small"}, map[string]string{"tier": ""}),
+				},
+				{
+					Name:       "fallback-2",
+					Allocation: &lang.Allocation{Service: "db"},
+				},
+			},
+		},
+		&lang.Contract{
+			TypeKind: lang.ContractObject.GetTypeKind(),
+			Metadata: lang.Metadata{Namespace: "main", Name: "app"},
+			Contexts: []*lang.Context{
+				{
+					Name:       "default",
+					Allocation: &lang.Allocation{Service: "app"},
+				},
+			},
+		},
+		&lang.Contract{
+			TypeKind: lang.ContractObject.GetTypeKind(),
+			Metadata: lang.Metadata{Namespace: "main", Name: "cache"},
+		},
+		&lang.Dependency{
+			TypeKind: lang.DependencyObject.GetTypeKind(),
+			Metadata: lang.Metadata{Namespace: "main", Name: "alice_app"},
+			User:     "alice",
+			Contract: "main/app",
+			Labels:   map[string]string{"env": "prod"},
+		},
+		makeRule("cluster-1", 10, nil, lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, "us-east")),
+		makeRule("cluster-2", 20, &lang.Criteria{RequireAny: []string{"true"}}, lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, "us-west")),
+		makeRule("cluster-3", 30, &lang.Criteria{RequireAll: []string{"env == 'prod'"}}, lang.NewLabelOperationsSetSingleLabel(lang.LabelCluster, "eu")),
+		makeRule("team", 40, &lang.Criteria{RequireAll: []string{`team == "dev"`}}, nil),
+		makeRule("unknown", 50, &lang.Criteria{RequireAll: []string{`zone == "us"`, "env == 'prod'"}}, nil),
+		makeRule("unknown-any", 60, &lang.Criteria{RequireAny: []string{`zone == "us"`, `region == "us"`}}, nil),
+		makeRule("known-any", 70, &lang.Criteria{RequireAny: []string{`zone == "us"`, "has('zone')", "tier == 'small'"}}, nil),
+	}
+	for _, obj := range objects {
+		err := policy.AddObject(obj)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return policy
+}
+
+func makeRule(name string, weight int, criteria *lang.Criteria, labelOps lang.LabelOperations) *lang.Rule {
+	return &lang.Rule{
+		TypeKind: lang.RuleObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: "main", Name: name},
+		Weight:   weight,
+		Criteria: criteria,
+		Actions:  &lang.RuleActions{ChangeLabels: labelOps},
+	}
+}
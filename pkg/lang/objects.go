@@ -11,6 +11,7 @@ var (
 		ClusterObject,
 		RuleObject,
 		ACLRuleObject,
+		QuotaObject,
 	}
 
 	policyObjectsMap = make(map[runtime.Kind]bool)
@@ -13,6 +13,7 @@ type APIPolicyNamespace struct {
 	Rules        map[string]*Rule
 	ACLRules     map[string]*Rule
 	Dependencies map[string]*Dependency
+	Quotas       map[string]*Quota
 }
 
 // APIPolicy returns Policy representation for API filtered for specific user
@@ -15,6 +15,7 @@ type PolicyNamespace struct {
 	Rules        map[string]*Rule       `validate:"dive"`
 	ACLRules     map[string]*Rule       `validate:"dive"`
 	Dependencies map[string]*Dependency `validate:"dive"`
+	Quotas       map[string]*Quota      `validate:"dive"`
 }
 
 // NewPolicyNamespace creates a new PolicyNamespace
@@ -27,6 +28,7 @@ func NewPolicyNamespace(name string) *PolicyNamespace {
 		Rules:        make(map[string]*Rule),
 		ACLRules:     make(map[string]*Rule),
 		Dependencies: make(map[string]*Dependency),
+		Quotas:       make(map[string]*Quota),
 	}
 }
 
@@ -44,6 +46,8 @@ func (policyNamespace *PolicyNamespace) addObject(obj Base) error {
 		policyNamespace.ACLRules[obj.GetName()] = obj.(*Rule)
 	case DependencyObject.Kind:
 		policyNamespace.Dependencies[obj.GetName()] = obj.(*Dependency)
+	case QuotaObject.Kind:
+		policyNamespace.Quotas[obj.GetName()] = obj.(*Quota)
 	default:
 		return fmt.Errorf("not supported by PolicyNamespace.addObject(): unknown kind %s", kind)
 	}
@@ -82,6 +86,11 @@ func (policyNamespace *PolicyNamespace) removeObject(obj Base) bool {
 			delete(policyNamespace.Dependencies, obj.GetName())
 			return true
 		}
+	case QuotaObject.Kind:
+		if _, exist := policyNamespace.Quotas[obj.GetName()]; exist {
+			delete(policyNamespace.Quotas, obj.GetName())
+			return true
+		}
 	}
 
 	return false
@@ -114,6 +123,10 @@ func (policyNamespace *PolicyNamespace) getObjectsByKind(kind string) []Base {
 		for _, dependency := range policyNamespace.Dependencies {
 			result = append(result, dependency)
 		}
+	case QuotaObject.Kind:
+		for _, quota := range policyNamespace.Quotas {
+			result = append(result, quota)
+		}
 	default:
 		panic(fmt.Sprintf("not supported by PolicyNamespace.getObjectsByKind(): unknown kind %s", kind))
 	}
@@ -148,6 +161,10 @@ func (policyNamespace *PolicyNamespace) getObject(kind string, name string) (run
 		if result, ok = policyNamespace.Dependencies[name]; !ok {
 			return nil, nil
 		}
+	case QuotaObject.Kind:
+		if result, ok = policyNamespace.Quotas[name]; !ok {
+			return nil, nil
+		}
 	default:
 		return nil, fmt.Errorf("not supported by PolicyNamespace.getObject(): unknown kind %s, %s", kind, name)
 	}
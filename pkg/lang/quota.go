@@ -0,0 +1,49 @@
+package lang
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// QuotaObject is an informational data structure with Kind and Constructor for Quota
+var QuotaObject = &runtime.Info{
+	Kind:        "quota",
+	Storable:    true,
+	Versioned:   true,
+	Deletable:   true,
+	Constructor: func() runtime.Object { return &Quota{} },
+}
+
+// Quota limits the amount of resources which can be requested by dependencies within a namespace. It applies to all
+// dependencies declared in the namespace where quota is defined, whose initial set of labels (dependency labels combined
+// with user labels) satisfies quota criteria.
+//
+// Dependencies get checked against quotas in the order of their keys. Once a limit is reached, all subsequent dependencies
+// which would go over it won't be resolved and will get a quota error instead.
+type Quota struct {
+	runtime.TypeKind `yaml:",inline"`
+	Metadata         `validate:"required"`
+
+	// Criteria - if it gets evaluated to true for a dependency, then quota applies to that dependency.
+	// It's an optional field, so if it's nil then quota applies to all dependencies in the namespace
+	Criteria *Criteria `yaml:",omitempty" validate:"omitempty"`
+
+	// MaxDependencies is the maximum number of dependencies which can be resolved (0 means no limit)
+	MaxDependencies int `yaml:"max-dependencies,omitempty" validate:"min=0"`
+
+	// MaxInstancesPerCluster is the maximum number of code component instances which can be running in a
+	// single cluster (0 means no limit)
+	MaxInstancesPerCluster int `yaml:"max-instances-per-cluster,omitempty" validate:"min=0"`
+
+	// MaxInstancesPerService is the maximum number of instances which can be created for a single
+	// service (0 means no limit)
+	MaxInstancesPerService int `yaml:"max-instances-per-service,omitempty" validate:"min=0"`
+}
+
+// Matches returns true if quota applies to the dependency, given a set of labels
+func (quota *Quota) Matches(params *expression.Parameters, cache *expression.Cache) (bool, error) {
+	if quota.Criteria == nil {
+		return true, nil
+	}
+	return quota.Criteria.allows(params, cache)
+}
@@ -61,6 +61,15 @@ func (rule *Rule) Matches(params *expression.Parameters, cache *expression.Cache
 	return rule.Criteria.allows(params, cache)
 }
 
+// MatchesBranch returns true if a rule matches, and also returns a description of the criteria branch which got
+// matched (empty if there are no alternatives in the criteria)
+func (rule *Rule) MatchesBranch(params *expression.Parameters, cache *expression.Cache) (bool, string, error) {
+	if rule.Criteria == nil {
+		return true, "", nil
+	}
+	return rule.Criteria.match(params, cache)
+}
+
 type ruleSorter []*Rule
 
 func (rs ruleSorter) Len() int {
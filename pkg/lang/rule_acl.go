@@ -97,6 +97,7 @@ var DomainAdmin = &ACLRole{
 			ContractObject.Kind:   fullAccess,
 			DependencyObject.Kind: fullAccess,
 			RuleObject.Kind:       fullAccess,
+			QuotaObject.Kind:      fullAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
 			ClusterObject.Kind: fullAccess,
@@ -116,6 +117,7 @@ var NamespaceAdmin = &ACLRole{
 			ContractObject.Kind:   fullAccess,
 			DependencyObject.Kind: fullAccess,
 			RuleObject.Kind:       fullAccess,
+			QuotaObject.Kind:      viewAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
 			ClusterObject.Kind: viewAccess,
@@ -135,6 +137,7 @@ var ServiceConsumer = &ACLRole{
 			ContractObject.Kind:   viewAccess,
 			DependencyObject.Kind: fullAccess,
 			RuleObject.Kind:       viewAccess,
+			QuotaObject.Kind:      viewAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
 			ClusterObject.Kind: viewAccess,
@@ -154,6 +157,7 @@ var nobody = &ACLRole{
 			ContractObject.Kind:   viewAccess,
 			DependencyObject.Kind: viewAccess,
 			RuleObject.Kind:       viewAccess,
+			QuotaObject.Kind:      viewAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
 			ClusterObject.Kind: viewAccess,
@@ -16,9 +16,10 @@ type ACLResolver struct {
 
 // NewACLResolver creates a new ACLResolver
 func NewACLResolver(rules map[string]*Rule) *ACLResolver {
+	// users are not required to have all labels, so missing labels in ACL rules are always ignored
 	return &ACLResolver{
 		rules:        GetRulesSortedByWeight(rules),
-		cache:        expression.NewCache(),
+		cache:        expression.NewCacheWithMode(expression.MissingParamsIgnore),
 		roleMapCache: sync.Map{},
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 	"sync"
+	"time"
 )
 
 // ServiceObject is an informational data structure with Kind and Constructor for Service
@@ -33,6 +34,13 @@ type Service struct {
 	// Components is the list of components service consists of
 	Components []*ServiceComponent `validate:"dive"`
 
+	// Outputs is the list of values which service delivers back to its consumers (e.g. connection strings)
+	Outputs []*ServiceOutput `yaml:"outputs,omitempty" validate:"dive"`
+
+	// Sticky, if set, means that existing instances of the service keep their placement (context, cluster and
+	// allocation keys) when rules or context criteria change. Instances only move after explicit migration is requested
+	Sticky bool `yaml:"sticky,omitempty"`
+
 	// Lazily evaluated fields (all components topologically sorted). Use via getter
 	componentsOrderedOnce sync.Once
 	componentsOrderedErr  error
@@ -60,6 +68,10 @@ type ServiceComponent struct {
 	// container image)
 	Code *Code `yaml:"code,omitempty" validate:"omitempty"`
 
+	// Hooks, if not empty, define lifecycle hooks which get executed before and after component instance gets
+	// created, updated or deleted. Hooks can only be defined for code components
+	Hooks *ComponentHooks `yaml:"hooks,omitempty" validate:"omitempty"`
+
 	// Discovery is a map of discovery parameters that this component exposes to other services
 	Discovery util.NestedParameterMap `yaml:"discovery,omitempty" validate:"omitempty,templateNestedMap"`
 
@@ -68,6 +80,20 @@ type ServiceComponent struct {
 	Dependencies []string `yaml:"dependencies,omitempty" validate:"dive,identifier"`
 }
 
+// ServiceOutput defines a named value which service delivers back to its consumers, once a dependency gets resolved
+// to an instance of the service
+type ServiceOutput struct {
+	// Name is a user-defined output name
+	Name string `validate:"identifier"`
+
+	// Value follows text template syntax and can refer to discovery parameters exposed by service components, as well
+	// as to labels and contract params
+	Value string `validate:"template"`
+
+	// Sensitive, if true, means that the value will be shown only to the user who owns the dependency
+	Sensitive bool `yaml:"sensitive,omitempty"`
+}
+
 // Code with type and parameters, used to instantiate/update/delete component instances
 type Code struct {
 	// Type represents code type (e.g. "helm"). It determines the plugin that will get executed for
@@ -78,6 +104,58 @@ type Code struct {
 	// and can refer to arbitrary labels, as well as discovery parameters exposed by other components (within the
 	// current service) and discovery parameters exposed by services the current service depends on
 	Params util.NestedParameterMap `validate:"omitempty,templateNestedMap"`
+
+	// Sensitive is a list of parameter keys, values of which hold sensitive data (e.g. passwords) and should never
+	// be displayed, logged or returned from the API. Keys matching default sensitive patterns are masked as well
+	Sensitive []string `yaml:",omitempty" validate:"-"`
+
+	// WaitForReady, if set, makes the engine wait until the component instance reports that it's ready after it gets
+	// created or updated. Actions of dependent components only start once the component instance is ready
+	WaitForReady *WaitForReady `yaml:"wait-for-ready,omitempty" validate:"omitempty"`
+
+	// Timeout, if set, limits the time which the deployment plugin can spend on creating, updating or deleting
+	// the component instance (e.g. '10m'). If it takes longer, the action gets cancelled and fails
+	Timeout string `yaml:"timeout,omitempty" validate:"omitempty,duration"`
+}
+
+// GetTimeout returns the maximum time which the deployment plugin can spend on a single action, or zero if there is
+// no limit
+func (code *Code) GetTimeout() time.Duration {
+	return parseDurationOrDefault(code.Timeout, 0)
+}
+
+// Default timeout and polling interval for waiting until component instance becomes ready
+const (
+	DefaultWaitForReadyTimeout  = 5 * time.Minute
+	DefaultWaitForReadyInterval = 5 * time.Second
+)
+
+// WaitForReady defines how long to wait for component instance to become ready, and how often to check its status
+type WaitForReady struct {
+	// Timeout is the maximum time to wait for component instance to become ready (e.g. '5m'). Defaults to 5 minutes
+	Timeout string `yaml:"timeout,omitempty" validate:"omitempty,duration"`
+
+	// Interval is the time between status checks (e.g. '10s'). Defaults to 5 seconds
+	Interval string `yaml:"interval,omitempty" validate:"omitempty,duration"`
+}
+
+// GetTimeout returns the maximum time to wait for component instance to become ready
+func (wait *WaitForReady) GetTimeout() time.Duration {
+	return parseDurationOrDefault(wait.Timeout, DefaultWaitForReadyTimeout)
+}
+
+// GetInterval returns the time between status checks of component instance
+func (wait *WaitForReady) GetInterval() time.Duration {
+	return parseDurationOrDefault(wait.Interval, DefaultWaitForReadyInterval)
+}
+
+// Parses duration, returning default value if it's empty or invalid (it should always be valid, as policy has been validated)
+func parseDurationOrDefault(value string, defaultValue time.Duration) time.Duration {
+	result, err := time.ParseDuration(value)
+	if err != nil || result <= 0 {
+		return defaultValue
+	}
+	return result
 }
 
 // Matches checks if component criteria is satisfied
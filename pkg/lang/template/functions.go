@@ -0,0 +1,736 @@
+package template
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/adler32"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	t "text/template"
+	"text/template/parse"
+)
+
+// Custom functions. All of them are deterministic, so evaluating the same template with the same parameters always
+// produces the same result. The only exception are random functions (see seededFuncNames), which produce stable
+// values derived from the seed (key of the component being processed)
+var textFuncMap = t.FuncMap{
+	// defaults
+	"default":  defaultValue,
+	"empty":    isEmpty,
+	"coalesce": coalesce,
+	"ternary":  ternary,
+
+	// strings
+	"upper":      func(s interface{}) string { return strings.ToUpper(toString(s)) },
+	"lower":      func(s interface{}) string { return strings.ToLower(toString(s)) },
+	"title":      func(s interface{}) string { return strings.Title(toString(s)) },
+	"trim":       func(s interface{}) string { return strings.TrimSpace(toString(s)) },
+	"trimAll":    func(cutset string, s interface{}) string { return strings.Trim(toString(s), cutset) },
+	"trimPrefix": func(prefix string, s interface{}) string { return strings.TrimPrefix(toString(s), prefix) },
+	"trimSuffix": func(suffix string, s interface{}) string { return strings.TrimSuffix(toString(s), suffix) },
+	"replace":    func(old string, new string, s interface{}) string { return strings.Replace(toString(s), old, new, -1) },
+	"repeat":     func(count int, s interface{}) string { return strings.Repeat(toString(s), count) },
+	"contains":   func(substr string, s interface{}) bool { return strings.Contains(toString(s), substr) },
+	"hasPrefix":  func(prefix string, s interface{}) bool { return strings.HasPrefix(toString(s), prefix) },
+	"hasSuffix":  func(suffix string, s interface{}) bool { return strings.HasSuffix(toString(s), suffix) },
+	"quote":      func(s interface{}) string { return strconv.Quote(toString(s)) },
+	"squote":     func(s interface{}) string { return "'" + toString(s) + "'" },
+	"nospace":    func(s interface{}) string { return strings.Join(strings.Fields(toString(s)), "") },
+	"substr":     substr,
+	"trunc":      trunc,
+	"indent":     indent,
+	"nindent":    func(spaces int, s interface{}) string { return "\n" + indent(spaces, s) },
+	"cat":        cat,
+	"toString":   toString,
+
+	// encoding and hashing
+	"b64enc":  func(s interface{}) string { return base64.StdEncoding.EncodeToString([]byte(toString(s))) },
+	"b64dec":  func(s interface{}) (string, error) { return decode(base64.StdEncoding.DecodeString(toString(s))) },
+	"b32enc":  func(s interface{}) string { return base32.StdEncoding.EncodeToString([]byte(toString(s))) },
+	"b32dec":  func(s interface{}) (string, error) { return decode(base32.StdEncoding.DecodeString(toString(s))) },
+	"sha1sum": func(s interface{}) string { sum := sha1.Sum([]byte(toString(s))); return hex.EncodeToString(sum[:]) },
+	"sha256sum": func(s interface{}) string {
+		sum := sha256.Sum256([]byte(toString(s)))
+		return hex.EncodeToString(sum[:])
+	},
+	"adler32sum": func(s interface{}) string {
+		return strconv.FormatUint(uint64(adler32.Checksum([]byte(toString(s)))), 10)
+	},
+	"toJson": toJSON,
+
+	// lists
+	"list":      func(values ...interface{}) []interface{} { return values },
+	"splitList": func(sep string, s interface{}) []string { return strings.Split(toString(s), sep) },
+	"join":      join,
+	"first":     first,
+	"last":      last,
+	"has":       has,
+	"uniq":      uniq,
+	"without":   without,
+	"compact":   compact,
+	"sortAlpha": sortAlpha,
+
+	// maps
+	"dict":   dict,
+	"get":    get,
+	"hasKey": hasKey,
+	"keys":   keys,
+	"pick":   pick,
+	"omit":   omit,
+
+	// math
+	"int": toInt,
+	"add": func(a interface{}, b interface{}) (int64, error) {
+		return arithmetic(a, b, func(x, y int64) int64 { return x + y })
+	},
+	"sub": func(a interface{}, b interface{}) (int64, error) {
+		return arithmetic(a, b, func(x, y int64) int64 { return x - y })
+	},
+	"mul": func(a interface{}, b interface{}) (int64, error) {
+		return arithmetic(a, b, func(x, y int64) int64 { return x * y })
+	},
+	"div": func(a interface{}, b interface{}) (int64, error) {
+		return division(a, b, func(x, y int64) int64 { return x / y })
+	},
+	"mod": func(a interface{}, b interface{}) (int64, error) {
+		return division(a, b, func(x, y int64) int64 { return x % y })
+	},
+	"max": func(a interface{}, b interface{}) (int64, error) { return arithmetic(a, b, maxInt64) },
+	"min": func(a interface{}, b interface{}) (int64, error) { return arithmetic(a, b, minInt64) },
+
+	// regular expressions
+	"regexMatch":      regexMatch,
+	"regexFind":       regexFind,
+	"regexReplaceAll": regexReplaceAll,
+}
+
+// Random functions, which require a seed. Without a seed they fail template execution
+var seededFuncNames = []string{"randAlphaNum", "randAlpha", "randNumeric", "randAscii", "uuidv4"}
+
+func init() {
+	for _, name := range seededFuncNames {
+		textFuncMap[name] = newSeededFuncs("", "").funcMap()[name]
+	}
+}
+
+/*
+	Defaults
+*/
+
+// If one argument, returns it (or empty string if it's nil). Otherwise first argument is default value and the
+// second is actual value
+func defaultValue(args ...interface{}) interface{} {
+	if len(args) == 0 || len(args) > 2 {
+		// will fail text template execution
+		return nil
+	}
+
+	// if one argument, return it
+	if len(args) == 1 {
+		value := args[0]
+		if value == nil {
+			return ""
+		}
+		return value
+	}
+
+	// otherwise first argument is default value and the second is actual value
+	arg := args[0]
+	value := args[1]
+	if value == nil {
+		return arg
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		if v.Len() == 0 {
+			return arg
+		}
+	case reflect.Bool:
+		if !v.Bool() {
+			return arg
+		}
+	}
+	return value
+}
+
+// Returns true if value is nil, false, zero or has zero length
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Returns the first non-empty value
+func coalesce(values ...interface{}) interface{} {
+	for _, value := range values {
+		if !isEmpty(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Returns first value if condition is true, second one otherwise
+func ternary(valueTrue interface{}, valueFalse interface{}, condition bool) interface{} {
+	if condition {
+		return valueTrue
+	}
+	return valueFalse
+}
+
+/*
+	Strings
+*/
+
+// Converts value to string
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// Returns substring from start to end (negative end means till the end of the string)
+func substr(start int, end int, s interface{}) string {
+	str := toString(s)
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 || end > len(str) {
+		end = len(str)
+	}
+	if start > end {
+		return ""
+	}
+	return str[start:end]
+}
+
+// Truncates string to a given length (negative length means trimming from the beginning)
+func trunc(length int, s interface{}) string {
+	str := toString(s)
+	if length < 0 && len(str)+length > 0 {
+		return str[len(str)+length:]
+	}
+	if length >= 0 && len(str) > length {
+		return str[:length]
+	}
+	return str
+}
+
+// Indents every line of a string with a given number of spaces
+func indent(spaces int, s interface{}) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(toString(s), "\n", "\n"+pad, -1)
+}
+
+// Concatenates non-nil values with spaces
+func cat(values ...interface{}) string {
+	result := []string{}
+	for _, value := range values {
+		if value != nil {
+			result = append(result, toString(value))
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+/*
+	Encoding
+*/
+
+func decode(data []byte, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+/*
+	Lists
+*/
+
+// Converts a slice or an array into a list of values
+func toList(value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{}, nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, got %T", value)
+	}
+	result := make([]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		result[i] = v.Index(i).Interface()
+	}
+	return result, nil
+}
+
+func join(sep string, value interface{}) (string, error) {
+	list, err := toList(value)
+	if err != nil {
+		return "", err
+	}
+	result := make([]string, len(list))
+	for i, item := range list {
+		result[i] = toString(item)
+	}
+	return strings.Join(result, sep), nil
+}
+
+func first(value interface{}) (interface{}, error) {
+	list, err := toList(value)
+	if err != nil || len(list) == 0 {
+		return "", err
+	}
+	return list[0], nil
+}
+
+func last(value interface{}) (interface{}, error) {
+	list, err := toList(value)
+	if err != nil || len(list) == 0 {
+		return "", err
+	}
+	return list[len(list)-1], nil
+}
+
+// Returns true if list contains a given value
+func has(needle interface{}, value interface{}) (bool, error) {
+	list, err := toList(value)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range list {
+		if reflect.DeepEqual(item, needle) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns list without duplicates, preserving the order
+func uniq(value interface{}) ([]interface{}, error) {
+	list, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	for _, item := range list {
+		if found, _ := has(item, result); !found {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// Returns list without given values
+func without(value interface{}, omitted ...interface{}) ([]interface{}, error) {
+	list, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	for _, item := range list {
+		if found, _ := has(item, omitted); !found {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// Returns list without empty values
+func compact(value interface{}) ([]interface{}, error) {
+	list, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	for _, item := range list {
+		if !isEmpty(item) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// Returns list of strings sorted in alphabetical order
+func sortAlpha(value interface{}) ([]string, error) {
+	list, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(list))
+	for i, item := range list {
+		result[i] = toString(item)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+/*
+	Maps
+*/
+
+// Converts a map with string keys into map[string]interface{}
+func toMap(value interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	if value == nil {
+		return result, nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("expected a map with string keys, got %T", value)
+	}
+	for _, key := range v.MapKeys() {
+		result[key.String()] = v.MapIndex(key).Interface()
+	}
+	return result, nil
+}
+
+// Creates a map from a list of key/value pairs
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects an even number of arguments, got %d", len(pairs))
+	}
+	result := make(map[string]interface{})
+	for i := 0; i < len(pairs); i += 2 {
+		result[toString(pairs[i])] = pairs[i+1]
+	}
+	return result, nil
+}
+
+// Returns value from a map by key, or empty string if key is not present
+func get(value interface{}, key string) (interface{}, error) {
+	m, err := toMap(value)
+	if err != nil {
+		return nil, err
+	}
+	if result, ok := m[key]; ok && result != nil {
+		return result, nil
+	}
+	return "", nil
+}
+
+func hasKey(value interface{}, key string) (bool, error) {
+	m, err := toMap(value)
+	if err != nil {
+		return false, err
+	}
+	_, ok := m[key]
+	return ok, nil
+}
+
+// Returns sorted list of map keys
+func keys(value interface{}) ([]string, error) {
+	m, err := toMap(value)
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Returns a new map, containing only given keys
+func pick(value interface{}, picked ...string) (map[string]interface{}, error) {
+	m, err := toMap(value)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	for _, key := range picked {
+		if v, ok := m[key]; ok {
+			result[key] = v
+		}
+	}
+	return result, nil
+}
+
+// Returns a new map, containing all keys except given ones
+func omit(value interface{}, omitted ...string) (map[string]interface{}, error) {
+	m, err := toMap(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range omitted {
+		delete(m, key)
+	}
+	return m, nil
+}
+
+/*
+	Math
+*/
+
+// Converts value (int, float or string, as labels are strings) into integer
+func toInt(value interface{}) (int64, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float()), nil
+	case reflect.String:
+		return strconv.ParseInt(strings.TrimSpace(v.String()), 10, 64)
+	}
+	return 0, fmt.Errorf("unable to convert %T to integer", value)
+}
+
+func arithmetic(a interface{}, b interface{}, op func(int64, int64) int64) (int64, error) {
+	x, err := toInt(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := toInt(b)
+	if err != nil {
+		return 0, err
+	}
+	return op(x, y), nil
+}
+
+func division(a interface{}, b interface{}, op func(int64, int64) int64) (int64, error) {
+	y, err := toInt(b)
+	if err == nil && y == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return arithmetic(a, b, op)
+}
+
+func maxInt64(x, y int64) int64 {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func minInt64(x, y int64) int64 {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+/*
+	Regular expressions
+*/
+
+var regexpCache sync.Map
+
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	if cached, ok := regexpCache.Load(expr); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	result, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(expr, result)
+	return result, nil
+}
+
+func regexMatch(expr string, s interface{}) (bool, error) {
+	re, err := compileRegexp(expr)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(toString(s)), nil
+}
+
+func regexFind(expr string, s interface{}) (string, error) {
+	re, err := compileRegexp(expr)
+	if err != nil {
+		return "", err
+	}
+	return re.FindString(toString(s)), nil
+}
+
+func regexReplaceAll(expr string, s interface{}, repl string) (string, error) {
+	re, err := compileRegexp(expr)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(toString(s), repl), nil
+}
+
+/*
+	Random functions, producing stable values for a given seed
+*/
+
+const (
+	alphabetAlpha   = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	alphabetNumeric = "0123456789"
+	alphabetASCII   = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+)
+
+// seededFuncs generates random values deterministically, as a function of the seed, function name, and its arguments.
+// Secret key gets mixed into all values, so they can't be guessed from the seed (component key) alone
+type seededFuncs struct {
+	seed string
+	key  []byte
+}
+
+func newSeededFuncs(seed string, key string) *seededFuncs {
+	return &seededFuncs{seed: seed, key: []byte(key)}
+}
+
+func (r *seededFuncs) funcMap() t.FuncMap {
+	return t.FuncMap{
+		"randAlphaNum": func(length int, name ...string) (string, error) {
+			return r.chars("randAlphaNum", length, name, alphabetAlpha+alphabetNumeric)
+		},
+		"randAlpha": func(length int, name ...string) (string, error) {
+			return r.chars("randAlpha", length, name, alphabetAlpha)
+		},
+		"randNumeric": func(length int, name ...string) (string, error) {
+			return r.chars("randNumeric", length, name, alphabetNumeric)
+		},
+		"randAscii": func(length int, name ...string) (string, error) {
+			return r.chars("randAscii", length, name, alphabetASCII)
+		},
+		"uuidv4": r.uuid,
+	}
+}
+
+// Returns n pseudo-random bytes, derived from the seed and the given arguments
+func (r *seededFuncs) bytes(n int, args ...string) ([]byte, error) {
+	if len(r.seed) <= 0 {
+		return nil, fmt.Errorf("random functions can only be used in code and discovery params of a component")
+	}
+
+	result := []byte{}
+	for block := uint64(0); len(result) < n; block++ {
+		mac := hmac.New(sha256.New, r.key)
+		counter := make([]byte, 8)
+		binary.BigEndian.PutUint64(counter, block)
+		data := strings.Join(append([]string{r.seed}, args...), "\x00")
+		mac.Write(append([]byte(data), counter...)) // nolint: errcheck
+		result = mac.Sum(result)
+	}
+	return result[:n], nil
+}
+
+// Returns a random string of a given length, consisting of characters from the alphabet. Values with different names
+// within the same component will be different
+func (r *seededFuncs) chars(function string, length int, name []string, alphabet string) (string, error) {
+	if length < 0 || len(name) > 1 {
+		return "", fmt.Errorf("%s expects a non-negative length and an optional name", function)
+	}
+
+	// bytes which don't fit into the largest multiple of the alphabet size are skipped, so that all characters are
+	// equally likely. the stream of bytes is the same for the same arguments, so it gets extended until enough
+	// characters are produced
+	limit := 256 - 256%len(alphabet)
+	result := make([]byte, 0, length)
+	for n := 2 * length; len(result) < length; n *= 2 {
+		data, err := r.bytes(n, append([]string{function, strconv.Itoa(length)}, name...)...)
+		if err != nil {
+			return "", err
+		}
+		result = result[:0]
+		for _, b := range data {
+			if int(b) < limit && len(result) < length {
+				result = append(result, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+	return string(result), nil
+}
+
+// Returns a random UUID (version 4)
+func (r *seededFuncs) uuid(name ...string) (string, error) {
+	if len(name) > 1 {
+		return "", fmt.Errorf("uuidv4 expects an optional name")
+	}
+	data, err := r.bytes(16, append([]string{"uuidv4"}, name...)...)
+	if err != nil {
+		return "", err
+	}
+	data[6] = (data[6] & 0x0f) | 0x40
+	data[8] = (data[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:]), nil
+}
+
+// Returns true if a parsed template calls any of the given functions
+func callsFunctions(node parse.Node, names map[string]bool) bool {
+	if reflect.ValueOf(node).IsNil() {
+		return false
+	}
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			if callsFunctions(child, names) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return callsFunctions(n.Pipe, names)
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			if callsFunctions(cmd, names) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if callsFunctions(arg, names) {
+				return true
+			}
+		}
+	case *parse.IdentifierNode:
+		return names[n.Ident]
+	case *parse.IfNode:
+		return callsFunctions(&n.BranchNode, names)
+	case *parse.RangeNode:
+		return callsFunctions(&n.BranchNode, names)
+	case *parse.WithNode:
+		return callsFunctions(&n.BranchNode, names)
+	case *parse.BranchNode:
+		return callsFunctions(n.Pipe, names) || callsFunctions(n.List, names) || callsFunctions(n.ElseList, names)
+	case *parse.TemplateNode:
+		return callsFunctions(n.Pipe, names)
+	}
+	return false
+}
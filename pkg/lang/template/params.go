@@ -3,9 +3,20 @@ package template
 // Parameters is a set of named parameters for the text template
 type Parameters struct {
 	params interface{}
+
+	// seed for random functions (random values are stable for the same seed) and a secret key mixed into them
+	seed string
+	key  string
 }
 
 // NewParams creates a new instance of Parameters
 func NewParams(params interface{}) *Parameters {
 	return &Parameters{params: params}
 }
+
+// NewParamsWithSeed creates a new instance of Parameters with a seed, which enables random functions in templates.
+// They produce stable values for the same seed, so the seed should be a key of the object being processed. The secret
+// key gets mixed into all random values, so they can't be guessed from the seed alone
+func NewParamsWithSeed(params interface{}, seed string, key string) *Parameters {
+	return &Parameters{params: params, seed: seed, key: key}
+}
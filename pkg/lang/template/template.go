@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/errors"
-	"reflect"
 	"strings"
 	t "text/template"
 )
@@ -13,45 +12,9 @@ import (
 type Template struct {
 	templateStr      string
 	templateCompiled *t.Template
-}
-
-// Custom functions
-var textFuncMap = t.FuncMap{
-	"default": func(args ...interface{}) interface{} {
-		if len(args) == 0 || len(args) > 2 {
-			// will fail text template execution
-			return nil
-		}
-
-		// if one argument, return it
-		if len(args) == 1 {
-			value := args[0]
-			if value == nil {
-				return ""
-			}
-			return value
-		}
-
-		// otherwise first argument is default value and the second is actual value
-		arg := args[0]
-		value := args[1]
-		if value == nil {
-			return arg
-		}
 
-		v := reflect.ValueOf(value)
-		switch v.Kind() {
-		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
-			if v.Len() == 0 {
-				return arg
-			}
-		case reflect.Bool:
-			if !v.Bool() {
-				return arg
-			}
-		}
-		return value
-	},
+	// whether template calls random functions, which need a seed
+	seeded bool
 }
 
 // NewTemplate compiles a text template and returns the result in Template struct
@@ -61,9 +24,19 @@ func NewTemplate(templateStr string) (*Template, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to compile template '%s': %s", templateStr, err)
 	}
+	seededFuncs := make(map[string]bool)
+	for _, name := range seededFuncNames {
+		seededFuncs[name] = true
+	}
+	seeded := false
+	for _, tmpl := range templateCompiled.Templates() {
+		seeded = seeded || (tmpl.Tree != nil && callsFunctions(tmpl.Tree.Root, seededFuncs))
+	}
+
 	return &Template{
 		templateStr:      templateStr,
 		templateCompiled: templateCompiled,
+		seeded:           seeded,
 	}, nil
 }
 
@@ -73,7 +46,17 @@ func (template *Template) Evaluate(params *Parameters) (string, error) {
 	var doc bytes.Buffer
 
 	// Multiple executions of the same template can execute safely in parallel
-	err := template.templateCompiled.Execute(&doc, params.params)
+	templateCompiled := template.templateCompiled
+	if template.seeded && len(params.seed) > 0 {
+		// random functions need to be bound to the seed, so a separate copy of the template is executed
+		var err error
+		templateCompiled, err = templateCompiled.Clone()
+		if err != nil {
+			return "", fmt.Errorf("unable to evaluate template '%s': %s", template.templateStr, err)
+		}
+		templateCompiled.Funcs(newSeededFuncs(params.seed, params.key).funcMap())
+	}
+	err := templateCompiled.Execute(&doc, params.params)
 	if err != nil {
 		return "", errors.NewErrorWithDetails(
 			fmt.Sprintf("unable to evaluate template '%s': %s", template.templateStr, err),
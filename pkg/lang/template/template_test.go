@@ -93,3 +93,110 @@ func TestTemplateEvaluation(t *testing.T) {
 	}
 
 }
+
+func TestTemplateFunctions(t *testing.T) {
+	params := NewParams(struct {
+		Labels interface{}
+	}{
+		map[string]string{
+			"name":    "MySQL",
+			"zones":   "us-east,us-west,us-east",
+			"replica": "3",
+			"empty":   "",
+		},
+	})
+
+	tests := []struct {
+		template       string
+		result         int
+		expectedString string
+	}{
+		// strings
+		{"{{ lower .Labels.name }}-{{ upper .Labels.name }}", ResSuccess, "mysql-MYSQL"},
+		{"{{ .Labels.name | trimPrefix \"My\" | quote }}", ResSuccess, "\"SQL\""},
+		{"{{ replace \"-\" \"_\" \"a-b-c\" }}", ResSuccess, "a_b_c"},
+		{"{{ trunc 2 .Labels.name }}/{{ substr 2 -1 .Labels.name }}", ResSuccess, "My/SQL"},
+		{"{{ indent 2 \"a\\nb\" }}", ResSuccess, "  a\n  b"},
+
+		// encoding and hashing
+		{"{{ b64enc .Labels.name }}", ResSuccess, "TXlTUUw="},
+		{"{{ b64enc .Labels.name | b64dec }}", ResSuccess, "MySQL"},
+		{"{{ b64dec \"%%%\" }}", ResEvalError, ""},
+		{"{{ sha256sum \"abc\" }}", ResSuccess, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"{{ toJson .Labels.name }}", ResSuccess, "\"MySQL\""},
+
+		// lists
+		{"{{ splitList \",\" .Labels.zones | uniq | join \";\" }}", ResSuccess, "us-east;us-west"},
+		{"{{ list \"b\" \"\" \"a\" | compact | sortAlpha | join \",\" }}", ResSuccess, "a,b"},
+		{"{{ splitList \",\" .Labels.zones | first }}", ResSuccess, "us-east"},
+		{"{{ has \"us-west\" (splitList \",\" .Labels.zones) }}", ResSuccess, "true"},
+		{"{{ join \",\" .Labels.name }}", ResEvalError, ""},
+
+		// maps
+		{"{{ keys .Labels | join \",\" }}", ResSuccess, "empty,name,replica,zones"},
+		{"{{ get .Labels \"name\" }}{{ get .Labels \"missing\" }}", ResSuccess, "MySQL"},
+		{"{{ hasKey .Labels \"replica\" }}", ResSuccess, "true"},
+		{"{{ pick .Labels \"name\" \"missing\" | keys | join \",\" }}", ResSuccess, "name"},
+		{"{{ omit .Labels \"name\" \"empty\" | keys | join \",\" }}", ResSuccess, "replica,zones"},
+		{"{{ dict \"a\" }}", ResEvalError, ""},
+
+		// math
+		{"{{ add .Labels.replica 2 }}-{{ mul .Labels.replica 2 }}-{{ max .Labels.replica 5 }}", ResSuccess, "5-6-5"},
+		{"{{ div .Labels.replica 0 }}", ResEvalError, ""},
+		{"{{ add .Labels.name 1 }}", ResEvalError, ""},
+
+		// defaults
+		{"{{ coalesce .Labels.empty .Labels.name }}", ResSuccess, "MySQL"},
+		{"{{ ternary \"yes\" \"no\" (empty .Labels.empty) }}", ResSuccess, "yes"},
+
+		// regular expressions
+		{"{{ regexMatch \"^My\" .Labels.name }}", ResSuccess, "true"},
+		{"{{ regexReplaceAll \"[aeiou]\" \"facebook\" \"_\" }}", ResSuccess, "f_c_b__k"},
+		{"{{ regexMatch \"(\" .Labels.name }}", ResEvalError, ""},
+
+		// random functions are not available without a seed
+		{"{{ randAlphaNum 16 }}", ResEvalError, ""},
+	}
+
+	for _, test := range tests {
+		evaluate(t, test.template, test.result, test.expectedString, params)
+	}
+
+	cache := NewCache()
+	for _, test := range tests {
+		evaluateWithCache(t, test.template, test.result, test.expectedString, params, cache)
+	}
+}
+
+func TestTemplateRandomFunctions(t *testing.T) {
+	key := ""
+	evaluateWithSeed := func(templateStr string, seed string) string {
+		t.Helper()
+		tmpl, err := NewTemplate(templateStr)
+		if !assert.NoError(t, err, "Template should be compiled: %s", templateStr) {
+			t.FailNow()
+		}
+		result, err := tmpl.Evaluate(NewParamsWithSeed(struct{}{}, seed, key))
+		if !assert.NoError(t, err, "Template should be evaluated: %s", templateStr) {
+			t.FailNow()
+		}
+		return result
+	}
+
+	// values should be stable for the same seed
+	password := evaluateWithSeed("{{ randAlphaNum 16 }}", "component-1")
+	assert.Regexp(t, "^[a-zA-Z0-9]{16}$", password, "Random value should have expected length and characters")
+	assert.Equal(t, password, evaluateWithSeed("{{ randAlphaNum 16 }}", "component-1"), "Random value should be stable for the same seed")
+
+	// values should be different for different seeds and names
+	assert.NotEqual(t, password, evaluateWithSeed("{{ randAlphaNum 16 }}", "component-2"), "Random value should be different for another seed")
+	assert.NotEqual(t, password, evaluateWithSeed("{{ randAlphaNum 16 \"admin\" }}", "component-1"), "Random value should be different for another name")
+
+	assert.Regexp(t, "^[0-9]{8}$", evaluateWithSeed("{{ randNumeric 8 }}", "component-1"), "Random number should consist of digits")
+	assert.Len(t, evaluateWithSeed("{{ randAscii 1000 }}", "component-1"), 1000, "Random value should have expected length, even if some random bytes are skipped")
+	assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", evaluateWithSeed("{{ uuidv4 }}", "component-1"), "UUID should be valid")
+
+	// key should be mixed into random values
+	key = "secret"
+	assert.NotEqual(t, password, evaluateWithSeed("{{ randAlphaNum 16 }}", "component-1"), "Random value should depend on the key")
+}
@@ -14,6 +14,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // Constants
@@ -23,6 +24,13 @@ var (
 	codeTypes       = []string{"helm", "raw"}
 	labelOpsKeys    = []string{"set", "remove"}
 	allowReject     = []string{"allow", "reject"}
+	paramTypes      = []string{ParamTypeString, ParamTypeInt, ParamTypeFloat, ParamTypeBool}
+
+	// structs which can be referred to from rule criteria (must be in sync with what the resolver exposes)
+	ruleStructRefs = []string{"Service", "Dependency"}
+
+	// structs which can be referred to from context and component criteria (must be in sync with what the resolver exposes)
+	contextStructRefs = []string{"Params"}
 )
 
 // Custom type for context key, so we don't have to use 'string' directly
@@ -64,6 +72,8 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 	_ = result.RegisterValidationCtx("identifier", validateIdentifier)
 	_ = result.RegisterValidationCtx("clustertype", validateClusterType)
 	_ = result.RegisterValidationCtx("codetype", validateCodeType)
+	_ = result.RegisterValidationCtx("paramtype", validateParamType)
+	_ = result.RegisterValidationCtx("duration", validateDuration)
 	_ = result.RegisterValidationCtx("expression", validateExpression)
 	_ = result.RegisterValidationCtx("template", validateTemplate)
 	_ = result.RegisterValidationCtx("templateNestedMap", validateTemplateNestedMap)
@@ -78,6 +88,7 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 	result.RegisterStructValidationCtx(validateService, Service{})
 	result.RegisterStructValidationCtx(validateDependency, Dependency{})
 	result.RegisterStructValidationCtx(validateContract, Contract{})
+	result.RegisterStructValidation(validateQuota, Quota{})
 
 	// context
 	ctx := context.WithValue(context.Background(), policyKey, policy)
@@ -105,6 +116,18 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 			tag:         "codetype",
 			translation: fmt.Sprintf("'{0}' is not valid, must be in %s", codeTypes),
 		},
+		{
+			tag:         "paramtype",
+			translation: fmt.Sprintf("'{0}' is not valid, must be in %s", paramTypes),
+		},
+		{
+			tag:         "duration",
+			translation: fmt.Sprintf("'{0}' is not a valid duration (e.g. '30s', '5m')"),
+		},
+		{
+			tag:         "params",
+			translation: fmt.Sprintf("'{0}' is not valid: {1}"),
+		},
 		{
 			tag:         "allowReject",
 			translation: fmt.Sprintf("'{0}' is not valid, must be in %s", allowReject),
@@ -149,6 +172,10 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 			tag:         "codeContractSingle",
 			translation: fmt.Sprintf("component '{0}' should either be code or contract"),
 		},
+		{
+			tag:         "hooksCodeOnly",
+			translation: fmt.Sprintf("component '{0}' can't have hooks, as only code components support them"),
+		},
 		{
 			tag:         "unique",
 			translation: fmt.Sprintf("'{0}' is not unique"),
@@ -161,10 +188,22 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 			tag:         "ruleActions",
 			translation: fmt.Sprintf("is a required field (at least one action must be specified)"),
 		},
+		{
+			tag:         "structRef",
+			translation: fmt.Sprintf("'{0}' refers to '{1}', which doesn't exist in this context"),
+		},
+		{
+			tag:         "contextsOrVersions",
+			translation: fmt.Sprintf("contexts should either be specified in the contract or in contract versions"),
+		},
 		{
 			tag:         "aclRuleActions",
 			translation: fmt.Sprintf("is a required field (role assignment map must be specified)"),
 		},
+		{
+			tag:         "quotaLimits",
+			translation: fmt.Sprintf("is a required field (at least one limit must be specified)"),
+		},
 	}
 	for _, t := range translations {
 		err = result.RegisterTranslation(t.tag, trans, registrationFunc(t.tag, t.translation), translateFunc)
@@ -250,6 +289,17 @@ func validateCodeType(ctx context.Context, fl validator.FieldLevel) bool {
 	return validateInStringArray(ctx, codeTypes, fl)
 }
 
+// checks if a given string is a valid contract param type
+func validateParamType(ctx context.Context, fl validator.FieldLevel) bool {
+	return validateInStringArray(ctx, paramTypes, fl)
+}
+
+// checks if a given string is a valid positive duration
+func validateDuration(ctx context.Context, fl validator.FieldLevel) bool {
+	value, err := time.ParseDuration(fl.Field().String())
+	return err == nil && value > 0
+}
+
 // checks if a given string is valid identifier
 func validateIdentifier(ctx context.Context, fl validator.FieldLevel) bool {
 	return isIdentifier(fl.Field().String())
@@ -350,6 +400,24 @@ func validateService(ctx context.Context, sl validator.StructLevel) {
 			return
 		}
 
+		// hooks can only be set for code components and should not have duplicate names
+		if component.Hooks != nil {
+			if component.Code == nil {
+				sl.ReportError(component.Name, fmt.Sprintf("Component[%s].Hooks", component.Name), "", "hooksCodeOnly", "")
+				return
+			}
+			hookNames := make(map[string]bool)
+			for _, phase := range HookPhases {
+				for _, hook := range component.Hooks.GetHooks(phase) {
+					if hookNames[hook.Name] {
+						sl.ReportError(hook.Name, fmt.Sprintf("Component[%s].Hooks[%s]", component.Name, hook.Name), "", "unique", "")
+						return
+					}
+					hookNames[hook.Name] = true
+				}
+			}
+		}
+
 		// if contract is set, it should point to an existing contract
 		if len(component.Contract) > 0 {
 			obj, err := policy.GetObject(ContractObject.Kind, component.Contract, service.Namespace)
@@ -357,9 +425,19 @@ func validateService(ctx context.Context, sl validator.StructLevel) {
 				sl.ReportError(component.Contract, fmt.Sprintf("Component[%s].Contract[%s/%s]", component.Name, service.Namespace, component.Contract), "", "exists", "")
 				return
 			}
+
+			// contract components don't supply params, so all required params of the contract should have defaults
+			if _, err = obj.(*Contract).ResolveParams(nil); err != nil {
+				sl.ReportError(component.Contract, fmt.Sprintf("Component[%s].Contract[%s/%s].Params", component.Name, service.Namespace, component.Contract), "", "params", err.Error())
+			}
 		}
 	}
 
+	// component criteria get evaluated on labels and contract params
+	for _, component := range service.Components {
+		validateCriteriaStructRefs(sl, component.Criteria, fmt.Sprintf("Component[%s].Criteria", component.Name), contextStructRefs)
+	}
+
 	// components should not have duplicate names
 	componentNames := make(map[string]bool)
 	for _, component := range service.Components {
@@ -385,6 +463,15 @@ func validateService(ctx context.Context, sl validator.StructLevel) {
 			}
 		}
 	}
+
+	// outputs should not have duplicate names
+	outputNames := make(map[string]bool)
+	for _, output := range service.Outputs {
+		if outputNames[output.Name] {
+			sl.ReportError(output.Name, fmt.Sprintf("Outputs[%s].Name", output.Name), "", "unique", "")
+		}
+		outputNames[output.Name] = true
+	}
 }
 
 // checks if dependency is valid
@@ -398,6 +485,16 @@ func validateDependency(ctx context.Context, sl validator.StructLevel) {
 		sl.ReportError(dependency.Contract, fmt.Sprintf("Contract[%s/%s]", dependency.Namespace, dependency.Contract), "", "exists", "")
 		return
 	}
+
+	// pinned version should exist in the contract
+	if len(dependency.Version) > 0 && obj.(*Contract).GetVersion(dependency.Version) == nil {
+		sl.ReportError(dependency.Version, fmt.Sprintf("Contract[%s/%s].Versions[%s]", dependency.Namespace, dependency.Contract, dependency.Version), "", "exists", "")
+	}
+
+	// params should be declared in the contract and conform to their types
+	if _, err = obj.(*Contract).ResolveParams(dependency.Params); err != nil {
+		sl.ReportError(fmt.Sprintf("%v", dependency.Params), "Params", "", "params", err.Error())
+	}
 }
 
 // checks if contract is valid
@@ -405,8 +502,46 @@ func validateContract(ctx context.Context, sl validator.StructLevel) {
 	contract := sl.Current().Addr().Interface().(*Contract)
 	policy := ctx.Value(policyKey).(*Policy)
 
+	// contexts should be defined either in the contract, or in contract versions
+	if len(contract.Versions) > 0 && len(contract.Contexts) > 0 {
+		sl.ReportError(contract.Contexts, "Contexts", "", "contextsOrVersions", "")
+	}
+
+	// version names should be unique
+	versionNames := make(map[string]bool)
+	for _, version := range contract.Versions {
+		if versionNames[version.Name] {
+			sl.ReportError(version.Name, fmt.Sprintf("Versions[%s]", version.Name), "", "unique", "")
+		}
+		versionNames[version.Name] = true
+	}
+
+	// param names should be unique, and default & allowed values should conform to param types
+	paramNames := make(map[string]bool)
+	for _, param := range contract.Params {
+		if paramNames[param.Name] {
+			sl.ReportError(param.Name, fmt.Sprintf("Params[%s]", param.Name), "", "unique", "")
+		}
+		paramNames[param.Name] = true
+
+		// invalid param types are reported by the 'paramtype' validator
+		if !util.ContainsString(paramTypes, param.GetType()) {
+			continue
+		}
+		for idx, value := range param.Allowed {
+			if _, err := convertParamValue(param.GetType(), value); err != nil {
+				sl.ReportError(fmt.Sprintf("%v", value), fmt.Sprintf("Params[%s].Allowed[%d]", param.Name, idx), "", "params", err.Error())
+			}
+		}
+		if param.Default != nil {
+			if _, err := param.Convert(param.Default); err != nil {
+				sl.ReportError(fmt.Sprintf("%v", param.Default), fmt.Sprintf("Params[%s].Default", param.Name), "", "params", err.Error())
+			}
+		}
+	}
+
 	// every context should point to an existing service
-	for _, contractCtx := range contract.Contexts {
+	for _, contractCtx := range contract.GetAllContexts() {
 		serviceName := ""
 		if contractCtx.Allocation != nil {
 			serviceName = contractCtx.Allocation.Service
@@ -417,12 +552,24 @@ func validateContract(ctx context.Context, sl validator.StructLevel) {
 			return
 		}
 	}
+
+	// context criteria get evaluated on labels and contract params
+	for _, contractCtx := range contract.GetAllContexts() {
+		validateCriteriaStructRefs(sl, contractCtx.Criteria, fmt.Sprintf("Contexts[%s].Criteria", contractCtx.Name), contextStructRefs)
+	}
 }
 
 // checks if rule is valid
 func validateRule(sl validator.StructLevel) {
 	rule := sl.Current().Addr().Interface().(*Rule)
 
+	// rule criteria can only refer to structs exposed to rules (and ACL rules get evaluated on user labels only)
+	if rule.GetKind() == RuleObject.Kind {
+		validateCriteriaStructRefs(sl, rule.Criteria, "Criteria", ruleStructRefs)
+	} else {
+		validateCriteriaStructRefs(sl, rule.Criteria, "Criteria", nil)
+	}
+
 	// regular rule should have at least one of the actions set
 	if rule.GetKind() == RuleObject.Kind {
 		hasActions := false
@@ -447,6 +594,64 @@ func validateRule(sl validator.StructLevel) {
 	}
 }
 
+// checks if quota is valid
+func validateQuota(sl validator.StructLevel) {
+	quota := sl.Current().Addr().Interface().(*Quota)
+
+	// quota criteria get evaluated on labels only
+	validateCriteriaStructRefs(sl, quota.Criteria, "Criteria", nil)
+
+	// quota should have at least one of the limits set, otherwise it doesn't limit anything
+	if quota.MaxDependencies <= 0 && quota.MaxInstancesPerCluster <= 0 && quota.MaxInstancesPerService <= 0 {
+		sl.ReportError(quota.MaxDependencies, "MaxDependencies", "", "quotaLimits", "")
+	}
+}
+
+// checks that criteria expressions don't refer to structs which will never be supplied to them during policy
+// resolution (e.g. a typo in 'Service.Name'). Labels can't be checked statically, as they are only known at runtime
+func validateCriteriaStructRefs(sl validator.StructLevel, criteria *Criteria, fieldName string, allowed []string) {
+	if criteria == nil {
+		return
+	}
+	clauses := []struct {
+		name        string
+		expressions []string
+	}{
+		{"RequireAll", criteria.RequireAll},
+		{"RequireAny", criteria.RequireAny},
+		{"RequireNone", criteria.RequireNone},
+	}
+	for _, clause := range clauses {
+		for idx, expressionStr := range clause.expressions {
+			expr, err := expression.NewExpression(expressionStr)
+			if err != nil {
+				// invalid expressions are reported by the 'expression' validator
+				continue
+			}
+			for _, ref := range expr.StructRefs() {
+				if !util.ContainsString(allowed, ref) {
+					sl.ReportError(expressionStr, fmt.Sprintf("%s.%s[%d]", fieldName, clause.name, idx), "", "structRef", ref)
+				}
+			}
+		}
+	}
+
+	// nested groups are checked recursively
+	groups := []struct {
+		name     string
+		criteria []*Criteria
+	}{
+		{"RequireAllOf", criteria.RequireAllOf},
+		{"RequireAnyOf", criteria.RequireAnyOf},
+		{"RequireNoneOf", criteria.RequireNoneOf},
+	}
+	for _, group := range groups {
+		for idx, nested := range group.criteria {
+			validateCriteriaStructRefs(sl, nested, fmt.Sprintf("%s.%s[%d]", fieldName, group.name, idx), allowed)
+		}
+	}
+}
+
 // checks if cluster is valid
 func validateCluster(sl validator.StructLevel) {
 	cluster := sl.Current().Addr().Interface().(*Cluster)
@@ -69,6 +69,92 @@ func TestPolicyValidationService(t *testing.T) {
 		service.Components = components
 		runValidationTests(t, ResFailure, false, []Base{service, contract})
 	}
+
+	// Wait for ready
+	waitTestsPass := []*WaitForReady{
+		{},
+		{Timeout: "30s"},
+		{Timeout: "5m", Interval: "500ms"},
+	}
+	for _, wait := range waitTestsPass {
+		service := makeService("service", Empty)
+		service.Components = makeServiceComponents(1, "", 1, 0)
+		service.Components[0].Code.WaitForReady = wait
+		runValidationTests(t, ResSuccess, false, []Base{service})
+	}
+	waitTestsFail := []*WaitForReady{
+		{Timeout: "5"},
+		{Timeout: "-5s"},
+		{Timeout: "5m", Interval: "often"},
+	}
+	for _, wait := range waitTestsFail {
+		service := makeService("service", Empty)
+		service.Components = makeServiceComponents(1, "", 1, 0)
+		service.Components[0].Code.WaitForReady = wait
+		runValidationTests(t, ResFailure, false, []Base{service})
+	}
+
+	// Code timeout
+	for _, timeout := range []string{"", "30s", "10m"} {
+		service := makeService("service", Empty)
+		service.Components = makeServiceComponents(1, "", 1, 0)
+		service.Components[0].Code.Timeout = timeout
+		runValidationTests(t, ResSuccess, false, []Base{service})
+	}
+	for _, timeout := range []string{"10", "-1m", "forever"} {
+		service := makeService("service", Empty)
+		service.Components = makeServiceComponents(1, "", 1, 0)
+		service.Components[0].Code.Timeout = timeout
+		runValidationTests(t, ResFailure, false, []Base{service})
+	}
+
+	// Hooks
+	hookCode := &Code{Type: "raw", Params: util.NestedParameterMap{"manifest": "{{ .Labels.cluster }}"}}
+	hookTestsPass := []*ComponentHooks{
+		{},
+		{PreUpdate: []*Hook{{Name: "migrate", Code: hookCode}}},
+		{PreCreate: []*Hook{{Name: "init", Code: hookCode}}, PreDelete: []*Hook{{Name: "backup", Code: hookCode}}},
+	}
+	for _, hooks := range hookTestsPass {
+		service := makeService("service", Empty)
+		service.Components = makeServiceComponents(1, "", 1, 0)
+		service.Components[0].Hooks = hooks
+		runValidationTests(t, ResSuccess, false, []Base{service})
+	}
+	hookTestsFail := []*ComponentHooks{
+		{PreUpdate: []*Hook{{Name: "_invalid", Code: hookCode}}},
+		{PreUpdate: []*Hook{{Name: "migrate"}}},
+		{PreUpdate: []*Hook{{Name: "migrate", Code: &Code{Type: "unknown"}}}},
+		{PreUpdate: []*Hook{{Name: "migrate", Code: hookCode}}, PostUpdate: []*Hook{{Name: "migrate", Code: hookCode}}},
+	}
+	for _, hooks := range hookTestsFail {
+		service := makeService("service", Empty)
+		service.Components = makeServiceComponents(1, "", 1, 0)
+		service.Components[0].Hooks = hooks
+		runValidationTests(t, ResFailure, false, []Base{service})
+	}
+	serviceHooksOnContract := makeService("service", Empty)
+	serviceHooksOnContract.Components = makeServiceComponents(1, contract.Name, Nil, 0)
+	serviceHooksOnContract.Components[0].Hooks = hookTestsPass[1]
+	runValidationTests(t, ResFailure, false, []Base{serviceHooksOnContract, contract})
+
+	// Service Outputs
+	service := makeService("service", Empty)
+	service.Outputs = []*ServiceOutput{
+		{Name: "url", Value: "{{ .Discovery.component.url }}"},
+		{Name: "password", Value: "{{ .Discovery.component.password }}", Sensitive: true},
+	}
+	runValidationTests(t, ResSuccess, false, []Base{service})
+	outputTestsFail := [][]*ServiceOutput{
+		{{Name: "_invalid", Value: "value"}},
+		{{Name: "url", Value: "{{ .Discovery.component.url "}},
+		{{Name: "url", Value: "a"}, {Name: "url", Value: "b"}},
+	}
+	for _, outputs := range outputTestsFail {
+		service = makeService("service", Empty)
+		service.Outputs = outputs
+		runValidationTests(t, ResFailure, false, []Base{service})
+	}
 }
 
 func TestPolicyValidationContract(t *testing.T) {
@@ -100,6 +186,30 @@ func TestPolicyValidationContract(t *testing.T) {
 		makeService("service", Empty),
 		invalidAllocationKeys(makeContract("test1", 0, "service")),
 	})
+
+	// Context criteria can't refer to structs, as they only get labels
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeService("service", Empty),
+		contextCriteria(makeContract("test1", 0, "service"), "specialname == 'b'"),
+	})
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		contextCriteria(makeContract("test1", 0, "service"), "Service.Name == 'b'"),
+	})
+
+	// Nested criteria groups get validated as well
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeService("service", Empty),
+		contextNestedCriteria(makeContract("test1", 0, "service"), "specialname == 'b'"),
+	})
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		contextNestedCriteria(makeContract("test1", 0, "service"), "Service.Name == 'b'"),
+	})
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		contextNestedCriteria(makeContract("test1", 0, "service"), "specialname + '123')((("),
+	})
 }
 
 func TestPolicyValidationDependency(t *testing.T) {
@@ -112,6 +222,82 @@ func TestPolicyValidationDependency(t *testing.T) {
 		makeContract("contract", 0, ""),
 		makeDependency("contract-unknown"),
 	})
+
+	// Dependency can pin an existing contract version
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeService("service", Empty),
+		makeVersionedContract("contract", "service", "v1", "v2"),
+		makeDependencyWithVersion("contract", "v1"),
+	})
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		makeVersionedContract("contract", "service", "v1", "v2"),
+		makeDependencyWithVersion("contract", "v3"),
+	})
+}
+
+func TestPolicyValidationContractParams(t *testing.T) {
+	// Dependency params should conform to contract params
+	contract := makeContract("contract", 0, "service")
+	contract.Params = []*ContractParam{
+		{Name: "size", Default: "small", Allowed: []interface{}{"small", "large"}},
+		{Name: "replicas", Type: ParamTypeInt},
+	}
+	dependency := makeDependency("contract")
+	dependency.Params = map[string]interface{}{"size": "large", "replicas": 3}
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeService("service", Empty),
+		contract,
+		dependency,
+	})
+
+	invalidParams := []map[string]interface{}{
+		{"size": "medium"},
+		{"replicas": "many"},
+		{"unknown": "value"},
+	}
+	for _, params := range invalidParams {
+		dependency = makeDependency("contract")
+		dependency.Params = params
+		runValidationTests(t, ResFailure, false, []Base{
+			makeService("service", Empty),
+			contract,
+			dependency,
+		})
+	}
+
+	// Contract params should have valid types, unique names and valid defaults
+	invalidContractParams := [][]*ContractParam{
+		{{Name: "size", Type: "list"}},
+		{{Name: "size"}, {Name: "size"}},
+		{{Name: "replicas", Type: ParamTypeInt, Default: "many"}},
+		{{Name: "size", Default: "medium", Allowed: []interface{}{"small", "large"}}},
+		{{Name: "replicas", Type: ParamTypeInt, Allowed: []interface{}{1, "two"}}},
+	}
+	for _, params := range invalidContractParams {
+		contract = makeContract("contract", 0, "service")
+		contract.Params = params
+		runValidationTests(t, ResFailure, false, []Base{
+			makeService("service", Empty),
+			contract,
+		})
+	}
+}
+
+func TestPolicyValidationContractVersions(t *testing.T) {
+	// Contract should not have both contexts and versions
+	contract := makeVersionedContract("contract", "service", "v1")
+	contract.Contexts = makeContract("contract", 0, "service").Contexts
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		contract,
+	})
+
+	// Contract version names should be unique
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		makeVersionedContract("contract", "service", "v1", "v1"),
+	})
 }
 
 func TestPolicyValidationRule(t *testing.T) {
@@ -120,10 +306,15 @@ func TestPolicyValidationRule(t *testing.T) {
 		makeRule(1, "true", 0, "labelName"),
 		makeRule(20, "", 1, Reject),
 		makeRule(100, "specialname + specialvalue == 'b'", 2, Reject),
+		makeRule(100, "has('specialname') && matches(specialvalue, '^b')", 2, Reject),
+		makeRule(100, "Service.Name == 'b' || Dependency.ID == 'b'", 2, Reject),
 	})
 	runValidationTests(t, ResFailure, true, []Base{
 		makeRule(-1, "true", 0, "labelName"),                               // negative weight
 		makeRule(100, "specialname + '123')(((", 0, "labelName"),           // bad expression
+		makeRule(100, "matches(specialname)", 0, "labelName"),              // wrong number of function arguments
+		makeRule(100, "matches(specialname, '(')", 0, "labelName"),         // bad regular expression
+		makeRule(100, "Servce.Name == 'b'", 0, "labelName"),                // refers to struct which doesn't exist
 		makeRule(100, "true", Empty, ""),                                   // no actions specified
 		makeRule(100, "true", Nil, ""),                                     // actions = nil
 		makeRule(100, "specialname + specialvalue == 'b'", 2, "notreject"), // action is not (allow, reject)
@@ -153,6 +344,19 @@ func TestPolicyValidationCluster(t *testing.T) {
 	})
 }
 
+func TestPolicyValidationQuota(t *testing.T) {
+	runValidationTests(t, ResSuccess, true, []Base{
+		makeQuota("", 10, 0, 0),
+		makeQuota("specialname == 'b'", 0, 5, 1),
+	})
+	runValidationTests(t, ResFailure, true, []Base{
+		makeQuota("specialname + '123')(((", 10, 0, 0), // bad expression
+		makeQuota("", -1, 5, 0),                        // negative limit
+		makeQuota("", 0, 0, 0),                         // no limits specified
+		makeQuota("Service.Name == 'b'", 10, 0, 0),     // quota criteria only get labels
+	})
+}
+
 func runValidationTests(t *testing.T, result int, every bool, objects []Base) {
 	t.Helper()
 
@@ -301,6 +505,40 @@ func invalidAllocationKeys(contract *Contract) *Contract {
 	return contract
 }
 
+func contextCriteria(contract *Contract, expr string) *Contract {
+	for _, context := range contract.Contexts {
+		context.Criteria = &Criteria{RequireAll: []string{expr}}
+	}
+	return contract
+}
+
+func contextNestedCriteria(contract *Contract, expr string) *Contract {
+	for _, context := range contract.Contexts {
+		context.Criteria = &Criteria{RequireAnyOf: []*Criteria{
+			{RequireAll: []string{"true"}},
+			{RequireNoneOf: []*Criteria{{RequireAll: []string{expr}}}},
+		}}
+	}
+	return contract
+}
+
+func makeQuota(expr string, maxDependencies, maxInstancesPerCluster, maxInstancesPerService int) *Quota {
+	quota := &Quota{
+		TypeKind: QuotaObject.GetTypeKind(),
+		Metadata: Metadata{
+			Namespace: "main",
+			Name:      "quota",
+		},
+		MaxDependencies:        maxDependencies,
+		MaxInstancesPerCluster: maxInstancesPerCluster,
+		MaxInstancesPerService: maxInstancesPerService,
+	}
+	if len(expr) > 0 {
+		quota.Criteria = &Criteria{RequireAll: []string{expr}}
+	}
+	return quota
+}
+
 func makeCluster(clusterType, ns string) *Cluster {
 	return &Cluster{
 		TypeKind: ClusterObject.GetTypeKind(),
@@ -349,6 +587,30 @@ func makeDependency(contract string) *Dependency {
 	return dependency
 }
 
+func makeDependencyWithVersion(contract string, version string) *Dependency {
+	dependency := makeDependency(contract)
+	dependency.Version = version
+	return dependency
+}
+
+func makeVersionedContract(name string, pointToService string, versions ...string) *Contract {
+	contract := makeContract(name, 0, "")
+	for _, version := range versions {
+		contract.Versions = append(contract.Versions, &ContractVersion{
+			Name: version,
+			Contexts: []*Context{
+				{
+					Name: "context-" + version,
+					Allocation: &Allocation{
+						Service: pointToService,
+					},
+				},
+			},
+		})
+	}
+	return contract
+}
+
 func makeServiceComponents(count int, contract string, codeNum int, discoveryNum int) []*ServiceComponent {
 	result := make([]*ServiceComponent, count)
 	for i := 0; i < count; i++ {
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// inFlight is a set of names of deployments, for which a blocking call is still running (possibly in background,
+	// after its context got cancelled)
+	inFlight     = make(map[string]bool)
+	inFlightLock sync.Mutex
+)
+
+// RunWithContext runs a blocking function, which doesn't support cancellation by itself (e.g. a call to Tiller or
+// Kubernetes API), and returns as soon as the function completes or the context gets cancelled, whichever comes
+// first. If context gets cancelled, the function keeps running in background and its result gets discarded.
+//
+// Calls are tracked by deployment name. While a call for a given deployment is still running (including the ones
+// which have been abandoned in background), new calls for the same deployment fail right away, so the deployment
+// doesn't get modified concurrently
+func RunWithContext(ctx context.Context, deployName string, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	inFlightLock.Lock()
+	if inFlight[deployName] {
+		inFlightLock.Unlock()
+		return fmt.Errorf("previous operation on '%s' is still in progress", deployName)
+	}
+	inFlight[deployName] = true
+	inFlightLock.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			inFlightLock.Lock()
+			delete(inFlight, deployName)
+			inFlightLock.Unlock()
+		}()
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TimeoutSeconds returns timeout in seconds for a blocking call, which has a timeout of its own (e.g. Tiller
+// operations), so the call doesn't outlive the context. If context has a deadline, the time remaining until the
+// deadline is returned (at least one second), unless the default timeout is shorter. Otherwise the default timeout
+// is returned. Zero means that the call should use its own default
+func TimeoutSeconds(ctx context.Context, defaultTimeout time.Duration) int64 {
+	result := int64(math.Ceil(defaultTimeout.Seconds()))
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return result
+	}
+
+	remaining := int64(math.Ceil(time.Until(deadline).Seconds()))
+	if remaining < 1 {
+		remaining = 1
+	}
+	if result > 0 && result < remaining {
+		return result
+	}
+	return remaining
+}
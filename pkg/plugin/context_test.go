@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRunWithContextInFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan bool)
+	release := make(chan bool)
+
+	// call gets abandoned once context is cancelled, but keeps running in background
+	done := make(chan error)
+	go func() {
+		done <- RunWithContext(ctx, "a-test", func() error {
+			started <- true
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	cancel()
+	assert.Equal(t, context.Canceled, <-done, "Call should return once context is cancelled")
+
+	// new calls for the same deployment should be rejected, while the abandoned one is still running
+	err := RunWithContext(context.Background(), "a-test", func() error { return nil })
+	assert.Error(t, err, "Call should be rejected while previous one is still in progress")
+
+	// calls for other deployments should not be affected
+	err = RunWithContext(context.Background(), "a-other", func() error { return nil })
+	assert.NoError(t, err, "Call for another deployment should be allowed")
+
+	// once abandoned call completes, new calls should be allowed again
+	release <- true
+	deadline := time.After(time.Second)
+	for {
+		err = RunWithContext(context.Background(), "a-test", func() error { return nil })
+		if err == nil {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("Call should be allowed once previous one has completed: %s", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestTimeoutSeconds(t *testing.T) {
+	// no deadline
+	assert.Equal(t, int64(0), TimeoutSeconds(context.Background(), 0), "Zero timeout should be returned if there is no deadline and no default")
+	assert.Equal(t, int64(300), TimeoutSeconds(context.Background(), 5*time.Minute), "Default timeout should be returned if there is no deadline")
+
+	// deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.Equal(t, int64(10), TimeoutSeconds(ctx, 0), "Remaining time should be returned if there is no default")
+	assert.Equal(t, int64(10), TimeoutSeconds(ctx, 5*time.Minute), "Remaining time should be returned if default is longer")
+	assert.Equal(t, int64(2), TimeoutSeconds(ctx, 2*time.Second), "Default should be returned if it's shorter")
+
+	// expired deadline
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+	assert.Equal(t, int64(1), TimeoutSeconds(expired, 0), "At least one second should be returned")
+}
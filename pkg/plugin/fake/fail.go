@@ -1,6 +1,7 @@
 package fake
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/plugin"
@@ -36,29 +37,29 @@ func (plugin *failCodePlugin) fail(action string, deployName string) error {
 	return fmt.Errorf(msg)
 }
 
-func (plugin *failCodePlugin) Create(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+func (plugin *failCodePlugin) Create(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
 	eventLog.NewEntry().Infof("[+] %s", deployName)
 	return plugin.fail("create", deployName)
 }
 
-func (plugin *failCodePlugin) Update(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+func (plugin *failCodePlugin) Update(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
 	eventLog.NewEntry().Infof("[*] %s", deployName)
 	return plugin.fail("update", deployName)
 }
 
-func (plugin *failCodePlugin) Destroy(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+func (plugin *failCodePlugin) Destroy(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
 	eventLog.NewEntry().Infof("[-] %s", deployName)
 	return plugin.fail("delete", deployName)
 }
 
-func (plugin *failCodePlugin) Endpoints(deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
+func (plugin *failCodePlugin) Endpoints(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
 	return make(map[string]string), nil
 }
 
-func (plugin *failCodePlugin) Resources(deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
+func (plugin *failCodePlugin) Resources(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
 	return nil, nil
 }
 
-func (plugin *failCodePlugin) Status(deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
+func (plugin *failCodePlugin) Status(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
 	return false, nil
 }
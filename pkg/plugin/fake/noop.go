@@ -1,6 +1,7 @@
 package fake
 
 import (
+	"context"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/external"
@@ -31,6 +32,21 @@ func NewNoOpCodePlugin(sleepTime time.Duration) plugin.CodePlugin {
 	}
 }
 
+// sleeps a given time amount, returning early with an error if context gets cancelled
+func (plugin *noOpPlugin) sleep(ctx context.Context) error {
+	if plugin.sleepTime <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(plugin.sleepTime)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (plugin *noOpPlugin) Validate() error {
 	return nil
 }
@@ -39,31 +55,27 @@ func (plugin *noOpPlugin) Cleanup() error {
 	return nil
 }
 
-func (plugin *noOpPlugin) Create(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
-	time.Sleep(plugin.sleepTime)
-	return nil
+func (plugin *noOpPlugin) Create(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	return plugin.sleep(ctx)
 }
 
-func (plugin *noOpPlugin) Update(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
-	time.Sleep(plugin.sleepTime)
-	return nil
+func (plugin *noOpPlugin) Update(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	return plugin.sleep(ctx)
 }
 
-func (plugin *noOpPlugin) Destroy(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
-	time.Sleep(plugin.sleepTime)
-	return nil
+func (plugin *noOpPlugin) Destroy(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	return plugin.sleep(ctx)
 }
 
-func (plugin *noOpPlugin) Endpoints(deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
-	time.Sleep(plugin.sleepTime)
-	return make(map[string]string), nil
+func (plugin *noOpPlugin) Endpoints(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
+	return make(map[string]string), plugin.sleep(ctx)
 }
 
-func (plugin *noOpPlugin) Resources(deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
+func (plugin *noOpPlugin) Resources(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
 	return nil, nil
 }
 
-func (plugin *noOpPlugin) Status(deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
+func (plugin *noOpPlugin) Status(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
 	return true, nil
 }
 
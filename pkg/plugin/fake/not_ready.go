@@ -0,0 +1,25 @@
+package fake
+
+import (
+	"context"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// notReadyCodePlugin is a plugin which successfully performs all of its actions, but never reports component
+// instances as ready
+type notReadyCodePlugin struct {
+	noOpPlugin
+}
+
+var _ plugin.CodePlugin = &notReadyCodePlugin{}
+
+// NewNotReadyCodePlugin returns fake code plugin which does nothing, and always reports component instances as not ready
+func NewNotReadyCodePlugin() plugin.CodePlugin {
+	return &notReadyCodePlugin{}
+}
+
+func (plugin *notReadyCodePlugin) Status(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
+	return false, nil
+}
@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/event"
@@ -13,6 +14,7 @@ import (
 	"gopkg.in/yaml.v2"
 	"k8s.io/helm/pkg/helm"
 	"k8s.io/helm/pkg/kube"
+	"k8s.io/helm/pkg/proto/hapi/services"
 	"strings"
 )
 
@@ -70,16 +72,16 @@ func (p *Plugin) Cleanup() error {
 }
 
 // Create implements creation of a new component instance in the cloud by deploying a Helm chart
-func (p *Plugin) Create(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
-	return p.createOrUpdate(deployName, params, eventLog, true)
+func (p *Plugin) Create(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	return p.createOrUpdate(ctx, deployName, params, eventLog, true)
 }
 
 // Update implements update of an existing component instance in the cloud by updating parameters of a helm chart
-func (p *Plugin) Update(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
-	return p.createOrUpdate(deployName, params, eventLog, false)
+func (p *Plugin) Update(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+	return p.createOrUpdate(ctx, deployName, params, eventLog, false)
 }
 
-func (p *Plugin) createOrUpdate(deployName string, params util.NestedParameterMap, eventLog *event.Log, create bool) error {
+func (p *Plugin) createOrUpdate(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log, create bool) error {
 	err := p.init(eventLog)
 	if err != nil {
 		return err
@@ -133,16 +135,18 @@ func (p *Plugin) createOrUpdate(deployName string, params util.NestedParameterMa
 			// Print installation line on info level
 			eventLog.NewEntry().Infof("Installing Helm release '%s', chart '%s', cluster: '%s'", releaseName, chartName, cluster.Name)
 
-			_, err = helmClient.InstallRelease(
-				chartPath,
-				p.kube.Namespace,
-				helm.ReleaseName(releaseName),
-				helm.ValueOverrides(helmParams),
-				helm.InstallReuseName(true),
-				helm.InstallTimeout(int64(p.config.Timeout)),
-			)
-
-			return err
+			// installation may take a long time, so stop waiting for it once the action gets cancelled
+			return plugin.RunWithContext(ctx, deployName, func() error {
+				_, installErr := helmClient.InstallRelease(
+					chartPath,
+					p.kube.Namespace,
+					helm.ReleaseName(releaseName),
+					helm.ValueOverrides(helmParams),
+					helm.InstallReuseName(true),
+					helm.InstallTimeout(plugin.TimeoutSeconds(ctx, p.config.Timeout)),
+				)
+				return installErr
+			})
 		}
 	}
 
@@ -160,12 +164,18 @@ func (p *Plugin) createOrUpdate(deployName string, params util.NestedParameterMa
 		return fmt.Errorf("it's not allowed to change namespace of the release %s (was %s, requested %s)", releaseName, status.Namespace, p.kube.Namespace)
 	}
 
-	newRelease, err := helmClient.UpdateRelease(
-		releaseName,
-		chartPath,
-		helm.UpdateValueOverrides(helmParams),
-		helm.UpgradeTimeout(int64(p.config.Timeout)),
-	)
+	// upgrade may take a long time, so stop waiting for it once the action gets cancelled
+	var newRelease *services.UpdateReleaseResponse
+	err = plugin.RunWithContext(ctx, deployName, func() error {
+		var updateErr error
+		newRelease, updateErr = helmClient.UpdateRelease(
+			releaseName,
+			chartPath,
+			helm.UpdateValueOverrides(helmParams),
+			helm.UpgradeTimeout(plugin.TimeoutSeconds(ctx, p.config.Timeout)),
+		)
+		return updateErr
+	})
 	if err != nil {
 		return err
 	}
@@ -197,7 +207,7 @@ func (p *Plugin) createOrUpdate(deployName string, params util.NestedParameterMa
 }
 
 // Destroy implements destruction of an existing component instance in the cloud by running "helm delete" on the corresponding helm chart
-func (p *Plugin) Destroy(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+func (p *Plugin) Destroy(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
 	err := p.init(eventLog)
 	if err != nil {
 		return err
@@ -212,16 +222,18 @@ func (p *Plugin) Destroy(deployName string, params util.NestedParameterMap, even
 
 	eventLog.NewEntry().Infof("Deleting Helm release '%s'", releaseName)
 
-	_, err = helmClient.DeleteRelease(
-		releaseName,
-		helm.DeletePurge(true),
-		helm.DeleteTimeout(int64(p.config.Timeout)),
-	)
-	return err
+	return plugin.RunWithContext(ctx, deployName, func() error {
+		_, deleteErr := helmClient.DeleteRelease(
+			releaseName,
+			helm.DeletePurge(true),
+			helm.DeleteTimeout(plugin.TimeoutSeconds(ctx, p.config.Timeout)),
+		)
+		return deleteErr
+	})
 }
 
 // Endpoints returns map from port type to url for all services of the current chart
-func (p *Plugin) Endpoints(deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
+func (p *Plugin) Endpoints(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
 	err := p.init(eventLog)
 	if err != nil {
 		return nil, err
@@ -243,7 +255,7 @@ func (p *Plugin) Endpoints(deployName string, params util.NestedParameterMap, ev
 }
 
 // Resources returns list of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Resources(deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
+func (p *Plugin) Resources(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
 	err := p.init(eventLog)
 	if err != nil {
 		return nil, err
@@ -265,7 +277,7 @@ func (p *Plugin) Resources(deployName string, params util.NestedParameterMap, ev
 }
 
 // Status returns readiness of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Status(deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
+func (p *Plugin) Status(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
 	err := p.init(eventLog)
 	if err != nil {
 		return false, err
@@ -24,11 +24,13 @@ func getHelmReleaseInfo(params util.NestedParameterMap) (repository, name, versi
 		return
 	}
 
-	if _, ok = params["chartVersion"]; !ok {
+	if value, exists := params["chartVersion"]; !exists || value == nil {
 		// version is optional. this will use the latest
 		version = ""
-	} else {
-		version = params["chartVersion"].(string)
+	} else if version, ok = value.(string); !ok {
+		// version parsed from yaml as a number can't be converted back reliably (e.g. 1.10 would become 1.1)
+		err = fmt.Errorf("chartVersion must be a string, please put it in quotes (e.g. \"1.10\"): %v", value)
+		return
 	}
 
 	return
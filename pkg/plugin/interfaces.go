@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/lang"
@@ -22,7 +23,7 @@ type Base interface {
 }
 
 // ClusterPlugin is a definition of cluster plugin which takes care of cluster operations such as validation
-// in the cloud. It's created for specific cluster and enforcement cycle or API call.
+// in the cloud. It's created for specific cluster and enforcement cycle or API call. It must be safe for concurrent use.
 type ClusterPlugin interface {
 	Base
 
@@ -34,15 +35,18 @@ type ClusterPluginConstructor func(cluster *lang.Cluster, cfg config.Plugins) (C
 
 // CodePlugin is a definition of deployment plugin which takes care of creating, updating and destroying
 // component instances in the cloud. It's created for specific cluster and enforcement cycle or API call.
+// It must be safe for concurrent use, since actions from different partitions of the action plan get applied in
+// parallel and share the same plugin instance. Plugin state should be initialized once (see sync.Init), while
+// everything specific to a call (e.g. clients) should be created per call
 type CodePlugin interface {
 	Base
 
-	Create(deployName string, params util.NestedParameterMap, eventLog *event.Log) error
-	Update(deployName string, params util.NestedParameterMap, eventLog *event.Log) error
-	Destroy(deployName string, params util.NestedParameterMap, eventLog *event.Log) error
-	Endpoints(deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error)
-	Resources(deployName string, params util.NestedParameterMap, eventLog *event.Log) (Resources, error)
-	Status(deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error)
+	Create(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error
+	Update(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error
+	Destroy(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error
+	Endpoints(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error)
+	Resources(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (Resources, error)
+	Status(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error)
 }
 
 // CodePluginConstructor represents constructor the the code plugin
@@ -1,6 +1,7 @@
 package k8sraw
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/event"
@@ -10,8 +11,12 @@ import (
 	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Aptomi/aptomi/pkg/util/sync"
 	"strings"
+	"time"
 )
 
+// defaultTimeout is the maximum time for creating or updating k8s objects, when action has no deadline
+const defaultTimeout = 42 * time.Second
+
 // Plugin represents Kubernetes Raw code plugin that supports deploying specified k8s objects into the cluster
 type Plugin struct {
 	once          sync.Init
@@ -62,7 +67,7 @@ func (p *Plugin) Cleanup() error {
 }
 
 // Create implements creation of a new component instance in the cloud by deploying raw k8s objects
-func (p *Plugin) Create(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+func (p *Plugin) Create(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
 	err := p.init()
 	if err != nil {
 		return err
@@ -73,14 +78,16 @@ func (p *Plugin) Create(deployName string, params util.NestedParameterMap, event
 		return err
 	}
 
-	targetManifest, ok := params["manifest"].(string)
-	if !ok {
-		return fmt.Errorf("manifest is a mandatory parameter")
+	targetManifest, err := getManifest(params)
+	if err != nil {
+		return err
 	}
 
 	client := p.kube.NewHelmKube(deployName, eventLog)
 
-	err = client.Create(p.kube.Namespace, strings.NewReader(targetManifest), 42, false)
+	err = plugin.RunWithContext(ctx, deployName, func() error {
+		return client.Create(p.kube.Namespace, strings.NewReader(targetManifest), plugin.TimeoutSeconds(ctx, defaultTimeout), false)
+	})
 	if err != nil {
 		return err
 	}
@@ -89,7 +96,7 @@ func (p *Plugin) Create(deployName string, params util.NestedParameterMap, event
 }
 
 // Update implements update of an existing component instance in the cloud by updating raw k8s objects
-func (p *Plugin) Update(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+func (p *Plugin) Update(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
 	err := p.init()
 	if err != nil {
 		return err
@@ -105,14 +112,16 @@ func (p *Plugin) Update(deployName string, params util.NestedParameterMap, event
 		return err
 	}
 
-	targetManifest, ok := params["manifest"].(string)
-	if !ok {
-		return fmt.Errorf("manifest is a mandatory parameter")
+	targetManifest, err := getManifest(params)
+	if err != nil {
+		return err
 	}
 
 	client := p.kube.NewHelmKube(deployName, eventLog)
 
-	err = client.Update(p.kube.Namespace, strings.NewReader(currentManifest), strings.NewReader(targetManifest), false, false, 42, false)
+	err = plugin.RunWithContext(ctx, deployName, func() error {
+		return client.Update(p.kube.Namespace, strings.NewReader(currentManifest), strings.NewReader(targetManifest), false, false, plugin.TimeoutSeconds(ctx, defaultTimeout), false)
+	})
 	if err != nil {
 		return err
 	}
@@ -121,7 +130,7 @@ func (p *Plugin) Update(deployName string, params util.NestedParameterMap, event
 }
 
 // Destroy implements destruction of an existing component instance in the cloud by deleting raw k8s objects
-func (p *Plugin) Destroy(deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
+func (p *Plugin) Destroy(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) error {
 	err := p.init()
 	if err != nil {
 		return err
@@ -132,14 +141,16 @@ func (p *Plugin) Destroy(deployName string, params util.NestedParameterMap, even
 		return err
 	}
 
-	deleteManifest, ok := params["manifest"].(string)
-	if !ok {
-		return fmt.Errorf("manifest is a mandatory parameter")
+	deleteManifest, err := getManifest(params)
+	if err != nil {
+		return err
 	}
 
 	client := p.kube.NewHelmKube(deployName, eventLog)
 
-	err = client.Delete(p.kube.Namespace, strings.NewReader(deleteManifest))
+	err = plugin.RunWithContext(ctx, deployName, func() error {
+		return client.Delete(p.kube.Namespace, strings.NewReader(deleteManifest))
+	})
 	if err != nil {
 		return err
 	}
@@ -148,45 +159,45 @@ func (p *Plugin) Destroy(deployName string, params util.NestedParameterMap, even
 }
 
 // Endpoints returns map from port type to url for all services of the deployed raw k8s objects
-func (p *Plugin) Endpoints(deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
+func (p *Plugin) Endpoints(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (map[string]string, error) {
 	err := p.init()
 	if err != nil {
 		return nil, err
 	}
 
-	targetManifest, ok := params["manifest"].(string)
-	if !ok {
-		return nil, fmt.Errorf("manifest is a mandatory parameter")
+	targetManifest, err := getManifest(params)
+	if err != nil {
+		return nil, err
 	}
 
 	return p.kube.EndpointsForManifests(deployName, targetManifest, eventLog)
 }
 
 // Resources returns list of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Resources(deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
+func (p *Plugin) Resources(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (plugin.Resources, error) {
 	err := p.init()
 	if err != nil {
 		return nil, err
 	}
 
-	targetManifest, ok := params["manifest"].(string)
-	if !ok {
-		return nil, fmt.Errorf("manifest is a mandatory parameter")
+	targetManifest, err := getManifest(params)
+	if err != nil {
+		return nil, err
 	}
 
 	return p.kube.ResourcesForManifest(deployName, targetManifest, eventLog)
 }
 
 // Status returns readiness of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Status(deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
+func (p *Plugin) Status(ctx context.Context, deployName string, params util.NestedParameterMap, eventLog *event.Log) (bool, error) {
 	err := p.init()
 	if err != nil {
 		return false, err
 	}
 
-	targetManifest, ok := params["manifest"].(string)
-	if !ok {
-		return false, fmt.Errorf("manifest is a mandatory parameter")
+	targetManifest, err := getManifest(params)
+	if err != nil {
+		return false, err
 	}
 
 	return p.kube.ReadinessStatusForManifest(deployName, targetManifest, eventLog)
@@ -2,6 +2,8 @@ package k8sraw
 
 import (
 	"fmt"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"gopkg.in/yaml.v2"
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -13,6 +15,30 @@ var (
 	configMapNameReplacer = strings.NewReplacer("#", "-", "_", "-")
 )
 
+// getManifest returns manifest from code params. Manifest can be either a string, or a list of k8s objects (each of
+// them can be either a string or a map), which will get combined into a single multi-document manifest
+func getManifest(params util.NestedParameterMap) (string, error) {
+	switch manifest := params["manifest"].(type) {
+	case string:
+		return manifest, nil
+	case []interface{}:
+		documents := []string{}
+		for _, item := range manifest {
+			if document, ok := item.(string); ok {
+				documents = append(documents, document)
+				continue
+			}
+			data, err := yaml.Marshal(item)
+			if err != nil {
+				return "", fmt.Errorf("error while marshaling object in manifest: %s", err)
+			}
+			documents = append(documents, string(data))
+		}
+		return strings.Join(documents, "\n---\n"), nil
+	}
+	return "", fmt.Errorf("manifest is a mandatory parameter")
+}
+
 func (p *Plugin) getManifestConfigMapName(deployName string) string {
 	return strings.ToLower(configMapNameReplacer.Replace(fmt.Sprintf("aptomi-raw-%s-%s", p.cluster.Name, deployName)))
 }
@@ -14,6 +14,11 @@ type Core interface {
 	Policy
 	Revision
 	ActualState
+	Audit
+	Migration
+	Pause
+	DependencyStatus
+	PasswordSeed
 }
 
 // Policy represents database operations for Policy object
@@ -43,3 +48,36 @@ type ActualState interface {
 	GetActualStateUpdater() actual.StateUpdater
 	ResetActualState() error
 }
+
+// Audit represents database operations for the audit log
+type Audit interface {
+	AppendAuditEntry(entry *engine.AuditEntry) error
+	GetAuditEntries(filter *engine.AuditFilter) ([]*engine.AuditEntry, error)
+}
+
+// Migration represents database operations for migrating dependencies to a new placement
+type Migration interface {
+	GetDependencyMigrations() (*engine.DependencyMigrations, error)
+	RequestDependencyMigration(dependencyKeys []string, requestedBy string) error
+	CompleteDependencyMigration(dependencyKeys []string) error
+}
+
+// Pause represents database operations for pausing and resuming the enforcer
+type Pause interface {
+	GetEnforcerPause() (*engine.EnforcerPause, error)
+	PauseEnforcer(scopeKey string, pausedBy string) (*engine.EnforcerPause, error)
+	ResumeEnforcer(scopeKey string) (*engine.EnforcerPause, error)
+	RecordPendingActions(pendingActions uint32, pendingInstances []string) error
+}
+
+// DependencyStatus represents database operations for statuses of dependencies, recorded by the enforcer
+type DependencyStatus interface {
+	GetDependencyStatus(dependencyKey string) (*engine.DependencyStatus, error)
+	GetAllDependencyStatuses() (engine.DependencyStatuses, error)
+	UpdateDependencyStatuses(statuses engine.DependencyStatuses) error
+}
+
+// PasswordSeed represents database operations for the secret key of random functions in templates
+type PasswordSeed interface {
+	GetPasswordSeed() (string, error)
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// AppendAuditEntry appends audit entry to the audit log as a new generation of the audit log object
+func (ds *defaultStore) AppendAuditEntry(entry *engine.AuditEntry) error {
+	ds.auditLock.Lock()
+	defer ds.auditLock.Unlock()
+
+	lastObj, err := ds.store.GetGen(engine.AuditEntryKey, runtime.LastGen)
+	if err != nil {
+		return fmt.Errorf("error while getting last audit entry: %s", err)
+	}
+
+	if lastObj == nil {
+		entry.SetGeneration(runtime.FirstGen)
+	} else {
+		entry.SetGeneration(lastObj.GetGeneration().Next())
+	}
+
+	_, err = ds.store.Save(entry)
+	if err != nil {
+		return fmt.Errorf("error while saving audit entry: %s", err)
+	}
+
+	return nil
+}
+
+// GetAuditEntries returns audit entries matching the given filter in chronological order. Entries are read starting
+// from the most recent one, so only as many generations get loaded as needed to find the requested number of entries
+func (ds *defaultStore) GetAuditEntries(filter *engine.AuditFilter) ([]*engine.AuditEntry, error) {
+	lastObj, err := ds.store.GetGen(engine.AuditEntryKey, runtime.LastGen)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting last audit entry: %s", err)
+	}
+
+	result := []*engine.AuditEntry{}
+	if lastObj == nil {
+		return result, nil
+	}
+
+	for gen := lastObj.GetGeneration(); gen >= runtime.FirstGen; gen-- {
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+		entryObj, err := ds.store.GetGen(engine.AuditEntryKey, gen)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting audit entry %s: %s", gen, err)
+		}
+		if entryObj == nil {
+			continue
+		}
+		entry := entryObj.(*engine.AuditEntry)
+		if filter.Matches(entry) {
+			result = append(result, entry)
+		}
+	}
+
+	// entries have been read in reverse chronological order
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result, nil
+}
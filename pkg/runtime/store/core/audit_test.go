@@ -0,0 +1,75 @@
+package core
+
+import (
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/runtime/store"
+	"github.com/Aptomi/aptomi/pkg/runtime/store/generic/bolt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendAuditEntry(t *testing.T) {
+	ds, cleanup := newTestStore(t)
+	defer cleanup()
+
+	// audit log should be empty initially
+	entries, err := ds.GetAuditEntries(&engine.AuditFilter{})
+	assert.NoError(t, err, "Audit entries should be retrieved from empty store")
+	assert.Empty(t, entries, "Audit log should be empty initially")
+
+	// every appended entry should get the next generation
+	users := []string{"alice", "bob", "alice"}
+	for idx, user := range users {
+		entry := engine.NewAuditEntry(engine.AuditActionLogin, user, "127.0.0.1")
+		assert.NoError(t, ds.AppendAuditEntry(entry), "Audit entry should be appended")
+		assert.Equal(t, runtime.Generation(idx+1), entry.GetGeneration(), "Audit entry should get the next generation")
+	}
+
+	// entries should be returned in chronological order
+	entries, err = ds.GetAuditEntries(&engine.AuditFilter{})
+	assert.NoError(t, err, "Audit entries should be retrieved")
+	if assert.Len(t, entries, len(users), "All audit entries should be retrieved") {
+		for idx, entry := range entries {
+			assert.Equal(t, runtime.Generation(idx+1), entry.GetGeneration(), "Audit entries should be in chronological order")
+			assert.Equal(t, users[idx], entry.User, "Audit entry should be stored as is")
+		}
+	}
+
+	// filter should be applied
+	entries, err = ds.GetAuditEntries(&engine.AuditFilter{User: "alice"})
+	assert.NoError(t, err, "Audit entries should be retrieved")
+	assert.Len(t, entries, 2, "Only audit entries matching the filter should be retrieved")
+
+	// only the most recent entries should be retrieved, if limit is set
+	entries, err = ds.GetAuditEntries(&engine.AuditFilter{Limit: 2})
+	assert.NoError(t, err, "Audit entries should be retrieved")
+	if assert.Len(t, entries, 2, "Number of audit entries should be limited") {
+		assert.Equal(t, runtime.Generation(2), entries[0].GetGeneration(), "Most recent audit entries should be retrieved in chronological order")
+		assert.Equal(t, runtime.Generation(3), entries[1].GetGeneration(), "Most recent audit entries should be retrieved in chronological order")
+	}
+}
+
+func newTestStore(t *testing.T) (store.Core, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "aptomi-store-test")
+	if err != nil {
+		panic(err)
+	}
+
+	b := bolt.NewGenericStore(runtime.NewRegistry().Append(store.Objects...))
+	err = b.Open(config.DB{Connection: filepath.Join(dir, "db.bolt")})
+	if err != nil {
+		panic(err)
+	}
+
+	return NewStore(b), func() {
+		b.Close()         // nolint: errcheck
+		os.RemoveAll(dir) // nolint: errcheck
+	}
+}
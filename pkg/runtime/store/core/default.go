@@ -9,10 +9,15 @@ import (
 // different engine objects into the object store
 type defaultStore struct {
 	policyChangeLock sync.Mutex
+	auditLock        sync.Mutex
+	migrationLock    sync.Mutex
+	pauseLock        sync.Mutex
+	statusLock       sync.Mutex
+	passwordSeedLock sync.Mutex
 	store            store.Generic
 }
 
 // NewStore returns default implementation of generic store
 func NewStore(store store.Generic) store.Core {
-	return &defaultStore{sync.Mutex{}, store}
+	return &defaultStore{store: store}
 }
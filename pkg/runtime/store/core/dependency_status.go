@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"time"
+)
+
+// GetDependencyStatus returns the status of a given dependency, or nil if it hasn't been recorded yet
+func (ds *defaultStore) GetDependencyStatus(dependencyKey string) (*engine.DependencyStatus, error) {
+	obj, err := ds.store.Get(engine.DependencyStatusKey(dependencyKey))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting status of dependency '%s': %s", dependencyKey, err)
+	}
+	if obj == nil {
+		return nil, nil
+	}
+
+	status, ok := obj.(*engine.DependencyStatus)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type while getting DependencyStatus from DB")
+	}
+	return status, nil
+}
+
+// GetAllDependencyStatuses returns statuses of all dependencies
+func (ds *defaultStore) GetAllDependencyStatuses() (engine.DependencyStatuses, error) {
+	statuses, err := ds.store.List(runtime.KeyFromParts(runtime.SystemNS, engine.DependencyStatusObject.Kind, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting all dependency statuses: %s", err)
+	}
+
+	result := make(engine.DependencyStatuses)
+	for _, statusObj := range statuses {
+		if status, ok := statusObj.(*engine.DependencyStatus); ok {
+			result[status.DependencyKey] = status
+		}
+	}
+	return result, nil
+}
+
+// UpdateDependencyStatuses records statuses of dependencies. Only statuses which have changed get saved, and statuses
+// of dependencies which are not present anymore get deleted
+func (ds *defaultStore) UpdateDependencyStatuses(statuses engine.DependencyStatuses) error {
+	ds.statusLock.Lock()
+	defer ds.statusLock.Unlock()
+
+	existing, err := ds.GetAllDependencyStatuses()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for dKey, next := range statuses {
+		status := existing[dKey]
+		if status == nil {
+			status = engine.NewDependencyStatus(dKey)
+		}
+		if status.Update(next, now) {
+			_, err = ds.store.Save(status)
+			if err != nil {
+				return fmt.Errorf("error while saving status of dependency '%s': %s", dKey, err)
+			}
+		}
+	}
+
+	for dKey := range existing {
+		if _, ok := statuses[dKey]; !ok {
+			err = ds.store.Delete(engine.DependencyStatusKey(dKey))
+			if err != nil {
+				return fmt.Errorf("error while deleting status of dependency '%s': %s", dKey, err)
+			}
+		}
+	}
+	return nil
+}
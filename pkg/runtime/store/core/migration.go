@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+)
+
+// GetDependencyMigrations returns dependencies, which have been requested to migrate to a new placement
+func (ds *defaultStore) GetDependencyMigrations() (*engine.DependencyMigrations, error) {
+	obj, err := ds.store.Get(engine.DependencyMigrationsKey)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting dependency migrations: %s", err)
+	}
+	if obj == nil {
+		return engine.NewDependencyMigrations(), nil
+	}
+
+	migrations, ok := obj.(*engine.DependencyMigrations)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type while getting DependencyMigrations from DB")
+	}
+	if migrations.Requested == nil {
+		migrations.Requested = make(map[string]string)
+	}
+	return migrations, nil
+}
+
+// RequestDependencyMigration records a request to migrate given dependencies to a new placement
+func (ds *defaultStore) RequestDependencyMigration(dependencyKeys []string, requestedBy string) error {
+	ds.migrationLock.Lock()
+	defer ds.migrationLock.Unlock()
+
+	migrations, err := ds.GetDependencyMigrations()
+	if err != nil {
+		return err
+	}
+	for _, dKey := range dependencyKeys {
+		migrations.Requested[dKey] = requestedBy
+	}
+	return ds.saveDependencyMigrations(migrations)
+}
+
+// CompleteDependencyMigration removes migration requests for given dependencies, once they have been processed
+func (ds *defaultStore) CompleteDependencyMigration(dependencyKeys []string) error {
+	ds.migrationLock.Lock()
+	defer ds.migrationLock.Unlock()
+
+	migrations, err := ds.GetDependencyMigrations()
+	if err != nil {
+		return err
+	}
+	for _, dKey := range dependencyKeys {
+		delete(migrations.Requested, dKey)
+	}
+	return ds.saveDependencyMigrations(migrations)
+}
+
+func (ds *defaultStore) saveDependencyMigrations(migrations *engine.DependencyMigrations) error {
+	_, err := ds.store.Save(migrations)
+	if err != nil {
+		return fmt.Errorf("error while saving dependency migrations: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"time"
+)
+
+// passwordSeedLength is the number of random bytes in the generated password seed
+const passwordSeedLength = 32
+
+// GetPasswordSeed returns the secret key for random functions in templates. If it doesn't exist in the store, it
+// will be generated and saved, so the same key will be returned after server restart
+func (ds *defaultStore) GetPasswordSeed() (string, error) {
+	ds.passwordSeedLock.Lock()
+	defer ds.passwordSeedLock.Unlock()
+
+	obj, err := ds.store.Get(engine.PasswordSeedKey)
+	if err != nil {
+		return "", fmt.Errorf("error while getting password seed: %s", err)
+	}
+	if obj != nil {
+		seed, ok := obj.(*engine.PasswordSeed)
+		if !ok {
+			return "", fmt.Errorf("unexpected type while getting PasswordSeed from DB")
+		}
+		return seed.Key, nil
+	}
+
+	data := make([]byte, passwordSeedLength)
+	_, err = rand.Read(data)
+	if err != nil {
+		return "", fmt.Errorf("error while generating password seed: %s", err)
+	}
+
+	seed := &engine.PasswordSeed{
+		TypeKind:  engine.PasswordSeedObject.GetTypeKind(),
+		Key:       hex.EncodeToString(data),
+		CreatedAt: time.Now(),
+	}
+	_, err = ds.store.Save(seed)
+	if err != nil {
+		return "", fmt.Errorf("error while saving password seed: %s", err)
+	}
+	return seed.Key, nil
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetPasswordSeed(t *testing.T) {
+	ds, cleanup := newTestStore(t)
+	defer cleanup()
+
+	// password seed should be generated on first call
+	seed, err := ds.GetPasswordSeed()
+	assert.NoError(t, err, "Password seed should be generated")
+	assert.Len(t, seed, 2*passwordSeedLength, "Password seed should be generated with the expected length")
+
+	// and it should stay the same afterwards
+	seedAgain, err := ds.GetPasswordSeed()
+	assert.NoError(t, err, "Password seed should be retrieved")
+	assert.Equal(t, seed, seedAgain, "Password seed should be persisted")
+
+	// different stores should get different seeds
+	another, anotherCleanup := newTestStore(t)
+	defer anotherCleanup()
+	anotherSeed, err := another.GetPasswordSeed()
+	assert.NoError(t, err, "Password seed should be generated")
+	assert.NotEqual(t, seed, anotherSeed, "Password seed should be random")
+}
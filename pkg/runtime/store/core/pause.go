@@ -0,0 +1,82 @@
+package core
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"time"
+)
+
+// GetEnforcerPause returns the paused state of the enforcer
+func (ds *defaultStore) GetEnforcerPause() (*engine.EnforcerPause, error) {
+	obj, err := ds.store.Get(engine.EnforcerPauseKey)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting enforcer pause: %s", err)
+	}
+	if obj == nil {
+		return engine.NewEnforcerPause(), nil
+	}
+
+	pause, ok := obj.(*engine.EnforcerPause)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type while getting EnforcerPause from DB")
+	}
+	if pause.Paused == nil {
+		pause.Paused = make(map[string]*engine.PauseRecord)
+	}
+	return pause, nil
+}
+
+// PauseEnforcer pauses the enforcer for a given scope
+func (ds *defaultStore) PauseEnforcer(scopeKey string, pausedBy string) (*engine.EnforcerPause, error) {
+	ds.pauseLock.Lock()
+	defer ds.pauseLock.Unlock()
+
+	pause, err := ds.GetEnforcerPause()
+	if err != nil {
+		return nil, err
+	}
+	if pause.Paused[scopeKey] == nil {
+		pause.Paused[scopeKey] = &engine.PauseRecord{User: pausedBy, PausedAt: time.Now()}
+	}
+	return pause, ds.saveEnforcerPause(pause)
+}
+
+// ResumeEnforcer resumes the enforcer for a given scope. If scope key is empty, the enforcer gets resumed for all scopes
+func (ds *defaultStore) ResumeEnforcer(scopeKey string) (*engine.EnforcerPause, error) {
+	ds.pauseLock.Lock()
+	defer ds.pauseLock.Unlock()
+
+	pause, err := ds.GetEnforcerPause()
+	if err != nil {
+		return nil, err
+	}
+	if len(scopeKey) > 0 {
+		delete(pause.Paused, scopeKey)
+	} else {
+		pause.Paused = make(map[string]*engine.PauseRecord)
+	}
+	return pause, ds.saveEnforcerPause(pause)
+}
+
+// RecordPendingActions records actions, which have been held back by the enforcer while paused
+func (ds *defaultStore) RecordPendingActions(pendingActions uint32, pendingInstances []string) error {
+	ds.pauseLock.Lock()
+	defer ds.pauseLock.Unlock()
+
+	pause, err := ds.GetEnforcerPause()
+	if err != nil {
+		return err
+	}
+	pause.PendingActions = pendingActions
+	pause.PendingInstances = pendingInstances
+	pause.CheckedAt = time.Now()
+	return ds.saveEnforcerPause(pause)
+}
+
+func (ds *defaultStore) saveEnforcerPause(pause *engine.EnforcerPause) error {
+	_, err := ds.store.Save(pause)
+	if err != nil {
+		return fmt.Errorf("error while saving enforcer pause: %s", err)
+	}
+	return nil
+}
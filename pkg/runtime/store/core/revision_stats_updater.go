@@ -62,6 +62,29 @@ func (updater *RevisionResultUpdaterImpl) Done() *action.ApplyResult {
 	return updater.revision.Result
 }
 
+// PartitionUpdated records progress of a single partition of the revision
+func (updater *RevisionResultUpdaterImpl) PartitionUpdated(partition string, result action.ApplyResult, done bool) {
+	updater.mutex.Lock()
+	if updater.revision.Partitions == nil {
+		updater.revision.Partitions = make(map[string]*engine.RevisionPartition)
+	}
+	status := &engine.RevisionPartition{
+		Status: engine.RevisionStatusInProgress,
+		Result: &result,
+	}
+	if done {
+		status.Status = engine.RevisionStatusCompleted
+		status.AppliedAt = time.Now()
+	}
+	updater.revision.Partitions[partition] = status
+	updater.mutex.Unlock()
+
+	// only save once partition is done, as all other changes get saved along with the revision progress
+	if done {
+		updater.save()
+	}
+}
+
 func (updater *RevisionResultUpdaterImpl) save() {
 	updater.mutex.Lock()
 	defer updater.mutex.Unlock()
@@ -1,14 +1,19 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/engine/apply"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/diff"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
 	log "github.com/Sirupsen/logrus"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -23,15 +28,42 @@ func (server *Server) enforceLoop() error {
 			logError(err)
 		}
 
-		// sleep for a specified time or wait until policy has changed, whichever comes first
-		timer := time.NewTimer(server.cfg.Enforcer.Interval)
-		select {
-		case <-server.runEnforcement:
-			break // nolint: megacheck
-		case <-timer.C:
-			break // nolint: megacheck
+		// wait until enforcement gets triggered (e.g. policy or users have changed) or enforcer interval expires,
+		// whichever comes first
+		batch := server.triggers.Wait(server.cfg.Enforcer.Interval)
+		log.Debugf("Enforcement triggered by: %s", batch)
+	}
+}
+
+// cancelLoop waits for cancellation requests and cancels the revision which is currently being applied. If requested
+// revision hasn't started to be applied yet, cancellation is recorded as pending and it gets cancelled right before
+// its actions start
+func (server *Server) cancelLoop() error {
+	for revisionGen := range server.cancelEnforcement {
+		server.enforcementCancelMutex.Lock()
+		if server.enforcementCancel != nil && server.enforcementRevision == revisionGen {
+			log.Infof("Cancelling revision %d that is in progress", revisionGen)
+			server.enforcementCancel()
+		} else {
+			log.Infof("Revision %d will be cancelled before it gets applied", revisionGen)
+			server.enforcementCancelPending = revisionGen
 		}
-		timer.Stop()
+		server.enforcementCancelMutex.Unlock()
+	}
+	return fmt.Errorf("cancellation channel has been closed")
+}
+
+// Sets cancel function for the revision which is currently being applied (or resets it, if nil is passed). If
+// cancellation of the revision has been requested before, it gets cancelled right away
+func (server *Server) setEnforcementCancel(revisionGen runtime.Generation, cancel context.CancelFunc) {
+	server.enforcementCancelMutex.Lock()
+	defer server.enforcementCancelMutex.Unlock()
+	server.enforcementRevision = revisionGen
+	server.enforcementCancel = cancel
+	if cancel != nil && server.enforcementCancelPending == revisionGen {
+		log.Infof("Cancelling revision %d before it gets applied", revisionGen)
+		server.enforcementCancelPending = 0
+		cancel()
 	}
 }
 
@@ -75,23 +107,56 @@ func (server *Server) enforce() error {
 		return fmt.Errorf("error while getting actual state: %s", err)
 	}
 
+	// dependencies on sticky services keep their placement from the actual state, unless migration has been requested
+	migrations, err := server.store.GetDependencyMigrations()
+	if err != nil {
+		return fmt.Errorf("error while getting dependency migrations: %s", err)
+	}
+	migrate := migrations.GetDependencyKeys()
+
 	resolveLog := event.NewLog(log.DebugLevel, fmt.Sprintf("enforce-%d-resolve", server.enforcementIdx)).AddConsoleHook(server.cfg.GetLogLevel())
-	resolver := resolve.NewPolicyResolver(desiredPolicy, server.externalData, resolveLog)
+	resolver := resolve.NewPolicyResolver(desiredPolicy, server.externalData, resolveLog).SetOptions(server.resolverOptions).SetStickyPlacement(actualState, migrate).SetCache(server.resolutionCache)
 	desiredState := resolver.ResolveAllDependencies()
+	server.lastResolution.Set(desiredPolicyGen, desiredState)
+
+	// code params of the actual state get logged too (e.g. when instances get updated or deleted), so they need to be masked
+	resolver.RegisterSensitiveParams(actualState)
 
 	stateDiff := diff.NewPolicyResolutionDiff(desiredState, actualState)
 
+	// while the enforcer is paused, actions on component instances in paused scopes don't get applied
+	actionPlan, heldPlan, err := server.holdPausedActions(stateDiff.ActionPlan, desiredState, actualState)
+	if err != nil {
+		return err
+	}
+
+	// migrated dependencies may be held back too, so migration requests must stay until everything gets applied
+	if heldPlan.NumberOfActions() > 0 {
+		migrate = nil
+	}
+
 	nextRevision, err := server.store.NewRevision(desiredPolicyGen)
 	if err != nil {
 		return fmt.Errorf("unable to get next revision: %s", err)
 	}
-	nextRevision.ResolveLog = resolveLog.AsAPIEvents()
+	nextRevision.ResolveLog = resolveLog.AsMaskedAPIEvents(desiredState.GetSecretMasker().MaskString)
+
+	// record status of every dependency before applying actions, so consumers can see why their dependencies are
+	// failing or what they are waiting for
+	statuses := engine.NewDependencyStatuses(desiredState, desiredState.GetSecretMasker().MaskString)
+	heldDeps := diff.AffectedDependencies(heldPlan, desiredState, actualState)
+	statuses.Mark(diff.AffectedDependencies(actionPlan, desiredState, actualState), engine.DependencyPhaseDeploying, "actions are being applied")
+	statuses.Mark(heldDeps, engine.DependencyPhaseResolved, "actions are held back, enforcer is paused")
+	err = server.store.UpdateDependencyStatuses(statuses)
+	if err != nil {
+		return fmt.Errorf("error while updating dependency statuses: %s", err)
+	}
 
 	// policy changes while no actions needed to achieve desired state
-	actionCnt := stateDiff.ActionPlan.NumberOfActions()
+	actionCnt := actionPlan.NumberOfActions()
 	if actionCnt <= 0 && currRevision != nil && currRevision.Policy == nextRevision.Policy {
 		log.Infof("(enforce-%d) No changes, policy gen %d", server.enforcementIdx, desiredPolicyGen)
-		return nil
+		return server.completeDependencyMigrations(migrate)
 	}
 	log.Infof("(enforce-%d) New revision %d, policy gen %d, %d actions need to be applied", server.enforcementIdx, nextRevision.GetGeneration(), desiredPolicyGen, actionCnt)
 
@@ -109,11 +174,22 @@ func (server *Server) enforce() error {
 
 	pluginRegistry := server.pluginRegistryFactory()
 	applyLog := event.NewLog(log.DebugLevel, fmt.Sprintf("enforce-%d-apply", server.enforcementIdx)).AddConsoleHook(server.cfg.GetLogLevel())
-	applier := apply.NewEngineApply(desiredPolicy, desiredState, actualState, server.store.GetActualStateUpdater(), server.externalData, pluginRegistry, stateDiff.ActionPlan, applyLog, server.store.NewRevisionResultUpdater(nextRevision))
-	_, _ = applier.Apply()
+	applier := apply.NewEngineApply(desiredPolicy, desiredState, actualState, server.store.GetActualStateUpdater(), server.externalData, pluginRegistry, actionPlan, applyLog, server.store.NewRevisionResultUpdater(nextRevision))
+	ctx, cancel := context.WithCancel(context.Background())
+	server.setEnforcementCancel(nextRevision.GetGeneration(), cancel)
+	_, _ = applier.Apply(ctx)
+	server.setEnforcementCancel(0, nil)
+	cancelled := ctx.Err() != nil
+	cancel()
+
+	// mark revision as cancelled, if it has been cancelled while actions were being applied
+	if cancelled {
+		nextRevision.Status = engine.RevisionStatusCancelled
+		log.Infof("(enforce-%d) Revision %d has been cancelled", server.enforcementIdx, nextRevision.GetGeneration())
+	}
 
 	// save apply log
-	nextRevision.ApplyLog = applyLog.AsAPIEvents()
+	nextRevision.ApplyLog = applyLog.AsMaskedAPIEvents(desiredState.GetSecretMasker().MaskString)
 	saveErr := server.store.UpdateRevision(nextRevision)
 	if saveErr != nil {
 		return fmt.Errorf("error while saving new revision with apply log: %s", saveErr)
@@ -121,5 +197,104 @@ func (server *Server) enforce() error {
 
 	log.Infof("(enforce-%d) New revision %d processed, %d component instances", server.enforcementIdx, nextRevision.GetGeneration(), len(desiredState.ComponentInstanceMap))
 
+	err = server.recordAppliedDependencyStatuses(statuses, heldDeps, desiredState, nextRevision.GetGeneration(), cancelled)
+	if err != nil {
+		return err
+	}
+
+	return server.completeDependencyMigrations(migrate)
+}
+
+// recordAppliedDependencyStatuses updates statuses of dependencies once actions have been applied. Dependencies, which
+// are still affected by actions (other than the ones held back), have either failed or been cancelled
+func (server *Server) recordAppliedDependencyStatuses(statuses engine.DependencyStatuses, heldDeps map[string]bool, desiredState *resolve.PolicyResolution, revisionGen runtime.Generation, cancelled bool) error {
+	actualState, err := server.store.GetActualState()
+	if err != nil {
+		return fmt.Errorf("error while getting actual state: %s", err)
+	}
+
+	remaining := diff.AffectedDependencies(diff.NewPolicyResolutionDiff(desiredState, actualState).ActionPlan, desiredState, actualState)
+	for dKey := range heldDeps {
+		delete(remaining, dKey)
+	}
+	for dKey, status := range statuses {
+		if status.Phase == engine.DependencyPhaseDeploying && !remaining[dKey] {
+			status.Phase = engine.DependencyPhaseReady
+			status.Message = ""
+		}
+	}
+	if cancelled {
+		statuses.Mark(remaining, engine.DependencyPhaseResolved, fmt.Sprintf("revision %d has been cancelled before all actions got applied", revisionGen))
+	} else {
+		statuses.Mark(remaining, engine.DependencyPhaseError, fmt.Sprintf("actions failed to apply, see apply log of revision %d", revisionGen))
+	}
+
+	err = server.store.UpdateDependencyStatuses(statuses)
+	if err != nil {
+		return fmt.Errorf("error while updating dependency statuses: %s", err)
+	}
+	return nil
+}
+
+// holdPausedActions splits the action plan according to the paused state of the enforcer. It returns the plan of
+// actions which can be applied and the plan of actions which have been held back (actions on component instances in
+// paused scopes, as well as all actions which depend on them). Held actions get recorded in the store
+func (server *Server) holdPausedActions(plan *action.Plan, desiredState *resolve.PolicyResolution, actualState *resolve.PolicyResolution) (*action.Plan, *action.Plan, error) {
+	pause, err := server.store.GetEnforcerPause()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting enforcer pause: %s", err)
+	}
+
+	if !pause.IsPaused() {
+		// clean up actions recorded while the enforcer was paused
+		if pause.PendingActions > 0 || len(pause.PendingInstances) > 0 {
+			err = server.store.RecordPendingActions(0, nil)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error while recording pending actions: %s", err)
+			}
+		}
+		return plan, action.NewPlan(), nil
+	}
+
+	runnable, held := plan.Split(func(key string) bool {
+		instance := desiredState.ComponentInstanceMap[key]
+		if instance == nil {
+			instance = actualState.ComponentInstanceMap[key]
+		}
+		if instance == nil {
+			return false
+		}
+		return pause.IsPausedFor(instance.GetCluster(), instance.Metadata.Key.Namespace)
+	})
+
+	heldInstances := []string{}
+	for key, node := range held.NodeMap {
+		if len(node.Actions) > 0 {
+			heldInstances = append(heldInstances, key)
+		}
+	}
+	sort.Strings(heldInstances)
+
+	heldCnt := held.NumberOfActions()
+	err = server.store.RecordPendingActions(heldCnt, heldInstances)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while recording pending actions: %s", err)
+	}
+	log.Infof("(enforce-%d) Enforcer is paused (%s), %d actions held back", server.enforcementIdx, strings.Join(util.GetSortedStringKeys(pause.Paused), ", "), heldCnt)
+
+	return runnable, held, nil
+}
+
+// completeDependencyMigrations removes processed migration requests, so that migrated dependencies stay sticky in
+// their new placement
+func (server *Server) completeDependencyMigrations(migrate map[string]bool) error {
+	if len(migrate) <= 0 {
+		return nil
+	}
+	err := server.store.CompleteDependencyMigration(util.GetSortedStringKeys(migrate))
+	if err != nil {
+		return fmt.Errorf("error while completing dependency migrations: %s", err)
+	}
+	log.Infof("(enforce-%d) Dependency migrations completed: %d", server.enforcementIdx, len(migrate))
 	return nil
 }
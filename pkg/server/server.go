@@ -1,14 +1,17 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"github.com/Aptomi/aptomi/pkg/api"
 	"github.com/Aptomi/aptomi/pkg/api/middleware"
 	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/external"
 	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/external/users"
 	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/plugin"
 	"github.com/Aptomi/aptomi/pkg/plugin/fake"
 	"github.com/Aptomi/aptomi/pkg/plugin/helm"
@@ -19,6 +22,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/runtime/store/core"
 	"github.com/Aptomi/aptomi/pkg/runtime/store/generic/bolt"
 	"github.com/Aptomi/aptomi/pkg/server/ui"
+	"github.com/Aptomi/aptomi/pkg/trigger"
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/handlers"
 	"github.com/julienschmidt/httprouter"
@@ -27,6 +31,7 @@ import (
 	"os/signal"
 	"runtime/pprof"
 	"runtime/trace"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -42,8 +47,26 @@ type Server struct {
 
 	httpServer *http.Server
 
-	runEnforcement chan bool
+	// triggers is a queue of enforcement triggers from policy updates, API calls and watchers of external data
+	triggers       *trigger.Queue
 	enforcementIdx uint
+
+	// resolutionCache keeps results of dependency resolution between enforcement cycles
+	resolutionCache *resolve.ResolutionCache
+
+	// lastResolution keeps the desired state calculated during the last enforcement cycle, so the API can reuse it
+	lastResolution *resolve.LastResolution
+
+	// resolverOptions are server-wide settings for policy resolution, which come from the config
+	resolverOptions *resolve.Options
+
+	// cancelEnforcement receives generations of revisions to cancel. If revision is being applied, it gets cancelled
+	// right away. Otherwise cancellation stays pending until the revision starts to be applied
+	cancelEnforcement        chan runtime.Generation
+	enforcementRevision      runtime.Generation
+	enforcementCancel        context.CancelFunc
+	enforcementCancelPending runtime.Generation
+	enforcementCancelMutex   sync.Mutex
 }
 
 // NewServer creates a new Aptomi Server
@@ -51,7 +74,11 @@ func NewServer(cfg *config.Server) *Server {
 	s := &Server{
 		cfg:              cfg,
 		backgroundErrors: make(chan string),
-		runEnforcement:   make(chan bool, 2048),
+		triggers:         trigger.NewQueue(cfg.Enforcer.Debounce, cfg.Enforcer.MaxDelay),
+		resolutionCache:  resolve.NewResolutionCache(),
+		lastResolution:   resolve.NewLastResolution(),
+
+		cancelEnforcement: make(chan runtime.Generation, 2048),
 	}
 
 	return s
@@ -64,6 +91,7 @@ func (server *Server) Start() {
 	server.initProfiling()
 	server.initStore()
 	server.initExternalData()
+	server.initPasswordSeed()
 	server.initPluginRegistryFactory()
 	server.initPolicyOnFirstRun()
 
@@ -99,9 +127,29 @@ func (server *Server) initExternalData() {
 	for _, file := range server.cfg.Users.File {
 		userLoaders = append(userLoaders, users.NewUserLoaderFromFile(file, server.cfg.DomainAdminOverrides))
 	}
+	server.resolverOptions = &resolve.Options{}
+	if len(server.cfg.Redaction.KeyPatterns) > 0 {
+		keyPatterns, err := secrets.CompileSensitiveKeyPatterns(server.cfg.Redaction.KeyPatterns)
+		if err != nil {
+			panic(fmt.Sprintf("can't configure redaction of sensitive values: %s", err))
+		}
+		server.resolverOptions.SensitiveKeyPatterns = keyPatterns
+	}
+	missingParamsMode, err := expression.ParseMissingParamsMode(server.cfg.Expressions.MissingParams)
+	if err != nil {
+		panic(fmt.Sprintf("can't configure evaluation of expressions: %s", err))
+	}
+	server.resolverOptions.MissingParamsMode = missingParamsMode
+
+	var secretLoader secrets.SecretLoader
+	if server.cfg.SecretsVault != nil {
+		secretLoader = secrets.NewSecretLoaderFromVault(*server.cfg.SecretsVault)
+	} else {
+		secretLoader = secrets.NewSecretLoaderFromDir(server.cfg.SecretsDir)
+	}
 	server.externalData = external.NewData(
 		users.NewUserLoaderMultipleSources(userLoaders),
-		secrets.NewSecretLoaderFromDir(server.cfg.SecretsDir),
+		secretLoader,
 	)
 }
 
@@ -161,6 +209,17 @@ func (server *Server) initStore() {
 	server.store = core.NewStore(b)
 }
 
+func (server *Server) initPasswordSeed() {
+	seed, err := server.store.GetPasswordSeed()
+	if err != nil {
+		panic(fmt.Sprintf("can't get password seed: %s", err))
+	}
+	if len(seed) <= 0 {
+		panic("password seed is empty, refusing to start (generated passwords would be predictable)")
+	}
+	server.resolverOptions.RandomKey = seed
+}
+
 func (server *Server) initPluginRegistryFactory() {
 	server.pluginRegistryFactory = func() plugin.Registry {
 		clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
@@ -205,7 +264,7 @@ func (server *Server) startHTTPServer() {
 		log.Warnf("The auth.secret not specified in config, using insecure default one")
 	}
 
-	api.Serve(router, server.store, server.externalData, server.pluginRegistryFactory, server.cfg.Auth.Secret, server.cfg.GetLogLevel(), server.runEnforcement)
+	api.Serve(router, server.store, server.externalData, server.pluginRegistryFactory, server.resolverOptions, server.cfg.Auth.Secret, server.cfg.API.TrustedProxies, server.cfg.GetLogLevel(), server.triggers, server.cancelEnforcement, server.lastResolution)
 	server.serveUI(router)
 
 	var handler http.Handler = router
@@ -259,5 +318,9 @@ func (server *Server) startEnforcer() {
 		server.runInBackground("Policy Enforcer", true, func() {
 			panic(server.enforceLoop())
 		})
+		server.runInBackground("Policy Enforcer Cancellation", true, func() {
+			panic(server.cancelLoop())
+		})
+		server.startWatchers()
 	}
 }
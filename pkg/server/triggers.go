@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/trigger"
+	log "github.com/Sirupsen/logrus"
+)
+
+// startWatchers starts background jobs, which check users, secrets and cluster health for changes and trigger
+// enforcement when they change
+func (server *Server) startWatchers() {
+	interval := server.cfg.Enforcer.WatchInterval
+	if interval <= 0 {
+		return
+	}
+
+	watchers := []*trigger.Watcher{
+		trigger.NewWatcher(trigger.SourceUsers, trigger.UsersFingerprint(server.externalData.UserLoader), server.triggers),
+		trigger.NewWatcher(trigger.SourceSecrets, trigger.SecretsFingerprint(server.externalData.UserLoader, server.externalData.SecretLoader, server.policyScopes), server.triggers),
+		trigger.NewWatcher(trigger.SourceClusters, trigger.ClustersFingerprint(server.policyClusters, server.pluginRegistryFactory), server.triggers),
+	}
+	for _, watcher := range watchers {
+		w := watcher
+		server.runInBackground(fmt.Sprintf("Enforcement Trigger Watcher (%s)", w.Source()), true, func() {
+			w.Run(interval, nil, func(err error) {
+				log.Warnf("Enforcement trigger watcher: %s", err)
+			})
+		})
+	}
+}
+
+// Returns scopes of all secrets which can be referred to from the latest policy
+func (server *Server) policyScopes() ([]secrets.Scope, error) {
+	policy, err := server.latestPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return trigger.PolicyScopes(policy), nil
+}
+
+// Returns all clusters defined in the latest policy
+func (server *Server) policyClusters() ([]*lang.Cluster, error) {
+	policy, err := server.latestPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return trigger.PolicyClusters(policy), nil
+}
+
+func (server *Server) latestPolicy() (*lang.Policy, error) {
+	policy, _, err := server.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting latest policy: %s", err)
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("latest policy does not exist in the store")
+	}
+	return policy, nil
+}
@@ -0,0 +1,4 @@
+// Package trigger implements triggers for policy enforcement. Changes in the store, changes in external data (users,
+// secrets), changes in cluster health and explicit API calls request enforcement through a queue, which debounces and
+// coalesces these requests, so that a burst of changes results in a single enforcement cycle.
+package trigger
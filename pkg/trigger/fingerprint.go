@@ -0,0 +1,116 @@
+package trigger
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
+	"github.com/Aptomi/aptomi/pkg/external/users"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"strings"
+)
+
+// UsersFingerprint returns a fingerprint function for all users, their labels and domain admin flags. If the user
+// loader caches user data, it gets refreshed first, so that changes are picked up right away
+func UsersFingerprint(loader users.UserLoader) FingerprintFunc {
+	return func() (string, error) {
+		if refresher, ok := loader.(users.Refresher); ok {
+			err := refresher.Refresh()
+			if err != nil {
+				return "", err
+			}
+		}
+
+		allUsers := loader.LoadUsersAll().Users
+		result := []string{}
+		for _, name := range util.GetSortedStringKeys(allUsers) {
+			user := allUsers[name]
+			result = append(result, fmt.Sprintf("%s|%t|%s", name, user.DomainAdmin, fingerprintOfMap(user.Labels)))
+		}
+		return strings.Join(result, "\n"), nil
+	}
+}
+
+// SecretsFingerprint returns a fingerprint function for secrets of all users, as well as secrets of all scopes
+// returned by a given function. Secret values don't get stored in fingerprints, only their hash does
+func SecretsFingerprint(userLoader users.UserLoader, secretLoader secrets.SecretLoader, scopes func() ([]secrets.Scope, error)) FingerprintFunc {
+	return func() (string, error) {
+		scopeList, err := scopes()
+		if err != nil {
+			return "", err
+		}
+
+		result := []string{}
+		allUsers := userLoader.LoadUsersAll().Users
+		for _, name := range util.GetSortedStringKeys(allUsers) {
+			result = append(result, "user/"+name+"|"+fingerprintOfMap(secretLoader.LoadSecretsByUserName(name)))
+		}
+		for _, scope := range scopeList {
+			result = append(result, scope.String()+"|"+fingerprintOfMap(secretLoader.LoadSecretsByScope(scope)))
+		}
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(result, "\n")))), nil
+	}
+}
+
+// ClustersFingerprint returns a fingerprint function for health of all clusters returned by a given function. Cluster
+// is considered healthy if its plugin can be created and the cluster passes validation
+func ClustersFingerprint(clusters func() ([]*lang.Cluster, error), registryFactory plugin.RegistryFactory) FingerprintFunc {
+	return func() (string, error) {
+		clusterList, err := clusters()
+		if err != nil {
+			return "", err
+		}
+
+		registry := registryFactory()
+		result := []string{}
+		for _, cluster := range clusterList {
+			health := "ok"
+			clusterPlugin, pluginErr := registry.ForCluster(cluster)
+			if pluginErr == nil {
+				pluginErr = clusterPlugin.Validate()
+			}
+			if pluginErr != nil {
+				health = "failed"
+			}
+			result = append(result, fmt.Sprintf("%s|%s|%s", cluster.Name, cluster.GetGeneration(), health))
+		}
+		return strings.Join(result, "\n"), nil
+	}
+}
+
+// PolicyScopes returns scopes of all secrets which can be referred to from the policy (all namespaces, services and
+// clusters), sorted by their names
+func PolicyScopes(policy *lang.Policy) []secrets.Scope {
+	result := []secrets.Scope{}
+	for _, ns := range util.GetSortedStringKeys(policy.Namespace) {
+		result = append(result, secrets.NamespaceScope(ns))
+		for _, name := range util.GetSortedStringKeys(policy.Namespace[ns].Services) {
+			result = append(result, secrets.ServiceScope(ns, name))
+		}
+	}
+	for _, cluster := range PolicyClusters(policy) {
+		result = append(result, secrets.ClusterScope(cluster.Name))
+	}
+	return result
+}
+
+// PolicyClusters returns all clusters defined in the policy, sorted by their names
+func PolicyClusters(policy *lang.Policy) []*lang.Cluster {
+	result := []*lang.Cluster{}
+	if systemNS := policy.Namespace[runtime.SystemNS]; systemNS != nil {
+		for _, name := range util.GetSortedStringKeys(systemNS.Clusters) {
+			result = append(result, systemNS.Clusters[name])
+		}
+	}
+	return result
+}
+
+func fingerprintOfMap(values map[string]string) string {
+	result := []string{}
+	for _, k := range util.GetSortedStringKeys(values) {
+		result = append(result, k+"="+values[k])
+	}
+	return strings.Join(result, ",")
+}
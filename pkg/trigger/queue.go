@@ -0,0 +1,158 @@
+package trigger
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources of enforcement triggers
+const (
+	// SourcePolicy means that policy has been changed
+	SourcePolicy = "policy"
+
+	// SourceAPI means that enforcement has been explicitly requested via API (e.g. actual state reset, enforcer
+	// resumed, dependency migration requested)
+	SourceAPI = "api"
+
+	// SourceUsers means that users or their labels have been changed
+	SourceUsers = "users"
+
+	// SourceSecrets means that secrets have been changed
+	SourceSecrets = "secrets"
+
+	// SourceClusters means that health of clusters has been changed
+	SourceClusters = "clusters"
+
+	// SourceInterval means that enforcer interval has expired without any other triggers
+	SourceInterval = "interval"
+)
+
+// Queue collects enforcement triggers from different sources. Triggers get debounced and coalesced, so that a burst
+// of triggers results in a single enforcement cycle
+type Queue struct {
+	// debounce is how long to wait for more triggers to come after the last one
+	debounce time.Duration
+
+	// maxDelay is the maximum time enforcement can be delayed by debouncing, counting from the first trigger
+	maxDelay time.Duration
+
+	mutex   sync.Mutex
+	pending map[string]int
+
+	// notify gets a signal when a trigger gets enqueued (signals get coalesced as well)
+	notify chan struct{}
+}
+
+// NewQueue creates a new queue of enforcement triggers. If debounce is zero, triggers don't get debounced. If
+// maxDelay is zero, enforcement can be delayed by debouncing indefinitely, as long as triggers keep coming
+func NewQueue(debounce time.Duration, maxDelay time.Duration) *Queue {
+	return &Queue{
+		debounce: debounce,
+		maxDelay: maxDelay,
+		pending:  make(map[string]int),
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Enqueue requests enforcement on behalf of a given source. It never blocks
+func (queue *Queue) Enqueue(source string) {
+	queue.add(source)
+
+	select {
+	case queue.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Wait blocks until enforcement has been requested, or until a given interval expires, whichever comes first. Once
+// a trigger comes, it waits for more triggers until there are no triggers for the debounce period (or max delay is
+// reached). It returns the batch of all triggers, which have been coalesced
+func (queue *Queue) Wait(interval time.Duration) *Batch {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-queue.notify:
+			// signal might be left from triggers, which have already been taken out of the queue
+			if queue.isEmpty() {
+				continue
+			}
+			queue.waitForMore()
+			return queue.take()
+		case <-timer.C:
+			queue.add(SourceInterval)
+			return queue.take()
+		}
+	}
+}
+
+func (queue *Queue) add(source string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.pending[source]++
+}
+
+func (queue *Queue) isEmpty() bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return len(queue.pending) <= 0
+}
+
+// Debounces triggers, waiting until there are no new triggers for the debounce period or max delay is reached
+func (queue *Queue) waitForMore() {
+	if queue.debounce <= 0 {
+		return
+	}
+	deadline := time.Now().Add(queue.maxDelay)
+	for {
+		wait := queue.debounce
+		if queue.maxDelay > 0 {
+			if remaining := time.Until(deadline); remaining < wait {
+				wait = remaining
+			}
+		}
+		if wait <= 0 {
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-queue.notify:
+			timer.Stop()
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// Takes all pending triggers out of the queue
+func (queue *Queue) take() *Batch {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	result := &Batch{Sources: queue.pending}
+	queue.pending = make(map[string]int)
+	return result
+}
+
+// Batch is a set of triggers which have been coalesced into a single enforcement cycle
+type Batch struct {
+	// Sources is a map from trigger source to the number of times it has been triggered
+	Sources map[string]int
+}
+
+// Has returns true if a given source is present in the batch
+func (batch *Batch) Has(source string) bool {
+	return batch.Sources[source] > 0
+}
+
+// String returns a human-readable representation of the batch, e.g. 'policy(2), users(1)'
+func (batch *Batch) String() string {
+	result := []string{}
+	for _, source := range util.GetSortedStringKeys(batch.Sources) {
+		result = append(result, fmt.Sprintf("%s(%d)", source, batch.Sources[source]))
+	}
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,76 @@
+package trigger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestQueueCoalescesTriggers(t *testing.T) {
+	queue := NewQueue(0, 0)
+	queue.Enqueue(SourcePolicy)
+	queue.Enqueue(SourceUsers)
+	queue.Enqueue(SourcePolicy)
+
+	batch := queue.Wait(time.Minute)
+	assert.Equal(t, map[string]int{SourcePolicy: 2, SourceUsers: 1}, batch.Sources, "All triggers should be coalesced into a single batch")
+	assert.Equal(t, "policy(2), users(1)", batch.String(), "Batch should be printed correctly")
+
+	// nothing is left in the queue, so the next wait should end up with interval expiring
+	batch = queue.Wait(10 * time.Millisecond)
+	assert.Equal(t, map[string]int{SourceInterval: 1}, batch.Sources, "Enforcement should be triggered by interval")
+}
+
+func TestQueueDebouncesTriggers(t *testing.T) {
+	queue := NewQueue(100*time.Millisecond, time.Minute)
+	go func() {
+		for i := 0; i < 5; i++ {
+			queue.Enqueue(SourceSecrets)
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	batch := queue.Wait(time.Minute)
+	assert.Equal(t, 5, batch.Sources[SourceSecrets], "Triggers coming within debounce period should be coalesced")
+	assert.False(t, batch.Has(SourceInterval), "Enforcement should not be triggered by interval")
+}
+
+func TestQueueMaxDelay(t *testing.T) {
+	queue := NewQueue(100*time.Millisecond, 200*time.Millisecond)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				queue.Enqueue(SourceClusters)
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}()
+
+	// triggers keep coming, but enforcement should not be delayed for longer than max delay
+	start := time.Now()
+	batch := queue.Wait(time.Minute)
+	assert.True(t, batch.Has(SourceClusters), "Enforcement should be triggered by clusters")
+	assert.True(t, time.Since(start) < 5*time.Second, "Enforcement should not be delayed for longer than max delay")
+}
+
+func TestQueueIgnoresSignalsForTakenTriggers(t *testing.T) {
+	queue := NewQueue(50*time.Millisecond, 0)
+	queue.Enqueue(SourceAPI)
+
+	// trigger comes while waiting for more triggers, and its signal is left in the queue after the batch is taken
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		queue.Enqueue(SourceAPI)
+	}()
+	batch := queue.Wait(time.Minute)
+	assert.Equal(t, 2, batch.Sources[SourceAPI], "Both triggers should be in the batch")
+
+	queue.notify <- struct{}{}
+	batch = queue.Wait(50 * time.Millisecond)
+	assert.Equal(t, map[string]int{SourceInterval: 1}, batch.Sources, "Empty batch should not be returned for a stale signal")
+}
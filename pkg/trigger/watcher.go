@@ -0,0 +1,76 @@
+package trigger
+
+import (
+	"fmt"
+	"time"
+)
+
+// FingerprintFunc calculates a fingerprint of the data being watched. Fingerprint should change when data changes
+type FingerprintFunc func() (string, error)
+
+// Watcher periodically checks data which can't notify Aptomi about its changes (e.g. users in LDAP, secrets in
+// Vault or health of clusters) and enqueues a trigger when the data changes
+type Watcher struct {
+	source      string
+	fingerprint FingerprintFunc
+	queue       *Queue
+
+	last        string
+	initialized bool
+}
+
+// NewWatcher creates a new watcher, which enqueues triggers with a given source into the queue
+func NewWatcher(source string, fingerprint FingerprintFunc, queue *Queue) *Watcher {
+	return &Watcher{
+		source:      source,
+		fingerprint: fingerprint,
+		queue:       queue,
+	}
+}
+
+// Source returns the source of triggers, which watcher enqueues
+func (watcher *Watcher) Source() string {
+	return watcher.source
+}
+
+// Check calculates fingerprint of the data and enqueues a trigger if it has changed since the last check. The first
+// check only records the fingerprint. If fingerprint can't be calculated, an error is returned and the previously
+// recorded fingerprint is kept
+func (watcher *Watcher) Check() (changed bool, err error) {
+	// loaders of external data may panic if data can't be loaded, so let's convert panics into errors
+	defer func() {
+		if r := recover(); r != nil {
+			changed, err = false, fmt.Errorf("panic while checking %s for changes: %s", watcher.source, r)
+		}
+	}()
+
+	fingerprint, err := watcher.fingerprint()
+	if err != nil {
+		return false, fmt.Errorf("error while checking %s for changes: %s", watcher.source, err)
+	}
+
+	changed = watcher.initialized && fingerprint != watcher.last
+	watcher.last = fingerprint
+	watcher.initialized = true
+	if changed {
+		watcher.queue.Enqueue(watcher.source)
+	}
+	return changed, nil
+}
+
+// Run checks data for changes with a given interval, until stop channel gets closed. Errors get passed to onError
+func (watcher *Watcher) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := watcher.Check(); err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package trigger
+
+import (
+	"fmt"
+	"github.com/Aptomi/aptomi/pkg/external/secrets"
+	"github.com/Aptomi/aptomi/pkg/external/users"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWatcherCheck(t *testing.T) {
+	queue := NewQueue(0, 0)
+	value := "a"
+	var fingerprintErr error
+	watcher := NewWatcher(SourceUsers, func() (string, error) {
+		return value, fingerprintErr
+	}, queue)
+
+	// first check only records fingerprint
+	changed, err := watcher.Check()
+	assert.NoError(t, err, "Check should succeed")
+	assert.False(t, changed, "First check should not report changes")
+
+	changed, _ = watcher.Check()
+	assert.False(t, changed, "Check should not report changes if fingerprint is the same")
+
+	value = "b"
+	changed, _ = watcher.Check()
+	assert.True(t, changed, "Check should report changes if fingerprint has changed")
+	assert.True(t, queue.take().Has(SourceUsers), "Trigger should be enqueued")
+
+	// errors should not change recorded fingerprint
+	value = "c"
+	fingerprintErr = fmt.Errorf("source is not available")
+	_, err = watcher.Check()
+	assert.Error(t, err, "Check should fail")
+	fingerprintErr = nil
+	changed, _ = watcher.Check()
+	assert.True(t, changed, "Change should be reported once source is available again")
+}
+
+func TestWatcherCheckPanic(t *testing.T) {
+	watcher := NewWatcher(SourceUsers, func() (string, error) {
+		panic("ldap is down")
+	}, NewQueue(0, 0))
+
+	_, err := watcher.Check()
+	assert.Error(t, err, "Panic should be converted into an error")
+}
+
+func TestUsersAndSecretsFingerprint(t *testing.T) {
+	userLoader := users.NewUserLoaderMock()
+	user := &lang.User{Name: "alice", Labels: map[string]string{"team": "a"}}
+	userLoader.AddUser(user)
+	secretLoader := secrets.NewSecretLoaderMock()
+	secretLoader.AddSecret("alice", "token", "1")
+	scope := secrets.NamespaceScope("main")
+	secretLoader.AddScopedSecret(scope, "password", "1")
+
+	usersFingerprint := UsersFingerprint(userLoader)
+	secretsFingerprint := SecretsFingerprint(userLoader, secretLoader, func() ([]secrets.Scope, error) {
+		return []secrets.Scope{scope}, nil
+	})
+	fingerprints := func() (string, string) {
+		t.Helper()
+		u, err := usersFingerprint()
+		assert.NoError(t, err, "Users fingerprint should be calculated")
+		s, err := secretsFingerprint()
+		assert.NoError(t, err, "Secrets fingerprint should be calculated")
+		return u, s
+	}
+
+	u1, s1 := fingerprints()
+
+	// change of user labels should only change users fingerprint
+	user.Labels["team"] = "b"
+	u2, s2 := fingerprints()
+	assert.NotEqual(t, u1, u2, "Users fingerprint should change after change of labels")
+	assert.Equal(t, s1, s2, "Secrets fingerprint should not change after change of labels")
+
+	// change of user secrets and scoped secrets should change secrets fingerprint
+	secretLoader.AddSecret("alice", "token", "2")
+	_, s3 := fingerprints()
+	assert.NotEqual(t, s2, s3, "Secrets fingerprint should change after change of user secrets")
+	secretLoader.AddScopedSecret(scope, "password", "2")
+	_, s4 := fingerprints()
+	assert.NotEqual(t, s3, s4, "Secrets fingerprint should change after change of scoped secrets")
+	assert.NotContains(t, s4, "password", "Secrets fingerprint should not contain secrets")
+}
@@ -11,7 +11,9 @@ import (
 	"strings"
 )
 
-// NestedParameterMap is a nested map of parameters, which allows to work with maps [string][string]...[string] -> string, int, bool values
+// NestedParameterMap is a nested map of parameters, which allows to work with maps [string][string]...[string] -> value,
+// where value can be string, int, float, bool, null, list of values or another nested map. Keys with null values
+// get unmarshalled as empty nested maps
 type NestedParameterMap map[string]interface{}
 
 // UnmarshalYAML is a custom unmarshal function for NestedParameterMap to deal with interface{} -> string conversions
@@ -20,44 +22,55 @@ func (src *NestedParameterMap) UnmarshalYAML(unmarshal func(interface{}) error)
 	if err := unmarshal(&result); err != nil {
 		return err
 	}
-	*src = NestedParameterMap{}
-	put(result, *src, "")
+	value, err := convertValue(result)
+	if err != nil {
+		return err
+	}
+	*src = value.(NestedParameterMap)
 	return nil
 }
 
-// Takes src map of map[interface{}]interface{} and puts it into dst
-func put(src interface{}, dst NestedParameterMap, key string) {
-	if src == nil {
-		return
-	}
-
-	// If it's a map, process it recursively
-	if pMap, ok := src.(map[interface{}]interface{}); ok {
-		if len(key) > 0 {
-			dst = dst.GetNestedMap(key)
+// Takes a value produced by YAML unmarshalling and converts it into a value which can be stored in NestedParameterMap
+// (all nested maps become NestedParameterMap and all integers become int). Null values of map keys become empty maps,
+// as they always did (e.g. keys without values in policy files), so stored parameters don't change. Nulls in lists are
+// kept as is
+func convertValue(src interface{}) (interface{}, error) {
+	switch v := src.(type) {
+	case nil, string, int, bool, float64:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float32:
+		return float64(v), nil
+	case map[interface{}]interface{}:
+		result := NestedParameterMap{}
+		for pKey, pValue := range v {
+			if pValue == nil {
+				result[fmt.Sprintf("%v", pKey)] = NestedParameterMap{}
+				continue
+			}
+			value, err := convertValue(pValue)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprintf("%v", pKey)] = value
 		}
-		for pKey, pValue := range pMap {
-			dst[pKey.(string)] = NestedParameterMap{}
-			put(pValue, dst, pKey.(string))
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for idx, item := range v {
+			value, err := convertValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[idx] = value
 		}
-		return
-	}
-
-	// Otherwise, just put string value into the map
-	if srcString, ok := src.(string); ok {
-		dst[key] = srcString
-		return
-	}
-	if srcInt, ok := src.(int); ok {
-		dst[key] = srcInt
-		return
-	}
-	if srcBool, ok := src.(bool); ok {
-		dst[key] = srcBool
-		return
+		return result, nil
 	}
 
-	panic("invalid type in NestedParameterMap (expected string, int, or bool)")
+	return nil, fmt.Errorf("invalid type in NestedParameterMap (expected string, int, float, bool, null, list or map): %v", src)
 }
 
 // MakeCopy makes a shallow copy of parameter structure
@@ -145,6 +158,16 @@ func ProcessIncludeMacros(node NestedParameterMap, baseDir string) error {
 			if err != nil {
 				return err
 			}
+		} else if list, listOk := value.([]interface{}); listOk {
+			// process maps inside lists
+			for _, item := range list {
+				if nestedMap, mapOk := item.(NestedParameterMap); mapOk {
+					err := ProcessIncludeMacros(nestedMap, baseDir)
+					if err != nil {
+						return err
+					}
+				}
+			}
 		}
 	}
 
@@ -188,65 +211,52 @@ func ProcessParameterTree(tree NestedParameterMap, parameters *template.Paramete
 		cache = template.NewCache()
 	}
 
-	result := NestedParameterMap{}
-	err := processParameterTreeNode(tree, parameters, result, "", cache, mode)
-	return result, err
-}
-
-func processParameterTreeNode(node interface{}, parameters *template.Parameters, result NestedParameterMap, key string, cache *template.Cache, mode int) error {
-	if node == nil {
-		return nil
+	result, err := processParameterTreeNode(tree, parameters, cache, mode)
+	if err != nil {
+		return nil, err
 	}
+	return result.(NestedParameterMap), nil
+}
 
-	// If it's a string, evaluate template
-	if templateStr, ok := node.(string); ok {
+func processParameterTreeNode(node interface{}, parameters *template.Parameters, cache *template.Cache, mode int) (interface{}, error) {
+	switch value := node.(type) {
+	case string:
+		// If it's a string, evaluate template
 		if mode == ModeEvaluate {
-			// evaluate and store
-			evaluatedValue, err := cache.Evaluate(templateStr, parameters)
-			if err != nil {
-				return err
-			}
-
-			result[key] = evaluatedValue
+			return cache.Evaluate(value, parameters)
 		} else if mode == ModeCompile {
 			// just compile
-			_, err := template.NewTemplate(templateStr)
+			_, err := template.NewTemplate(value)
+			return value, err
+		}
+		return nil, fmt.Errorf("unknown mode: %d", mode)
+	case nil, int, bool, float64:
+		// If it's a null, int, float or bool, put as is
+		return value, nil
+	case NestedParameterMap:
+		// If it's a map, process it recursively
+		result := NestedParameterMap{}
+		for pKey, pValue := range value {
+			processed, err := processParameterTreeNode(pValue, parameters, cache, mode)
 			if err != nil {
-				return err
+				return nil, err
 			}
-		} else {
-			return fmt.Errorf("unknown mode: %d", mode)
+			result[pKey] = processed
 		}
-		return nil
-	}
-
-	// If it's a int, put as is
-	if valueInt, ok := node.(int); ok {
-		result[key] = valueInt
-		return nil
-	}
-
-	// If it's a bool, put as is
-	if valueBool, ok := node.(bool); ok {
-		result[key] = valueBool
-		return nil
-	}
-
-	// If it's a map, process it recursively
-	if paramsMap, ok := node.(NestedParameterMap); ok {
-		if len(key) > 0 {
-			result = result.GetNestedMap(key)
-		}
-		for pKey, pValue := range paramsMap {
-			result[pKey] = NestedParameterMap{}
-			err := processParameterTreeNode(pValue, parameters, result, pKey, cache, mode)
+		return result, nil
+	case []interface{}:
+		// If it's a list, process every item
+		result := make([]interface{}, len(value))
+		for idx, item := range value {
+			processed, err := processParameterTreeNode(item, parameters, cache, mode)
 			if err != nil {
-				return err
+				return nil, err
 			}
+			result[idx] = processed
 		}
-		return nil
+		return result, nil
 	}
 
 	// Unknown type, return an error
-	return fmt.Errorf("invalid type in NestedParameterMap (expected string, int, or bool): %v", node)
+	return nil, fmt.Errorf("invalid type in NestedParameterMap (expected string, int, float, bool, null, list or map): %v", node)
 }
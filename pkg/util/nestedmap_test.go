@@ -0,0 +1,100 @@
+package util
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang/template"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	"testing"
+)
+
+func TestNestedParameterMapUnmarshal(t *testing.T) {
+	data := `
+name: mysql
+replicas: 3
+ratio: 0.75
+enabled: true
+nothing:
+hosts:
+  - a.example.com
+  - b.example.com
+tolerations:
+  - key: dedicated
+    value: db
+    effect: NoSchedule
+nested:
+  list: [1, 2.5, null, [a, b]]
+`
+	params := NestedParameterMap{}
+	err := yaml.Unmarshal([]byte(data), &params)
+	if !assert.NoError(t, err, "Nested parameter map should be unmarshalled") {
+		return
+	}
+
+	expected := NestedParameterMap{
+		"name":     "mysql",
+		"replicas": 3,
+		"ratio":    0.75,
+		"enabled":  true,
+		"nothing":  NestedParameterMap{},
+		"hosts":    []interface{}{"a.example.com", "b.example.com"},
+		"tolerations": []interface{}{
+			NestedParameterMap{"key": "dedicated", "value": "db", "effect": "NoSchedule"},
+		},
+		"nested": NestedParameterMap{
+			"list": []interface{}{1, 2.5, nil, []interface{}{"a", "b"}},
+		},
+	}
+	assert.True(t, expected.DeepEqual(params), "Unmarshalled map should contain lists, floats, nulls in lists and empty maps for keys without values: %s", expected.Diff(params))
+
+	// marshalling and unmarshalling again should result in the same map
+	marshalled, err := yaml.Marshal(params)
+	if !assert.NoError(t, err, "Nested parameter map should be marshalled") {
+		return
+	}
+	paramsCopy := NestedParameterMap{}
+	err = yaml.Unmarshal(marshalled, &paramsCopy)
+	if assert.NoError(t, err, "Nested parameter map should be unmarshalled") {
+		assert.True(t, params.DeepEqual(paramsCopy), "Map should stay the same after marshalling: %s", params.Diff(paramsCopy))
+	}
+}
+
+func TestNestedParameterMapProcessParameterTree(t *testing.T) {
+	params := NestedParameterMap{
+		"hosts": []interface{}{"{{ .Name }}.example.com", "static.example.com"},
+		"env": []interface{}{
+			NestedParameterMap{"name": "NAME", "value": "{{ .Name }}"},
+		},
+		"ratio":   0.5,
+		"nothing": nil,
+	}
+
+	result, err := ProcessParameterTree(params, template.NewParams(struct{ Name string }{"db"}), nil, ModeEvaluate)
+	if !assert.NoError(t, err, "Parameter tree should be processed") {
+		return
+	}
+	expected := NestedParameterMap{
+		"hosts": []interface{}{"db.example.com", "static.example.com"},
+		"env": []interface{}{
+			NestedParameterMap{"name": "NAME", "value": "db"},
+		},
+		"ratio":   0.5,
+		"nothing": nil,
+	}
+	assert.True(t, expected.DeepEqual(result), "Templates inside lists should be evaluated: %s", expected.Diff(result))
+	assert.Equal(t, "{{ .Name }}.example.com", params["hosts"].([]interface{})[0], "Original parameter tree should not be modified")
+
+	// changes inside lists should be detected
+	changed, err := ProcessParameterTree(params, template.NewParams(struct{ Name string }{"web"}), nil, ModeEvaluate)
+	if assert.NoError(t, err, "Parameter tree should be processed") {
+		assert.False(t, result.DeepEqual(changed), "Change inside a list should be detected")
+		assert.Contains(t, result.Diff(changed), "web", "Diff should contain changed list value")
+	}
+
+	// invalid templates inside lists should be detected
+	_, err = ProcessParameterTree(NestedParameterMap{"hosts": []interface{}{"{{ bad"}}, nil, nil, ModeCompile)
+	assert.Error(t, err, "Invalid template inside a list should be detected")
+
+	// unsupported types should be reported
+	_, err = ProcessParameterTree(NestedParameterMap{"value": struct{}{}}, nil, nil, ModeCompile)
+	assert.Error(t, err, "Unsupported type should be reported")
+}
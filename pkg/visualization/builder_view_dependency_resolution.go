@@ -77,7 +77,7 @@ func (b *GraphBuilder) traceDependencyResolution(keySrc string, dependency *lang
 			svcInstNode := serviceInstanceNode{instance: instanceCurrent, service: service}
 
 			// let's see if we need to show last -> contract -> serviceInstance, or skip contract all together
-			trivialContract := len(contract.Contexts) <= 1
+			trivialContract := len(contract.GetAllContexts()) <= 1
 			if cfg.showContracts && (!trivialContract || cfg.showTrivialContracts) {
 				// show 'last' -> 'contract' -> 'serviceInstance' -> (continue)
 				b.graph.addNode(ctrNode, level)
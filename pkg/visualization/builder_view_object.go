@@ -27,23 +27,28 @@ func (b *GraphBuilder) traceContract(contract *lang.Contract, last graphNode, la
 		b.graph.addEdge(newEdge(last, ctrNode, lastLabel))
 	}
 
-	// show all contexts within a given contract
-	for _, context := range contract.Contexts {
-		// contract -> [context] as edge label -> service
-		// lookup the corresponding service
-		serviceObj, errService := b.policy.GetObject(lang.ServiceObject.Kind, context.Allocation.Service, contract.Namespace)
-		if errService != nil {
-			b.graph.addNode(errorNode{err: errService}, level)
-			continue
-		}
-		service := serviceObj.(*lang.Service)
+	// show all contexts within a given contract (across all contract versions)
+	for _, version := range contract.GetVersions() {
+		for _, context := range version.Contexts {
+			// contract -> [context] as edge label -> service
+			// lookup the corresponding service
+			serviceObj, errService := b.policy.GetObject(lang.ServiceObject.Kind, context.Allocation.Service, contract.Namespace)
+			if errService != nil {
+				b.graph.addNode(errorNode{err: errService}, level)
+				continue
+			}
+			service := serviceObj.(*lang.Service)
 
-		// context -> service
-		contextName := context.Name
-		if len(context.Allocation.Keys) > 0 {
-			contextName += " (+)"
+			// context -> service
+			contextName := context.Name
+			if len(version.Name) > 0 {
+				contextName += " @" + version.Name
+			}
+			if len(context.Allocation.Keys) > 0 {
+				contextName += " (+)"
+			}
+			b.traceService(service, ctrNode, contextName, level+1, cfg)
 		}
-		b.traceService(service, ctrNode, contextName, level+1, cfg)
 	}
 }
 
@@ -37,7 +37,7 @@ func (b *GraphBuilder) Policy(cfg *PolicyCfg) *Graph {
 }
 
 func (b *GraphBuilder) findEdgesIn(contract *lang.Contract, edgesIn map[string]int) {
-	for _, context := range contract.Contexts {
+	for _, context := range contract.GetAllContexts() {
 		serviceObj, errService := b.policy.GetObject(lang.ServiceObject.Kind, context.Allocation.Service, contract.Namespace)
 		if errService != nil {
 			continue
@@ -30,7 +30,15 @@ func (n dependencyNode) getID() string {
 }
 
 func (n dependencyNode) getLabel() string {
-	return n.dependency.Metadata.Namespace + "/" + n.dependency.Name
+	result := n.dependency.Metadata.Namespace + "/" + n.dependency.Name
+	dResolution := n.b.resolution.GetDependencyInstanceMap()[runtime.KeyForStorable(n.dependency)]
+	if dResolution != nil && len(dResolution.ContractVersion) > 0 {
+		result += fmt.Sprintf("\nversion: <i>%s</i>", html.EscapeString(dResolution.ContractVersion))
+		if len(dResolution.Deprecated) > 0 {
+			result += " (deprecated)"
+		}
+	}
+	return result
 }
 
 /*